@@ -5,15 +5,47 @@ import (
 	"log"
 	"os"
 
+	"github.com/glebarez/sqlite"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
 
 var DB *gorm.DB
 
+// Database drivers selectable with DB_DRIVER
+const (
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite"
+)
+
+// Driver returns the configured database driver, PostgreSQL by default
+func Driver() string {
+	if os.Getenv("DB_DRIVER") == DriverSQLite {
+		return DriverSQLite
+	}
+	return DriverPostgres
+}
+
 func Connect() {
 	var err error
 
+	if Driver() == DriverSQLite {
+		path := os.Getenv("SQLITE_PATH")
+		if path == "" {
+			path = "truckpe.db"
+		}
+		log.Printf("Opening SQLite database: %s", path)
+
+		DB, err = OpenSQLite(path)
+		if err != nil {
+			log.Printf("Failed to open database: %v", err)
+			panic(err)
+		}
+
+		log.Println("✅ Database connected successfully!")
+		return
+	}
+
 	// Get environment variables
 	dbUser := os.Getenv("DB_USER")
 	if dbUser == "" {
@@ -52,3 +84,15 @@ func Connect() {
 
 	log.Println("✅ Database connected successfully!")
 }
+
+// OpenSQLite opens (creating if needed) the SQLite file at path using the
+// pure-Go driver, so builds keep CGO_ENABLED=0. WAL lets readers run while a
+// write is in progress, and immediate transactions take the write lock up
+// front so concurrent bookings wait on busy_timeout instead of failing
+func OpenSQLite(path string, opts ...gorm.Option) (*gorm.DB, error) {
+	dsn := path + "?_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)&_pragma=foreign_keys(1)&_txlock=immediate"
+	if len(opts) == 0 {
+		opts = []gorm.Option{&gorm.Config{}}
+	}
+	return gorm.Open(sqlite.Open(dsn), opts...)
+}
@@ -0,0 +1,302 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Schema changes are SQL files in migrations/<driver>, named
+// NNNN_description.up.sql with a matching .down.sql that reverts them
+//
+//go:embed migrations
+var migrationFiles embed.FS
+
+// migrationLockKey is the PostgreSQL advisory lock held while migrating, so
+// instances starting together apply each migration once
+const migrationLockKey = 7239450117
+
+// Migration is one versioned schema change
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// MigrationState is a migration and when it was applied, if it has been
+type MigrationState struct {
+	Migration
+	AppliedAt *time.Time
+}
+
+// Migrations returns the migrations of a driver ordered by version
+func Migrations(driver string) ([]Migration, error) {
+	dir := path.Join("migrations", driver)
+	entries, err := fs.ReadDir(migrationFiles, dir)
+	if err != nil {
+		return nil, fmt.Errorf("no migrations for driver %q", driver)
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		name := entry.Name()
+		base, direction := strings.TrimSuffix(name, ".up.sql"), "up"
+		if strings.HasSuffix(name, ".down.sql") {
+			base, direction = strings.TrimSuffix(name, ".down.sql"), "down"
+		} else if base == name {
+			return nil, fmt.Errorf("migration %s: want NNNN_name.up.sql or NNNN_name.down.sql", name)
+		}
+
+		number, description, _ := strings.Cut(base, "_")
+		version, err := strconv.Atoi(number)
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("migration %s: invalid version %q", name, number)
+		}
+
+		body, err := fs.ReadFile(migrationFiles, path.Join(dir, name))
+		if err != nil {
+			return nil, err
+		}
+
+		m := byVersion[version]
+		if m == nil {
+			m = &Migration{Version: version, Name: description}
+			byVersion[version] = m
+		} else if m.Name != description {
+			return nil, fmt.Errorf("migration %d is named both %s and %s", version, m.Name, description)
+		}
+		if direction == "up" {
+			m.Up = string(body)
+		} else {
+			m.Down = string(body)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" || m.Down == "" {
+			return nil, fmt.Errorf("migration %04d_%s needs both an up and a down file", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// Migrate applies every pending migration in version order
+func Migrate(ctx context.Context, db *gorm.DB) error {
+	_, err := MigrateUp(ctx, db, 0)
+	return err
+}
+
+// MigrateUp applies up to steps pending migrations (all of them when steps
+// is 0) and returns the ones it applied
+func MigrateUp(ctx context.Context, db *gorm.DB, steps int) ([]Migration, error) {
+	var applied []Migration
+	err := withMigrationLock(ctx, db, func(m *migrator) error {
+		for _, migration := range m.migrations {
+			if steps > 0 && len(applied) == steps {
+				break
+			}
+			done, err := m.apply(ctx, migration)
+			if err != nil {
+				return fmt.Errorf("migration %04d_%s: %w", migration.Version, migration.Name, err)
+			}
+			if done {
+				applied = append(applied, migration)
+			}
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// MigrateDown reverts the latest steps applied migrations (1 when steps is
+// 0) and returns the ones it reverted
+func MigrateDown(ctx context.Context, db *gorm.DB, steps int) ([]Migration, error) {
+	if steps <= 0 {
+		steps = 1
+	}
+
+	var reverted []Migration
+	err := withMigrationLock(ctx, db, func(m *migrator) error {
+		for i := len(m.migrations) - 1; i >= 0 && len(reverted) < steps; i-- {
+			migration := m.migrations[i]
+			done, err := m.revert(ctx, migration)
+			if err != nil {
+				return fmt.Errorf("migration %04d_%s: %w", migration.Version, migration.Name, err)
+			}
+			if done {
+				reverted = append(reverted, migration)
+			}
+		}
+		return nil
+	})
+	return reverted, err
+}
+
+// MigrationStatus lists every migration with when it was applied
+func MigrationStatus(ctx context.Context, db *gorm.DB) ([]MigrationState, error) {
+	var states []MigrationState
+	err := withMigrationLock(ctx, db, func(m *migrator) error {
+		appliedAt, err := m.appliedAt(ctx)
+		if err != nil {
+			return err
+		}
+		for _, migration := range m.migrations {
+			state := MigrationState{Migration: migration}
+			if at, ok := appliedAt[migration.Version]; ok {
+				state.AppliedAt = &at
+			}
+			states = append(states, state)
+		}
+		return nil
+	})
+	return states, err
+}
+
+// migrator runs migrations on a single connection, which holds the advisory
+// lock on PostgreSQL
+type migrator struct {
+	conn       *sql.Conn
+	driver     string
+	migrations []Migration
+}
+
+// withMigrationLock runs fn on one connection while no other process is
+// migrating the same database. PostgreSQL uses a session advisory lock;
+// SQLite takes its write lock in each migration's transaction, where the
+// version is checked again before applying
+func withMigrationLock(ctx context.Context, db *gorm.DB, fn func(m *migrator) error) error {
+	driver := db.Dialector.Name()
+	migrations, err := Migrations(driver)
+	if err != nil {
+		return err
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if driver == DriverPostgres {
+		if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", migrationLockKey); err != nil {
+			return fmt.Errorf("acquire migration lock: %w", err)
+		}
+		defer conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", migrationLockKey)
+	}
+
+	m := &migrator{conn: conn, driver: driver, migrations: migrations}
+	if _, err := conn.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+    version bigint PRIMARY KEY,
+    name text NOT NULL,
+    applied_at timestamp NOT NULL
+)`); err != nil {
+		return fmt.Errorf("create schema_migrations: %w", err)
+	}
+	return fn(m)
+}
+
+// bind rewrites ? placeholders as $n for PostgreSQL
+func (m *migrator) bind(query string) string {
+	if m.driver != DriverPostgres {
+		return query
+	}
+	var b strings.Builder
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// isApplied reports whether a version is recorded, inside tx
+func (m *migrator) isApplied(ctx context.Context, tx *sql.Tx, version int) (bool, error) {
+	var count int
+	err := tx.QueryRowContext(ctx, m.bind("SELECT COUNT(*) FROM schema_migrations WHERE version = ?"), version).Scan(&count)
+	return count > 0, err
+}
+
+// apply runs a migration's up SQL and records it in one transaction,
+// reporting false if it was already applied
+func (m *migrator) apply(ctx context.Context, migration Migration) (bool, error) {
+	return m.inTx(ctx, func(tx *sql.Tx) (bool, error) {
+		if applied, err := m.isApplied(ctx, tx, migration.Version); err != nil || applied {
+			return false, err
+		}
+		if _, err := tx.ExecContext(ctx, migration.Up); err != nil {
+			return false, err
+		}
+		_, err := tx.ExecContext(ctx, m.bind("INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)"),
+			migration.Version, migration.Name, time.Now().UTC())
+		return err == nil, err
+	})
+}
+
+// revert runs a migration's down SQL and forgets it in one transaction,
+// reporting false if it was not applied
+func (m *migrator) revert(ctx context.Context, migration Migration) (bool, error) {
+	return m.inTx(ctx, func(tx *sql.Tx) (bool, error) {
+		if applied, err := m.isApplied(ctx, tx, migration.Version); err != nil || !applied {
+			return false, err
+		}
+		if _, err := tx.ExecContext(ctx, migration.Down); err != nil {
+			return false, err
+		}
+		_, err := tx.ExecContext(ctx, m.bind("DELETE FROM schema_migrations WHERE version = ?"), migration.Version)
+		return err == nil, err
+	})
+}
+
+func (m *migrator) inTx(ctx context.Context, fn func(tx *sql.Tx) (bool, error)) (bool, error) {
+	tx, err := m.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	done, err := fn(tx)
+	if err != nil {
+		tx.Rollback()
+		return false, err
+	}
+	return done, tx.Commit()
+}
+
+// appliedAt returns when each recorded version was applied
+func (m *migrator) appliedAt(ctx context.Context) (map[int]time.Time, error) {
+	rows, err := m.conn.QueryContext(ctx, "SELECT version, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := make(map[int]time.Time)
+	for rows.Next() {
+		var version int
+		var at time.Time
+		if err := rows.Scan(&version, &at); err != nil {
+			return nil, err
+		}
+		applied[version] = at
+	}
+	return applied, rows.Err()
+}
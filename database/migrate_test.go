@@ -0,0 +1,353 @@
+package database
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"gorm.io/gorm/schema"
+
+	"github.com/Ananth-NQI/truckpe-backend/internal/models"
+)
+
+// storedModels are the models the migrations must create tables for
+var storedModels = []interface{}{
+	&models.Trucker{},
+	&models.Load{},
+	&models.Booking{},
+	&models.WhatsAppSession{},
+	&models.Shipper{},
+	&models.TrackingLink{},
+	&models.Event{},
+	&models.WebhookEndpoint{},
+	&models.WebhookDelivery{},
+	&models.LaneAlert{},
+	&models.AlertNotification{},
+	&models.BackhaulSuggestion{},
+	&models.RecurringLoad{},
+	&models.OutboxMessage{},
+	&models.HandledEvent{},
+}
+
+var quiet = &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)}
+
+func TestMigrationFiles(t *testing.T) {
+	pg, err := Migrations(DriverPostgres)
+	if err != nil {
+		t.Fatalf("postgres migrations: %v", err)
+	}
+	lite, err := Migrations(DriverSQLite)
+	if err != nil {
+		t.Fatalf("sqlite migrations: %v", err)
+	}
+
+	// Both drivers go through the same schema versions
+	if len(pg) != len(lite) {
+		t.Fatalf("%d postgres and %d sqlite migrations, want the same", len(pg), len(lite))
+	}
+	for i := range pg {
+		if pg[i].Version != i+1 || lite[i].Version != pg[i].Version || lite[i].Name != pg[i].Name {
+			t.Fatalf("migration %d is %04d_%s (postgres) and %04d_%s (sqlite), want version %d in both",
+				i, pg[i].Version, pg[i].Name, lite[i].Version, lite[i].Name, i+1)
+		}
+	}
+}
+
+func TestMigrationsSQLite(t *testing.T) {
+	dir := t.TempDir()
+	testMigrations(t, func(t *testing.T) *gorm.DB {
+		db, err := OpenSQLite(filepath.Join(dir, "truckpe.db"), quiet)
+		if err != nil {
+			t.Fatalf("open sqlite: %v", err)
+		}
+		closeOnCleanup(t, db)
+		return db
+	})
+}
+
+// TestMigrationsPostgres runs against the throwaway database in
+// TEST_POSTGRES_DSN, which CI provides; the database is emptied first
+func TestMigrationsPostgres(t *testing.T) {
+	testMigrations(t, emptyPostgres(t))
+}
+
+// TestMigrationsAdoptBaselinePostgres migrates a database that AutoMigrate
+// created from the baseline models, as production's was, and checks its
+// rows read back through the current models
+func TestMigrationsAdoptBaselinePostgres(t *testing.T) {
+	ctx := context.Background()
+	db := emptyPostgres(t)(t)
+	if err := db.AutoMigrate(&baselineTrucker{}, &baselineLoad{}, &baselineBooking{}, &baselineSession{}, &baselineShipper{}); err != nil {
+		t.Fatalf("AutoMigrate baseline: %v", err)
+	}
+	loadingDate := time.Now().Add(48 * time.Hour).Truncate(time.Second)
+	rows := []interface{}{
+		&baselineTrucker{TruckerID: "TR1700000000001", Name: "Old", Phone: "+919800000001", VehicleNo: "MH12AB0001", Capacity: 20, Available: true},
+		&baselineShipper{ShipperID: "SH1700000000001", CompanyName: "Old", GSTNumber: "27AAAAA0001A1Z5", Phone: "+919900000001"},
+		&baselineLoad{LoadID: "LD1700000000001", ShipperID: "SH1700000000001", Weight: 10, LoadingDate: loadingDate, Status: models.LoadStatusAvailable},
+		&baselineLoad{LoadID: "LD1700000000002", ShipperID: "SH1700000000001", Weight: 12, LoadingDate: loadingDate, Status: models.LoadStatusBooked},
+		&baselineBooking{BookingID: "BK1700000000001", LoadID: "LD1700000000002", TruckerID: "TR1700000000001", Status: models.BookingStatusConfirmed},
+	}
+	for _, row := range rows {
+		if err := db.Create(row).Error; err != nil {
+			t.Fatalf("create baseline %T: %v", row, err)
+		}
+	}
+
+	if err := Migrate(ctx, db); err != nil {
+		t.Fatalf("Migrate baseline database: %v", err)
+	}
+	expectModelSchema(t, db)
+
+	var trucker models.Trucker
+	var open, booked models.Load
+	var booking models.Booking
+	for _, read := range []*gorm.DB{
+		db.First(&trucker, "trucker_id = ?", "TR1700000000001"),
+		db.First(&open, "load_id = ?", "LD1700000000001"),
+		db.First(&booked, "load_id = ?", "LD1700000000002"),
+		db.First(&booking, "booking_id = ?", "BK1700000000001"),
+	} {
+		if read.Error != nil {
+			t.Fatalf("read baseline row: %v", read.Error)
+		}
+	}
+	if !trucker.Active || trucker.Version != 1 {
+		t.Fatalf("baseline trucker = active %v, version %d; want active at version 1", trucker.Active, trucker.Version)
+	}
+	if open.Trucks() != 1 || open.TrucksBooked != 0 || open.ExpiresAt == nil || !open.ExpiresAt.Equal(loadingDate.Add(models.LoadExpiry)) {
+		t.Fatalf("baseline open load = %d/%d trucks booked, expires %v", open.TrucksBooked, open.Trucks(), open.ExpiresAt)
+	}
+	if booked.TrucksBooked != 1 || booked.BookedWeight != 12 || !booked.FullyAllocated() {
+		t.Fatalf("baseline booked load = %d trucks and %.0f tons booked, want all of it", booked.TrucksBooked, booked.BookedWeight)
+	}
+	if booking.Weight != 12 || booking.PartLoad {
+		t.Fatalf("baseline booking = %.0f tons, part load %v; want the whole load", booking.Weight, booking.PartLoad)
+	}
+}
+
+// emptyPostgres opens the throwaway database in TEST_POSTGRES_DSN, which CI
+// provides, after emptying it; it skips the test when the DSN is not set
+func emptyPostgres(t *testing.T) func(t *testing.T) *gorm.DB {
+	dsn := os.Getenv("TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("TEST_POSTGRES_DSN not set")
+	}
+	open := func(t *testing.T) *gorm.DB {
+		db, err := gorm.Open(postgres.Open(dsn), quiet)
+		if err != nil {
+			t.Fatalf("open postgres: %v", err)
+		}
+		closeOnCleanup(t, db)
+		return db
+	}
+
+	db := open(t)
+	if err := db.Exec("DROP SCHEMA public CASCADE; CREATE SCHEMA public").Error; err != nil {
+		t.Fatalf("reset database: %v", err)
+	}
+	return open
+}
+
+func testMigrations(t *testing.T, open func(t *testing.T) *gorm.DB) {
+	ctx := context.Background()
+	db := open(t)
+	migrations, err := Migrations(db.Dialector.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Instances starting together apply each migration once
+	var wg sync.WaitGroup
+	errs := make(chan error, 4)
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- Migrate(ctx, open(t))
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("concurrent Migrate: %v", err)
+		}
+	}
+	expectApplied(t, db, len(migrations))
+	expectModelSchema(t, db)
+
+	applied, err := MigrateUp(ctx, db, 0)
+	if err != nil || len(applied) != 0 {
+		t.Fatalf("MigrateUp on a current schema = %d applied, %v; want none", len(applied), err)
+	}
+
+	// Every migration can be reverted and applied again
+	reverted, err := MigrateDown(ctx, db, len(migrations))
+	if err != nil || len(reverted) != len(migrations) {
+		t.Fatalf("MigrateDown = %d reverted, %v; want %d", len(reverted), err, len(migrations))
+	}
+	expectApplied(t, db, 0)
+	for _, model := range storedModels {
+		if db.Migrator().HasTable(model) {
+			t.Fatalf("table of %T remains after reverting every migration", model)
+		}
+	}
+
+	applied, err = MigrateUp(ctx, db, 1)
+	if err != nil || len(applied) != 1 || applied[0].Version != 1 {
+		t.Fatalf("MigrateUp(1) = %v, %v; want the first migration", applied, err)
+	}
+	if err := Migrate(ctx, db); err != nil {
+		t.Fatalf("Migrate: %v", err)
+	}
+	expectModelSchema(t, db)
+
+	states, err := MigrationStatus(ctx, db)
+	if err != nil || len(states) != len(migrations) {
+		t.Fatalf("MigrationStatus = %d states, %v; want %d", len(states), err, len(migrations))
+	}
+	for _, state := range states {
+		if state.AppliedAt == nil || state.AppliedAt.IsZero() {
+			t.Fatalf("migration %04d_%s is not reported as applied", state.Version, state.Name)
+		}
+	}
+}
+
+func expectApplied(t *testing.T, db *gorm.DB, want int) {
+	t.Helper()
+	var count int64
+	if err := db.Table("schema_migrations").Count(&count).Error; err != nil {
+		t.Fatalf("count schema_migrations: %v", err)
+	}
+	if count != int64(want) {
+		t.Fatalf("schema_migrations has %d rows, want %d", count, want)
+	}
+}
+
+// expectModelSchema checks the migrated tables have every column and index
+// the models declare, so a model change without a migration fails here
+func expectModelSchema(t *testing.T, db *gorm.DB) {
+	t.Helper()
+	for _, model := range storedModels {
+		s, err := schema.Parse(model, &sync.Map{}, db.NamingStrategy)
+		if err != nil {
+			t.Fatalf("parse %T: %v", model, err)
+		}
+		if !db.Migrator().HasTable(model) {
+			t.Fatalf("no table %s for %T", s.Table, model)
+		}
+		for _, field := range s.Fields {
+			if field.DBName != "" && !db.Migrator().HasColumn(model, field.DBName) {
+				t.Fatalf("table %s has no column %s for %T.%s", s.Table, field.DBName, model, field.Name)
+			}
+		}
+		for _, index := range s.ParseIndexes() {
+			if !db.Migrator().HasIndex(model, index.Name) {
+				t.Fatalf("table %s has no index %s", s.Table, index.Name)
+			}
+		}
+	}
+}
+
+func closeOnCleanup(t *testing.T, db *gorm.DB) {
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+}
+
+// The models as they were before versioned migrations, when AutoMigrate
+// created their tables at startup
+type baselineTrucker struct {
+	gorm.Model
+	TruckerID    string `gorm:"uniqueIndex"`
+	Name         string
+	Phone        string `gorm:"uniqueIndex"`
+	AadhaarLast4 string
+	VehicleNo    string `gorm:"uniqueIndex"`
+	VehicleType  string
+	Capacity     float64
+	Verified     bool    `gorm:"default:false"`
+	Rating       float64 `gorm:"default:5.0"`
+	TotalTrips   int     `gorm:"default:0"`
+	CurrentCity  string
+	Available    bool `gorm:"default:true"`
+}
+
+type baselineLoad struct {
+	gorm.Model
+	LoadID       string `gorm:"uniqueIndex"`
+	ShipperID    string `gorm:"index"`
+	ShipperName  string
+	ShipperPhone string `gorm:"index"`
+	FromCity     string `gorm:"index"`
+	ToCity       string `gorm:"index"`
+	PickupPoint  string
+	DropPoint    string
+	Distance     float64
+	Material     string
+	Weight       float64
+	VehicleType  string `gorm:"index"`
+	Price        float64
+	PaymentTerms string
+	LoadingDate  time.Time `gorm:"index"`
+	Status       string    `gorm:"default:available;index"`
+}
+
+type baselineBooking struct {
+	gorm.Model
+	BookingID     string `gorm:"uniqueIndex"`
+	LoadID        string `gorm:"index"`
+	TruckerID     string `gorm:"index"`
+	ShipperID     string `gorm:"index"`
+	AgreedPrice   float64
+	Commission    float64
+	NetAmount     float64
+	Status        string `gorm:"default:confirmed"`
+	PaymentStatus string `gorm:"default:pending"`
+	PaymentID     string
+	OTP           string `gorm:"size:6"`
+	PodURL        string
+	ConfirmedAt   *time.Time
+	PickedUpAt    *time.Time
+	DeliveredAt   *time.Time
+	CompletedAt   *time.Time
+}
+
+type baselineSession struct {
+	gorm.Model
+	PhoneNumber string `gorm:"uniqueIndex"`
+	LastCommand string
+	Context     string
+	ExpiresAt   time.Time
+}
+
+type baselineShipper struct {
+	gorm.Model
+	ShipperID   string `gorm:"unique;not null"`
+	CompanyName string `gorm:"not null"`
+	GSTNumber   string `gorm:"unique;not null"`
+	Phone       string `gorm:"unique;not null"`
+	Email       string
+	Address     string
+	City        string
+	State       string
+	Verified    bool    `gorm:"default:false"`
+	Active      bool    `gorm:"default:true"`
+	TotalLoads  int     `gorm:"default:0"`
+	Rating      float64 `gorm:"default:5.0"`
+}
+
+func (baselineTrucker) TableName() string { return "truckers" }
+func (baselineLoad) TableName() string    { return "loads" }
+func (baselineBooking) TableName() string { return "bookings" }
+func (baselineSession) TableName() string { return "whats_app_sessions" }
+func (baselineShipper) TableName() string { return "shippers" }
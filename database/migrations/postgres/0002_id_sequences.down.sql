@@ -0,0 +1,8 @@
+DROP SEQUENCE IF EXISTS tr_id_seq;
+DROP SEQUENCE IF EXISTS sh_id_seq;
+DROP SEQUENCE IF EXISTS ld_id_seq;
+DROP SEQUENCE IF EXISTS bk_id_seq;
+DROP SEQUENCE IF EXISTS wh_id_seq;
+DROP SEQUENCE IF EXISTS wd_id_seq;
+DROP SEQUENCE IF EXISTS al_id_seq;
+DROP SEQUENCE IF EXISTS rl_id_seq;
@@ -0,0 +1,9 @@
+-- Sequences numbering public IDs such as LD0000G7, one per prefix
+CREATE SEQUENCE IF NOT EXISTS tr_id_seq;
+CREATE SEQUENCE IF NOT EXISTS sh_id_seq;
+CREATE SEQUENCE IF NOT EXISTS ld_id_seq;
+CREATE SEQUENCE IF NOT EXISTS bk_id_seq;
+CREATE SEQUENCE IF NOT EXISTS wh_id_seq;
+CREATE SEQUENCE IF NOT EXISTS wd_id_seq;
+CREATE SEQUENCE IF NOT EXISTS al_id_seq;
+CREATE SEQUENCE IF NOT EXISTS rl_id_seq;
@@ -0,0 +1,3 @@
+ALTER TABLE truckers DROP COLUMN IF EXISTS version;
+ALTER TABLE bookings DROP COLUMN IF EXISTS version;
+ALTER TABLE loads DROP COLUMN IF EXISTS version;
@@ -0,0 +1,4 @@
+-- Row versions for optimistic concurrency on loads, bookings and truckers
+ALTER TABLE loads ADD COLUMN IF NOT EXISTS version bigint NOT NULL DEFAULT 1;
+ALTER TABLE bookings ADD COLUMN IF NOT EXISTS version bigint NOT NULL DEFAULT 1;
+ALTER TABLE truckers ADD COLUMN IF NOT EXISTS version bigint NOT NULL DEFAULT 1;
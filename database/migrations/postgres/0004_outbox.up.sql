@@ -0,0 +1,12 @@
+-- Events waiting to be published, written in the same transaction as their change
+CREATE TABLE IF NOT EXISTS outbox_messages (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    idempotency_key text,
+    event_id bigint
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_outbox_messages_idempotency_key ON outbox_messages (idempotency_key);
+CREATE INDEX IF NOT EXISTS idx_outbox_messages_event_id ON outbox_messages (event_id);
+CREATE INDEX IF NOT EXISTS idx_outbox_messages_deleted_at ON outbox_messages (deleted_at);
@@ -0,0 +1,12 @@
+-- Fails if a deleted trucker or shipper has since registered again
+DROP INDEX IF EXISTS idx_shippers_gst_number;
+ALTER TABLE shippers ADD CONSTRAINT uni_shippers_gst_number UNIQUE (gst_number);
+DROP INDEX IF EXISTS idx_shippers_phone;
+ALTER TABLE shippers ADD CONSTRAINT uni_shippers_phone UNIQUE (phone);
+
+DROP INDEX IF EXISTS idx_truckers_vehicle_no;
+CREATE UNIQUE INDEX idx_truckers_vehicle_no ON truckers (vehicle_no);
+DROP INDEX IF EXISTS idx_truckers_phone;
+CREATE UNIQUE INDEX idx_truckers_phone ON truckers (phone);
+
+ALTER TABLE truckers DROP COLUMN active;
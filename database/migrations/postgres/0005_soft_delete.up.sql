@@ -0,0 +1,13 @@
+-- Account deactivation, and unique phone, vehicle and GST numbers among live
+-- rows only, so a soft-deleted trucker or shipper can register again
+ALTER TABLE truckers ADD COLUMN IF NOT EXISTS active boolean DEFAULT true;
+
+DROP INDEX IF EXISTS idx_truckers_phone;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_truckers_phone ON truckers (phone) WHERE deleted_at IS NULL;
+DROP INDEX IF EXISTS idx_truckers_vehicle_no;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_truckers_vehicle_no ON truckers (vehicle_no) WHERE deleted_at IS NULL;
+
+ALTER TABLE shippers DROP CONSTRAINT IF EXISTS uni_shippers_phone;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_shippers_phone ON shippers (phone) WHERE deleted_at IS NULL;
+ALTER TABLE shippers DROP CONSTRAINT IF EXISTS uni_shippers_gst_number;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_shippers_gst_number ON shippers (gst_number) WHERE deleted_at IS NULL;
@@ -0,0 +1,3 @@
+-- Nothing to revert: the columns and indexes are part of the 0001 schema,
+-- whose down migration drops them with their tables
+SELECT 1;
@@ -0,0 +1,41 @@
+-- Columns and indexes added to the baseline tables after the schema GORM
+-- AutoMigrate created before versioned migrations. 0001 leaves tables that
+-- already exist alone, so databases created that way get them here, and
+-- their older rows are filled in the way the stores write new ones.
+ALTER TABLE truckers ADD COLUMN IF NOT EXISTS current_city_id text;
+ALTER TABLE truckers ADD COLUMN IF NOT EXISTS active boolean DEFAULT true;
+ALTER TABLE truckers ADD COLUMN IF NOT EXISTS version bigint NOT NULL DEFAULT 1;
+
+ALTER TABLE loads ADD COLUMN IF NOT EXISTS from_city_id text;
+ALTER TABLE loads ADD COLUMN IF NOT EXISTS to_city_id text;
+ALTER TABLE loads ADD COLUMN IF NOT EXISTS truck_count bigint DEFAULT 1;
+ALTER TABLE loads ADD COLUMN IF NOT EXISTS splittable boolean;
+ALTER TABLE loads ADD COLUMN IF NOT EXISTS trucks_booked bigint;
+ALTER TABLE loads ADD COLUMN IF NOT EXISTS booked_weight decimal;
+ALTER TABLE loads ADD COLUMN IF NOT EXISTS recurring_id text;
+ALTER TABLE loads ADD COLUMN IF NOT EXISTS expires_at timestamptz;
+ALTER TABLE loads ADD COLUMN IF NOT EXISTS expiry_reminded_at timestamptz;
+ALTER TABLE loads ADD COLUMN IF NOT EXISTS version bigint NOT NULL DEFAULT 1;
+CREATE INDEX IF NOT EXISTS idx_loads_from_city_id ON loads (from_city_id);
+CREATE INDEX IF NOT EXISTS idx_loads_to_city_id ON loads (to_city_id);
+CREATE INDEX IF NOT EXISTS idx_loads_recurring_id ON loads (recurring_id);
+CREATE INDEX IF NOT EXISTS idx_loads_expires_at ON loads (expires_at);
+
+ALTER TABLE bookings ADD COLUMN IF NOT EXISTS weight decimal;
+ALTER TABLE bookings ADD COLUMN IF NOT EXISTS part_load boolean;
+ALTER TABLE bookings ADD COLUMN IF NOT EXISTS version bigint NOT NULL DEFAULT 1;
+
+-- Before multi-truck loads every load took one truck, booked for its whole
+-- weight once the load left available
+UPDATE loads SET truck_count = 1 WHERE truck_count IS NULL;
+UPDATE loads SET splittable = false WHERE splittable IS NULL;
+UPDATE loads SET
+    trucks_booked = CASE WHEN status = 'available' THEN 0 ELSE 1 END,
+    booked_weight = CASE WHEN status = 'available' THEN 0 ELSE weight END
+WHERE trucks_booked IS NULL;
+UPDATE bookings SET weight = loads.weight FROM loads WHERE loads.load_id = bookings.load_id AND bookings.weight IS NULL;
+UPDATE bookings SET part_load = false WHERE part_load IS NULL;
+
+-- Open loads expire like new ones, a day (models.LoadExpiry) after loading
+UPDATE loads SET expires_at = loading_date + interval '24 hours' WHERE expires_at IS NULL AND status = 'available';
+UPDATE truckers SET active = true WHERE active IS NULL;
@@ -0,0 +1,2 @@
+-- Row versions for optimistic concurrency on recurring loads
+ALTER TABLE recurring_loads ADD COLUMN IF NOT EXISTS version bigint NOT NULL DEFAULT 1;
@@ -0,0 +1,13 @@
+DROP TABLE IF EXISTS recurring_loads;
+DROP TABLE IF EXISTS backhaul_suggestions;
+DROP TABLE IF EXISTS alert_notifications;
+DROP TABLE IF EXISTS lane_alerts;
+DROP TABLE IF EXISTS webhook_deliveries;
+DROP TABLE IF EXISTS webhook_endpoints;
+DROP TABLE IF EXISTS events;
+DROP TABLE IF EXISTS tracking_links;
+DROP TABLE IF EXISTS shippers;
+DROP TABLE IF EXISTS whats_app_sessions;
+DROP TABLE IF EXISTS bookings;
+DROP TABLE IF EXISTS loads;
+DROP TABLE IF EXISTS truckers;
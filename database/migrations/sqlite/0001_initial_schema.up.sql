@@ -0,0 +1,308 @@
+-- Baseline schema, matching the tables GORM AutoMigrate created before
+-- versioned migrations. IF NOT EXISTS lets databases created that way
+-- adopt this migration without changes.
+CREATE TABLE IF NOT EXISTS truckers (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime,
+    updated_at datetime,
+    deleted_at datetime,
+    trucker_id text,
+    name text,
+    phone text,
+    aadhaar_last4 text,
+    vehicle_no text,
+    vehicle_type text,
+    capacity real,
+    verified numeric DEFAULT false,
+    rating real DEFAULT 5,
+    total_trips integer DEFAULT 0,
+    current_city text,
+    current_city_id text,
+    available numeric DEFAULT true
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_truckers_vehicle_no ON truckers (vehicle_no);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_truckers_phone ON truckers (phone);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_truckers_trucker_id ON truckers (trucker_id);
+CREATE INDEX IF NOT EXISTS idx_truckers_deleted_at ON truckers (deleted_at);
+
+CREATE TABLE IF NOT EXISTS loads (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime,
+    updated_at datetime,
+    deleted_at datetime,
+    load_id text,
+    shipper_id text,
+    shipper_name text,
+    shipper_phone text,
+    from_city text,
+    to_city text,
+    from_city_id text,
+    to_city_id text,
+    pickup_point text,
+    drop_point text,
+    distance real,
+    material text,
+    weight real,
+    vehicle_type text,
+    truck_count integer DEFAULT 1,
+    splittable numeric,
+    trucks_booked integer,
+    booked_weight real,
+    price real,
+    payment_terms text,
+    loading_date datetime,
+    status text DEFAULT 'available',
+    recurring_id text,
+    expires_at datetime,
+    expiry_reminded_at datetime
+);
+CREATE INDEX IF NOT EXISTS idx_loads_expires_at ON loads (expires_at);
+CREATE INDEX IF NOT EXISTS idx_loads_recurring_id ON loads (recurring_id);
+CREATE INDEX IF NOT EXISTS idx_loads_status ON loads (status);
+CREATE INDEX IF NOT EXISTS idx_loads_loading_date ON loads (loading_date);
+CREATE INDEX IF NOT EXISTS idx_loads_vehicle_type ON loads (vehicle_type);
+CREATE INDEX IF NOT EXISTS idx_loads_to_city_id ON loads (to_city_id);
+CREATE INDEX IF NOT EXISTS idx_loads_from_city_id ON loads (from_city_id);
+CREATE INDEX IF NOT EXISTS idx_loads_to_city ON loads (to_city);
+CREATE INDEX IF NOT EXISTS idx_loads_from_city ON loads (from_city);
+CREATE INDEX IF NOT EXISTS idx_loads_shipper_phone ON loads (shipper_phone);
+CREATE INDEX IF NOT EXISTS idx_loads_shipper_id ON loads (shipper_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_loads_load_id ON loads (load_id);
+CREATE INDEX IF NOT EXISTS idx_loads_deleted_at ON loads (deleted_at);
+
+CREATE TABLE IF NOT EXISTS bookings (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime,
+    updated_at datetime,
+    deleted_at datetime,
+    booking_id text,
+    load_id text,
+    trucker_id text,
+    shipper_id text,
+    agreed_price real,
+    commission real,
+    net_amount real,
+    weight real,
+    part_load numeric,
+    status text DEFAULT 'confirmed',
+    payment_status text DEFAULT 'pending',
+    payment_id text,
+    otp text,
+    pod_url text,
+    confirmed_at datetime,
+    picked_up_at datetime,
+    delivered_at datetime,
+    completed_at datetime
+);
+CREATE INDEX IF NOT EXISTS idx_bookings_shipper_id ON bookings (shipper_id);
+CREATE INDEX IF NOT EXISTS idx_bookings_trucker_id ON bookings (trucker_id);
+CREATE INDEX IF NOT EXISTS idx_bookings_load_id ON bookings (load_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_bookings_booking_id ON bookings (booking_id);
+CREATE INDEX IF NOT EXISTS idx_bookings_deleted_at ON bookings (deleted_at);
+
+CREATE TABLE IF NOT EXISTS whats_app_sessions (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime,
+    updated_at datetime,
+    deleted_at datetime,
+    phone_number text,
+    last_command text,
+    context text,
+    expires_at datetime
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_whats_app_sessions_phone_number ON whats_app_sessions (phone_number);
+CREATE INDEX IF NOT EXISTS idx_whats_app_sessions_deleted_at ON whats_app_sessions (deleted_at);
+
+CREATE TABLE IF NOT EXISTS shippers (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime,
+    updated_at datetime,
+    deleted_at datetime,
+    shipper_id text NOT NULL,
+    company_name text NOT NULL,
+    gst_number text NOT NULL,
+    phone text NOT NULL,
+    email text,
+    address text,
+    city text,
+    state text,
+    verified numeric DEFAULT false,
+    active numeric DEFAULT true,
+    total_loads integer DEFAULT 0,
+    rating real DEFAULT 5,
+    CONSTRAINT uni_shippers_shipper_id UNIQUE (shipper_id),
+    CONSTRAINT uni_shippers_gst_number UNIQUE (gst_number),
+    CONSTRAINT uni_shippers_phone UNIQUE (phone)
+);
+CREATE INDEX IF NOT EXISTS idx_shippers_deleted_at ON shippers (deleted_at);
+
+CREATE TABLE IF NOT EXISTS tracking_links (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime,
+    updated_at datetime,
+    deleted_at datetime,
+    token text,
+    booking_id text,
+    shipper_id text,
+    expires_at datetime,
+    revoked_at datetime
+);
+CREATE INDEX IF NOT EXISTS idx_tracking_links_shipper_id ON tracking_links (shipper_id);
+CREATE INDEX IF NOT EXISTS idx_tracking_links_booking_id ON tracking_links (booking_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_tracking_links_token ON tracking_links (token);
+CREATE INDEX IF NOT EXISTS idx_tracking_links_deleted_at ON tracking_links (deleted_at);
+
+CREATE TABLE IF NOT EXISTS events (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime,
+    updated_at datetime,
+    deleted_at datetime,
+    entity_type text,
+    entity_id text,
+    event_type text,
+    actor_id text,
+    channel text,
+    "before" text,
+    "after" text,
+    occurred_at datetime,
+    shipper_id text,
+    trucker_id text
+);
+CREATE INDEX IF NOT EXISTS idx_events_trucker_id ON events (trucker_id);
+CREATE INDEX IF NOT EXISTS idx_events_shipper_id ON events (shipper_id);
+CREATE INDEX IF NOT EXISTS idx_events_occurred_at ON events (occurred_at);
+CREATE INDEX IF NOT EXISTS idx_events_event_type ON events (event_type);
+CREATE INDEX IF NOT EXISTS idx_event_entity ON events (entity_type, entity_id);
+CREATE INDEX IF NOT EXISTS idx_events_deleted_at ON events (deleted_at);
+
+CREATE TABLE IF NOT EXISTS webhook_endpoints (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime,
+    updated_at datetime,
+    deleted_at datetime,
+    endpoint_id text,
+    shipper_id text,
+    url text,
+    secret text,
+    event_types text,
+    active numeric DEFAULT true
+);
+CREATE INDEX IF NOT EXISTS idx_webhook_endpoints_shipper_id ON webhook_endpoints (shipper_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_webhook_endpoints_endpoint_id ON webhook_endpoints (endpoint_id);
+CREATE INDEX IF NOT EXISTS idx_webhook_endpoints_deleted_at ON webhook_endpoints (deleted_at);
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime,
+    updated_at datetime,
+    deleted_at datetime,
+    delivery_id text,
+    endpoint_id text,
+    shipper_id text,
+    event_type text,
+    payload text,
+    status text DEFAULT 'pending',
+    attempts integer,
+    response_code integer,
+    last_error text,
+    next_attempt_at datetime,
+    delivered_at datetime
+);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_next_attempt_at ON webhook_deliveries (next_attempt_at);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_status ON webhook_deliveries (status);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_shipper_id ON webhook_deliveries (shipper_id);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_endpoint_id ON webhook_deliveries (endpoint_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_webhook_deliveries_delivery_id ON webhook_deliveries (delivery_id);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_deleted_at ON webhook_deliveries (deleted_at);
+
+CREATE TABLE IF NOT EXISTS lane_alerts (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime,
+    updated_at datetime,
+    deleted_at datetime,
+    alert_id text,
+    trucker_id text,
+    from_city text,
+    from_city_id text,
+    to_city text,
+    to_city_id text,
+    vehicle_type text,
+    min_price real,
+    active numeric DEFAULT true
+);
+CREATE INDEX IF NOT EXISTS idx_lane_alerts_active ON lane_alerts (active);
+CREATE INDEX IF NOT EXISTS idx_lane_alerts_from_city ON lane_alerts (from_city);
+CREATE INDEX IF NOT EXISTS idx_lane_alerts_trucker_id ON lane_alerts (trucker_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_lane_alerts_alert_id ON lane_alerts (alert_id);
+CREATE INDEX IF NOT EXISTS idx_lane_alerts_deleted_at ON lane_alerts (deleted_at);
+
+CREATE TABLE IF NOT EXISTS alert_notifications (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime,
+    updated_at datetime,
+    deleted_at datetime,
+    trucker_id text,
+    load_id text,
+    alert_id text,
+    sent_at datetime
+);
+CREATE INDEX IF NOT EXISTS idx_alert_notifications_sent_at ON alert_notifications (sent_at);
+CREATE INDEX IF NOT EXISTS idx_alert_notifications_load_id ON alert_notifications (load_id);
+CREATE INDEX IF NOT EXISTS idx_alert_notifications_trucker_id ON alert_notifications (trucker_id);
+CREATE INDEX IF NOT EXISTS idx_alert_notifications_deleted_at ON alert_notifications (deleted_at);
+
+CREATE TABLE IF NOT EXISTS backhaul_suggestions (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime,
+    updated_at datetime,
+    deleted_at datetime,
+    trucker_id text,
+    delivered_booking_id text,
+    load_id text,
+    "rank" integer,
+    distance_km real,
+    sent_at datetime,
+    converted_booking_id text,
+    converted_at datetime
+);
+CREATE INDEX IF NOT EXISTS idx_backhaul_suggestions_sent_at ON backhaul_suggestions (sent_at);
+CREATE INDEX IF NOT EXISTS idx_backhaul_suggestions_load_id ON backhaul_suggestions (load_id);
+CREATE INDEX IF NOT EXISTS idx_backhaul_suggestions_delivered_booking_id ON backhaul_suggestions (delivered_booking_id);
+CREATE INDEX IF NOT EXISTS idx_backhaul_suggestions_trucker_id ON backhaul_suggestions (trucker_id);
+CREATE INDEX IF NOT EXISTS idx_backhaul_suggestions_deleted_at ON backhaul_suggestions (deleted_at);
+
+CREATE TABLE IF NOT EXISTS recurring_loads (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime,
+    updated_at datetime,
+    deleted_at datetime,
+    recurring_id text,
+    shipper_id text,
+    shipper_name text,
+    shipper_phone text,
+    from_city text,
+    from_city_id text,
+    to_city text,
+    to_city_id text,
+    pickup_point text,
+    drop_point text,
+    distance real,
+    material text,
+    weight real,
+    vehicle_type text,
+    truck_count integer,
+    price real,
+    payment_terms text,
+    days text,
+    "time" text,
+    start_date datetime,
+    end_date datetime,
+    skip_dates text,
+    contract_trucker_id text,
+    paused numeric,
+    scheduled_until datetime
+);
+CREATE INDEX IF NOT EXISTS idx_recurring_loads_paused ON recurring_loads (paused);
+CREATE INDEX IF NOT EXISTS idx_recurring_loads_shipper_id ON recurring_loads (shipper_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_recurring_loads_recurring_id ON recurring_loads (recurring_id);
+CREATE INDEX IF NOT EXISTS idx_recurring_loads_deleted_at ON recurring_loads (deleted_at);
@@ -0,0 +1,6 @@
+-- Counters numbering public IDs such as LD0000G7, one row per prefix;
+-- SQLite has no sequences
+CREATE TABLE IF NOT EXISTS id_sequences (
+    prefix text PRIMARY KEY,
+    value integer NOT NULL
+);
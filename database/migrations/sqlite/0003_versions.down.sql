@@ -0,0 +1,3 @@
+ALTER TABLE truckers DROP COLUMN version;
+ALTER TABLE bookings DROP COLUMN version;
+ALTER TABLE loads DROP COLUMN version;
@@ -0,0 +1,12 @@
+-- Events waiting to be published, written in the same transaction as their change
+CREATE TABLE IF NOT EXISTS outbox_messages (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime,
+    updated_at datetime,
+    deleted_at datetime,
+    idempotency_key text,
+    event_id integer
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_outbox_messages_idempotency_key ON outbox_messages (idempotency_key);
+CREATE INDEX IF NOT EXISTS idx_outbox_messages_event_id ON outbox_messages (event_id);
+CREATE INDEX IF NOT EXISTS idx_outbox_messages_deleted_at ON outbox_messages (deleted_at);
@@ -0,0 +1,34 @@
+-- Fails if a deleted trucker or shipper has since registered again
+CREATE TABLE shippers_old (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime,
+    updated_at datetime,
+    deleted_at datetime,
+    shipper_id text NOT NULL,
+    company_name text NOT NULL,
+    gst_number text NOT NULL,
+    phone text NOT NULL,
+    email text,
+    address text,
+    city text,
+    state text,
+    verified numeric DEFAULT false,
+    active numeric DEFAULT true,
+    total_loads integer DEFAULT 0,
+    rating real DEFAULT 5,
+    CONSTRAINT uni_shippers_shipper_id UNIQUE (shipper_id),
+    CONSTRAINT uni_shippers_gst_number UNIQUE (gst_number),
+    CONSTRAINT uni_shippers_phone UNIQUE (phone)
+);
+INSERT INTO shippers_old SELECT id, created_at, updated_at, deleted_at, shipper_id, company_name, gst_number, phone,
+    email, address, city, state, verified, active, total_loads, rating FROM shippers;
+DROP TABLE shippers;
+ALTER TABLE shippers_old RENAME TO shippers;
+CREATE INDEX idx_shippers_deleted_at ON shippers (deleted_at);
+
+DROP INDEX IF EXISTS idx_truckers_vehicle_no;
+CREATE UNIQUE INDEX idx_truckers_vehicle_no ON truckers (vehicle_no);
+DROP INDEX IF EXISTS idx_truckers_phone;
+CREATE UNIQUE INDEX idx_truckers_phone ON truckers (phone);
+
+ALTER TABLE truckers DROP COLUMN active;
@@ -0,0 +1,36 @@
+-- Account deactivation, and unique phone, vehicle and GST numbers among live
+-- rows only, so a soft-deleted trucker or shipper can register again
+ALTER TABLE truckers ADD COLUMN active numeric DEFAULT true;
+
+DROP INDEX IF EXISTS idx_truckers_phone;
+CREATE UNIQUE INDEX idx_truckers_phone ON truckers (phone) WHERE deleted_at IS NULL;
+DROP INDEX IF EXISTS idx_truckers_vehicle_no;
+CREATE UNIQUE INDEX idx_truckers_vehicle_no ON truckers (vehicle_no) WHERE deleted_at IS NULL;
+
+-- SQLite cannot drop a table constraint, so the shippers table is rebuilt
+CREATE TABLE shippers_new (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime,
+    updated_at datetime,
+    deleted_at datetime,
+    shipper_id text NOT NULL,
+    company_name text NOT NULL,
+    gst_number text NOT NULL,
+    phone text NOT NULL,
+    email text,
+    address text,
+    city text,
+    state text,
+    verified numeric DEFAULT false,
+    active numeric DEFAULT true,
+    total_loads integer DEFAULT 0,
+    rating real DEFAULT 5,
+    CONSTRAINT uni_shippers_shipper_id UNIQUE (shipper_id)
+);
+INSERT INTO shippers_new SELECT id, created_at, updated_at, deleted_at, shipper_id, company_name, gst_number, phone,
+    email, address, city, state, verified, active, total_loads, rating FROM shippers;
+DROP TABLE shippers;
+ALTER TABLE shippers_new RENAME TO shippers;
+CREATE INDEX idx_shippers_deleted_at ON shippers (deleted_at);
+CREATE UNIQUE INDEX idx_shippers_phone ON shippers (phone) WHERE deleted_at IS NULL;
+CREATE UNIQUE INDEX idx_shippers_gst_number ON shippers (gst_number) WHERE deleted_at IS NULL;
@@ -0,0 +1,4 @@
+-- Nothing to adopt: SQLite support came after the baseline, so databases
+-- GORM AutoMigrate created already have every column and index the
+-- PostgreSQL migration adds
+SELECT 1;
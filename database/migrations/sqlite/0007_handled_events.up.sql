@@ -0,0 +1,8 @@
+-- Events each subscriber acted on, so it skips them when they are published again
+CREATE TABLE IF NOT EXISTS handled_events (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    consumer text,
+    idempotency_key text,
+    created_at datetime
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_handled_events_consumer_key ON handled_events (consumer, idempotency_key);
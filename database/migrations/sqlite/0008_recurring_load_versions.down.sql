@@ -0,0 +1 @@
+ALTER TABLE recurring_loads DROP COLUMN version;
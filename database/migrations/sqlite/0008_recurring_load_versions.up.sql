@@ -0,0 +1,2 @@
+-- Row versions for optimistic concurrency on recurring loads
+ALTER TABLE recurring_loads ADD COLUMN version integer NOT NULL DEFAULT 1;
@@ -0,0 +1,278 @@
+// Package cities is the master list of cities and industrial localities that
+// loads move between, with a resolver that maps the many ways people type a
+// city ("Bangalore", "Blr", "बेंगलुरु", "Bengalore") to one canonical entry.
+package cities
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+//go:embed cities.json
+var dataset []byte
+
+// City is one entry of the master list.
+// Localities (industrial areas, ports, suburbs) have the ID of their city as Parent.
+type City struct {
+	ID      string   `json:"id"` // e.g. "KA-BLR"
+	Name    string   `json:"name"`
+	State   string   `json:"state"`
+	Pincode string   `json:"pincode"`
+	Lat     float64  `json:"lat"`
+	Lng     float64  `json:"lng"`
+	Parent  string   `json:"parent,omitempty"`
+	Aliases []string `json:"aliases,omitempty"` // older names, abbreviations, Hindi and Tamil scripts
+}
+
+// IsLocality reports whether the entry is part of a larger city
+func (c *City) IsLocality() bool {
+	return c.Parent != ""
+}
+
+// Canonical returns the city a locality belongs to, or the city itself
+func (c *City) Canonical() *City {
+	if parent, ok := byID[c.Parent]; ok {
+		return parent
+	}
+	return c
+}
+
+// Names lists the name and every alias of the city
+func (c *City) Names() []string {
+	return append([]string{c.Name}, c.Aliases...)
+}
+
+var (
+	all   []*City
+	byID  = map[string]*City{}
+	byKey = map[string][]*City{} // normalized name, alias or pincode
+)
+
+func init() {
+	if err := json.Unmarshal(dataset, &all); err != nil {
+		panic(fmt.Sprintf("cities: invalid dataset: %v", err))
+	}
+
+	for _, c := range all {
+		byID[c.ID] = c
+		for _, name := range c.Names() {
+			index(normalize(name), c)
+		}
+		if c.Pincode != "" {
+			index(c.Pincode, c)
+		}
+	}
+}
+
+func index(key string, c *City) {
+	for _, existing := range byKey[key] {
+		if existing == c {
+			return
+		}
+	}
+	byKey[key] = append(byKey[key], c)
+}
+
+// All lists every city and locality in the master
+func All() []*City {
+	return all
+}
+
+// Get looks up a city by ID
+func Get(id string) (*City, bool) {
+	c, ok := byID[id]
+	return c, ok
+}
+
+// Lookup finds a city by exact name, alias or pincode, ignoring case and spacing.
+// It does not correct typos; use Resolve for user input.
+func Lookup(name string) (*City, bool) {
+	matches := byKey[normalize(name)]
+	if len(matches) != 1 {
+		return nil, false
+	}
+	return matches[0], true
+}
+
+// UnknownError is returned by Resolve when nothing in the master resembles the input
+type UnknownError struct {
+	Input string
+}
+
+func (e *UnknownError) Error() string {
+	return fmt.Sprintf("unknown city: %s", e.Input)
+}
+
+// AmbiguousError is returned by Resolve when the input is close to several cities
+type AmbiguousError struct {
+	Input      string
+	Candidates []*City
+}
+
+func (e *AmbiguousError) Error() string {
+	return fmt.Sprintf("%s matches more than one city, did you mean %s?", e.Input, e.Suggestion())
+}
+
+// Suggestion lists the candidates for a "did you mean" prompt, e.g. "Tirupati, Tiruppur or Trichy"
+func (e *AmbiguousError) Suggestion() string {
+	names := make([]string, len(e.Candidates))
+	for i, c := range e.Candidates {
+		names[i] = c.Name
+		if c.IsLocality() {
+			names[i] += " (" + c.Canonical().Name + ")"
+		}
+	}
+	if len(names) == 1 {
+		return names[0]
+	}
+	return strings.Join(names[:len(names)-1], ", ") + " or " + names[len(names)-1]
+}
+
+// maxCandidates caps the suggestions of an AmbiguousError
+const maxCandidates = 3
+
+// Resolve maps user input to a city. It tries, in order: an exact name, alias
+// or pincode; names within a small edit distance (one typo for 5-7 letters,
+// two for longer names); and names starting with the input. A single best
+// match is returned; several equally good ones give an *AmbiguousError and
+// none an *UnknownError.
+func Resolve(input string) (*City, error) {
+	key := normalize(input)
+	if key == "" {
+		return nil, &UnknownError{Input: input}
+	}
+
+	if matches := byKey[key]; len(matches) > 0 {
+		return pick(input, matches)
+	}
+
+	if maxDistance := typoTolerance(key); maxDistance > 0 {
+		best := maxDistance
+		var matches []*City
+		for name, cs := range byKey {
+			d := distance(key, name, best+1)
+			if d > best {
+				continue
+			}
+			if d < best {
+				best, matches = d, nil
+			}
+			matches = appendUnique(matches, cs...)
+		}
+		if len(matches) > 0 {
+			return pick(input, matches)
+		}
+	}
+
+	if len([]rune(key)) >= 3 {
+		var matches []*City
+		for name, cs := range byKey {
+			if strings.HasPrefix(name, key) {
+				matches = appendUnique(matches, cs...)
+			}
+		}
+		if len(matches) > 0 {
+			return pick(input, matches)
+		}
+	}
+
+	return nil, &UnknownError{Input: input}
+}
+
+// pick returns the only match, or an AmbiguousError listing the first few by name
+func pick(input string, matches []*City) (*City, error) {
+	if len(matches) == 1 {
+		return matches[0], nil
+	}
+
+	sort.Slice(matches, func(i, j int) bool { return matches[i].Name < matches[j].Name })
+	if len(matches) > maxCandidates {
+		matches = matches[:maxCandidates]
+	}
+	return nil, &AmbiguousError{Input: input, Candidates: matches}
+}
+
+func appendUnique(list []*City, cs ...*City) []*City {
+	for _, c := range cs {
+		found := false
+		for _, existing := range list {
+			if existing == c {
+				found = true
+				break
+			}
+		}
+		if !found {
+			list = append(list, c)
+		}
+	}
+	return list
+}
+
+// typoTolerance is the edit distance accepted for an input. Short names and
+// abbreviations must match exactly, as a single edit already turns "Kol" into "Kota".
+func typoTolerance(key string) int {
+	switch n := len([]rune(key)); {
+	case n <= 4:
+		return 0
+	case n <= 7:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// normalize lower-cases a name and collapses punctuation and spacing,
+// so "Navi-Mumbai", "navi mumbai" and " Navi  Mumbai." are the same key
+func normalize(s string) string {
+	s = strings.Map(func(r rune) rune {
+		if r == '-' || r == '.' || r == ',' || r == '_' {
+			return ' '
+		}
+		return unicode.ToLower(r)
+	}, s)
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// distance is the edit distance between a and b, counting an adjacent swap
+// ("Mumbia") as one edit. It works on runes so Devanagari and Tamil names work
+// too, and stops early once the distance reaches limit, returning limit.
+func distance(a, b string, limit int) int {
+	ra, rb := []rune(a), []rune(b)
+	if diff := len(ra) - len(rb); diff >= limit || -diff >= limit {
+		return limit
+	}
+
+	// Three rows of the dynamic programming table: i-2, i-1 and i
+	prev2 := make([]int, len(rb)+1)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		rowMin := curr[0]
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				curr[j] = min(curr[j], prev2[j-2]+1)
+			}
+			rowMin = min(rowMin, curr[j])
+		}
+		if rowMin >= limit {
+			return limit
+		}
+		prev2, prev, curr = prev, curr, prev2
+	}
+
+	return min(prev[len(rb)], limit)
+}
@@ -0,0 +1,155 @@
+package events
+
+import (
+	"log"
+	"sync"
+
+	"github.com/Ananth-NQI/truckpe-backend/internal/models"
+)
+
+// historySize is how many recent events are kept for Last-Event-ID replay
+const historySize = 1000
+
+// Bus is an in-process publish/subscribe hub for store events.
+// Publishing never blocks: slow subscribers miss events rather than
+// holding up the store mutation that produced them. When a durable
+// subscriber misses one, Publish reports it so the store keeps the event
+// in its outbox for the dispatcher to publish again. An event published
+// again after every durable subscriber took it, as the outbox dispatcher
+// may do, is not delivered again.
+type Bus struct {
+	mu          sync.RWMutex
+	subscribers map[int]*Subscription
+	nextID      int
+	history     []*models.Event
+	inHistory   map[string]bool // idempotency keys of the history: whether every durable subscriber took the event
+}
+
+// Subscription receives every event published after it was created
+type Subscription struct {
+	C <-chan *models.Event
+
+	id      int
+	ch      chan *models.Event
+	bus     *Bus
+	durable bool
+}
+
+// NewBus creates a new event bus
+func NewBus() *Bus {
+	return &Bus{
+		subscribers: make(map[int]*Subscription),
+		inHistory:   make(map[string]bool),
+	}
+}
+
+// Publish delivers an event to all current subscribers and reports whether
+// every durable subscriber took it. An event that one missed is delivered
+// to every subscriber again when published again, so durable subscribers
+// must skip events they already handled by their IdempotencyKey.
+func (b *Bus) Publish(event *models.Event) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var key string
+	if event.ID != 0 {
+		key = event.IdempotencyKey()
+		delivered, seen := b.inHistory[key]
+		if delivered {
+			return true
+		}
+		if !seen {
+			b.appendHistory(event)
+		}
+	} else {
+		b.appendHistory(event)
+	}
+
+	delivered := true
+	for _, sub := range b.subscribers {
+		select {
+		case sub.ch <- event:
+		default:
+			if sub.durable {
+				delivered = false
+				log.Printf("⚠️  Event bus subscriber %d is full, event %d stays in the outbox", sub.id, event.ID)
+			} else {
+				log.Printf("⚠️  Event bus subscriber %d is full, dropping event %d", sub.id, event.ID)
+			}
+		}
+	}
+	if key != "" {
+		b.inHistory[key] = delivered
+	}
+	return delivered
+}
+
+// appendHistory adds an event to the history, forgetting the oldest ones
+func (b *Bus) appendHistory(event *models.Event) {
+	if event.ID != 0 {
+		b.inHistory[event.IdempotencyKey()] = false
+	}
+	b.history = append(b.history, event)
+	if len(b.history) > historySize {
+		for _, old := range b.history[:len(b.history)-historySize] {
+			delete(b.inHistory, old.IdempotencyKey())
+		}
+		b.history = b.history[len(b.history)-historySize:]
+	}
+}
+
+// Subscribe registers a new subscriber with the given channel buffer size.
+// It misses the events published while its buffer is full.
+func (b *Bus) Subscribe(buffer int) *Subscription {
+	return b.subscribe(buffer, false)
+}
+
+// SubscribeDurable registers a subscriber that must see every event, such
+// as a service that acts on them. An event published while its buffer is
+// full stays in the store's outbox and is published again.
+func (b *Bus) SubscribeDurable(buffer int) *Subscription {
+	return b.subscribe(buffer, true)
+}
+
+func (b *Bus) subscribe(buffer int, durable bool) *Subscription {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.nextID++
+	ch := make(chan *models.Event, buffer)
+	sub := &Subscription{
+		C:       ch,
+		id:      b.nextID,
+		ch:      ch,
+		bus:     b,
+		durable: durable,
+	}
+	b.subscribers[sub.id] = sub
+
+	return sub
+}
+
+// Since returns buffered events with an ID greater than lastID, oldest first
+func (b *Bus) Since(lastID uint) []*models.Event {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	var events []*models.Event
+	for _, event := range b.history {
+		if event.ID > lastID {
+			events = append(events, event)
+		}
+	}
+	return events
+}
+
+// Close unsubscribes and closes the subscription channel
+func (s *Subscription) Close() {
+	s.bus.mu.Lock()
+	defer s.bus.mu.Unlock()
+
+	if _, exists := s.bus.subscribers[s.id]; exists {
+		delete(s.bus.subscribers, s.id)
+		close(s.ch)
+	}
+}
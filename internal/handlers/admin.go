@@ -0,0 +1,227 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"strings"
+
+	"github.com/Ananth-NQI/truckpe-backend/internal/models"
+	"github.com/Ananth-NQI/truckpe-backend/internal/storage"
+	"github.com/gofiber/fiber/v2"
+)
+
+// AdminHandler handles deactivating, deleting and restoring truckers,
+// shippers and loads
+type AdminHandler struct {
+	store storage.Store
+}
+
+// NewAdminHandler creates a new admin handler
+func NewAdminHandler(store storage.Store) *AdminHandler {
+	return &AdminHandler{
+		store: store,
+	}
+}
+
+// AdminAuth lets through only requests carrying key as a bearer token,
+// e.g. Authorization: Bearer <ADMIN_API_KEY>
+func AdminAuth(key string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		token, found := strings.CutPrefix(c.Get(fiber.HeaderAuthorization), "Bearer ")
+		if !found || subtle.ConstantTimeCompare([]byte(token), []byte(key)) != 1 {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "Admin API key required",
+			})
+		}
+		return c.Next()
+	}
+}
+
+// adminActor identifies an admin in the audit log. Admins may pass their
+// name in the X-Actor-ID header.
+func adminActor(c *fiber.Ctx) models.Actor {
+	id := "admin"
+	if name := c.Get("X-Actor-ID"); name != "" {
+		id = "admin:" + name
+	}
+	return models.Actor{ID: id, Channel: models.ChannelREST}
+}
+
+// DeactivateTrucker stops a trucker booking loads and cancels their bookings not yet picked up
+func (h *AdminHandler) DeactivateTrucker(c *fiber.Ctx) error {
+	id, err := parseID(models.PrefixTrucker, c.Params("id"))
+	if err != nil {
+		return err
+	}
+
+	ctx, err := ifMatch(c, "trucker")
+	if err != nil {
+		return err
+	}
+
+	trucker, err := h.store.DeactivateTrucker(ctx, id, adminActor(c))
+	if err != nil {
+		return err
+	}
+
+	setETag(c, trucker.Version)
+	return c.JSON(fiber.Map{
+		"message": "Trucker deactivated successfully",
+		"trucker": trucker,
+	})
+}
+
+// DeleteTrucker deactivates and soft-deletes a trucker
+func (h *AdminHandler) DeleteTrucker(c *fiber.Ctx) error {
+	id, err := parseID(models.PrefixTrucker, c.Params("id"))
+	if err != nil {
+		return err
+	}
+
+	ctx, err := ifMatch(c, "trucker")
+	if err != nil {
+		return err
+	}
+
+	if err := h.store.DeleteTrucker(ctx, id, adminActor(c)); err != nil {
+		return err
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Trucker deleted successfully",
+	})
+}
+
+// RestoreTrucker undeletes and reactivates a trucker
+func (h *AdminHandler) RestoreTrucker(c *fiber.Ctx) error {
+	id, err := parseID(models.PrefixTrucker, c.Params("id"))
+	if err != nil {
+		return err
+	}
+
+	trucker, err := h.store.RestoreTrucker(c.UserContext(), id, adminActor(c))
+	if err != nil {
+		return err
+	}
+
+	setETag(c, trucker.Version)
+	return c.JSON(fiber.Map{
+		"message": "Trucker restored successfully",
+		"trucker": trucker,
+	})
+}
+
+// DeactivateShipper stops a shipper posting loads and withdraws their open loads
+func (h *AdminHandler) DeactivateShipper(c *fiber.Ctx) error {
+	id, err := parseID(models.PrefixShipper, c.Params("id"))
+	if err != nil {
+		return err
+	}
+
+	shipper, err := h.store.DeactivateShipper(c.UserContext(), id, adminActor(c))
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Shipper deactivated successfully",
+		"shipper": shipper,
+	})
+}
+
+// DeleteShipper deactivates and soft-deletes a shipper
+func (h *AdminHandler) DeleteShipper(c *fiber.Ctx) error {
+	id, err := parseID(models.PrefixShipper, c.Params("id"))
+	if err != nil {
+		return err
+	}
+
+	if err := h.store.DeleteShipper(c.UserContext(), id, adminActor(c)); err != nil {
+		return err
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Shipper deleted successfully",
+	})
+}
+
+// RestoreShipper undeletes and reactivates a shipper
+func (h *AdminHandler) RestoreShipper(c *fiber.Ctx) error {
+	id, err := parseID(models.PrefixShipper, c.Params("id"))
+	if err != nil {
+		return err
+	}
+
+	shipper, err := h.store.RestoreShipper(c.UserContext(), id, adminActor(c))
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Shipper restored successfully",
+		"shipper": shipper,
+	})
+}
+
+// DeactivateLoad withdraws a load and cancels its bookings not yet picked up
+func (h *AdminHandler) DeactivateLoad(c *fiber.Ctx) error {
+	id, err := parseID(models.PrefixLoad, c.Params("id"))
+	if err != nil {
+		return err
+	}
+
+	ctx, err := ifMatch(c, "load")
+	if err != nil {
+		return err
+	}
+
+	load, err := h.store.DeactivateLoad(ctx, id, adminActor(c))
+	if err != nil {
+		return err
+	}
+
+	setETag(c, load.Version)
+	return c.JSON(fiber.Map{
+		"message": "Load deactivated successfully",
+		"load":    load,
+	})
+}
+
+// DeleteLoad withdraws and soft-deletes a load
+func (h *AdminHandler) DeleteLoad(c *fiber.Ctx) error {
+	id, err := parseID(models.PrefixLoad, c.Params("id"))
+	if err != nil {
+		return err
+	}
+
+	ctx, err := ifMatch(c, "load")
+	if err != nil {
+		return err
+	}
+
+	if err := h.store.DeleteLoad(ctx, id, adminActor(c)); err != nil {
+		return err
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Load deleted successfully",
+	})
+}
+
+// RestoreLoad undeletes a load and relists it if it was withdrawn
+func (h *AdminHandler) RestoreLoad(c *fiber.Ctx) error {
+	id, err := parseID(models.PrefixLoad, c.Params("id"))
+	if err != nil {
+		return err
+	}
+
+	load, err := h.store.RestoreLoad(c.UserContext(), id, adminActor(c))
+	if err != nil {
+		return err
+	}
+
+	setETag(c, load.Version)
+	return c.JSON(fiber.Map{
+		"message": "Load restored successfully",
+		"load":    load,
+	})
+}
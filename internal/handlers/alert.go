@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"github.com/Ananth-NQI/truckpe-backend/internal/models"
+	"github.com/Ananth-NQI/truckpe-backend/internal/services"
+	"github.com/gofiber/fiber/v2"
+)
+
+// AlertHandler handles truckers' lane alert subscriptions
+type AlertHandler struct {
+	alertService *services.AlertService
+}
+
+// NewAlertHandler creates a new alert handler
+func NewAlertHandler(alertService *services.AlertService) *AlertHandler {
+	return &AlertHandler{
+		alertService: alertService,
+	}
+}
+
+// CreateAlert subscribes a trucker to new loads on a lane.
+// Body: from_city (required), to_city, vehicle_type and min_price.
+func (h *AlertHandler) CreateAlert(c *fiber.Ctx) error {
+	var alert models.LaneAlert
+
+	if err := c.BodyParser(&alert); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	id, err := parseID(models.PrefixTrucker, c.Params("id"))
+	if err != nil {
+		return err
+	}
+
+	created, err := h.alertService.Subscribe(c.UserContext(), id, &alert)
+	if err != nil {
+		return cityError(c, err)
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"message": "Alert created successfully",
+		"alert":   created,
+	})
+}
+
+// GetAlerts lists a trucker's active lane alerts
+func (h *AlertHandler) GetAlerts(c *fiber.Ctx) error {
+	id, err := parseID(models.PrefixTrucker, c.Params("id"))
+	if err != nil {
+		return err
+	}
+
+	alerts, err := h.alertService.Alerts(c.UserContext(), id)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(fiber.Map{
+		"alerts": alerts,
+		"count":  len(alerts),
+	})
+}
+
+// DeleteAlert stops one of a trucker's lane alerts
+func (h *AlertHandler) DeleteAlert(c *fiber.Ctx) error {
+	id, err := parseID(models.PrefixTrucker, c.Params("id"))
+	if err != nil {
+		return err
+	}
+
+	if err := h.alertService.Unsubscribe(c.UserContext(), id, c.Params("alertID")); err != nil {
+		return err
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Alert deleted successfully",
+	})
+}
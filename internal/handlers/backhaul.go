@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"time"
+
+	"github.com/Ananth-NQI/truckpe-backend/internal/models"
+	"github.com/Ananth-NQI/truckpe-backend/internal/services"
+	"github.com/Ananth-NQI/truckpe-backend/internal/storage"
+	"github.com/gofiber/fiber/v2"
+)
+
+// BackhaulHandler handles return-load suggestions
+type BackhaulHandler struct {
+	store           storage.Store
+	backhaulService *services.BackhaulService
+}
+
+// NewBackhaulHandler creates a new backhaul handler
+func NewBackhaulHandler(store storage.Store, backhaulService *services.BackhaulService) *BackhaulHandler {
+	return &BackhaulHandler{
+		store:           store,
+		backhaulService: backhaulService,
+	}
+}
+
+// GetSuggestions lists return loads for a trucker near ?city=, or near their current city
+func (h *BackhaulHandler) GetSuggestions(c *fiber.Ctx) error {
+	id, err := parseID(models.PrefixTrucker, c.Params("id"))
+	if err != nil {
+		return err
+	}
+
+	trucker, err := h.store.GetTrucker(c.UserContext(), id)
+	if err != nil {
+		return err
+	}
+
+	city, err := models.ResolveCity(c.Query("city", trucker.CurrentCity))
+	if err != nil {
+		return cityError(c, err)
+	}
+	if city.Name == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "City is required when the trucker's location is unknown",
+		})
+	}
+
+	suggestions, err := h.backhaulService.Suggest(c.UserContext(), trucker, city.Name)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(fiber.Map{
+		"city":        city.Name,
+		"suggestions": suggestions,
+		"count":       len(suggestions),
+	})
+}
+
+// GetStats reports the conversion of suggestions sent since ?from= (YYYY-MM-DD, default the last 30 days)
+func (h *BackhaulHandler) GetStats(c *fiber.Ctx) error {
+	since := time.Now().AddDate(0, 0, -30)
+	if from := c.Query("from"); from != "" {
+		date, err := time.ParseInLocation("2006-01-02", from, time.Local)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid from date, use YYYY-MM-DD",
+			})
+		}
+		since = date
+	}
+
+	stats, err := h.backhaulService.Stats(c.UserContext(), since)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(stats)
+}
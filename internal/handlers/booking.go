@@ -21,8 +21,9 @@ func NewBookingHandler(store storage.Store) *BookingHandler { // Changed paramet
 // CreateBooking handles creating a new booking
 func (h *BookingHandler) CreateBooking(c *fiber.Ctx) error {
 	var req struct {
-		LoadID    string `json:"load_id"`
-		TruckerID string `json:"trucker_id"`
+		LoadID    string  `json:"load_id"`
+		TruckerID string  `json:"trucker_id"`
+		Weight    float64 `json:"weight"` // tons of a part load, 0 for as much as fits
 	}
 
 	if err := c.BodyParser(&req); err != nil {
@@ -37,35 +38,25 @@ func (h *BookingHandler) CreateBooking(c *fiber.Ctx) error {
 			"error": "Load ID and Trucker ID are required",
 		})
 	}
+	if req.Weight < 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Weight cannot be negative",
+		})
+	}
+
+	loadID, err := parseID(models.PrefixLoad, req.LoadID)
+	if err != nil {
+		return err
+	}
+	truckerID, err := parseID(models.PrefixTrucker, req.TruckerID)
+	if err != nil {
+		return err
+	}
 
 	// Create booking
-	booking, err := h.store.CreateBooking(req.LoadID, req.TruckerID)
-	if err != nil {
-		// Handle specific errors
-		if err.Error() == "load not found" {
-			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-				"error": "Load not found",
-			})
-		}
-		if err.Error() == "trucker not found" {
-			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-				"error": "Trucker not found",
-			})
-		}
-		if err.Error() == "load not available" {
-			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
-				"error": "Load is not available for booking",
-			})
-		}
-		if err.Error() == "trucker not available" {
-			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
-				"error": "Trucker is not available",
-			})
-		}
-
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to create booking",
-		})
+	booking, err := h.store.CreateBooking(c.UserContext(), loadID, truckerID, req.Weight, restActor(c))
+	if err != nil {
+		return err
 	}
 
 	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
@@ -83,17 +74,22 @@ func (h *BookingHandler) GetBooking(c *fiber.Ctx) error {
 		})
 	}
 
-	booking, err := h.store.GetBooking(id)
+	id, err := parseID(models.PrefixBooking, id)
 	if err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-			"error": "Booking not found",
-		})
+		return err
 	}
 
+	booking, err := h.store.GetBooking(c.UserContext(), id)
+	if err != nil {
+		return err
+	}
+
+	setETag(c, booking.Version)
 	return c.JSON(booking)
 }
 
-// GetTruckerBookings retrieves all bookings for a trucker
+// GetTruckerBookings retrieves a page of bookings for a trucker.
+// Supports limit, cursor, sort (price, created_at), order and status query params.
 func (h *BookingHandler) GetTruckerBookings(c *fiber.Ctx) error {
 	truckerID := c.Params("truckerID")
 	if truckerID == "" {
@@ -102,20 +98,30 @@ func (h *BookingHandler) GetTruckerBookings(c *fiber.Ctx) error {
 		})
 	}
 
-	bookings, err := h.store.GetBookingsByTrucker(truckerID)
+	truckerID, err := parseID(models.PrefixTrucker, truckerID)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to retrieve bookings",
-		})
+		return err
+	}
+
+	opts := listOptions(c)
+	if err := opts.Normalize(models.BookingSorts); err != nil {
+		return err
+	}
+
+	page, err := h.store.ListBookings(c.UserContext(), &models.BookingFilter{TruckerID: truckerID, Status: c.Query("status")}, opts)
+	if err != nil {
+		return err
 	}
 
 	return c.JSON(fiber.Map{
-		"bookings": bookings,
-		"count":    len(bookings),
+		"bookings":    page.Bookings,
+		"count":       len(page.Bookings),
+		"next_cursor": page.NextCursor,
 	})
 }
 
-// GetLoadBookings retrieves all bookings for a load
+// GetLoadBookings retrieves a page of bookings for a load.
+// Supports limit, cursor, sort (price, created_at), order and status query params.
 func (h *BookingHandler) GetLoadBookings(c *fiber.Ctx) error {
 	loadID := c.Params("loadID")
 	if loadID == "" {
@@ -124,16 +130,25 @@ func (h *BookingHandler) GetLoadBookings(c *fiber.Ctx) error {
 		})
 	}
 
-	bookings, err := h.store.GetBookingsByLoad(loadID)
+	loadID, err := parseID(models.PrefixLoad, loadID)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to retrieve bookings",
-		})
+		return err
+	}
+
+	opts := listOptions(c)
+	if err := opts.Normalize(models.BookingSorts); err != nil {
+		return err
+	}
+
+	page, err := h.store.ListBookings(c.UserContext(), &models.BookingFilter{LoadID: loadID, Status: c.Query("status")}, opts)
+	if err != nil {
+		return err
 	}
 
 	return c.JSON(fiber.Map{
-		"bookings": bookings,
-		"count":    len(bookings),
+		"bookings":    page.Bookings,
+		"count":       len(page.Bookings),
+		"next_cursor": page.NextCursor,
 	})
 }
 
@@ -146,6 +161,11 @@ func (h *BookingHandler) UpdateBookingStatus(c *fiber.Ctx) error {
 		})
 	}
 
+	id, err := parseID(models.PrefixBooking, id)
+	if err != nil {
+		return err
+	}
+
 	var req struct {
 		Status string `json:"status"`
 	}
@@ -171,13 +191,99 @@ func (h *BookingHandler) UpdateBookingStatus(c *fiber.Ctx) error {
 		})
 	}
 
-	if err := h.store.UpdateBookingStatus(id, req.Status); err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-			"error": "Failed to update booking status",
-		})
+	ctx, err := ifMatch(c, "booking")
+	if err != nil {
+		return err
+	}
+
+	if err := h.store.UpdateBookingStatus(ctx, id, req.Status, restActor(c)); err != nil {
+		return err
 	}
 
+	booking, err := h.store.GetBooking(c.UserContext(), id)
+	if err != nil {
+		return err
+	}
+
+	setETag(c, booking.Version)
 	return c.JSON(fiber.Map{
 		"message": "Booking status updated successfully",
+		"booking": booking,
+	})
+}
+
+// UploadPOD records the proof of delivery document for a booking
+func (h *BookingHandler) UploadPOD(c *fiber.Ctx) error {
+	id := c.Params("id")
+	if id == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Booking ID is required",
+		})
+	}
+
+	id, err := parseID(models.PrefixBooking, id)
+	if err != nil {
+		return err
+	}
+
+	var req struct {
+		PodURL string `json:"pod_url"`
+	}
+
+	if err := c.BodyParser(&req); err != nil || req.PodURL == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "POD URL is required",
+		})
+	}
+
+	ctx, err := ifMatch(c, "booking")
+	if err != nil {
+		return err
+	}
+
+	if err := h.store.UpdateBookingPOD(ctx, id, req.PodURL, restActor(c)); err != nil {
+		return err
+	}
+
+	booking, err := h.store.GetBooking(c.UserContext(), id)
+	if err != nil {
+		return err
+	}
+
+	setETag(c, booking.Version)
+	return c.JSON(fiber.Map{
+		"message": "Proof of delivery uploaded successfully",
+		"booking": booking,
+	})
+}
+
+// GetTimeline retrieves the event history of a booking
+func (h *BookingHandler) GetTimeline(c *fiber.Ctx) error {
+	id := c.Params("id")
+	if id == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Booking ID is required",
+		})
+	}
+
+	id, err := parseID(models.PrefixBooking, id)
+	if err != nil {
+		return err
+	}
+
+	booking, err := h.store.GetBooking(c.UserContext(), id)
+	if err != nil {
+		return err
+	}
+
+	events, err := h.store.GetEvents(c.UserContext(), models.EntityBooking, booking.BookingID)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(fiber.Map{
+		"booking_id": booking.BookingID,
+		"events":     events,
+		"count":      len(events),
 	})
 }
@@ -0,0 +1,33 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/Ananth-NQI/truckpe-backend/internal/cities"
+	"github.com/Ananth-NQI/truckpe-backend/internal/models"
+	"github.com/gofiber/fiber/v2"
+)
+
+// cityError replies to a city that could not be resolved, listing the
+// candidates when the name matched more than one city. Any other error is
+// left to ErrorHandler.
+func cityError(c *fiber.Ctx, err error) error {
+	var ambiguous *cities.AmbiguousError
+	if errors.As(err, &ambiguous) {
+		suggestions := make([]fiber.Map, len(ambiguous.Candidates))
+		for i, city := range ambiguous.Candidates {
+			suggestions[i] = fiber.Map{"id": city.ID, "name": city.Name, "state": city.State}
+		}
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":       "Did you mean " + ambiguous.Suggestion() + "?",
+			"code":        "city_ambiguous",
+			"suggestions": suggestions,
+		})
+	}
+
+	var unknown *cities.UnknownError
+	if errors.As(err, &unknown) {
+		return ErrorHandler(c, models.Invalid("city", "", err.Error()))
+	}
+	return err
+}
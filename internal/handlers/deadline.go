@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	// DefaultRequestTimeout bounds a request unless REQUEST_TIMEOUT_SECONDS says otherwise
+	DefaultRequestTimeout = 10 * time.Second
+
+	// UploadRequestTimeout bounds requests that import a file of loads
+	UploadRequestTimeout = 2 * time.Minute
+)
+
+// RequestTimeout is the default request deadline, from REQUEST_TIMEOUT_SECONDS
+func RequestTimeout() time.Duration {
+	if seconds, err := strconv.Atoi(os.Getenv("REQUEST_TIMEOUT_SECONDS")); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	return DefaultRequestTimeout
+}
+
+// Deadline gives the request context (c.UserContext()) a deadline, so store
+// queries and outgoing messages still running when it passes are cancelled.
+// A Deadline on a route replaces the one set on its group rather than nesting
+// inside it.
+func Deadline(timeout time.Duration) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(c.UserContext()), timeout)
+		defer cancel()
+
+		c.SetUserContext(ctx)
+		return c.Next()
+	}
+}
@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"strings"
+
+	"github.com/Ananth-NQI/truckpe-backend/internal/models"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
+)
+
+// errorStatuses maps each domain error kind to its HTTP status
+var errorStatuses = []struct {
+	kind   error
+	status int
+}{
+	{models.ErrNotFound, fiber.StatusNotFound},
+	{models.ErrConflict, fiber.StatusConflict},
+	{models.ErrUnavailable, fiber.StatusConflict},
+	{models.ErrForbidden, fiber.StatusForbidden},
+	{models.ErrInvalid, fiber.StatusBadRequest},
+	{models.ErrExpired, fiber.StatusGone},
+	{models.ErrPreconditionFailed, fiber.StatusPreconditionFailed},
+}
+
+// ErrorHandler is the app's Fiber error handler. Handlers return domain errors
+// as they are and this replies with the matching status and a body such as
+//
+//	{"error": "load not available", "code": "load_unavailable", "entity": "load"}
+//
+// Any other error is logged and reported as an internal error.
+func ErrorHandler(c *fiber.Ctx, err error) error {
+	var domainErr *models.Error
+	if errors.As(err, &domainErr) {
+		status := fiber.StatusInternalServerError
+		for _, mapping := range errorStatuses {
+			if errors.Is(err, mapping.kind) {
+				status = mapping.status
+				break
+			}
+		}
+
+		body := fiber.Map{
+			"error": domainErr.Message,
+			"code":  domainErr.Code(),
+		}
+		if domainErr.Entity != "" {
+			body["entity"] = domainErr.Entity
+		}
+		if domainErr.Field != "" {
+			body["field"] = domainErr.Field
+		}
+		return c.Status(status).JSON(body)
+	}
+
+	var fiberErr *fiber.Error
+	if errors.As(err, &fiberErr) {
+		return c.Status(fiberErr.Code).JSON(fiber.Map{
+			"error": fiberErr.Message,
+			"code":  strings.ToLower(strings.ReplaceAll(utils.StatusMessage(fiberErr.Code), " ", "_")),
+		})
+	}
+
+	log.Printf("❌ %s %s failed: %v", c.Method(), c.Path(), err)
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		"error": "Internal server error",
+		"code":  "internal_error",
+	})
+}
@@ -0,0 +1,33 @@
+package handlers
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/Ananth-NQI/truckpe-backend/internal/models"
+	"github.com/gofiber/fiber/v2"
+)
+
+// setETag tags a response with the version of the load, booking or trucker
+// it carries, e.g. ETag: "3"
+func setETag(c *fiber.Ctx, version uint) {
+	c.Set(fiber.HeaderETag, `"`+strconv.FormatUint(uint64(version), 10)+`"`)
+}
+
+// ifMatch returns the request context, asking the store to refuse the update
+// with 412 if the entity is no longer at the version in the If-Match header.
+// Without the header, or with If-Match: *, the update is unconditional.
+func ifMatch(c *fiber.Ctx, entity string) (context.Context, error) {
+	header := strings.TrimSpace(c.Get(fiber.HeaderIfMatch))
+	if header == "" || header == "*" {
+		return c.UserContext(), nil
+	}
+
+	tag := strings.Trim(strings.TrimPrefix(header, "W/"), `"`)
+	version, err := strconv.ParseUint(tag, 10, 64)
+	if err != nil || version == 0 {
+		return nil, models.Invalid(entity, "version", `If-Match must be the entity's ETag, e.g. "3"`)
+	}
+	return models.WithExpectedVersion(c.UserContext(), uint(version)), nil
+}
@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/Ananth-NQI/truckpe-backend/internal/models"
+	"github.com/Ananth-NQI/truckpe-backend/internal/services"
+	"github.com/gofiber/fiber/v2"
+)
+
+// ExportHandler handles data export downloads
+type ExportHandler struct {
+	exportService *services.ExportService
+}
+
+// NewExportHandler creates a new export handler
+func NewExportHandler(exportService *services.ExportService) *ExportHandler {
+	return &ExportHandler{
+		exportService: exportService,
+	}
+}
+
+// Export streams the authenticated shipper's loads, bookings, payouts or invoices
+// as CSV, XLSX or JSONL. Query params: format, from and to (YYYY-MM-DD, to is
+// inclusive), trucker_id, status.
+func (h *ExportHandler) Export(c *fiber.Ctx) error {
+	dataset := c.Params("dataset")
+	format := c.Query("format", models.ExportFormatCSV)
+
+	filter := &models.ExportFilter{
+		ShipperID: authenticatedShipper(c),
+		TruckerID: c.Query("trucker_id"),
+		Status:    c.Query("status"),
+	}
+
+	if from := c.Query("from"); from != "" {
+		date, err := time.ParseInLocation("2006-01-02", from, time.Local)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid from date, use YYYY-MM-DD",
+			})
+		}
+		filter.From = &date
+	}
+	if to := c.Query("to"); to != "" {
+		date, err := time.ParseInLocation("2006-01-02", to, time.Local)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid to date, use YYYY-MM-DD",
+			})
+		}
+		// Include the whole of the last day
+		date = date.AddDate(0, 0, 1)
+		filter.To = &date
+	}
+
+	if err := h.exportService.Validate(dataset, format, filter); err != nil {
+		return err
+	}
+
+	return h.stream(c, dataset, format, filter, h.exportService.Filename(dataset, format))
+}
+
+// Statement downloads a shipper's monthly statement from a signed link sent over WhatsApp
+func (h *ExportHandler) Statement(c *fiber.Ctx) error {
+	shipperID, month, err := h.exportService.ResolveStatement(c.Params("token"))
+	if err != nil {
+		return err
+	}
+
+	filename := fmt.Sprintf("statement-%s-%s.xlsx", shipperID, month.Format("2006-01"))
+	return h.stream(c, models.ExportInvoices, models.ExportFormatXLSX, services.StatementFilter(shipperID, month), filename)
+}
+
+// stream writes the export as the response body while rows are read from the store
+func (h *ExportHandler) stream(c *fiber.Ctx, dataset, format string, filter *models.ExportFilter, filename string) error {
+	c.Attachment(filename)
+	c.Set(fiber.HeaderContentType, services.ExportContentTypes[format])
+
+	// The body is written after the handler returns, past the request deadline
+	// and after c is recycled, so the export keeps only the request's values
+	ctx := context.WithoutCancel(c.UserContext())
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		// Headers are already sent, so a failure can only cut the file short
+		if err := h.exportService.Export(ctx, w, dataset, format, filter); err != nil {
+			log.Printf("❌ Export of %s failed: %v", dataset, err)
+		}
+		w.Flush()
+	})
+
+	return nil
+}
@@ -0,0 +1,16 @@
+package handlers
+
+import (
+	"strconv"
+
+	"github.com/Ananth-NQI/truckpe-backend/internal/models"
+)
+
+// parseID validates a load, booking or trucker ID from a request and returns
+// its canonical form. Plain numbers are database IDs and pass through.
+func parseID(prefix, id string) (string, error) {
+	if _, err := strconv.ParseUint(id, 10, 64); err == nil {
+		return id, nil
+	}
+	return models.ParseID(prefix, id)
+}
@@ -0,0 +1,17 @@
+package handlers
+
+import (
+	"github.com/Ananth-NQI/truckpe-backend/internal/models"
+	"github.com/gofiber/fiber/v2"
+)
+
+// listOptions reads pagination and sorting from the query string:
+// ?limit=20&sort=price&order=asc&cursor=<next_cursor of the previous page>
+func listOptions(c *fiber.Ctx) *models.ListOptions {
+	return &models.ListOptions{
+		Cursor: c.Query("cursor"),
+		Limit:  c.QueryInt("limit"),
+		Sort:   c.Query("sort"),
+		Order:  c.Query("order"),
+	}
+}
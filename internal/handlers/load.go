@@ -1,20 +1,31 @@
 package handlers
 
 import (
+	"io"
+	"sort"
+	"time"
+
 	"github.com/Ananth-NQI/truckpe-backend/internal/models"
+	"github.com/Ananth-NQI/truckpe-backend/internal/services"
 	"github.com/Ananth-NQI/truckpe-backend/internal/storage"
 	"github.com/gofiber/fiber/v2"
 )
 
 // LoadHandler handles load-related requests
 type LoadHandler struct {
-	store storage.Store // Changed from *storage.MemoryStore to interface
+	store         storage.Store // Changed from *storage.MemoryStore to interface
+	importService *services.LoadImportService
+	expiryService *services.LoadExpiryService
+	editService   *services.LoadEditService
 }
 
 // NewLoadHandler creates a new load handler
-func NewLoadHandler(store storage.Store) *LoadHandler { // Changed parameter type
+func NewLoadHandler(store storage.Store, importService *services.LoadImportService, expiryService *services.LoadExpiryService, editService *services.LoadEditService) *LoadHandler { // Changed parameter type
 	return &LoadHandler{
-		store: store,
+		store:         store,
+		importService: importService,
+		expiryService: expiryService,
+		editService:   editService,
 	}
 }
 
@@ -29,30 +40,19 @@ func (h *LoadHandler) CreateLoad(c *fiber.Ctx) error {
 	}
 
 	// Basic validation
-	if load.FromCity == "" || load.ToCity == "" || load.Material == "" {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "From city, to city, and material are required",
-		})
+	if err := load.Validate(); err != nil {
+		return err
 	}
 
-	if load.ShipperID == "" || load.ShipperName == "" || load.ShipperPhone == "" {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Shipper details are required",
-		})
-	}
-
-	if load.Weight <= 0 || load.Price <= 0 {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Weight and price must be greater than zero",
-		})
+	// Map the route to canonical cities ("Blr" -> "Bengaluru")
+	if err := load.ResolveCities(); err != nil {
+		return cityError(c, err)
 	}
 
 	// Create load
-	createdLoad, err := h.store.CreateLoad(&load)
+	createdLoad, err := h.store.CreateLoad(c.UserContext(), &load, restActor(c))
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to create load",
-		})
+		return err
 	}
 
 	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
@@ -61,6 +61,62 @@ func (h *LoadHandler) CreateLoad(c *fiber.Ctx) error {
 	})
 }
 
+// BulkUpload creates loads from a CSV or XLSX file.
+// The file is sent as multipart field "file" (or as the raw request body) with
+// shipper_id, mode ("atomic" or "best_effort") and dry_run as form or query values.
+func (h *LoadHandler) BulkUpload(c *fiber.Ctx) error {
+	formValue := func(key string) string {
+		if value := c.FormValue(key); value != "" {
+			return value
+		}
+		return c.Query(key)
+	}
+
+	shipperID := formValue("shipper_id")
+	if shipperID == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Shipper ID is required",
+		})
+	}
+
+	var data []byte
+	if fileHeader, err := c.FormFile("file"); err == nil {
+		file, err := fileHeader.Open()
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid file",
+			})
+		}
+		defer file.Close()
+
+		data, err = io.ReadAll(io.LimitReader(file, services.MaxImportFileSize+1))
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid file",
+			})
+		}
+	} else {
+		data = c.Body()
+	}
+
+	dryRun := formValue("dry_run") == "true" || formValue("dry_run") == "1"
+
+	result, err := h.importService.Import(c.UserContext(), shipperID, data, formValue("mode"), dryRun, restActor(c))
+	if err != nil {
+		return err
+	}
+
+	status := fiber.StatusCreated
+	switch {
+	case dryRun:
+		status = fiber.StatusOK
+	case len(result.Created) == 0:
+		status = fiber.StatusUnprocessableEntity
+	}
+
+	return c.Status(status).JSON(result)
+}
+
 // GetLoad retrieves a single load by ID
 func (h *LoadHandler) GetLoad(c *fiber.Ctx) error {
 	id := c.Params("id")
@@ -70,28 +126,52 @@ func (h *LoadHandler) GetLoad(c *fiber.Ctx) error {
 		})
 	}
 
-	load, err := h.store.GetLoad(id)
+	id, err := parseID(models.PrefixLoad, id)
 	if err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-			"error": "Load not found",
-		})
+		return err
 	}
 
+	load, err := h.store.GetLoad(c.UserContext(), id)
+	if err != nil {
+		return err
+	}
+
+	setETag(c, load.Version)
 	return c.JSON(load)
 }
 
-// GetLoads retrieves all available loads
+// GetLoads retrieves a page of loads, available ones unless ?status= says otherwise (status=all for every load).
+// Supports limit, cursor, sort (price, rate_per_ton, rate_per_km, loading_date, created_at), order,
+// shipper_id, from_city, to_city and vehicle_type query params.
 func (h *LoadHandler) GetLoads(c *fiber.Ctx) error {
-	loads, err := h.store.GetAvailableLoads()
+	opts := listOptions(c)
+	if err := opts.Normalize(models.LoadSorts); err != nil {
+		return err
+	}
+
+	filter := &models.LoadFilter{
+		ShipperID:   c.Query("shipper_id"),
+		Status:      c.Query("status", models.LoadStatusAvailable),
+		FromCity:    c.Query("from_city"),
+		ToCity:      c.Query("to_city"),
+		VehicleType: c.Query("vehicle_type"),
+	}
+	if filter.Status == "all" {
+		filter.Status = ""
+	}
+	if err := filter.ResolveCities(); err != nil {
+		return cityError(c, err)
+	}
+
+	page, err := h.store.ListLoads(c.UserContext(), filter, opts)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to retrieve loads",
-		})
+		return err
 	}
 
 	return c.JSON(fiber.Map{
-		"loads": loads,
-		"count": len(loads),
+		"loads":       page.Loads,
+		"count":       len(page.Loads),
+		"next_cursor": page.NextCursor,
 	})
 }
 
@@ -105,13 +185,36 @@ func (h *LoadHandler) SearchLoads(c *fiber.Ctx) error {
 		})
 	}
 
-	results, err := h.store.SearchLoads(&search)
-	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to search loads",
+	if search.MinPrice < 0 || search.MaxPrice < 0 || search.MinWeight < 0 || search.MaxWeight < 0 || search.RadiusKm < 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Search values cannot be negative",
+		})
+	}
+
+	if search.MaterialCategory != "" && models.MaterialsInCategory(search.MaterialCategory) == nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Unknown material category",
 		})
 	}
 
+	if err := search.ResolveCities(); err != nil {
+		return cityError(c, err)
+	}
+
+	// Only show loads the trucker's truck can carry
+	if search.TruckerID != "" {
+		trucker, err := h.store.GetTrucker(c.UserContext(), search.TruckerID)
+		if err != nil {
+			return err
+		}
+		search.FitCapacity(trucker.Capacity)
+	}
+
+	results, err := h.store.SearchLoads(c.UserContext(), &search)
+	if err != nil {
+		return err
+	}
+
 	return c.JSON(fiber.Map{
 		"results": results,
 		"count":   len(results),
@@ -127,6 +230,11 @@ func (h *LoadHandler) UpdateLoadStatus(c *fiber.Ctx) error {
 		})
 	}
 
+	id, err := parseID(models.PrefixLoad, id)
+	if err != nil {
+		return err
+	}
+
 	var req struct {
 		Status string `json:"status"`
 	}
@@ -143,6 +251,8 @@ func (h *LoadHandler) UpdateLoadStatus(c *fiber.Ctx) error {
 		models.LoadStatusBooked:    true,
 		models.LoadStatusInTransit: true,
 		models.LoadStatusDelivered: true,
+		models.LoadStatusExpired:   true,
+		models.LoadStatusWithdrawn: true,
 	}
 
 	if !validStatuses[req.Status] {
@@ -151,13 +261,225 @@ func (h *LoadHandler) UpdateLoadStatus(c *fiber.Ctx) error {
 		})
 	}
 
-	if err := h.store.UpdateLoadStatus(id, req.Status); err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-			"error": "Failed to update load status",
-		})
+	ctx, err := ifMatch(c, "load")
+	if err != nil {
+		return err
+	}
+
+	if err := h.store.UpdateLoadStatus(ctx, id, req.Status, restActor(c)); err != nil {
+		return err
+	}
+
+	load, err := h.store.GetLoad(c.UserContext(), id)
+	if err != nil {
+		return err
 	}
 
+	setETag(c, load.Version)
 	return c.JSON(fiber.Map{
 		"message": "Load status updated successfully",
+		"load":    load,
+	})
+}
+
+// RenewLoad moves a load to a new loading date and relists it if it expired.
+// Body: shipper_id, loading_date (YYYY-MM-DD HH:MM or YYYY-MM-DD) and optional price.
+func (h *LoadHandler) RenewLoad(c *fiber.Ctx) error {
+	var req struct {
+		ShipperID   string  `json:"shipper_id"`
+		LoadingDate string  `json:"loading_date"`
+		Price       float64 `json:"price"`
+	}
+
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if req.ShipperID == "" || req.LoadingDate == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Shipper ID and loading date are required",
+		})
+	}
+
+	loadingDate, err := parseLoadingDate(req.LoadingDate)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid loading date, use YYYY-MM-DD HH:MM",
+		})
+	}
+
+	id, err := parseID(models.PrefixLoad, c.Params("id"))
+	if err != nil {
+		return err
+	}
+
+	ctx, err := ifMatch(c, "load")
+	if err != nil {
+		return err
+	}
+
+	load, err := h.expiryService.Renew(ctx, req.ShipperID, id, loadingDate, req.Price, restActor(c))
+	if err != nil {
+		return err
+	}
+
+	setETag(c, load.Version)
+	return c.JSON(fiber.Map{
+		"message": "Load renewed successfully",
+		"load":    load,
+	})
+}
+
+// EditLoad changes a load nobody has booked yet.
+// Body: shipper_id and any of price, loading_date (YYYY-MM-DD HH:MM or YYYY-MM-DD) and vehicle_type.
+func (h *LoadHandler) EditLoad(c *fiber.Ctx) error {
+	var req struct {
+		ShipperID   string   `json:"shipper_id"`
+		Price       *float64 `json:"price"`
+		LoadingDate *string  `json:"loading_date"`
+		VehicleType *string  `json:"vehicle_type"`
+	}
+
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if req.ShipperID == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Shipper ID is required",
+		})
+	}
+
+	changes := &models.LoadChanges{Price: req.Price, VehicleType: req.VehicleType}
+	if req.LoadingDate != nil {
+		loadingDate, err := parseLoadingDate(*req.LoadingDate)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid loading date, use YYYY-MM-DD HH:MM",
+			})
+		}
+		changes.LoadingDate = &loadingDate
+	}
+
+	id, err := parseID(models.PrefixLoad, c.Params("id"))
+	if err != nil {
+		return err
+	}
+
+	ctx, err := ifMatch(c, "load")
+	if err != nil {
+		return err
+	}
+
+	load, err := h.editService.Edit(ctx, req.ShipperID, id, changes, restActor(c))
+	if err != nil {
+		return err
+	}
+
+	setETag(c, load.Version)
+	return c.JSON(fiber.Map{
+		"message": "Load updated successfully",
+		"load":    load,
+	})
+}
+
+// WithdrawLoad takes a load nobody has booked yet off the marketplace.
+// Body: shipper_id.
+func (h *LoadHandler) WithdrawLoad(c *fiber.Ctx) error {
+	var req struct {
+		ShipperID string `json:"shipper_id"`
+	}
+
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if req.ShipperID == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Shipper ID is required",
+		})
+	}
+
+	id, err := parseID(models.PrefixLoad, c.Params("id"))
+	if err != nil {
+		return err
+	}
+
+	ctx, err := ifMatch(c, "load")
+	if err != nil {
+		return err
+	}
+
+	load, err := h.editService.Withdraw(ctx, req.ShipperID, id, restActor(c))
+	if err != nil {
+		return err
+	}
+
+	setETag(c, load.Version)
+	return c.JSON(fiber.Map{
+		"message": "Load withdrawn successfully",
+		"load":    load,
+	})
+}
+
+// parseLoadingDate reads a loading date given as YYYY-MM-DD HH:MM or YYYY-MM-DD in local time
+func parseLoadingDate(value string) (time.Time, error) {
+	if loadingDate, err := time.ParseInLocation("2006-01-02 15:04", value, time.Local); err == nil {
+		return loadingDate, nil
+	}
+	return time.ParseInLocation("2006-01-02", value, time.Local)
+}
+
+// GetTimeline retrieves the event history of a load and its bookings
+func (h *LoadHandler) GetTimeline(c *fiber.Ctx) error {
+	id := c.Params("id")
+	if id == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Load ID is required",
+		})
+	}
+
+	id, err := parseID(models.PrefixLoad, id)
+	if err != nil {
+		return err
+	}
+
+	load, err := h.store.GetLoad(c.UserContext(), id)
+	if err != nil {
+		return err
+	}
+
+	events, err := h.store.GetEvents(c.UserContext(), models.EntityLoad, load.LoadID)
+	if err != nil {
+		return err
+	}
+
+	// Include the history of every booking made against this load
+	bookings, err := h.store.GetBookingsByLoad(c.UserContext(), load.LoadID)
+	if err != nil {
+		return err
+	}
+	for _, booking := range bookings {
+		bookingEvents, err := h.store.GetEvents(c.UserContext(), models.EntityBooking, booking.BookingID)
+		if err != nil {
+			return err
+		}
+		events = append(events, bookingEvents...)
+	}
+
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].OccurredAt.Before(events[j].OccurredAt)
+	})
+
+	return c.JSON(fiber.Map{
+		"load_id": load.LoadID,
+		"events":  events,
+		"count":   len(events),
 	})
 }
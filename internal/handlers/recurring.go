@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"time"
+
+	"github.com/Ananth-NQI/truckpe-backend/internal/models"
+	"github.com/Ananth-NQI/truckpe-backend/internal/services"
+	"github.com/gofiber/fiber/v2"
+)
+
+// RecurringLoadHandler handles shippers' recurring loads
+type RecurringLoadHandler struct {
+	recurringService *services.RecurringLoadService
+}
+
+// NewRecurringLoadHandler creates a new recurring load handler
+func NewRecurringLoadHandler(recurringService *services.RecurringLoadService) *RecurringLoadHandler {
+	return &RecurringLoadHandler{
+		recurringService: recurringService,
+	}
+}
+
+// CreateRecurringLoad saves a recurring load and posts its first occurrences.
+// Body: the load fields plus days ("daily", "weekdays", "mon,wed,fri"), time
+// (HH:MM), start_date and end_date (YYYY-MM-DD) and contract_trucker_id.
+func (h *RecurringLoadHandler) CreateRecurringLoad(c *fiber.Ctx) error {
+	var req struct {
+		models.RecurringLoad
+		StartDate string `json:"start_date"`
+		EndDate   string `json:"end_date"`
+	}
+
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if req.ShipperID == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Shipper ID is required",
+		})
+	}
+
+	recurring := req.RecurringLoad
+	if req.StartDate != "" {
+		date, err := time.ParseInLocation("2006-01-02", req.StartDate, time.Local)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid start date, use YYYY-MM-DD",
+			})
+		}
+		recurring.StartDate = date
+	}
+	if req.EndDate != "" {
+		date, err := time.ParseInLocation("2006-01-02", req.EndDate, time.Local)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid end date, use YYYY-MM-DD",
+			})
+		}
+		recurring.EndDate = &date
+	}
+
+	created, err := h.recurringService.Create(c.UserContext(), req.ShipperID, &recurring)
+	if err != nil {
+		return cityError(c, err)
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"message":        "Recurring load created successfully",
+		"recurring_load": created,
+	})
+}
+
+// GetRecurringLoads lists a shipper's recurring loads
+func (h *RecurringLoadHandler) GetRecurringLoads(c *fiber.Ctx) error {
+	shipperID := c.Query("shipper_id")
+	if shipperID == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Shipper ID is required",
+		})
+	}
+
+	recurringLoads, err := h.recurringService.RecurringLoads(c.UserContext(), shipperID)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(fiber.Map{
+		"recurring_loads": recurringLoads,
+		"count":           len(recurringLoads),
+	})
+}
+
+// PauseRecurringLoad stops posting occurrences. Body: shipper_id.
+func (h *RecurringLoadHandler) PauseRecurringLoad(c *fiber.Ctx) error {
+	return h.change(c, func(shipperID string) (*models.RecurringLoad, error) {
+		return h.recurringService.Pause(c.UserContext(), shipperID, c.Params("id"))
+	})
+}
+
+// ResumeRecurringLoad restarts posting occurrences from now on. Body: shipper_id.
+func (h *RecurringLoadHandler) ResumeRecurringLoad(c *fiber.Ctx) error {
+	return h.change(c, func(shipperID string) (*models.RecurringLoad, error) {
+		return h.recurringService.Resume(c.UserContext(), shipperID, c.Params("id"))
+	})
+}
+
+// SkipOccurrence cancels the occurrence on one date. Body: shipper_id and date (YYYY-MM-DD).
+func (h *RecurringLoadHandler) SkipOccurrence(c *fiber.Ctx) error {
+	var req struct {
+		Date string `json:"date"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	date, err := time.ParseInLocation("2006-01-02", req.Date, time.Local)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid date, use YYYY-MM-DD",
+		})
+	}
+
+	return h.change(c, func(shipperID string) (*models.RecurringLoad, error) {
+		return h.recurringService.Skip(c.UserContext(), shipperID, c.Params("id"), date)
+	})
+}
+
+// change applies a pause, resume or skip for the shipper named in the body
+func (h *RecurringLoadHandler) change(c *fiber.Ctx, apply func(shipperID string) (*models.RecurringLoad, error)) error {
+	var req struct {
+		ShipperID string `json:"shipper_id"`
+	}
+	if err := c.BodyParser(&req); err != nil || req.ShipperID == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Shipper ID is required",
+		})
+	}
+
+	recurring, err := apply(req.ShipperID)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(fiber.Map{
+		"message":        "Recurring load updated successfully",
+		"recurring_load": recurring,
+	})
+}
@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/Ananth-NQI/truckpe-backend/internal/events"
+	"github.com/Ananth-NQI/truckpe-backend/internal/models"
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	// streamHeartbeat keeps idle connections open through proxies
+	streamHeartbeat = 20 * time.Second
+
+	// streamBuffer is how many events a slow client may fall behind by
+	streamBuffer = 64
+)
+
+// StreamHandler serves live load and booking updates as Server-Sent Events
+type StreamHandler struct {
+	bus *events.Bus
+}
+
+// NewStreamHandler creates a new stream handler
+func NewStreamHandler(bus *events.Bus) *StreamHandler {
+	return &StreamHandler{
+		bus: bus,
+	}
+}
+
+// Stream pushes events for the shipper or trucker AccountAuth authenticated
+// until the client disconnects. Clients reconnecting with Last-Event-ID
+// receive the events they missed.
+func (h *StreamHandler) Stream(c *fiber.Ctx) error {
+	shipperID := authenticatedShipper(c)
+	truckerID := authenticatedTrucker(c)
+	if shipperID == "" && truckerID == "" {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Shipper or trucker API key required",
+		})
+	}
+
+	// EventSource sends Last-Event-ID as a header; allow a query param for manual clients
+	lastEventID := c.Get("Last-Event-ID", c.Query("last_event_id"))
+	var lastID uint
+	if lastEventID != "" {
+		if id, err := strconv.ParseUint(lastEventID, 10, 64); err == nil {
+			lastID = uint(id)
+		}
+	}
+
+	matches := func(event *models.Event) bool {
+		if shipperID != "" && event.ShipperID == shipperID {
+			return true
+		}
+		if truckerID != "" && (event.TruckerID == truckerID || event.IsPublic()) {
+			return true
+		}
+		return false
+	}
+
+	// Subscribe before replaying so nothing published in between is lost
+	sub := h.bus.Subscribe(streamBuffer)
+	var missed []*models.Event
+	if lastEventID != "" {
+		missed = h.bus.Since(lastID)
+	}
+
+	c.Set(fiber.HeaderContentType, "text/event-stream")
+	c.Set(fiber.HeaderCacheControl, "no-cache")
+	c.Set(fiber.HeaderConnection, "keep-alive")
+	c.Set("X-Accel-Buffering", "no")
+
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		defer sub.Close()
+
+		fmt.Fprintf(w, "retry: 3000\n\n")
+
+		sent := lastID
+		for _, event := range missed {
+			if matches(event) {
+				writeStreamEvent(w, event)
+			}
+			sent = event.ID
+		}
+		if err := w.Flush(); err != nil {
+			return
+		}
+
+		heartbeat := time.NewTicker(streamHeartbeat)
+		defer heartbeat.Stop()
+
+		for {
+			select {
+			case event, ok := <-sub.C:
+				if !ok {
+					return
+				}
+				// Skip anything already replayed from history
+				if event.ID <= sent || !matches(event) {
+					continue
+				}
+				writeStreamEvent(w, event)
+			case <-heartbeat.C:
+				fmt.Fprintf(w, ": ping\n\n")
+			}
+
+			// A failed flush means the client has gone away
+			if err := w.Flush(); err != nil {
+				return
+			}
+		}
+	})
+
+	return nil
+}
+
+// writeStreamEvent writes one event in SSE wire format
+func writeStreamEvent(w *bufio.Writer, event *models.Event) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return
+	}
+	fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.EventType, data)
+}
@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"bytes"
+	"html/template"
+	"time"
+
+	"github.com/Ananth-NQI/truckpe-backend/internal/models"
+	"github.com/Ananth-NQI/truckpe-backend/internal/services"
+	"github.com/gofiber/fiber/v2"
+)
+
+// TrackingHandler handles shareable tracking links for consignees
+type TrackingHandler struct {
+	trackingService *services.TrackingService
+}
+
+// NewTrackingHandler creates a new tracking handler
+func NewTrackingHandler(trackingService *services.TrackingService) *TrackingHandler {
+	return &TrackingHandler{
+		trackingService: trackingService,
+	}
+}
+
+// CreateLink issues a public tracking link for a booking of the authenticated shipper
+func (h *TrackingHandler) CreateLink(c *fiber.Ctx) error {
+	id := c.Params("id")
+	if id == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Booking ID is required",
+		})
+	}
+
+	id, err := parseID(models.PrefixBooking, id)
+	if err != nil {
+		return err
+	}
+
+	var req struct {
+		TTLHours float64 `json:"ttl_hours"`
+	}
+
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid request body",
+			})
+		}
+	}
+
+	ttl := time.Duration(req.TTLHours * float64(time.Hour))
+	link, err := h.trackingService.CreateLink(c.UserContext(), id, authenticatedShipper(c), ttl, restActor(c))
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"message":    "Tracking link created successfully",
+		"url":        h.trackingService.LinkURL(link.Token),
+		"token":      link.Token,
+		"expires_at": link.ExpiresAt,
+	})
+}
+
+// RevokeLink revokes all tracking links for a booking of the authenticated shipper
+func (h *TrackingHandler) RevokeLink(c *fiber.Ctx) error {
+	id := c.Params("id")
+	if id == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Booking ID is required",
+		})
+	}
+
+	id, err := parseID(models.PrefixBooking, id)
+	if err != nil {
+		return err
+	}
+
+	if err := h.trackingService.RevokeLinks(c.UserContext(), id, authenticatedShipper(c), restActor(c)); err != nil {
+		return err
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Tracking link revoked successfully",
+	})
+}
+
+// PublicTrack serves the public tracking page (HTML) or JSON for a token
+func (h *TrackingHandler) PublicTrack(c *fiber.Ctx) error {
+	view, err := h.trackingService.Resolve(c.UserContext(), c.Params("token"))
+	if err != nil {
+		return err
+	}
+
+	if c.Query("format") == "json" || c.Accepts(fiber.MIMETextHTML, fiber.MIMEApplicationJSON) != fiber.MIMETextHTML {
+		return c.JSON(view)
+	}
+
+	var buf bytes.Buffer
+	if err := trackingPageTemplate.Execute(&buf, view); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to render tracking page",
+		})
+	}
+
+	c.Set(fiber.HeaderContentType, fiber.MIMETextHTMLCharsetUTF8)
+	return c.Send(buf.Bytes())
+}
+
+var trackingPageTemplate = template.Must(template.New("tracking").Funcs(template.FuncMap{
+	"formatTime": func(t *time.Time) string {
+		if t == nil {
+			return "—"
+		}
+		return t.Format("02 Jan 2006, 3:04 PM")
+	},
+	"statusLabel": func(status string) string {
+		switch status {
+		case models.BookingStatusConfirmed, models.BookingStatusTruckerAssigned:
+			return "Awaiting pickup"
+		case models.BookingStatusInTransit:
+			return "In transit"
+		case models.BookingStatusDelivered, models.BookingStatusCompleted:
+			return "Delivered"
+		}
+		return status
+	},
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>TruckPe Tracking - {{.BookingID}}</title>
+<style>
+body { font-family: sans-serif; max-width: 480px; margin: 2em auto; padding: 0 1em; color: #222; }
+h1 { font-size: 1.3em; }
+dt { font-weight: bold; margin-top: .8em; }
+dd { margin: 0; }
+</style>
+</head>
+<body>
+<h1>🚛 Shipment {{.BookingID}}</h1>
+<dl>
+<dt>Status</dt><dd>{{statusLabel .Status}}</dd>
+<dt>Route</dt><dd>{{.FromCity}} → {{.ToCity}}</dd>
+<dt>Last location</dt><dd>{{.LastLocation}}</dd>
+<dt>Estimated arrival</dt><dd>{{formatTime .ETA}}</dd>
+<dt>Trucker</dt><dd>{{.TruckerName}} ({{.VehicleNo}})</dd>
+</dl>
+<p><small>Last updated {{.LastUpdated.Format "02 Jan 2006, 3:04 PM"}}</small></p>
+</body>
+</html>
+`))
@@ -36,23 +36,9 @@ func (h *TruckerHandler) Register(c *fiber.Ctx) error {
 	}
 
 	// Create trucker
-	trucker, err := h.store.CreateTrucker(&reg)
+	trucker, err := h.store.CreateTrucker(c.UserContext(), &reg, restActor(c))
 	if err != nil {
-		// Check for specific errors
-		if err.Error() == "phone number already registered" {
-			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
-				"error": "Phone number already registered",
-			})
-		}
-		if err.Error() == "vehicle already registered" {
-			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
-				"error": "Vehicle already registered",
-			})
-		}
-
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to register trucker",
-		})
+		return err
 	}
 
 	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
@@ -70,13 +56,17 @@ func (h *TruckerHandler) GetTrucker(c *fiber.Ctx) error {
 		})
 	}
 
-	trucker, err := h.store.GetTrucker(id)
+	id, err := parseID(models.PrefixTrucker, id)
 	if err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-			"error": "Trucker not found",
-		})
+		return err
+	}
+
+	trucker, err := h.store.GetTrucker(c.UserContext(), id)
+	if err != nil {
+		return err
 	}
 
+	setETag(c, trucker.Version)
 	return c.JSON(trucker)
 }
 
@@ -89,12 +79,65 @@ func (h *TruckerHandler) GetTruckerByPhone(c *fiber.Ctx) error {
 		})
 	}
 
-	trucker, err := h.store.GetTruckerByPhone(phone)
+	trucker, err := h.store.GetTruckerByPhone(c.UserContext(), phone)
 	if err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-			"error": "Trucker not found",
-		})
+		return err
 	}
 
+	setETag(c, trucker.Version)
 	return c.JSON(trucker)
 }
+
+// UpdateLocation sets the trucker's current city, resolved against the city master
+func (h *TruckerHandler) UpdateLocation(c *fiber.Ctx) error {
+	var req struct {
+		City string `json:"city"`
+	}
+
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if req.City == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "City is required",
+		})
+	}
+
+	city, err := models.ResolveCity(req.City)
+	if err != nil {
+		return cityError(c, err)
+	}
+
+	id, err := parseID(models.PrefixTrucker, c.Params("id"))
+	if err != nil {
+		return err
+	}
+
+	trucker, err := h.store.GetTrucker(c.UserContext(), id)
+	if err != nil {
+		return err
+	}
+
+	ctx, err := ifMatch(c, "trucker")
+	if err != nil {
+		return err
+	}
+
+	if err := h.store.UpdateTruckerLocation(ctx, trucker.TruckerID, city.Name, city.ID, restActor(c)); err != nil {
+		return err
+	}
+
+	if trucker, err = h.store.GetTrucker(c.UserContext(), trucker.TruckerID); err != nil {
+		return err
+	}
+
+	setETag(c, trucker.Version)
+	return c.JSON(fiber.Map{
+		"message":         "Location updated successfully",
+		"current_city":    city.Name,
+		"current_city_id": city.ID,
+	})
+}
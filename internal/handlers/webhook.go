@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"github.com/Ananth-NQI/truckpe-backend/internal/models"
+	"github.com/Ananth-NQI/truckpe-backend/internal/services"
+	"github.com/Ananth-NQI/truckpe-backend/internal/storage"
+	"github.com/gofiber/fiber/v2"
+)
+
+// WebhookHandler handles shipper webhook endpoint management
+type WebhookHandler struct {
+	store          storage.Store
+	webhookService *services.WebhookService
+}
+
+// NewWebhookHandler creates a new webhook handler
+func NewWebhookHandler(store storage.Store, webhookService *services.WebhookService) *WebhookHandler {
+	return &WebhookHandler{
+		store:          store,
+		webhookService: webhookService,
+	}
+}
+
+// RegisterEndpoint registers a webhook endpoint for the authenticated shipper
+func (h *WebhookHandler) RegisterEndpoint(c *fiber.Ctx) error {
+	var req struct {
+		URL        string   `json:"url"`
+		EventTypes []string `json:"event_types"`
+	}
+
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if req.URL == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "URL is required",
+		})
+	}
+
+	endpoint, err := h.webhookService.RegisterEndpoint(c.UserContext(), authenticatedShipper(c), req.URL, req.EventTypes)
+	if err != nil {
+		return err
+	}
+
+	// The secret is only ever returned here
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"message":  "Webhook registered successfully",
+		"endpoint": endpoint,
+		"secret":   endpoint.Secret,
+	})
+}
+
+// GetEndpoints lists the authenticated shipper's webhook endpoints
+func (h *WebhookHandler) GetEndpoints(c *fiber.Ctx) error {
+	endpoints, err := h.store.GetWebhookEndpointsByShipper(c.UserContext(), authenticatedShipper(c))
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(fiber.Map{
+		"endpoints": endpoints,
+		"count":     len(endpoints),
+	})
+}
+
+// DeleteEndpoint deactivates a webhook endpoint of the authenticated shipper
+func (h *WebhookHandler) DeleteEndpoint(c *fiber.Ctx) error {
+	id := c.Params("id")
+	if id == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Webhook ID is required",
+		})
+	}
+
+	if _, err := h.ownEndpoint(c, id); err != nil {
+		return err
+	}
+
+	if err := h.store.DeactivateWebhookEndpoint(c.UserContext(), id); err != nil {
+		return err
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Webhook deleted successfully",
+	})
+}
+
+// GetDeliveries returns the delivery log of an endpoint of the authenticated
+// shipper. Use ?status=dead for the dead-letter list.
+func (h *WebhookHandler) GetDeliveries(c *fiber.Ctx) error {
+	id := c.Params("id")
+	if id == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Webhook ID is required",
+		})
+	}
+
+	if _, err := h.ownEndpoint(c, id); err != nil {
+		return err
+	}
+
+	deliveries, err := h.store.GetWebhookDeliveries(c.UserContext(), id, c.Query("status"))
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(fiber.Map{
+		"deliveries": deliveries,
+		"count":      len(deliveries),
+	})
+}
+
+// ReplayDelivery re-sends a delivery of the authenticated shipper, e.g. from the dead-letter list
+func (h *WebhookHandler) ReplayDelivery(c *fiber.Ctx) error {
+	id := c.Params("deliveryID")
+	if id == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Delivery ID is required",
+		})
+	}
+
+	delivery, err := h.webhookService.Replay(c.UserContext(), id, authenticatedShipper(c))
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusAccepted).JSON(fiber.Map{
+		"message":  "Delivery queued for replay",
+		"delivery": delivery,
+	})
+}
+
+// ownEndpoint fetches a webhook endpoint, refusing one of another shipper
+func (h *WebhookHandler) ownEndpoint(c *fiber.Ctx, id string) (*models.WebhookEndpoint, error) {
+	endpoint, err := h.store.GetWebhookEndpoint(c.UserContext(), id)
+	if err != nil {
+		return nil, err
+	}
+	if endpoint.ShipperID != authenticatedShipper(c) {
+		return nil, models.Forbidden("webhook endpoint", "webhook does not belong to shipper")
+	}
+	return endpoint, nil
+}
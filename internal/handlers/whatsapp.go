@@ -1,39 +1,50 @@
 package handlers
 
 import (
+	"context"
 	"log"
+	"os"
+	"strings"
 
 	"github.com/Ananth-NQI/truckpe-backend/internal/services"
 	"github.com/Ananth-NQI/truckpe-backend/internal/storage"
 	"github.com/gofiber/fiber/v2"
 )
 
-// WhatsAppHandler handles WhatsApp webhook requests
 // WhatsAppHandler handles WhatsApp webhook requests
 type WhatsAppHandler struct {
 	store           storage.Store
 	whatsappService *services.WhatsAppService
 	twilioService   *services.TwilioService // ADD THIS
-}
 
-// NewWhatsAppHandler creates a new WhatsApp handler
-func NewWhatsAppHandler(store storage.Store) *WhatsAppHandler {
-	// Initialize Twilio service
-	twilioSvc, err := services.NewTwilioService()
-	if err != nil {
-		log.Printf("⚠️  Warning: Twilio service not initialized: %v", err)
-		// Continue without Twilio for testing
-	}
+	// baseURL is the public URL Twilio calls, which its signatures cover
+	baseURL string
 
+	// development lets /test/whatsapp import media; never on Cloud Run
+	development bool
+}
+
+// NewWhatsAppHandler creates a new WhatsApp handler; twilioService may be nil when Twilio is not configured
+func NewWhatsAppHandler(store storage.Store, twilioService *services.TwilioService, trackingService *services.TrackingService, loadImportService *services.LoadImportService, exportService *services.ExportService, alertService *services.AlertService, recurringService *services.RecurringLoadService, expiryService *services.LoadExpiryService, editService *services.LoadEditService, keyService *services.APIKeyService) *WhatsAppHandler {
 	return &WhatsAppHandler{
 		store:           store,
-		whatsappService: services.NewWhatsAppService(store),
-		twilioService:   twilioSvc,
+		whatsappService: services.NewWhatsAppService(store, trackingService, loadImportService, exportService, alertService, recurringService, expiryService, editService, keyService),
+		twilioService:   twilioService,
+		baseURL:         strings.TrimSuffix(os.Getenv("PUBLIC_BASE_URL"), "/"),
+		development:     os.Getenv("INSTANCE_CONNECTION_NAME") == "",
 	}
 }
 
 // HandleWebhook processes incoming WhatsApp messages
 func (h *WhatsAppHandler) HandleWebhook(c *fiber.Ctx) error {
+	// Only Twilio may post here; it signs every request with our auth token
+	if !h.validSignature(c) {
+		log.Printf("❌ Rejected WhatsApp webhook with an invalid Twilio signature")
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "Invalid Twilio signature",
+		})
+	}
+
 	// Twilio sends different payloads for different events
 	var payload TwilioWebhookPayload
 
@@ -48,15 +59,22 @@ func (h *WhatsAppHandler) HandleWebhook(c *fiber.Ctx) error {
 	log.Printf("📱 WhatsApp Message from %s: %s", payload.From, payload.Body)
 
 	// Process only incoming messages (not status updates)
-	if payload.Body != "" && payload.From != "" {
+	hasMedia := payload.NumMedia != "" && payload.NumMedia != "0" && payload.MediaUrl0 != ""
+	if (payload.Body != "" || hasMedia) && payload.From != "" {
 		// Remove 'whatsapp:' prefix if present
 		from := payload.From
 		if len(from) > 9 && from[:9] == "whatsapp:" {
 			from = from[9:]
 		}
 
-		// Process the message
-		response, err := h.whatsappService.ProcessMessage(from, payload.Body)
+		// Process the message; a document attachment is a bulk load upload
+		var response string
+		var err error
+		if hasMedia {
+			response, err = h.processDocument(c.UserContext(), from, payload.MediaUrl0, payload.MediaContentType0, payload.Body)
+		} else {
+			response, err = h.whatsappService.ProcessMessage(c.UserContext(), from, payload.Body)
+		}
 		if err != nil {
 			log.Printf("Error processing message: %v", err)
 			response = "❌ Sorry, something went wrong. Please try again."
@@ -64,7 +82,7 @@ func (h *WhatsAppHandler) HandleWebhook(c *fiber.Ctx) error {
 
 		// Send the response back via Twilio
 		if h.twilioService != nil && response != "" {
-			err = h.twilioService.SendWhatsAppMessage(from, response)
+			err = h.twilioService.SendWhatsAppMessage(c.UserContext(), from, response)
 			if err != nil {
 				log.Printf("❌ Failed to send WhatsApp response: %v", err)
 			} else {
@@ -79,6 +97,21 @@ func (h *WhatsAppHandler) HandleWebhook(c *fiber.Ctx) error {
 	return c.SendStatus(fiber.StatusOK)
 }
 
+// validSignature checks X-Twilio-Signature against the URL Twilio called and the posted form
+func (h *WhatsAppHandler) validSignature(c *fiber.Ctx) bool {
+	baseURL := h.baseURL
+	if baseURL == "" {
+		baseURL = c.BaseURL()
+	}
+
+	params := make(map[string]string)
+	c.Request().PostArgs().VisitAll(func(key, value []byte) {
+		params[string(key)] = string(value)
+	})
+
+	return h.twilioService.ValidateWebhook(baseURL+c.OriginalURL(), params, c.Get("X-Twilio-Signature"))
+}
+
 // TwilioWebhookPayload represents incoming WhatsApp message from Twilio
 type TwilioWebhookPayload struct {
 	MessageSid          string `form:"MessageSid"`
@@ -92,10 +125,26 @@ type TwilioWebhookPayload struct {
 	MediaContentType0   string `form:"MediaContentType0"`
 }
 
+// processDocument downloads a WhatsApp attachment and imports it as loads
+func (h *WhatsAppHandler) processDocument(ctx context.Context, from, mediaURL, contentType, caption string) (string, error) {
+	if !services.IsSpreadsheetContentType(contentType) {
+		return "❌ Only CSV or Excel files can be sent. Type HELP to see available commands.", nil
+	}
+
+	data, err := h.twilioService.DownloadMedia(ctx, mediaURL, services.MaxImportFileSize)
+	if err != nil {
+		return "", err
+	}
+
+	return h.whatsappService.ProcessDocument(ctx, from, data, caption)
+}
+
 // For testing without Twilio
 type TestWebhookPayload struct {
-	From    string `json:"from"`
-	Message string `json:"message"`
+	From             string `json:"from"`
+	Message          string `json:"message"`
+	MediaURL         string `json:"media_url"`
+	MediaContentType string `json:"media_content_type"`
 }
 
 // HandleTestWebhook processes test WhatsApp messages (for development)
@@ -108,11 +157,24 @@ func (h *WhatsAppHandler) HandleTestWebhook(c *fiber.Ctx) error {
 		})
 	}
 
+	// Anyone can call this endpoint, so it never fetches media in production
+	if payload.MediaURL != "" && !h.development {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "Media is only accepted from Twilio",
+		})
+	}
+
 	// ADD THESE LOGS
 	log.Printf("🧪 Test webhook received from %s: %s", payload.From, payload.Message)
 
 	// Process the message
-	response, err := h.whatsappService.ProcessMessage(payload.From, payload.Message)
+	var response string
+	var err error
+	if payload.MediaURL != "" {
+		response, err = h.processDocument(c.UserContext(), payload.From, payload.MediaURL, payload.MediaContentType, payload.Message)
+	} else {
+		response, err = h.whatsappService.ProcessMessage(c.UserContext(), payload.From, payload.Message)
+	}
 	if err != nil {
 		log.Printf("Error processing message: %v", err)
 		response = "❌ Sorry, something went wrong. Please try again."
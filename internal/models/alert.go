@@ -0,0 +1,104 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// MaxLaneAlertsPerTrucker caps how many active lanes a trucker can subscribe to
+	MaxLaneAlertsPerTrucker = 10
+
+	// MaxAlertMessagesPerDay caps how many new-load alerts a trucker receives per day
+	MaxAlertMessagesPerDay = 20
+)
+
+// LaneAlert is a trucker's saved search: they are messaged on WhatsApp
+// whenever a matching load is posted
+type LaneAlert struct {
+	gorm.Model
+
+	AlertID     string  `json:"alert_id" gorm:"uniqueIndex"`
+	TruckerID   string  `json:"trucker_id" gorm:"index"`
+	FromCity    string  `json:"from_city" gorm:"index"`
+	FromCityID  string  `json:"from_city_id"`
+	ToCity      string  `json:"to_city"` // empty for any destination
+	ToCityID    string  `json:"to_city_id"`
+	VehicleType string  `json:"vehicle_type"` // empty for any vehicle
+	MinPrice    float64 `json:"min_price"`    // price floor, 0 for none
+	Active      bool    `json:"active" gorm:"default:true;index"`
+}
+
+// BeforeCreate hook to auto-generate AlertID
+func (a *LaneAlert) BeforeCreate(tx *gorm.DB) error {
+	if a.AlertID == "" {
+		id, err := nextID(tx, PrefixLaneAlert)
+		if err != nil {
+			return err
+		}
+		a.AlertID = id
+	}
+	return nil
+}
+
+// ResolveCities maps the lane to canonical cities and their master IDs
+func (a *LaneAlert) ResolveCities() error {
+	from, err := ResolveCity(a.FromCity)
+	if err != nil {
+		return err
+	}
+	to, err := ResolveCity(a.ToCity)
+	if err != nil {
+		return err
+	}
+
+	a.FromCity, a.FromCityID = from.Name, from.ID
+	a.ToCity, a.ToCityID = to.Name, to.ID
+	return nil
+}
+
+// Lane describes the alert for messages, e.g. "Delhi → Mumbai (32ft, min ₹40000)"
+func (a *LaneAlert) Lane() string {
+	lane := a.FromCity + " → "
+	if a.ToCity != "" {
+		lane += a.ToCity
+	} else {
+		lane += "Anywhere"
+	}
+
+	var extras []string
+	if a.VehicleType != "" {
+		extras = append(extras, a.VehicleType)
+	}
+	if a.MinPrice > 0 {
+		extras = append(extras, fmt.Sprintf("min ₹%.0f", a.MinPrice))
+	}
+	if len(extras) > 0 {
+		lane += " (" + strings.Join(extras, ", ") + ")"
+	}
+	return lane
+}
+
+// Matches checks whether a newly posted load is on the alert's lane
+func (a *LaneAlert) Matches(l *Load) bool {
+	if !a.Active || !containsFold(CityNames(a.FromCity), l.FromCity) {
+		return false
+	}
+	if a.ToCity != "" && !containsFold(CityNames(a.ToCity), l.ToCity) {
+		return false
+	}
+	return l.AcceptsVehicle(a.VehicleType) && l.Price >= a.MinPrice
+}
+
+// AlertNotification records a new-load alert sent to a trucker, for the daily limit
+type AlertNotification struct {
+	gorm.Model
+
+	TruckerID string    `json:"trucker_id" gorm:"index"`
+	LoadID    string    `json:"load_id" gorm:"index"`
+	AlertID   string    `json:"alert_id"`
+	SentAt    time.Time `json:"sent_at" gorm:"index"`
+}
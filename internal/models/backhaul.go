@@ -0,0 +1,36 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Return-load (backhaul) suggestion settings
+const (
+	BackhaulRadiusKm       = 100 // how far from the drop city a return load may start
+	BackhaulWindowDays     = 3   // how many days ahead the return load may be loaded
+	BackhaulMaxSuggestions = 3   // how many loads are suggested after a delivery
+)
+
+// BackhaulSuggestion records a return load suggested to a trucker after a
+// delivery, and the booking it led to if the trucker took it
+type BackhaulSuggestion struct {
+	gorm.Model
+
+	TruckerID          string     `json:"trucker_id" gorm:"index"`
+	DeliveredBookingID string     `json:"delivered_booking_id" gorm:"index"` // the delivery that left the truck empty
+	LoadID             string     `json:"load_id" gorm:"index"`
+	Rank               int        `json:"rank"`        // 1 for the top suggestion
+	DistanceKm         float64    `json:"distance_km"` // from the drop city to the load's pickup city
+	SentAt             time.Time  `json:"sent_at" gorm:"index"`
+	ConvertedBookingID string     `json:"converted_booking_id"` // booking made from the suggestion, empty if none
+	ConvertedAt        *time.Time `json:"converted_at"`
+}
+
+// BackhaulStats summarises how often suggested return loads get booked
+type BackhaulStats struct {
+	Suggested      int     `json:"suggested"`
+	Converted      int     `json:"converted"`
+	ConversionRate float64 `json:"conversion_rate"` // Converted / Suggested
+}
@@ -24,8 +24,13 @@ type Booking struct {
 	Commission  float64 `json:"commission"` // TruckPe's 5% commission
 	NetAmount   float64 `json:"net_amount"` // Amount trucker receives
 
+	// Share of the load carried, in tons. Part loads (bookings of a splittable
+	// load) can share a truck with other part loads.
+	Weight   float64 `json:"weight"`
+	PartLoad bool    `json:"part_load"`
+
 	// Status tracking
-	Status string `json:"status" gorm:"default:confirmed"` // "confirmed", "trucker_assigned", "in_transit", "delivered", "completed"
+	Status string `json:"status" gorm:"default:confirmed"` // "confirmed", "trucker_assigned", "in_transit", "delivered", "completed", "cancelled"
 
 	// Payment status
 	PaymentStatus string `json:"payment_status" gorm:"default:pending"` // "pending", "escrow", "released", "completed"
@@ -41,6 +46,9 @@ type Booking struct {
 	DeliveredAt *time.Time `json:"delivered_at"`
 	CompletedAt *time.Time `json:"completed_at"`
 
+	// Version is incremented by every update, see WithExpectedVersion
+	Version uint `json:"version" gorm:"not null;default:1"`
+
 	// Note: CreatedAt and UpdatedAt are automatically handled by gorm.Model
 
 	// If you want to add relationships later (optional)
@@ -52,7 +60,14 @@ type Booking struct {
 func (b *Booking) BeforeCreate(tx *gorm.DB) error {
 	// Generate BookingID if not set
 	if b.BookingID == "" {
-		b.BookingID = fmt.Sprintf("BK%d%03d", time.Now().Unix(), rand.Intn(1000))
+		id, err := nextID(tx, PrefixBooking)
+		if err != nil {
+			return err
+		}
+		b.BookingID = id
+	}
+	if b.Version == 0 {
+		b.Version = 1
 	}
 
 	// Generate OTP if not set
@@ -82,6 +97,7 @@ const (
 	BookingStatusInTransit       = "in_transit"
 	BookingStatusDelivered       = "delivered"
 	BookingStatusCompleted       = "completed"
+	BookingStatusCancelled       = "cancelled" // the trucker, shipper or load was taken off the platform before pickup
 
 	PaymentStatusPending   = "pending"
 	PaymentStatusEscrow    = "escrow"
@@ -89,6 +105,20 @@ const (
 	PaymentStatusCompleted = "completed"
 )
 
+// ActiveBookingStatuses are the statuses of bookings that still occupy the truck
+var ActiveBookingStatuses = []string{BookingStatusConfirmed, BookingStatusTruckerAssigned, BookingStatusInTransit}
+
+// IsActive reports whether the booking still occupies the truck (not yet delivered)
+func (b *Booking) IsActive() bool {
+	return containsFold(ActiveBookingStatuses, b.Status)
+}
+
+// IsCancellable reports whether the booking can still be cancelled: it is
+// active and its truck has not picked the load up
+func (b *Booking) IsCancellable() bool {
+	return b.IsActive() && b.Status != BookingStatusInTransit
+}
+
 // Helper methods you can add
 func (b *Booking) MarkAsPickedUp() {
 	now := time.Now()
@@ -0,0 +1,84 @@
+package models
+
+import (
+	"errors"
+	"strings"
+)
+
+// Domain error kinds. Store, model and service errors wrap one of these, so
+// callers test the kind with errors.Is instead of matching message text.
+var (
+	ErrNotFound    = errors.New("not found")   // the entity does not exist or is not visible to the caller
+	ErrConflict    = errors.New("conflict")    // a unique field is already taken
+	ErrUnavailable = errors.New("unavailable") // the entity exists but its state does not allow the change
+	ErrForbidden   = errors.New("forbidden")   // the entity belongs to someone else
+	ErrInvalid     = errors.New("invalid")     // the request itself is malformed
+	ErrExpired     = errors.New("expired")     // a link or token is no longer valid
+
+	ErrPreconditionFailed = errors.New("precondition failed") // the entity changed since the version the caller expected
+)
+
+// Error is a domain error about one entity and, optionally, one of its fields.
+// Its message is what users see; Kind and Code are for programs.
+type Error struct {
+	Kind    error  // one of the Err* kinds above
+	Entity  string // e.g. "load", "trucker"
+	Field   string // e.g. "phone", empty when the error is about the entity as a whole
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Unwrap lets errors.Is match the kind
+func (e *Error) Unwrap() error {
+	return e.Kind
+}
+
+// Code is the machine-readable error code, e.g. "load_not_found" or "trucker_phone_conflict"
+func (e *Error) Code() string {
+	parts := []string{}
+	for _, part := range []string{e.Entity, e.Field, e.Kind.Error()} {
+		if part != "" {
+			parts = append(parts, strings.ReplaceAll(part, " ", "_"))
+		}
+	}
+	return strings.Join(parts, "_")
+}
+
+// NotFound reports that an entity does not exist: "load not found"
+func NotFound(entity string) error {
+	return &Error{Kind: ErrNotFound, Entity: entity, Message: entity + " not found"}
+}
+
+// Conflict reports a unique field already in use by another entity
+func Conflict(entity, field, message string) error {
+	return &Error{Kind: ErrConflict, Entity: entity, Field: field, Message: message}
+}
+
+// Unavailable reports an entity whose state does not allow the operation,
+// with the field at fault if there is one
+func Unavailable(entity, field, message string) error {
+	return &Error{Kind: ErrUnavailable, Entity: entity, Field: field, Message: message}
+}
+
+// Forbidden reports an entity that belongs to someone other than the caller
+func Forbidden(entity, message string) error {
+	return &Error{Kind: ErrForbidden, Entity: entity, Message: message}
+}
+
+// Invalid reports a bad value for a field
+func Invalid(entity, field, message string) error {
+	return &Error{Kind: ErrInvalid, Entity: entity, Field: field, Message: message}
+}
+
+// Expired reports a link or token past its expiry
+func Expired(entity, message string) error {
+	return &Error{Kind: ErrExpired, Entity: entity, Message: message}
+}
+
+// PreconditionFailed reports an entity no longer at the version the caller expected
+func PreconditionFailed(entity, message string) error {
+	return &Error{Kind: ErrPreconditionFailed, Entity: entity, Message: message}
+}
@@ -0,0 +1,146 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Event is an append-only audit record of a change to a load, booking, trucker or shipper
+type Event struct {
+	gorm.Model
+
+	EntityType string    `json:"entity_type" gorm:"index:idx_event_entity"` // "load", "booking", "trucker", "shipper"
+	EntityID   string    `json:"entity_id" gorm:"index:idx_event_entity"`   // LoadID, BookingID, TruckerID or ShipperID
+	EventType  string    `json:"event_type" gorm:"index"`
+	ActorID    string    `json:"actor_id"`
+	Channel    string    `json:"channel"` // "whatsapp", "rest", "system"
+	Before     EventData `json:"before"`
+	After      EventData `json:"after"`
+	OccurredAt time.Time `json:"occurred_at" gorm:"index"`
+
+	// Parties the event concerns, used to filter live streams
+	ShipperID string `json:"shipper_id,omitempty" gorm:"index"`
+	TruckerID string `json:"trucker_id,omitempty" gorm:"index"`
+}
+
+// EventData holds the changed field values of an event, stored as JSON
+type EventData map[string]interface{}
+
+// Value implements driver.Valuer
+func (d EventData) Value() (driver.Value, error) {
+	if d == nil {
+		return nil, nil
+	}
+	b, err := json.Marshal(d)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+// Scan implements sql.Scanner
+func (d *EventData) Scan(value interface{}) error {
+	if value == nil {
+		*d = nil
+		return nil
+	}
+
+	var b []byte
+	switch v := value.(type) {
+	case []byte:
+		b = v
+	case string:
+		b = []byte(v)
+	default:
+		return fmt.Errorf("unsupported event data type %T", value)
+	}
+	return json.Unmarshal(b, d)
+}
+
+// GormDataType stores event data as text
+func (EventData) GormDataType() string {
+	return "text"
+}
+
+// Actor identifies who made a change and through which channel
+type Actor struct {
+	ID      string `json:"id"`
+	Channel string `json:"channel"`
+}
+
+// SystemActor is used for changes made by the platform itself
+var SystemActor = Actor{ID: "system", Channel: ChannelSystem}
+
+// Channel constants
+const (
+	ChannelWhatsApp = "whatsapp"
+	ChannelREST     = "rest"
+	ChannelSystem   = "system"
+)
+
+// Entity type constants
+const (
+	EntityLoad    = "load"
+	EntityBooking = "booking"
+	EntityTrucker = "trucker"
+	EntityShipper = "shipper"
+)
+
+// Event type constants
+const (
+	EventLoadCreated          = "load_created"
+	EventLoadStatusChanged    = "load_status_changed"
+	EventLoadAllocated        = "load_allocated" // a share of a multi-truck or part load was booked
+	EventLoadRenewed          = "load_renewed"   // loading date, expiry or price changed by RENEW
+	EventLoadUpdated          = "load_updated"   // edited by the shipper; before/after hold the changed fields
+	EventLoadReleased         = "load_released"  // a cancelled booking handed its share back
+	EventLoadDeleted          = "load_deleted"
+	EventLoadRestored         = "load_restored"
+	EventBookingCreated       = "booking_created"
+	EventBookingStatusChanged = "booking_status_changed"
+	EventPODUploaded          = "pod_uploaded"
+	EventTrackingLinkCreated  = "tracking_link_created"
+	EventTrackingLinkRevoked  = "tracking_link_revoked"
+	EventTruckerRegistered    = "trucker_registered"
+	EventTruckerUpdated       = "trucker_updated"
+	EventTruckerDeactivated   = "trucker_deactivated"
+	EventTruckerDeleted       = "trucker_deleted"
+	EventTruckerRestored      = "trucker_restored"
+	EventShipperRegistered    = "shipper_registered"
+	EventShipperDeactivated   = "shipper_deactivated"
+	EventShipperDeleted       = "shipper_deleted"
+	EventShipperRestored      = "shipper_restored"
+)
+
+// NewEvent builds an event stamped with the current time
+func NewEvent(entityType, entityID, eventType string, actor Actor, before, after EventData) *Event {
+	return &Event{
+		EntityType: entityType,
+		EntityID:   entityID,
+		EventType:  eventType,
+		ActorID:    actor.ID,
+		Channel:    actor.Channel,
+		Before:     before,
+		After:      after,
+		OccurredAt: time.Now(),
+	}
+}
+
+// For sets the shipper and trucker an event concerns
+func (e *Event) For(shipperID, truckerID string) *Event {
+	e.ShipperID = shipperID
+	e.TruckerID = truckerID
+	return e
+}
+
+// IsPublic reports whether every trucker may see the event (new, partly booked and booked loads on the marketplace)
+func (e *Event) IsPublic() bool {
+	return e.EntityType == EntityLoad &&
+		(e.EventType == EventLoadCreated || e.EventType == EventLoadStatusChanged || e.EventType == EventLoadAllocated ||
+			e.EventType == EventLoadRenewed || e.EventType == EventLoadUpdated || e.EventType == EventLoadReleased ||
+			e.EventType == EventLoadRestored)
+}
@@ -0,0 +1,82 @@
+package models
+
+import "time"
+
+// ExportFilter narrows the rows of a data export.
+// Loads and bookings are filtered on their creation time; payouts and
+// invoices (ByDeliveryDate) on the booking's delivery time.
+type ExportFilter struct {
+	From           *time.Time // inclusive
+	To             *time.Time // exclusive
+	ShipperID      string
+	TruckerID      string
+	Status         string
+	ByDeliveryDate bool
+}
+
+// Export dataset constants
+const (
+	ExportLoads    = "loads"
+	ExportBookings = "bookings"
+	ExportPayouts  = "payouts"
+	ExportInvoices = "invoices"
+)
+
+// Export format constants
+const (
+	ExportFormatCSV   = "csv"
+	ExportFormatXLSX  = "xlsx"
+	ExportFormatJSONL = "jsonl"
+)
+
+// Payout is what a trucker is owed for a delivered booking
+type Payout struct {
+	BookingID     string     `json:"booking_id"`
+	LoadID        string     `json:"load_id"`
+	TruckerID     string     `json:"trucker_id"`
+	GrossAmount   float64    `json:"gross_amount"`
+	Commission    float64    `json:"commission"`
+	NetAmount     float64    `json:"net_amount"`
+	PaymentStatus string     `json:"payment_status"`
+	PaymentID     string     `json:"payment_id"`
+	DeliveredAt   *time.Time `json:"delivered_at"`
+}
+
+// Invoice is what a shipper is billed for a delivered booking
+type Invoice struct {
+	InvoiceNo     string     `json:"invoice_no"`
+	BookingID     string     `json:"booking_id"`
+	LoadID        string     `json:"load_id"`
+	ShipperID     string     `json:"shipper_id"`
+	Amount        float64    `json:"amount"`
+	PaymentStatus string     `json:"payment_status"`
+	IssuedAt      *time.Time `json:"issued_at"`
+}
+
+// NewPayout derives the payout of a delivered booking
+func NewPayout(b *Booking) *Payout {
+	return &Payout{
+		BookingID:     b.BookingID,
+		LoadID:        b.LoadID,
+		TruckerID:     b.TruckerID,
+		GrossAmount:   b.AgreedPrice,
+		Commission:    b.Commission,
+		NetAmount:     b.NetAmount,
+		PaymentStatus: b.PaymentStatus,
+		PaymentID:     b.PaymentID,
+		DeliveredAt:   b.DeliveredAt,
+	}
+}
+
+// NewInvoice derives the invoice of a delivered booking, issued on delivery
+func NewInvoice(b *Booking) *Invoice {
+	return &Invoice{
+		InvoiceNo:     "INV-" + b.BookingID,
+		BookingID:     b.BookingID,
+		LoadID:        b.LoadID,
+		ShipperID:     b.ShipperID,
+		Amount:        b.AgreedPrice,
+		PaymentStatus: b.PaymentStatus,
+		IssuedAt:      b.DeliveredAt,
+	}
+}
@@ -0,0 +1,122 @@
+package models
+
+import (
+	"errors"
+	"math"
+	"strings"
+
+	"github.com/Ananth-NQI/truckpe-backend/internal/cities"
+)
+
+// Coordinates is a latitude/longitude pair in degrees
+type Coordinates struct {
+	Lat float64 `json:"lat"`
+	Lng float64 `json:"lng"`
+}
+
+// ResolvedCity is a typed city name mapped to the city master (see package cities)
+type ResolvedCity struct {
+	Name     string `json:"name"`               // canonical city name, or the input title-cased if unknown
+	ID       string `json:"id,omitempty"`       // city master ID, empty if unknown
+	Locality string `json:"locality,omitempty"` // set when the input named a locality of the city
+}
+
+// ResolveCity maps user input such as "Blr", "Bangalore" or "Peenya" to its
+// canonical city. Towns missing from the master are kept as typed with no ID;
+// input close to several cities returns a *cities.AmbiguousError.
+func ResolveCity(input string) (*ResolvedCity, error) {
+	input = strings.TrimSpace(input)
+	if input == "" {
+		return &ResolvedCity{}, nil
+	}
+
+	city, err := cities.Resolve(input)
+	if err != nil {
+		var unknown *cities.UnknownError
+		if errors.As(err, &unknown) {
+			return &ResolvedCity{Name: strings.Title(strings.ToLower(input))}, nil
+		}
+		return nil, err
+	}
+
+	resolved := &ResolvedCity{Name: city.Canonical().Name, ID: city.Canonical().ID}
+	if city.IsLocality() {
+		resolved.Locality = city.Name
+	}
+	return resolved, nil
+}
+
+// CityCoordinates looks up a city by name, alias or pincode, case-insensitively
+func CityCoordinates(city string) (Coordinates, bool) {
+	c, ok := cities.Lookup(city)
+	if !ok {
+		return Coordinates{}, false
+	}
+	return Coordinates{Lat: c.Lat, Lng: c.Lng}, true
+}
+
+// CityNames lists the lower-case names a city goes by, so loads stored under
+// an older name ("Bangalore") still match the canonical one ("Bengaluru").
+// An unknown city only matches itself.
+func CityNames(city string) []string {
+	c, ok := cities.Lookup(city)
+	if !ok {
+		return []string{strings.ToLower(strings.TrimSpace(city))}
+	}
+	return lowerNames(c)
+}
+
+// CitiesWithin lists the lower-case names of the known cities within radiusKm
+// of the given city, including itself. An unknown city only matches itself.
+func CitiesWithin(city string, radiusKm float64) []string {
+	center, ok := CityCoordinates(city)
+	if !ok {
+		return []string{strings.ToLower(strings.TrimSpace(city))}
+	}
+
+	var names []string
+	for _, c := range cities.All() {
+		if DistanceKm(center, Coordinates{Lat: c.Lat, Lng: c.Lng}) <= radiusKm {
+			names = append(names, lowerNames(c)...)
+		}
+	}
+	return names
+}
+
+func lowerNames(c *cities.City) []string {
+	names := c.Names()
+	for i, name := range names {
+		names[i] = strings.ToLower(name)
+	}
+	return names
+}
+
+// DistanceKm is the great-circle distance between two points
+func DistanceKm(a, b Coordinates) float64 {
+	const earthRadiusKm = 6371.0
+
+	lat1 := a.Lat * math.Pi / 180
+	lat2 := b.Lat * math.Pi / 180
+	dLat := (b.Lat - a.Lat) * math.Pi / 180
+	dLng := (b.Lng - a.Lng) * math.Pi / 180
+
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * earthRadiusKm * math.Asin(math.Sqrt(h))
+}
+
+// materialCategories groups common materials, keyed by lower-case category
+var materialCategories = map[string][]string{
+	"metals":       {"steel", "iron", "aluminium", "aluminum", "copper", "coils", "scrap", "pipes"},
+	"construction": {"cement", "sand", "bricks", "tiles", "marble", "granite", "gravel", "paint"},
+	"agriculture":  {"rice", "wheat", "grain", "pulses", "sugar", "cotton", "spices", "onion", "potato", "vegetables", "fruits", "fertilizer"},
+	"fmcg":         {"fmcg", "groceries", "beverages", "food", "packaged food", "soap"},
+	"electronics":  {"electronics", "appliances", "mobiles", "computers"},
+	"textiles":     {"textiles", "garments", "yarn", "fabric", "cloth"},
+	"chemicals":    {"chemicals", "plastic", "plastics", "polymer", "resin"},
+	"machinery":    {"machinery", "machines", "auto parts", "spare parts", "equipment"},
+}
+
+// MaterialsInCategory lists the materials of a category; nil if the category is unknown
+func MaterialsInCategory(category string) []string {
+	return materialCategories[strings.ToLower(strings.TrimSpace(category))]
+}
@@ -0,0 +1,148 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// Public ID prefixes, one per entity
+const (
+	PrefixTrucker         = "TR"
+	PrefixShipper         = "SH"
+	PrefixLoad            = "LD"
+	PrefixBooking         = "BK"
+	PrefixWebhookEndpoint = "WH"
+	PrefixWebhookDelivery = "WD"
+	PrefixLaneAlert       = "AL"
+	PrefixRecurringLoad   = "RL"
+)
+
+// idEntities names the entity behind each prefix in validation errors
+var idEntities = map[string]string{
+	PrefixTrucker:         "trucker",
+	PrefixShipper:         "shipper",
+	PrefixLoad:            "load",
+	PrefixBooking:         "booking",
+	PrefixWebhookEndpoint: "webhook endpoint",
+	PrefixWebhookDelivery: "webhook delivery",
+	PrefixLaneAlert:       "lane alert",
+	PrefixRecurringLoad:   "recurring load",
+}
+
+// idAlphabet is Crockford's base32: no I, L, O or U, so IDs read out over
+// the phone or typed on WhatsApp are hard to get wrong
+const idAlphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// idDigits is the minimum number of base32 digits before the check character
+const idDigits = 5
+
+// FormatID turns the nth ID of an entity into its public form: the prefix,
+// n in base32 padded to five digits and a check character, e.g. "LD0000G7".
+// n comes from a database sequence or the memory store's counter, so IDs
+// never collide
+func FormatID(prefix string, n uint64) string {
+	var digits []byte
+	for ; n > 0; n /= 32 {
+		digits = append([]byte{idAlphabet[n%32]}, digits...)
+	}
+	for len(digits) < idDigits {
+		digits = append([]byte{'0'}, digits...)
+	}
+	return prefix + string(digits) + string(idCheck(string(digits)))
+}
+
+// ParseID validates an ID typed by a user or sent to the API and returns its
+// canonical form. Case does not matter and O, I and L are read as 0, 1 and 1.
+// The check character catches any single mistyped character and most swapped
+// pairs before the lookup can land on someone else's load or booking.
+//
+// IDs issued before this scheme (a prefix and 5 or 13 digits) are accepted
+// as they are so existing loads and bookings can still be found.
+func ParseID(prefix, id string) (string, error) {
+	entity := idEntities[prefix]
+	id = strings.ToUpper(strings.TrimSpace(id))
+	if !strings.HasPrefix(id, prefix) {
+		return "", Invalid(entity, "id", fmt.Sprintf("%s IDs start with %s", entity, prefix))
+	}
+
+	rest := id[len(prefix):]
+	if isLegacyID(rest) {
+		return id, nil
+	}
+	if len(rest) <= idDigits {
+		return "", Invalid(entity, "id", fmt.Sprintf("%s is too short for a %s ID", id, entity))
+	}
+
+	var canonical strings.Builder
+	for _, r := range rest {
+		switch r {
+		case 'O':
+			r = '0'
+		case 'I', 'L':
+			r = '1'
+		}
+		if !strings.ContainsRune(idAlphabet, r) {
+			return "", Invalid(entity, "id", fmt.Sprintf("%s is not a valid %s ID", id, entity))
+		}
+		canonical.WriteRune(r)
+	}
+
+	digits := canonical.String()
+	if idCheck(digits[:len(digits)-1]) != digits[len(digits)-1] {
+		return "", Invalid(entity, "id", fmt.Sprintf("%s is not a valid %s ID, please check it for typos", id, entity))
+	}
+	return prefix + digits, nil
+}
+
+// isLegacyID reports whether the part after the prefix is one of the old
+// formats: five digits (SH00001) or Unix seconds and three digits
+func isLegacyID(rest string) bool {
+	if len(rest) != 5 && len(rest) != 13 {
+		return false
+	}
+	for _, r := range rest {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// idCheck computes the Luhn mod 32 check character of base32 digits
+func idCheck(digits string) byte {
+	sum := 0
+	double := true
+	for i := len(digits) - 1; i >= 0; i-- {
+		v := strings.IndexByte(idAlphabet, digits[i])
+		if double {
+			v *= 2
+			v = v/32 + v%32
+		}
+		sum += v
+		double = !double
+	}
+	return idAlphabet[(32-sum%32)%32]
+}
+
+// nextID issues the next public ID of an entity from a database sequence,
+// inside the transaction creating the row. PostgreSQL uses a native sequence
+// per prefix; SQLite, which has none, counts in the id_sequences table.
+func nextID(tx *gorm.DB, prefix string) (string, error) {
+	db := tx.Session(&gorm.Session{NewDB: true})
+
+	var n uint64
+	var err error
+	if tx.Dialector.Name() == "postgres" {
+		err = db.Raw("SELECT nextval(?)", strings.ToLower(prefix)+"_id_seq").Scan(&n).Error
+	} else {
+		err = db.Raw(`INSERT INTO id_sequences (prefix, value) VALUES (?, 1)
+			ON CONFLICT (prefix) DO UPDATE SET value = id_sequences.value + 1
+			RETURNING value`, prefix).Scan(&n).Error
+	}
+	if err != nil {
+		return "", fmt.Errorf("next %s ID: %w", idEntities[prefix], err)
+	}
+	return FormatID(prefix, n), nil
+}
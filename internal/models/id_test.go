@@ -0,0 +1,74 @@
+package models
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestFormatID(t *testing.T) {
+	for _, tc := range []struct {
+		n    uint64
+		want string
+	}{
+		{1, "LD00001Y"},
+		{2, "LD00002W"},
+		{1234567, "LD15NM7C"},
+		{32 * 32 * 32 * 32 * 32, "LD100000" + string(idCheck("100000"))},
+	} {
+		if got := FormatID(PrefixLoad, tc.n); got != tc.want {
+			t.Errorf("FormatID(LD, %d) = %s, want %s", tc.n, got, tc.want)
+		}
+	}
+}
+
+func TestParseID(t *testing.T) {
+	for _, tc := range []struct {
+		prefix, id, want string
+	}{
+		{PrefixLoad, "LD00001Y", "LD00001Y"},
+		{PrefixLoad, " ld00001y ", "LD00001Y"},
+		{PrefixLoad, "LDOOOO1Y", "LD00001Y"},    // O read as 0
+		{PrefixLoad, "LD15NM7C", "LD15NM7C"},    // more than five digits
+		{PrefixBooking, "BKOOOOIY", "BK00001Y"}, // I read as 1
+		{PrefixShipper, "SH00001", "SH00001"},   // legacy five digits
+		{PrefixLoad, "LD1718000000042", "LD1718000000042"},
+	} {
+		got, err := ParseID(tc.prefix, tc.id)
+		if err != nil || got != tc.want {
+			t.Errorf("ParseID(%s, %q) = %s, %v; want %s", tc.prefix, tc.id, got, err, tc.want)
+		}
+	}
+
+	for _, tc := range []struct {
+		prefix, id, code string
+	}{
+		{PrefixLoad, "BK00001Y", "load_id_invalid"},       // wrong prefix
+		{PrefixLoad, "LD0001Y", "load_id_invalid"},        // too short
+		{PrefixLoad, "LD00001U", "load_id_invalid"},       // not base32
+		{PrefixLoad, "LD00002Y", "load_id_invalid"},       // mistyped digit
+		{PrefixLoad, "LD51NM7C", "load_id_invalid"},       // swapped digits
+		{PrefixBooking, "BK", "booking_id_invalid"},       // no digits
+		{PrefixTrucker, "", "trucker_id_invalid"},         // empty
+		{PrefixTrucker, "TR00001Z", "trucker_id_invalid"}, // wrong check character
+	} {
+		_, err := ParseID(tc.prefix, tc.id)
+		var domainErr *Error
+		if !errors.As(err, &domainErr) || !errors.Is(err, ErrInvalid) || domainErr.Code() != tc.code {
+			t.Errorf("ParseID(%s, %q) = %v, want %s", tc.prefix, tc.id, err, tc.code)
+		}
+	}
+
+	// Every single-character typo of an ID is caught
+	id := FormatID(PrefixLoad, 987654)
+	for i := len(PrefixLoad); i < len(id); i++ {
+		for _, r := range idAlphabet {
+			if byte(r) == id[i] {
+				continue
+			}
+			typo := id[:i] + string(r) + id[i+1:]
+			if _, err := ParseID(PrefixLoad, typo); err == nil {
+				t.Fatalf("ParseID accepted %s, a typo of %s", typo, id)
+			}
+		}
+	}
+}
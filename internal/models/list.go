@@ -0,0 +1,247 @@
+package models
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultPageSize is used when a list request does not set a limit
+	DefaultPageSize = 20
+
+	// MaxPageSize caps the limit of a list request
+	MaxPageSize = 100
+)
+
+// Sort options for list requests
+const (
+	SortPrice       = "price"        // load price, or agreed price for bookings
+	SortRatePerTon  = "rate_per_ton" // loads only, see Load.CalculateRate
+	SortRatePerKm   = "rate_per_km"  // loads only, see Load.CalculateRatePerKm
+	SortLoadingDate = "loading_date" // loads only
+	SortCreatedAt   = "created_at"
+
+	SortAsc  = "asc"
+	SortDesc = "desc"
+)
+
+// LoadSorts and BookingSorts are the sort options each list supports
+var (
+	LoadSorts    = []string{SortPrice, SortRatePerTon, SortRatePerKm, SortLoadingDate, SortCreatedAt}
+	BookingSorts = []string{SortPrice, SortCreatedAt}
+)
+
+// ListOptions selects one page of a sorted list.
+// Cursor is the NextCursor of the previous page, empty for the first page.
+type ListOptions struct {
+	Cursor string `json:"cursor"`
+	Limit  int    `json:"limit"`
+	Sort   string `json:"sort"`
+	Order  string `json:"order"`
+}
+
+// Normalize fills in defaults (newest first, DefaultPageSize) and validates the options
+func (o *ListOptions) Normalize(sorts []string) error {
+	if o.Limit <= 0 {
+		o.Limit = DefaultPageSize
+	}
+	if o.Limit > MaxPageSize {
+		o.Limit = MaxPageSize
+	}
+
+	o.Sort = strings.ToLower(o.Sort)
+	if o.Sort == "" {
+		o.Sort = SortCreatedAt
+	}
+	valid := false
+	for _, s := range sorts {
+		if o.Sort == s {
+			valid = true
+			break
+		}
+	}
+	if !valid {
+		return Invalid("", "sort", "invalid sort: "+o.Sort)
+	}
+
+	o.Order = strings.ToLower(o.Order)
+	if o.Order == "" {
+		o.Order = SortDesc
+	}
+	if o.Order != SortAsc && o.Order != SortDesc {
+		return Invalid("", "order", "invalid order: "+o.Order)
+	}
+
+	if o.Cursor != "" {
+		if _, err := ParseCursor(o.Cursor); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// IsTimeSort reports whether the sort key is a timestamp rather than a number
+func IsTimeSort(sort string) bool {
+	return sort == SortLoadingDate || sort == SortCreatedAt
+}
+
+// LoadFilter narrows a load list; empty fields match everything.
+// Cities match any of their names (see CityNames), vehicle type as a substring.
+type LoadFilter struct {
+	ShipperID   string `json:"shipper_id"`
+	Status      string `json:"status"`
+	FromCity    string `json:"from_city"`
+	ToCity      string `json:"to_city"`
+	VehicleType string `json:"vehicle_type"`
+}
+
+// Matches checks a load against the filter
+func (f *LoadFilter) Matches(l *Load) bool {
+	if f.ShipperID != "" && l.ShipperID != f.ShipperID {
+		return false
+	}
+	if f.Status != "" && l.Status != f.Status {
+		return false
+	}
+	if f.Status == LoadStatusAvailable && !l.IsListed(time.Now()) {
+		return false
+	}
+	if f.FromCity != "" && !containsFold(CityNames(f.FromCity), l.FromCity) {
+		return false
+	}
+	if f.ToCity != "" && !containsFold(CityNames(f.ToCity), l.ToCity) {
+		return false
+	}
+	if f.VehicleType != "" && !strings.Contains(strings.ToLower(l.VehicleType), strings.ToLower(f.VehicleType)) {
+		return false
+	}
+	return true
+}
+
+// ResolveCities replaces the typed city names with canonical ones from the city master
+func (f *LoadFilter) ResolveCities() error {
+	for _, city := range []*string{&f.FromCity, &f.ToCity} {
+		resolved, err := ResolveCity(*city)
+		if err != nil {
+			return err
+		}
+		*city = resolved.Name
+	}
+	return nil
+}
+
+// BookingFilter narrows a booking list; empty fields match everything
+type BookingFilter struct {
+	TruckerID string `json:"trucker_id"`
+	ShipperID string `json:"shipper_id"`
+	LoadID    string `json:"load_id"`
+	Status    string `json:"status"`
+}
+
+// Matches checks a booking against the filter
+func (f *BookingFilter) Matches(b *Booking) bool {
+	if f.TruckerID != "" && b.TruckerID != f.TruckerID {
+		return false
+	}
+	if f.ShipperID != "" && b.ShipperID != f.ShipperID {
+		return false
+	}
+	if f.LoadID != "" && b.LoadID != f.LoadID {
+		return false
+	}
+	if f.Status != "" && b.Status != f.Status {
+		return false
+	}
+	return true
+}
+
+// LoadPage is one page of a load list
+type LoadPage struct {
+	Loads      []*Load `json:"loads"`
+	NextCursor string  `json:"next_cursor,omitempty"`
+}
+
+// BookingPage is one page of a booking list
+type BookingPage struct {
+	Bookings   []*Booking `json:"bookings"`
+	NextCursor string     `json:"next_cursor,omitempty"`
+}
+
+// SortKey is the position of a row in a sorted list: the sort value
+// (Time for date sorts, Value otherwise) with the row ID as tie-breaker
+type SortKey struct {
+	Value float64   `json:"v,omitempty"`
+	Time  time.Time `json:"t"`
+	ID    uint      `json:"id"`
+}
+
+// Compare orders two keys ascending
+func (k SortKey) Compare(other SortKey) int {
+	switch {
+	case k.Time.Before(other.Time):
+		return -1
+	case k.Time.After(other.Time):
+		return 1
+	case k.Value < other.Value:
+		return -1
+	case k.Value > other.Value:
+		return 1
+	case k.ID < other.ID:
+		return -1
+	case k.ID > other.ID:
+		return 1
+	}
+	return 0
+}
+
+// Cursor encodes the key as an opaque page cursor
+func (k SortKey) Cursor() string {
+	b, _ := json.Marshal(k)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// ParseCursor decodes a cursor produced by SortKey.Cursor
+func ParseCursor(cursor string) (*SortKey, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, Invalid("", "cursor", "invalid cursor")
+	}
+	var key SortKey
+	if err := json.Unmarshal(raw, &key); err != nil {
+		return nil, Invalid("", "cursor", "invalid cursor")
+	}
+	return &key, nil
+}
+
+// SortKey returns the load's position for a sort option
+func (l *Load) SortKey(sort string) SortKey {
+	key := SortKey{ID: l.ID}
+	switch sort {
+	case SortPrice:
+		key.Value = l.Price
+	case SortRatePerTon:
+		key.Value = l.CalculateRate()
+	case SortRatePerKm:
+		key.Value = l.CalculateRatePerKm()
+	case SortLoadingDate:
+		key.Time = l.LoadingDate
+	default:
+		key.Time = l.CreatedAt
+	}
+	return key
+}
+
+// SortKey returns the booking's position for a sort option
+func (b *Booking) SortKey(sort string) SortKey {
+	key := SortKey{ID: b.ID}
+	switch sort {
+	case SortPrice:
+		key.Value = b.AgreedPrice
+	default:
+		key.Time = b.CreatedAt
+	}
+	return key
+}
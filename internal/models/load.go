@@ -2,6 +2,7 @@ package models
 
 import (
 	"fmt"
+	"math"
 	"strings"
 	"time"
 
@@ -20,8 +21,10 @@ type Load struct {
 	ShipperPhone string `json:"shipper_phone" gorm:"index"`
 
 	// Route details
-	FromCity    string  `json:"from_city" gorm:"index"` // Index for faster search
-	ToCity      string  `json:"to_city" gorm:"index"`   // Index for faster search
+	FromCity    string  `json:"from_city" gorm:"index"`    // Index for faster search
+	ToCity      string  `json:"to_city" gorm:"index"`      // Index for faster search
+	FromCityID  string  `json:"from_city_id" gorm:"index"` // city master ID, empty for towns not in the master
+	ToCityID    string  `json:"to_city_id" gorm:"index"`   // city master ID, empty for towns not in the master
 	PickupPoint string  `json:"pickup_point"`
 	DropPoint   string  `json:"drop_point"`
 	Distance    float64 `json:"distance"` // in km
@@ -31,6 +34,14 @@ type Load struct {
 	Weight      float64 `json:"weight"`                    // in tons
 	VehicleType string  `json:"vehicle_type" gorm:"index"` // required vehicle type - indexed for search
 
+	// Multi-truck and part loads. A load needing several trucks is shared
+	// equally by TruckCount bookings; a Splittable (LTL) load is booked by
+	// weight instead. The load stays available until fully allocated.
+	TruckCount   int     `json:"truck_count" gorm:"default:1"`
+	Splittable   bool    `json:"splittable"`
+	TrucksBooked int     `json:"trucks_booked"` // bookings made so far
+	BookedWeight float64 `json:"booked_weight"` // tons allocated to bookings so far
+
 	// Pricing
 	Price        float64 `json:"price"`         // offered price
 	PaymentTerms string  `json:"payment_terms"` // e.g., "Advance", "To-Pay", "POD"
@@ -41,6 +52,15 @@ type Load struct {
 	// Status
 	Status string `json:"status" gorm:"default:available;index"` // "available", "booked", "in-transit", "delivered"
 
+	RecurringID string `json:"recurring_id,omitempty" gorm:"index"` // recurring load this occurrence was posted from
+
+	// Expiry: an available load is unlisted once ExpiresAt passes, see LoadExpiry
+	ExpiresAt        *time.Time `json:"expires_at" gorm:"index"`
+	ExpiryRemindedAt *time.Time `json:"-"` // when the shipper was warned of the expiry
+
+	// Version is incremented by every update, see WithExpectedVersion
+	Version uint `json:"version" gorm:"not null;default:1"`
+
 	// Note: CreatedAt and UpdatedAt are automatically handled by gorm.Model
 
 	// Relationships (optional - add when you need them)
@@ -51,12 +71,23 @@ type Load struct {
 func (l *Load) BeforeCreate(tx *gorm.DB) error {
 	// Generate LoadID if not set
 	if l.LoadID == "" {
-		l.LoadID = fmt.Sprintf("LD%d%03d", time.Now().Unix(), time.Now().Nanosecond()%1000)
+		id, err := nextID(tx, PrefixLoad)
+		if err != nil {
+			return err
+		}
+		l.LoadID = id
+	}
+	if l.Version == 0 {
+		l.Version = 1
 	}
 
-	// Normalize city names (Title case)
-	l.FromCity = strings.Title(strings.ToLower(strings.TrimSpace(l.FromCity)))
-	l.ToCity = strings.Title(strings.ToLower(strings.TrimSpace(l.ToCity)))
+	// Normalize city names (Title case) unless they are canonical names from the city master
+	if l.FromCityID == "" {
+		l.FromCity = strings.Title(strings.ToLower(strings.TrimSpace(l.FromCity)))
+	}
+	if l.ToCityID == "" {
+		l.ToCity = strings.Title(strings.ToLower(strings.TrimSpace(l.ToCity)))
+	}
 
 	// Normalize phone number (ensure it starts with +91 if not already)
 	if l.ShipperPhone != "" && !strings.HasPrefix(l.ShipperPhone, "+") {
@@ -68,15 +99,70 @@ func (l *Load) BeforeCreate(tx *gorm.DB) error {
 		l.Status = "available"
 	}
 
+	if l.TruckCount <= 0 {
+		l.TruckCount = 1
+	}
+
+	l.SetDefaultExpiry()
+
 	return nil
 }
 
-// LoadSearch parameters for searching loads (KEEPING YOUR STRUCT AS IS)
+// LoadSearch parameters for searching loads.
+// Zero values are ignored; dates are YYYY-MM-DD and both ends are inclusive.
 type LoadSearch struct {
 	FromCity    string `json:"from_city"`
 	ToCity      string `json:"to_city"`
 	VehicleType string `json:"vehicle_type"`
 	DateFrom    string `json:"date_from"`
+	DateTo      string `json:"date_to"`
+
+	// Price and rate
+	MinPrice      float64 `json:"min_price"`
+	MaxPrice      float64 `json:"max_price"`
+	MinRatePerTon float64 `json:"min_rate_per_ton"`
+	MinRatePerKm  float64 `json:"min_rate_per_km"`
+
+	// Weight in tons; see FitCapacity. MaxWeight applies to what one truck
+	// carries, and part loads always match it since any share can be booked.
+	MinWeight float64 `json:"min_weight"`
+	MaxWeight float64 `json:"max_weight"`
+
+	Material         string `json:"material"`          // substring match
+	MaterialCategory string `json:"material_category"` // e.g. "metals", see MaterialsInCategory
+	PaymentTerms     string `json:"payment_terms"`
+
+	// Pickup within RadiusKm of NearCity (only NearCity itself if no radius)
+	NearCity string  `json:"near_city"`
+	RadiusKm float64 `json:"radius_km"`
+
+	// TruckerID limits results to loads the trucker's truck can carry.
+	// It is resolved by the caller with FitCapacity; stores ignore it.
+	TruckerID string `json:"trucker_id"`
+}
+
+// ResolveCities replaces the typed city names with canonical ones from the city master
+func (s *LoadSearch) ResolveCities() error {
+	for _, city := range []*string{&s.FromCity, &s.ToCity, &s.NearCity} {
+		resolved, err := ResolveCity(*city)
+		if err != nil {
+			return err
+		}
+		*city = resolved.Name
+	}
+	return nil
+}
+
+// FitCapacity restricts the search to loads no heavier than a truck's capacity
+func (s *LoadSearch) FitCapacity(capacity float64) {
+	if capacity > 0 && (s.MaxWeight == 0 || s.MaxWeight > capacity) {
+		s.MaxWeight = capacity
+	}
+}
+
+// NearbyCities lists the lower-case pickup cities accepted by NearCity and RadiusKm
+func (s *LoadSearch) NearbyCities() []string {
+	return CitiesWithin(s.NearCity, s.RadiusKm)
 }
 
 // Load Status constants
@@ -85,8 +171,14 @@ const (
 	LoadStatusBooked    = "booked"
 	LoadStatusInTransit = "in-transit"
 	LoadStatusDelivered = "delivered"
+	LoadStatusExpired   = "expired"   // never booked before its expiry
+	LoadStatusWithdrawn = "withdrawn" // taken off the marketplace by the shipper
 )
 
+// LoadExpiry is how long after its loading date an unbooked load stays listed.
+// LOAD_EXPIRY_HOURS overrides it at startup.
+var LoadExpiry = 24 * time.Hour
+
 // Payment Terms constants
 const (
 	PaymentTermsAdvance = "Advance"
@@ -94,11 +186,89 @@ const (
 	PaymentTermsPOD     = "POD"
 )
 
+// Validate checks the fields every new load must have, whichever channel it was posted from
+func (l *Load) Validate() error {
+	if l.FromCity == "" || l.ToCity == "" || l.Material == "" {
+		return Invalid("load", "", "From city, to city, and material are required")
+	}
+
+	if l.ShipperID == "" || l.ShipperName == "" || l.ShipperPhone == "" {
+		return Invalid("load", "shipper_id", "Shipper details are required")
+	}
+
+	if l.Weight <= 0 || l.Price <= 0 {
+		return Invalid("load", "", "Weight and price must be greater than zero")
+	}
+
+	if l.TruckCount < 0 {
+		return Invalid("load", "truck_count", "Truck count cannot be negative")
+	}
+
+	if l.Splittable && l.TruckCount > 1 {
+		return Invalid("load", "splittable", "A load is split by truck count or by weight, not both")
+	}
+
+	return nil
+}
+
+// ResolveCities maps the typed route to canonical cities and their master IDs.
+// A locality ("Peenya") becomes its city ("Bengaluru") and is kept as the
+// pickup or drop point unless one was given.
+func (l *Load) ResolveCities() error {
+	from, err := ResolveCity(l.FromCity)
+	if err != nil {
+		return err
+	}
+	to, err := ResolveCity(l.ToCity)
+	if err != nil {
+		return err
+	}
+
+	l.FromCity, l.FromCityID = from.Name, from.ID
+	if l.PickupPoint == "" {
+		l.PickupPoint = from.Locality
+	}
+	l.ToCity, l.ToCityID = to.Name, to.ID
+	if l.DropPoint == "" {
+		l.DropPoint = to.Locality
+	}
+	return nil
+}
+
 // Helper methods for the Load model
 func (l *Load) IsAvailable() bool {
 	return l.Status == LoadStatusAvailable
 }
 
+// IsListed reports whether the load is available and not past its expiry,
+// so stale loads drop out of search before the sweeper marks them expired
+func (l *Load) IsListed(now time.Time) bool {
+	return l.IsAvailable() && (l.ExpiresAt == nil || l.ExpiresAt.After(now))
+}
+
+// SetDefaultExpiry sets ExpiresAt to LoadExpiry after the loading date unless already set
+func (l *Load) SetDefaultExpiry() {
+	if l.ExpiresAt == nil && !l.LoadingDate.IsZero() {
+		expiresAt := l.LoadingDate.Add(LoadExpiry)
+		l.ExpiresAt = &expiresAt
+	}
+}
+
+// Renew moves the loading date, resets the expiry and relists an expired load.
+// A price of 0 keeps the current price.
+func (l *Load) Renew(loadingDate time.Time, price float64) {
+	expiresAt := loadingDate.Add(LoadExpiry)
+	l.LoadingDate = loadingDate
+	l.ExpiresAt = &expiresAt
+	l.ExpiryRemindedAt = nil
+	if price > 0 {
+		l.Price = price
+	}
+	if l.Status == LoadStatusExpired {
+		l.Status = LoadStatusAvailable
+	}
+}
+
 func (l *Load) Book() {
 	l.Status = LoadStatusBooked
 }
@@ -111,6 +281,128 @@ func (l *Load) MarkDelivered() {
 	l.Status = LoadStatusDelivered
 }
 
+// weightTolerance absorbs float rounding when comparing allocated weights, in tons
+const weightTolerance = 0.001
+
+// Trucks is the number of trucks the load needs
+func (l *Load) Trucks() int {
+	if l.TruckCount < 1 {
+		return 1
+	}
+	return l.TruckCount
+}
+
+// RemainingWeight is the weight not yet allocated to bookings, in tons
+func (l *Load) RemainingWeight() float64 {
+	return math.Max(l.Weight-l.BookedWeight, 0)
+}
+
+// TruckWeight is what one booking carries: the whole load, an equal share of
+// a multi-truck load, or at most the weight left on a part load
+func (l *Load) TruckWeight() float64 {
+	if l.Splittable {
+		return l.RemainingWeight()
+	}
+	return l.Weight / float64(l.Trucks())
+}
+
+// FullyAllocated reports whether every truck (or every ton of a part load) is booked
+func (l *Load) FullyAllocated() bool {
+	if l.Splittable {
+		return l.RemainingWeight() <= weightTolerance
+	}
+	return l.TrucksBooked >= l.Trucks()
+}
+
+// Allocate works out a trucker's share of the load: the weight the new
+// booking carries and the price it pays. active are the trucker's
+// undelivered bookings. A full truckload needs an empty truck, while part
+// loads can share one as long as their combined weight fits its capacity.
+// weight only applies to splittable loads; 0 books as much as is left and fits.
+func (l *Load) Allocate(weight float64, trucker *Trucker, active []*Booking) (float64, float64, error) {
+	if !l.IsListed(time.Now()) {
+		return 0, 0, Unavailable("load", "", "load not available")
+	}
+
+	if !trucker.Active || (!trucker.Available && (len(active) == 0 || !l.Splittable)) {
+		return 0, 0, Unavailable("trucker", "", "trucker not available")
+	}
+	free := math.Inf(1)
+	if trucker.Capacity > 0 {
+		free = trucker.Capacity
+	}
+	for _, booking := range active {
+		if !l.Splittable || !booking.PartLoad {
+			return 0, 0, Unavailable("trucker", "", "trucker not available")
+		}
+		free -= booking.Weight
+	}
+
+	if !l.Splittable {
+		weight = l.TruckWeight()
+		if weight > free+weightTolerance {
+			return 0, 0, Unavailable("trucker", "capacity", "load exceeds truck capacity")
+		}
+		return weight, l.Price / float64(l.Trucks()), nil
+	}
+
+	remaining := l.RemainingWeight()
+	if weight <= 0 {
+		weight = math.Min(remaining, free)
+	}
+	if weight > remaining+weightTolerance {
+		return 0, 0, Unavailable("load", "weight", fmt.Sprintf("only %.1f tons left on this load", remaining))
+	}
+	if weight <= 0 || weight > free+weightTolerance {
+		return 0, 0, Unavailable("trucker", "capacity", "load exceeds truck capacity")
+	}
+	return weight, l.Price * weight / l.Weight, nil
+}
+
+// AddBooking allocates a booking's weight, marking the load booked once nothing is left
+func (l *Load) AddBooking(weight float64) {
+	l.TrucksBooked++
+	l.BookedWeight += weight
+	if l.FullyAllocated() {
+		l.Book()
+	}
+}
+
+// RemoveBooking hands back the weight of a cancelled booking, relisting a
+// load that was booked only because it was fully allocated
+func (l *Load) RemoveBooking(weight float64) {
+	l.TrucksBooked = max(l.TrucksBooked-1, 0)
+	l.BookedWeight = math.Max(l.BookedWeight-weight, 0)
+	if l.Status == LoadStatusBooked && !l.FullyAllocated() {
+		l.Status = LoadStatusAvailable
+	}
+}
+
+// IsOpen reports whether the load is still on the marketplace or waiting
+// for its trucks: available, or booked and not yet picked up or delivered
+func (l *Load) IsOpen() bool {
+	return l.Status == LoadStatusAvailable || l.Status == LoadStatusBooked
+}
+
+// WeightText describes the weight for messages, e.g. "100.0 tons (4 trucks × 25.0 t, 2 left)"
+func (l *Load) WeightText() string {
+	switch {
+	case l.Splittable:
+		return fmt.Sprintf("%.1f tons (part load, %.1f t left)", l.Weight, l.RemainingWeight())
+	case l.Trucks() > 1:
+		return fmt.Sprintf("%.1f tons (%d trucks × %.1f t, %d left)", l.Weight, l.Trucks(), l.TruckWeight(), l.Trucks()-l.TrucksBooked)
+	default:
+		return fmt.Sprintf("%.1f tons", l.Weight)
+	}
+}
+
+// AcceptsVehicle reports whether a truck of the given type can take the load;
+// loads posted for "Any" vehicle accept every type
+func (l *Load) AcceptsVehicle(vehicleType string) bool {
+	return vehicleType == "" || l.VehicleType == "" || strings.EqualFold(l.VehicleType, "Any") ||
+		strings.Contains(strings.ToLower(l.VehicleType), strings.ToLower(vehicleType))
+}
+
 // CalculateRate returns price per ton
 func (l *Load) CalculateRate() float64 {
 	if l.Weight == 0 {
@@ -127,16 +419,17 @@ func (l *Load) CalculateRatePerKm() float64 {
 	return l.Price / l.Distance
 }
 
-// MatchesSearch checks if load matches search criteria
+// MatchesSearch checks if load matches search criteria.
+// DatabaseStore.SearchLoads implements the same rules in SQL.
 func (l *Load) MatchesSearch(search LoadSearch) bool {
 	matches := true
 
 	if search.FromCity != "" {
-		matches = matches && strings.EqualFold(l.FromCity, search.FromCity)
+		matches = matches && containsFold(CityNames(search.FromCity), l.FromCity)
 	}
 
 	if search.ToCity != "" {
-		matches = matches && strings.EqualFold(l.ToCity, search.ToCity)
+		matches = matches && containsFold(CityNames(search.ToCity), l.ToCity)
 	}
 
 	if search.VehicleType != "" {
@@ -149,5 +442,111 @@ func (l *Load) MatchesSearch(search LoadSearch) bool {
 		}
 	}
 
+	if search.DateTo != "" {
+		if date, err := time.Parse("2006-01-02", search.DateTo); err == nil {
+			matches = matches && l.LoadingDate.Before(date.AddDate(0, 0, 1))
+		}
+	}
+
+	if search.MinPrice > 0 {
+		matches = matches && l.Price >= search.MinPrice
+	}
+
+	if search.MaxPrice > 0 {
+		matches = matches && l.Price <= search.MaxPrice
+	}
+
+	if search.MinRatePerTon > 0 {
+		matches = matches && l.CalculateRate() >= search.MinRatePerTon
+	}
+
+	if search.MinRatePerKm > 0 {
+		matches = matches && l.CalculateRatePerKm() >= search.MinRatePerKm
+	}
+
+	if search.MinWeight > 0 {
+		matches = matches && l.Weight >= search.MinWeight
+	}
+
+	if search.MaxWeight > 0 {
+		matches = matches && (l.Splittable || l.TruckWeight() <= search.MaxWeight)
+	}
+
+	if search.Material != "" {
+		matches = matches && strings.Contains(strings.ToLower(l.Material), strings.ToLower(search.Material))
+	}
+
+	if search.MaterialCategory != "" {
+		matches = matches && containsFold(MaterialsInCategory(search.MaterialCategory), l.Material)
+	}
+
+	if search.PaymentTerms != "" {
+		matches = matches && strings.EqualFold(l.PaymentTerms, search.PaymentTerms)
+	}
+
+	if search.NearCity != "" {
+		matches = matches && containsFold(search.NearbyCities(), l.FromCity)
+	}
+
 	return matches
 }
+
+// containsFold reports whether list contains value, ignoring case
+func containsFold(list []string, value string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// LoadChanges are the edits a shipper may make while a load is still open for
+// booking. Nil fields are left unchanged.
+type LoadChanges struct {
+	Price       *float64   `json:"price"`
+	LoadingDate *time.Time `json:"loading_date"`
+	VehicleType *string    `json:"vehicle_type"`
+}
+
+// Validate checks the new values
+func (c *LoadChanges) Validate() error {
+	if c.Price == nil && c.LoadingDate == nil && c.VehicleType == nil {
+		return Invalid("load", "", "nothing to change")
+	}
+	if c.Price != nil && *c.Price <= 0 {
+		return Invalid("load", "price", "price must be greater than zero")
+	}
+	if c.LoadingDate != nil && !c.LoadingDate.After(time.Now()) {
+		return Invalid("load", "loading_date", "loading date must be in the future")
+	}
+	if c.VehicleType != nil && strings.TrimSpace(*c.VehicleType) == "" {
+		return Invalid("load", "vehicle_type", "vehicle type cannot be empty")
+	}
+	return nil
+}
+
+// Apply edits the load and returns the previous and new values of the
+// changed fields. A new loading date also moves the expiry.
+func (c *LoadChanges) Apply(l *Load) (EventData, EventData) {
+	before, after := EventData{}, EventData{}
+	if c.Price != nil && *c.Price != l.Price {
+		before["price"], after["price"] = l.Price, *c.Price
+		l.Price = *c.Price
+	}
+	if c.LoadingDate != nil && !c.LoadingDate.Equal(l.LoadingDate) {
+		before["loading_date"], after["loading_date"] = l.LoadingDate, *c.LoadingDate
+		l.Renew(*c.LoadingDate, 0)
+	}
+	if c.VehicleType != nil && *c.VehicleType != l.VehicleType {
+		before["vehicle_type"], after["vehicle_type"] = l.VehicleType, *c.VehicleType
+		l.VehicleType = *c.VehicleType
+	}
+	return before, after
+}
+
+// IsEditable reports whether the shipper may still edit or withdraw the load:
+// it must be available with no truck booked yet
+func (l *Load) IsEditable() bool {
+	return l.IsAvailable() && l.TrucksBooked == 0
+}
@@ -0,0 +1,40 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// OutboxMessage is an event waiting to be handed to in-process subscribers.
+// It is written in the same transaction as the change the event describes
+// and deleted once the event has been published, so an event is published
+// at least once if and only if its change was committed.
+type OutboxMessage struct {
+	gorm.Model
+
+	IdempotencyKey string `json:"idempotency_key" gorm:"uniqueIndex"`
+	EventID        uint   `json:"event_id" gorm:"index"`
+	Event          *Event `json:"event,omitempty"`
+}
+
+// HandledEvent records that a subscriber acted on an event, so that it
+// skips the event when the outbox publishes it again
+type HandledEvent struct {
+	ID             uint      `json:"id" gorm:"primarykey"`
+	Consumer       string    `json:"consumer" gorm:"uniqueIndex:idx_handled_events_consumer_key"`
+	IdempotencyKey string    `json:"idempotency_key" gorm:"uniqueIndex:idx_handled_events_consumer_key"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// IdempotencyKey identifies an event across redeliveries. Subscribers that
+// must act on an event only once can remember the keys they have handled.
+func (e *Event) IdempotencyKey() string {
+	return fmt.Sprintf("event-%d", e.ID)
+}
+
+// NewOutboxMessage queues a recorded event for publishing
+func NewOutboxMessage(event *Event) *OutboxMessage {
+	return &OutboxMessage{IdempotencyKey: event.IdempotencyKey(), EventID: event.ID, Event: event}
+}
@@ -0,0 +1,244 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// RecurringHorizon is how far ahead occurrences of a recurring load are posted
+	RecurringHorizon = 48 * time.Hour
+
+	// DefaultRecurringTime is the loading time used when a schedule gives none
+	DefaultRecurringTime = "08:00"
+)
+
+// scheduleWeekdays are the day names accepted in a schedule, in calendar order
+var scheduleWeekdays = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+
+// schedulePresets are the named schedules accepted by ParseScheduleDays
+var schedulePresets = map[string]string{
+	"daily":    "",
+	"everyday": "",
+	"weekdays": "mon,tue,wed,thu,fri",
+	"weekends": "sun,sat",
+}
+
+// RecurringLoad is a template for a load a shipper dispatches regularly on the
+// same lane. The scheduler posts each occurrence as a Load ahead of time and,
+// on a contracted lane, books it straight to the contracted trucker.
+type RecurringLoad struct {
+	gorm.Model
+
+	RecurringID  string `json:"recurring_id" gorm:"uniqueIndex"`
+	ShipperID    string `json:"shipper_id" gorm:"index"`
+	ShipperName  string `json:"shipper_name"`
+	ShipperPhone string `json:"shipper_phone"`
+
+	// Lane and load details copied to every occurrence
+	FromCity     string  `json:"from_city"`
+	FromCityID   string  `json:"from_city_id"`
+	ToCity       string  `json:"to_city"`
+	ToCityID     string  `json:"to_city_id"`
+	PickupPoint  string  `json:"pickup_point"`
+	DropPoint    string  `json:"drop_point"`
+	Distance     float64 `json:"distance"`
+	Material     string  `json:"material"`
+	Weight       float64 `json:"weight"`
+	VehicleType  string  `json:"vehicle_type"`
+	TruckCount   int     `json:"truck_count"`
+	Price        float64 `json:"price"`
+	PaymentTerms string  `json:"payment_terms"`
+
+	// Schedule: loading days (comma-separated sun..sat, empty for every day)
+	// at Time, from StartDate until EndDate (open-ended when nil)
+	Days      string     `json:"days"`
+	Time      string     `json:"time"` // HH:MM, 24-hour
+	StartDate time.Time  `json:"start_date"`
+	EndDate   *time.Time `json:"end_date"`
+	SkipDates string     `json:"skip_dates"` // comma-separated YYYY-MM-DD occurrences not to post
+
+	// Contracted trucker the occurrences are booked to, empty for the open market
+	ContractTruckerID string `json:"contract_trucker_id"`
+
+	Paused         bool      `json:"paused" gorm:"index"`
+	ScheduledUntil time.Time `json:"scheduled_until"` // loading time of the last occurrence posted
+
+	// Version is incremented by every update, so a change made from a
+	// stale copy fails instead of undoing another
+	Version uint `json:"version" gorm:"not null;default:1"`
+}
+
+// BeforeCreate hook to auto-generate RecurringID
+func (r *RecurringLoad) BeforeCreate(tx *gorm.DB) error {
+	if r.RecurringID == "" {
+		id, err := nextID(tx, PrefixRecurringLoad)
+		if err != nil {
+			return err
+		}
+		r.RecurringID = id
+	}
+	if r.Version == 0 {
+		r.Version = 1
+	}
+	return nil
+}
+
+// ParseScheduleDays turns "daily", "weekdays", "weekends" or a list of day
+// names ("mon,wed,fri") into the Days stored on a recurring load
+func ParseScheduleDays(schedule string) (string, error) {
+	schedule = strings.ToLower(strings.TrimSpace(schedule))
+	if days, ok := schedulePresets[schedule]; ok {
+		return days, nil
+	}
+
+	selected := make(map[string]bool)
+	for _, day := range strings.FieldsFunc(schedule, func(r rune) bool { return r == ',' || r == ' ' }) {
+		if len(day) < 3 || !containsFold(scheduleWeekdays, day[:3]) {
+			return "", Invalid("recurring load", "days", "invalid schedule: "+schedule)
+		}
+		selected[day[:3]] = true
+	}
+	if len(selected) == 0 {
+		return "", Invalid("recurring load", "days", "invalid schedule: "+schedule)
+	}
+
+	var days []string
+	for _, day := range scheduleWeekdays {
+		if selected[day] {
+			days = append(days, day)
+		}
+	}
+	return strings.Join(days, ","), nil
+}
+
+// Validate checks the fields every recurring load must have
+func (r *RecurringLoad) Validate() error {
+	if r.FromCity == "" || r.ToCity == "" || r.Material == "" {
+		return Invalid("recurring load", "", "From city, to city, and material are required")
+	}
+
+	if r.Weight <= 0 || r.Price <= 0 {
+		return Invalid("recurring load", "", "Weight and price must be greater than zero")
+	}
+
+	if r.TruckCount < 0 {
+		return Invalid("recurring load", "truck_count", "Truck count cannot be negative")
+	}
+
+	if _, err := time.Parse("15:04", r.Time); err != nil {
+		return Invalid("recurring load", "time", "invalid time: "+r.Time)
+	}
+
+	if r.Days != "" {
+		if _, err := ParseScheduleDays(r.Days); err != nil {
+			return err
+		}
+	}
+
+	if r.EndDate != nil && r.EndDate.Before(r.StartDate) {
+		return Invalid("recurring load", "end_date", "end date is before start date")
+	}
+
+	return nil
+}
+
+// ResolveCities maps the lane to canonical cities and their master IDs, keeping
+// a locality as the pickup or drop point like Load.ResolveCities
+func (r *RecurringLoad) ResolveCities() error {
+	load := &Load{FromCity: r.FromCity, ToCity: r.ToCity, PickupPoint: r.PickupPoint, DropPoint: r.DropPoint}
+	if err := load.ResolveCities(); err != nil {
+		return err
+	}
+
+	r.FromCity, r.FromCityID, r.PickupPoint = load.FromCity, load.FromCityID, load.PickupPoint
+	r.ToCity, r.ToCityID, r.DropPoint = load.ToCity, load.ToCityID, load.DropPoint
+	return nil
+}
+
+// Schedule describes when the load repeats, e.g. "mon,tue,wed,thu,fri at 08:00"
+func (r *RecurringLoad) Schedule() string {
+	switch r.Days {
+	case "":
+		return "daily at " + r.Time
+	case schedulePresets["weekdays"]:
+		return "weekdays at " + r.Time
+	case schedulePresets["weekends"]:
+		return "weekends at " + r.Time
+	default:
+		return r.Days + " at " + r.Time
+	}
+}
+
+// RunsOn reports whether the schedule has an occurrence on the given day
+func (r *RecurringLoad) RunsOn(day time.Time) bool {
+	date := day.Format("2006-01-02")
+	if date < r.StartDate.Format("2006-01-02") {
+		return false
+	}
+	if r.EndDate != nil && date > r.EndDate.Format("2006-01-02") {
+		return false
+	}
+	if r.Days != "" && !containsFold(strings.Split(r.Days, ","), scheduleWeekdays[day.Weekday()]) {
+		return false
+	}
+	return !containsFold(strings.Split(r.SkipDates, ","), date)
+}
+
+// Occurrences lists the loading times after one time and up to another
+func (r *RecurringLoad) Occurrences(after, until time.Time) []time.Time {
+	clock, err := time.Parse("15:04", r.Time)
+	if err != nil {
+		return nil
+	}
+
+	var occurrences []time.Time
+	day := time.Date(after.Year(), after.Month(), after.Day(), 0, 0, 0, 0, after.Location())
+	for !day.After(until) {
+		loading := time.Date(day.Year(), day.Month(), day.Day(), clock.Hour(), clock.Minute(), 0, 0, day.Location())
+		if loading.After(after) && !loading.After(until) && r.RunsOn(loading) {
+			occurrences = append(occurrences, loading)
+		}
+		day = day.AddDate(0, 0, 1)
+	}
+	return occurrences
+}
+
+// Skip stops the occurrence on the given date from being posted
+func (r *RecurringLoad) Skip(date time.Time) {
+	day := date.Format("2006-01-02")
+	if containsFold(strings.Split(r.SkipDates, ","), day) {
+		return
+	}
+	if r.SkipDates != "" {
+		r.SkipDates += ","
+	}
+	r.SkipDates += day
+}
+
+// NewLoad builds the load for one occurrence
+func (r *RecurringLoad) NewLoad(loadingDate time.Time) *Load {
+	return &Load{
+		ShipperID:    r.ShipperID,
+		ShipperName:  r.ShipperName,
+		ShipperPhone: r.ShipperPhone,
+		FromCity:     r.FromCity,
+		FromCityID:   r.FromCityID,
+		ToCity:       r.ToCity,
+		ToCityID:     r.ToCityID,
+		PickupPoint:  r.PickupPoint,
+		DropPoint:    r.DropPoint,
+		Distance:     r.Distance,
+		Material:     r.Material,
+		Weight:       r.Weight,
+		VehicleType:  r.VehicleType,
+		TruckCount:   r.TruckCount,
+		Price:        r.Price,
+		PaymentTerms: r.PaymentTerms,
+		LoadingDate:  loadingDate,
+		Status:       LoadStatusAvailable,
+		RecurringID:  r.RecurringID,
+	}
+}
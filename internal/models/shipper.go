@@ -1,8 +1,6 @@
 package models
 
 import (
-	"fmt"
-
 	"gorm.io/gorm"
 )
 
@@ -10,14 +8,14 @@ type Shipper struct {
 	gorm.Model
 	ShipperID   string `gorm:"unique;not null"`
 	CompanyName string `gorm:"not null"`
-	GSTNumber   string `gorm:"unique;not null"`
-	Phone       string `gorm:"unique;not null"`
+	GSTNumber   string `gorm:"not null;uniqueIndex:idx_shippers_gst_number,where:deleted_at IS NULL"`
+	Phone       string `gorm:"not null;uniqueIndex:idx_shippers_phone,where:deleted_at IS NULL"`
 	Email       string
 	Address     string
 	City        string
 	State       string
 	Verified    bool    `gorm:"default:false"`
-	Active      bool    `gorm:"default:true"`
+	Active      bool    `gorm:"default:true"` // false once deactivated or deleted; no new loads
 	TotalLoads  int     `gorm:"default:0"`
 	Rating      float64 `gorm:"default:5.0"`
 }
@@ -25,9 +23,11 @@ type Shipper struct {
 // BeforeCreate generates ShipperID
 func (s *Shipper) BeforeCreate(tx *gorm.DB) error {
 	if s.ShipperID == "" {
-		var count int64
-		tx.Model(&Shipper{}).Count(&count)
-		s.ShipperID = fmt.Sprintf("SH%05d", count+1)
+		id, err := nextID(tx, PrefixShipper)
+		if err != nil {
+			return err
+		}
+		s.ShipperID = id
 	}
 	return nil
 }
@@ -0,0 +1,60 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// TrackingLink is a shareable, expiring link that lets a consignee follow a booking
+type TrackingLink struct {
+	gorm.Model
+
+	Token     string     `json:"token" gorm:"uniqueIndex"`
+	BookingID string     `json:"booking_id" gorm:"index"`
+	ShipperID string     `json:"shipper_id" gorm:"index"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	RevokedAt *time.Time `json:"revoked_at"`
+}
+
+// IsActive reports whether the link can still be used
+func (t *TrackingLink) IsActive() bool {
+	return t.RevokedAt == nil && time.Now().Before(t.ExpiresAt)
+}
+
+// PublicTracking is the minimal booking view exposed on public tracking links.
+// It must never carry OTPs, prices or phone numbers.
+type PublicTracking struct {
+	BookingID    string     `json:"booking_id"`
+	Status       string     `json:"status"`
+	FromCity     string     `json:"from_city"`
+	ToCity       string     `json:"to_city"`
+	LastLocation string     `json:"last_location"`
+	LastUpdated  time.Time  `json:"last_updated"`
+	ETA          *time.Time `json:"eta"`
+	TruckerName  string     `json:"trucker_name"`
+	VehicleNo    string     `json:"vehicle_no"`
+}
+
+// MaskName keeps the first name and the initial of the last name ("Rajesh K.").
+// A single name may be the trucker's only identifier, so only its initial is kept ("R.").
+func MaskName(name string) string {
+	parts := strings.Fields(name)
+	if len(parts) == 0 {
+		return ""
+	}
+	initial := string([]rune(parts[len(parts)-1])[0]) + "."
+	if len(parts) == 1 {
+		return initial
+	}
+	return parts[0] + " " + initial
+}
+
+// MaskVehicleNo hides the middle of a vehicle number ("TN01****34")
+func MaskVehicleNo(vehicleNo string) string {
+	if len(vehicleNo) <= 6 {
+		return strings.Repeat("*", len(vehicleNo))
+	}
+	return vehicleNo[:4] + strings.Repeat("*", len(vehicleNo)-6) + vehicleNo[len(vehicleNo)-2:]
+}
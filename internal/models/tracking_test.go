@@ -0,0 +1,34 @@
+package models
+
+import "testing"
+
+func TestMaskName(t *testing.T) {
+	for _, tc := range []struct {
+		name, want string
+	}{
+		{"Rajesh Kumar", "Rajesh K."},
+		{"Rajesh Kumar Sharma", "Rajesh S."},
+		{"  Rajesh   Kumar ", "Rajesh K."},
+		{"Rajesh", "R."},
+		{"", ""},
+		{"   ", ""},
+	} {
+		if got := MaskName(tc.name); got != tc.want {
+			t.Errorf("MaskName(%q) = %q, want %q", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestMaskVehicleNo(t *testing.T) {
+	for _, tc := range []struct {
+		vehicleNo, want string
+	}{
+		{"TN01AB1234", "TN01****34"},
+		{"MH12AB", "******"},
+		{"", ""},
+	} {
+		if got := MaskVehicleNo(tc.vehicleNo); got != tc.want {
+			t.Errorf("MaskVehicleNo(%q) = %q, want %q", tc.vehicleNo, got, tc.want)
+		}
+	}
+}
@@ -1,9 +1,7 @@
 package models
 
 import (
-	"fmt"
 	"strings"
-	"time"
 
 	"gorm.io/gorm"
 )
@@ -14,18 +12,23 @@ type Trucker struct {
 	gorm.Model
 
 	// Keep your TruckerID as string for backward compatibility
-	TruckerID    string  `json:"trucker_id" gorm:"uniqueIndex"`
-	Name         string  `json:"name"`
-	Phone        string  `json:"phone" gorm:"uniqueIndex"`      // WhatsApp number - unique
-	AadhaarLast4 string  `json:"aadhaar_last4"`                 // Last 4 digits for privacy
-	VehicleNo    string  `json:"vehicle_no" gorm:"uniqueIndex"` // Vehicle number should be unique
-	VehicleType  string  `json:"vehicle_type"`                  // e.g., "32ft multi axle", "19ft truck"
-	Capacity     float64 `json:"capacity"`                      // in tons
-	Verified     bool    `json:"verified" gorm:"default:false"`
-	Rating       float64 `json:"rating" gorm:"default:5.0"`
-	TotalTrips   int     `json:"total_trips" gorm:"default:0"`
-	CurrentCity  string  `json:"current_city"`
-	Available    bool    `json:"available" gorm:"default:true"`
+	TruckerID     string  `json:"trucker_id" gorm:"uniqueIndex"`
+	Name          string  `json:"name"`
+	Phone         string  `json:"phone" gorm:"uniqueIndex:idx_truckers_phone,where:deleted_at IS NULL"`           // WhatsApp number - unique among live truckers
+	AadhaarLast4  string  `json:"aadhaar_last4"`                                                                  // Last 4 digits for privacy
+	VehicleNo     string  `json:"vehicle_no" gorm:"uniqueIndex:idx_truckers_vehicle_no,where:deleted_at IS NULL"` // Vehicle number should be unique
+	VehicleType   string  `json:"vehicle_type"`                                                                   // e.g., "32ft multi axle", "19ft truck"
+	Capacity      float64 `json:"capacity"`                                                                       // in tons
+	Verified      bool    `json:"verified" gorm:"default:false"`
+	Rating        float64 `json:"rating" gorm:"default:5.0"`
+	TotalTrips    int     `json:"total_trips" gorm:"default:0"`
+	CurrentCity   string  `json:"current_city"`
+	CurrentCityID string  `json:"current_city_id"` // city master ID, empty for towns not in the master
+	Available     bool    `json:"available" gorm:"default:true"`
+	Active        bool    `json:"active" gorm:"default:true"` // false once deactivated by an admin or deleted
+
+	// Version is incremented by every update, see WithExpectedVersion
+	Version uint `json:"version" gorm:"not null;default:1"`
 
 	// Note: CreatedAt and UpdatedAt are automatically handled by gorm.Model
 
@@ -37,7 +40,14 @@ type Trucker struct {
 func (t *Trucker) BeforeCreate(tx *gorm.DB) error {
 	// Generate TruckerID if not set
 	if t.TruckerID == "" {
-		t.TruckerID = fmt.Sprintf("TR%d%03d", time.Now().Unix(), time.Now().Nanosecond()%1000)
+		id, err := nextID(tx, PrefixTrucker)
+		if err != nil {
+			return err
+		}
+		t.TruckerID = id
+	}
+	if t.Version == 0 {
+		t.Version = 1
 	}
 
 	// Normalize vehicle number (remove spaces, convert to uppercase)
@@ -70,8 +80,9 @@ func (t *Trucker) SetAvailable(available bool) {
 	t.Available = available
 }
 
-func (t *Trucker) UpdateLocation(city string) {
+func (t *Trucker) UpdateLocation(city, cityID string) {
 	t.CurrentCity = city
+	t.CurrentCityID = cityID
 }
 
 func (t *Trucker) CompleteTrip(rating float64) {
@@ -86,7 +97,8 @@ func (t *Trucker) CompleteTrip(rating float64) {
 
 // IsEligibleForLoad checks if trucker can take a new load
 func (t *Trucker) IsEligibleForLoad(requiredCapacity float64, requiredVehicleType string) bool {
-	return t.Available &&
+	return t.Active &&
+		t.Available &&
 		t.Verified &&
 		t.Capacity >= requiredCapacity &&
 		(requiredVehicleType == "" || strings.Contains(strings.ToLower(t.VehicleType), strings.ToLower(requiredVehicleType)))
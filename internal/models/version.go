@@ -0,0 +1,29 @@
+package models
+
+import (
+	"context"
+	"fmt"
+)
+
+// Loads, bookings and truckers carry a Version that every update increments.
+// Stores update a row only if it still has the version they read, so two
+// writers racing on the same row cannot both succeed; REST clients can do
+// the same across requests by sending the version back in If-Match.
+
+type expectedVersionKey struct{}
+
+// WithExpectedVersion returns a context asking the store to update an entity
+// only if its version is still the given one, e.g. from an If-Match header
+func WithExpectedVersion(ctx context.Context, version uint) context.Context {
+	return context.WithValue(ctx, expectedVersionKey{}, version)
+}
+
+// CheckVersion fails with ErrPreconditionFailed if ctx expects a version
+// other than the entity's current one
+func CheckVersion(ctx context.Context, entity string, current uint) error {
+	expected, ok := ctx.Value(expectedVersionKey{}).(uint)
+	if !ok || expected == current {
+		return nil
+	}
+	return PreconditionFailed(entity, fmt.Sprintf("%s has changed since version %d, it is now at version %d", entity, expected, current))
+}
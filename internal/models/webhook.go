@@ -0,0 +1,118 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// WebhookEndpoint is a shipper-registered URL that receives booking updates
+type WebhookEndpoint struct {
+	gorm.Model
+
+	EndpointID string `json:"endpoint_id" gorm:"uniqueIndex"`
+	ShipperID  string `json:"shipper_id" gorm:"index"`
+	URL        string `json:"url"`
+	Secret     string `json:"-"`           // HMAC signing secret, only shown once at registration
+	EventTypes string `json:"event_types"` // Comma separated list of subscribed event types
+	Active     bool   `json:"active" gorm:"default:true"`
+}
+
+// BeforeCreate hook to auto-generate EndpointID
+func (e *WebhookEndpoint) BeforeCreate(tx *gorm.DB) error {
+	if e.EndpointID == "" {
+		id, err := nextID(tx, PrefixWebhookEndpoint)
+		if err != nil {
+			return err
+		}
+		e.EndpointID = id
+	}
+	return nil
+}
+
+// Subscribes reports whether the endpoint wants the given event type
+func (e *WebhookEndpoint) Subscribes(eventType string) bool {
+	for _, t := range strings.Split(e.EventTypes, ",") {
+		if strings.TrimSpace(t) == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookDelivery is one attempt-tracked delivery of an event to an endpoint
+type WebhookDelivery struct {
+	gorm.Model
+
+	DeliveryID    string     `json:"delivery_id" gorm:"uniqueIndex"`
+	EndpointID    string     `json:"endpoint_id" gorm:"index"`
+	ShipperID     string     `json:"shipper_id" gorm:"index"`
+	EventType     string     `json:"event_type"`
+	Payload       string     `json:"payload" gorm:"type:text"`
+	Status        string     `json:"status" gorm:"default:pending;index"` // "pending", "succeeded", "dead"
+	Attempts      int        `json:"attempts"`
+	ResponseCode  int        `json:"response_code"`
+	LastError     string     `json:"last_error"`
+	NextAttemptAt *time.Time `json:"next_attempt_at" gorm:"index"`
+	DeliveredAt   *time.Time `json:"delivered_at"`
+}
+
+// BeforeCreate hook to auto-generate DeliveryID
+func (d *WebhookDelivery) BeforeCreate(tx *gorm.DB) error {
+	if d.DeliveryID == "" {
+		id, err := nextID(tx, PrefixWebhookDelivery)
+		if err != nil {
+			return err
+		}
+		d.DeliveryID = id
+	}
+	if d.Status == "" {
+		d.Status = WebhookDeliveryPending
+	}
+	return nil
+}
+
+// Webhook event types shippers can subscribe to
+const (
+	WebhookEventLoadBooked    = "load.booked"
+	WebhookEventPickedUp      = "booking.picked_up"
+	WebhookEventDelivered     = "booking.delivered"
+	WebhookEventPODUploaded   = "booking.pod_uploaded"
+	WebhookEventCancelled     = "booking.cancelled"
+	WebhookEventInvoiceIssued = "invoice.issued"
+)
+
+// Webhook delivery status constants
+const (
+	WebhookDeliveryPending   = "pending"
+	WebhookDeliverySucceeded = "succeeded"
+	WebhookDeliveryDead      = "dead" // Gave up after max attempts - dead-letter list
+)
+
+// Webhook retry policy
+const (
+	WebhookDeliveryMaxAttempts  = 6
+	WebhookDeliveryTimeout      = 10 * time.Second
+	WebhookDeliveryInitialDelay = 30 * time.Second // Doubled after every failed attempt
+)
+
+// WebhookEventTypes lists every event type that can be subscribed to
+var WebhookEventTypes = []string{
+	WebhookEventLoadBooked,
+	WebhookEventPickedUp,
+	WebhookEventDelivered,
+	WebhookEventPODUploaded,
+	WebhookEventCancelled,
+	WebhookEventInvoiceIssued,
+}
+
+// IsValidWebhookEventType checks an event type against WebhookEventTypes
+func IsValidWebhookEventType(eventType string) bool {
+	for _, t := range WebhookEventTypes {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
@@ -1,20 +1,61 @@
 package routes
 
 import (
+	"log"
+	"os"
+
+	"github.com/Ananth-NQI/truckpe-backend/internal/events"
 	"github.com/Ananth-NQI/truckpe-backend/internal/handlers"
+	"github.com/Ananth-NQI/truckpe-backend/internal/services"
 	"github.com/Ananth-NQI/truckpe-backend/internal/storage"
 	"github.com/gofiber/fiber/v2"
 )
 
 // SetupRoutes configures all API routes
-func SetupRoutes(app *fiber.App, store storage.Store) { // Changed from *storage.MemoryStore to interface
+func SetupRoutes(app *fiber.App, store storage.Store, bus *events.Bus) { // Changed from *storage.MemoryStore to interface
+
+	// Initialize Twilio service
+	twilioService, err := services.NewTwilioService()
+	if err != nil {
+		log.Printf("⚠️  Warning: Twilio service not initialized: %v", err)
+		// Continue without Twilio for testing
+	}
+
+	// Initialize shared services
+	trackingService := services.NewTrackingService(store)
+	apiKeyService := services.NewAPIKeyService(store)
+	webhookService := services.NewWebhookService(store, bus)
+	webhookService.Start()
+	loadImportService := services.NewLoadImportService(store)
+	exportService := services.NewExportService(store)
+	alertService := services.NewAlertService(store, bus, twilioService)
+	alertService.Start()
+	backhaulService := services.NewBackhaulService(store, bus, twilioService)
+	backhaulService.Start()
+	recurringService := services.NewRecurringLoadService(store)
+	recurringService.Start()
+	expiryService := services.NewLoadExpiryService(store, twilioService)
+	expiryService.Start()
+	loadEditService := services.NewLoadEditService(store, bus, twilioService)
+	loadEditService.Start()
+	// Last, so events left unpublished before a restart reach every service
+	outboxDispatcher := services.NewOutboxDispatcher(store, bus)
+	outboxDispatcher.Start()
 
 	// Initialize handlers
 	healthHandler := handlers.NewHealthHandler("1.0.0")
 	truckerHandler := handlers.NewTruckerHandler(store)
-	loadHandler := handlers.NewLoadHandler(store)
+	loadHandler := handlers.NewLoadHandler(store, loadImportService, expiryService, loadEditService)
 	bookingHandler := handlers.NewBookingHandler(store)
-	whatsappHandler := handlers.NewWhatsAppHandler(store)
+	whatsappHandler := handlers.NewWhatsAppHandler(store, twilioService, trackingService, loadImportService, exportService, alertService, recurringService, expiryService, loadEditService, apiKeyService)
+	trackingHandler := handlers.NewTrackingHandler(trackingService)
+	webhookHandler := handlers.NewWebhookHandler(store, webhookService)
+	streamHandler := handlers.NewStreamHandler(bus)
+	exportHandler := handlers.NewExportHandler(exportService)
+	alertHandler := handlers.NewAlertHandler(alertService)
+	backhaulHandler := handlers.NewBackhaulHandler(store, backhaulService)
+	recurringHandler := handlers.NewRecurringLoadHandler(recurringService)
+	adminHandler := handlers.NewAdminHandler(store)
 
 	// Root endpoint
 	app.Get("/", func(c *fiber.Ctx) error {
@@ -26,6 +67,8 @@ func SetupRoutes(app *fiber.App, store storage.Store) { // Changed from *storage
 				"api":           "/api",
 				"webhook":       "/webhook/whatsapp",
 				"test_whatsapp": "/test/whatsapp",
+				"tracking":      "/t/:token",
+				"statement":     "/r/:token",
 			},
 		})
 	})
@@ -33,22 +76,47 @@ func SetupRoutes(app *fiber.App, store storage.Store) { // Changed from *storage
 	// Health check
 	app.Get("/health", healthHandler.Check)
 
+	// Request deadline for store queries and outgoing messages; file uploads get longer
+	deadline := handlers.Deadline(handlers.RequestTimeout())
+	uploadDeadline := handlers.Deadline(handlers.UploadRequestTimeout)
+
+	// Routes acting for a shipper take the one whose API key (WhatsApp: API KEY) is sent as a bearer token
+	shipperAuth := handlers.ShipperAuth(apiKeyService)
+
 	// API routes
-	api := app.Group("/api")
+	api := app.Group("/api", deadline)
 
 	// Trucker routes
 	truckers := api.Group("/truckers")
 	truckers.Post("/register", truckerHandler.Register)
 	truckers.Get("/:id", truckerHandler.GetTrucker)
 	truckers.Get("/", truckerHandler.GetTruckerByPhone) // Query param: ?phone=+919876543210
+	truckers.Put("/:id/location", truckerHandler.UpdateLocation)
+	truckers.Get("/:id/alerts", alertHandler.GetAlerts)
+	truckers.Post("/:id/alerts", alertHandler.CreateAlert)
+	truckers.Delete("/:id/alerts/:alertID", alertHandler.DeleteAlert)
+	truckers.Get("/:id/backhaul", backhaulHandler.GetSuggestions) // Query param: ?city=Chennai (default: current city)
 
 	// Load routes
 	loads := api.Group("/loads")
 	loads.Get("/", loadHandler.GetLoads)
 	loads.Post("/", loadHandler.CreateLoad)
+	loads.Post("/bulk", uploadDeadline, loadHandler.BulkUpload) // CSV or XLSX; see services.LoadImportColumns
 	loads.Get("/:id", loadHandler.GetLoad)
+	loads.Patch("/:id", loadHandler.EditLoad)
 	loads.Post("/search", loadHandler.SearchLoads)
 	loads.Put("/:id/status", loadHandler.UpdateLoadStatus)
+	loads.Put("/:id/renew", loadHandler.RenewLoad)
+	loads.Put("/:id/withdraw", loadHandler.WithdrawLoad)
+	loads.Get("/:id/timeline", loadHandler.GetTimeline)
+
+	// Recurring load routes
+	recurring := api.Group("/recurring-loads")
+	recurring.Post("/", recurringHandler.CreateRecurringLoad)
+	recurring.Get("/", recurringHandler.GetRecurringLoads) // Query param: ?shipper_id=SH00001Y
+	recurring.Put("/:id/pause", recurringHandler.PauseRecurringLoad)
+	recurring.Put("/:id/resume", recurringHandler.ResumeRecurringLoad)
+	recurring.Post("/:id/skip", recurringHandler.SkipOccurrence)
 
 	// Booking routes
 	bookings := api.Group("/bookings")
@@ -57,10 +125,53 @@ func SetupRoutes(app *fiber.App, store storage.Store) { // Changed from *storage
 	bookings.Get("/trucker/:truckerID", bookingHandler.GetTruckerBookings)
 	bookings.Get("/load/:loadID", bookingHandler.GetLoadBookings)
 	bookings.Put("/:id/status", bookingHandler.UpdateBookingStatus)
+	bookings.Get("/:id/timeline", bookingHandler.GetTimeline)
+	bookings.Put("/:id/pod", bookingHandler.UploadPOD)
+	bookings.Post("/:id/tracking-link", shipperAuth, trackingHandler.CreateLink)
+	bookings.Delete("/:id/tracking-link", shipperAuth, trackingHandler.RevokeLink)
+
+	// Return-load suggestion conversion
+	api.Get("/backhaul/stats", backhaulHandler.GetStats) // Query param: ?from=2026-09-01
+
+	// Live updates (Server-Sent Events)
+	api.Get("/stream", handlers.AccountAuth(apiKeyService), streamHandler.Stream) // Shipper or trucker API key, or ?api_key= for EventSource
+
+	// Data exports: loads, bookings, payouts or invoices
+	api.Get("/exports/:dataset", shipperAuth, exportHandler.Export) // Query params: ?format=csv|xlsx|jsonl&from=2026-09-01&to=2026-09-30&trucker_id=&status=
+
+	// Shipper webhook routes
+	webhooks := api.Group("/webhooks", shipperAuth)
+	webhooks.Post("/", webhookHandler.RegisterEndpoint)
+	webhooks.Get("/", webhookHandler.GetEndpoints)
+	webhooks.Delete("/:id", webhookHandler.DeleteEndpoint)
+	webhooks.Get("/:id/deliveries", webhookHandler.GetDeliveries) // Query param: ?status=dead for dead letters
+	webhooks.Post("/deliveries/:deliveryID/replay", webhookHandler.ReplayDelivery)
+
+	// Admin routes: deactivate, soft-delete and restore accounts and loads
+	if adminKey := os.Getenv("ADMIN_API_KEY"); adminKey != "" {
+		admin := api.Group("/admin", handlers.AdminAuth(adminKey))
+		admin.Post("/truckers/:id/deactivate", adminHandler.DeactivateTrucker)
+		admin.Delete("/truckers/:id", adminHandler.DeleteTrucker)
+		admin.Post("/truckers/:id/restore", adminHandler.RestoreTrucker)
+		admin.Post("/shippers/:id/deactivate", adminHandler.DeactivateShipper)
+		admin.Delete("/shippers/:id", adminHandler.DeleteShipper)
+		admin.Post("/shippers/:id/restore", adminHandler.RestoreShipper)
+		admin.Post("/loads/:id/deactivate", adminHandler.DeactivateLoad)
+		admin.Delete("/loads/:id", adminHandler.DeleteLoad)
+		admin.Post("/loads/:id/restore", adminHandler.RestoreLoad)
+	} else {
+		log.Println("⚠️  ADMIN_API_KEY not set - admin routes disabled")
+	}
+
+	// Public tracking links for consignees (no authentication)
+	app.Get("/t/:token", deadline, trackingHandler.PublicTrack)
+
+	// Signed monthly statement downloads sent by the WhatsApp REPORT command
+	app.Get("/r/:token", deadline, exportHandler.Statement)
 
 	// WhatsApp webhook (for production Twilio)
-	app.Post("/webhook/whatsapp", whatsappHandler.HandleWebhook)
+	app.Post("/webhook/whatsapp", uploadDeadline, whatsappHandler.HandleWebhook) // messages may carry a file of loads
 
 	// Test WhatsApp endpoint (for development)
-	app.Post("/test/whatsapp", whatsappHandler.HandleTestWebhook)
+	app.Post("/test/whatsapp", uploadDeadline, whatsappHandler.HandleTestWebhook)
 }
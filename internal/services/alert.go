@@ -0,0 +1,171 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/Ananth-NQI/truckpe-backend/internal/events"
+	"github.com/Ananth-NQI/truckpe-backend/internal/models"
+	"github.com/Ananth-NQI/truckpe-backend/internal/storage"
+)
+
+const (
+	// alertEventBuffer is how many store events may queue up before the
+	// outbox has to publish them again
+	alertEventBuffer = 1024
+
+	// alertConsumer records the load events the service alerted truckers about
+	alertConsumer = "alerts"
+)
+
+// AlertService manages truckers' lane subscriptions and messages them about new matching loads
+type AlertService struct {
+	store  storage.Store
+	bus    *events.Bus
+	twilio *TwilioService // nil when Twilio is not configured, see TwilioService.Notify
+}
+
+// NewAlertService creates a new lane alert service
+func NewAlertService(store storage.Store, bus *events.Bus, twilio *TwilioService) *AlertService {
+	return &AlertService{
+		store:  store,
+		bus:    bus,
+		twilio: twilio,
+	}
+}
+
+// Subscribe saves a lane alert for a trucker after resolving its cities
+func (a *AlertService) Subscribe(ctx context.Context, truckerID string, alert *models.LaneAlert) (*models.LaneAlert, error) {
+	trucker, err := a.store.GetTrucker(ctx, truckerID)
+	if err != nil {
+		return nil, err
+	}
+
+	if alert.FromCity == "" {
+		return nil, models.Invalid("lane alert", "from_city", "from city is required")
+	}
+	if alert.MinPrice < 0 {
+		return nil, models.Invalid("lane alert", "min_price", "minimum price cannot be negative")
+	}
+	if err := alert.ResolveCities(); err != nil {
+		return nil, err
+	}
+
+	existing, err := a.store.GetLaneAlertsByTrucker(ctx, trucker.TruckerID)
+	if err != nil {
+		return nil, err
+	}
+	if len(existing) >= models.MaxLaneAlertsPerTrucker {
+		return nil, models.Unavailable("lane alert", "", fmt.Sprintf("you can have at most %d alerts", models.MaxLaneAlertsPerTrucker))
+	}
+
+	alert.TruckerID = trucker.TruckerID
+	return a.store.CreateLaneAlert(ctx, alert)
+}
+
+// Alerts lists a trucker's active lane alerts
+func (a *AlertService) Alerts(ctx context.Context, truckerID string) ([]*models.LaneAlert, error) {
+	return a.store.GetLaneAlertsByTrucker(ctx, truckerID)
+}
+
+// Unsubscribe deactivates one of the trucker's lane alerts
+func (a *AlertService) Unsubscribe(ctx context.Context, truckerID, alertID string) error {
+	alert, err := a.store.GetLaneAlert(ctx, alertID)
+	if err != nil || alert.TruckerID != truckerID || !alert.Active {
+		return models.NotFound("lane alert")
+	}
+	return a.store.DeactivateLaneAlert(ctx, alertID)
+}
+
+// Start subscribes to store events and alerts truckers as loads are created
+func (a *AlertService) Start() {
+	sub := a.bus.SubscribeDurable(alertEventBuffer)
+	go func() {
+		ctx := context.Background()
+		for event := range sub.C {
+			if event.EntityType != models.EntityLoad || event.EventType != models.EventLoadCreated {
+				continue
+			}
+
+			handleOnce(ctx, a.store, alertConsumer, event, func() {
+				load, err := a.store.GetLoad(ctx, event.EntityID)
+				if err != nil {
+					log.Printf("❌ Alert event for unknown load %s: %v", event.EntityID, err)
+					return
+				}
+				a.NotifyLoad(ctx, load)
+			})
+		}
+	}()
+}
+
+// NotifyLoad messages every trucker with an alert matching the load.
+// A trucker with several matching alerts gets one message, and nobody
+// gets more than MaxAlertMessagesPerDay.
+func (a *AlertService) NotifyLoad(ctx context.Context, load *models.Load) {
+	alerts, err := a.store.GetLaneAlertsByOrigin(ctx, load.FromCity)
+	if err != nil {
+		log.Printf("❌ Failed to load lane alerts for %s: %v", load.LoadID, err)
+		return
+	}
+
+	notified := make(map[string]bool)
+	for _, alert := range alerts {
+		if notified[alert.TruckerID] || !alert.Matches(load) {
+			continue
+		}
+		notified[alert.TruckerID] = true
+
+		trucker, err := a.store.GetTrucker(ctx, alert.TruckerID)
+		if err != nil || (trucker.Capacity > 0 && !load.Splittable && load.TruckWeight() > trucker.Capacity) {
+			continue
+		}
+
+		now := time.Now()
+		today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+		sent, err := a.store.CountAlertNotifications(ctx, trucker.TruckerID, today)
+		if err != nil {
+			log.Printf("❌ Failed to count alerts for %s: %v", trucker.TruckerID, err)
+			continue
+		}
+		if sent >= models.MaxAlertMessagesPerDay {
+			continue
+		}
+
+		if err := a.twilio.Notify(ctx, trucker.Phone, alertMessage(alert, load)); err != nil {
+			continue
+		}
+
+		err = a.store.CreateAlertNotification(ctx, &models.AlertNotification{
+			TruckerID: trucker.TruckerID,
+			LoadID:    load.LoadID,
+			AlertID:   alert.AlertID,
+			SentAt:    now,
+		})
+		if err != nil {
+			log.Printf("❌ Failed to record alert for %s: %v", trucker.TruckerID, err)
+		}
+	}
+}
+
+// alertMessage is the WhatsApp text for a new load on a subscribed lane
+func alertMessage(alert *models.LaneAlert, load *models.Load) string {
+	return fmt.Sprintf(`🔔 *New load on your lane!*
+%s
+
+📦 *Load ID:* %s
+📍 *Route:* %s → %s
+📦 *Material:* %s
+⚖️ *Weight:* %s
+💰 *Price:* ₹%.0f
+🚛 *Vehicle:* %s
+📅 *Loading:* %s
+
+To book, type: BOOK %s
+To stop this alert, type: UNALERT %s`,
+		alert.Lane(), load.LoadID, load.FromCity, load.ToCity, load.Material,
+		load.WeightText(), load.Price, load.VehicleType, load.LoadingDate.Format("02 Jan"),
+		load.LoadID, alert.AlertID)
+}
@@ -0,0 +1,86 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/Ananth-NQI/truckpe-backend/internal/models"
+	"github.com/Ananth-NQI/truckpe-backend/internal/storage"
+)
+
+// apiKeyPrefix starts every API key, so leaked keys are easy to spot
+const apiKeyPrefix = "tpk_"
+
+// APIKeyService issues and checks the API keys shippers and truckers use on
+// the REST API. A key is "tpk_<shipper or trucker ID>.<signature>"; it stays
+// valid while the account is active, and changing API_KEY_SECRET revokes
+// every key at once.
+type APIKeyService struct {
+	store  storage.Store
+	secret []byte
+}
+
+// NewAPIKeyService creates a new API key service
+func NewAPIKeyService(store storage.Store) *APIKeyService {
+	secret := os.Getenv("API_KEY_SECRET")
+	if secret == "" {
+		// Keys will stop working after a restart, fine for local testing
+		log.Println("⚠️  API_KEY_SECRET not set - using a random secret")
+		buf := make([]byte, 32)
+		rand.Read(buf)
+		secret = hex.EncodeToString(buf)
+	}
+
+	return &APIKeyService{
+		store:  store,
+		secret: []byte(secret),
+	}
+}
+
+// Issue returns the API key of a shipper or trucker
+func (s *APIKeyService) Issue(accountID string) string {
+	return apiKeyPrefix + accountID + "." + s.sign(accountID)
+}
+
+// Authenticate returns the ID of the active shipper or trucker a key was issued to
+func (s *APIKeyService) Authenticate(ctx context.Context, key string) (string, error) {
+	accountID, signature, found := strings.Cut(strings.TrimPrefix(key, apiKeyPrefix), ".")
+	if !found || !strings.HasPrefix(key, apiKeyPrefix) || !hmac.Equal([]byte(signature), []byte(s.sign(accountID))) {
+		return "", models.Invalid("api key", "", "invalid API key")
+	}
+
+	switch {
+	case strings.HasPrefix(accountID, models.PrefixShipper):
+		shipper, err := s.store.GetShipper(ctx, accountID)
+		if err != nil {
+			return "", err
+		}
+		if !shipper.Active {
+			return "", models.Unavailable("shipper", "", "shipper account is deactivated")
+		}
+		return shipper.ShipperID, nil
+	case strings.HasPrefix(accountID, models.PrefixTrucker):
+		trucker, err := s.store.GetTrucker(ctx, accountID)
+		if err != nil {
+			return "", err
+		}
+		if !trucker.Active {
+			return "", models.Unavailable("trucker", "", "trucker account is deactivated")
+		}
+		return trucker.TruckerID, nil
+	}
+	return "", models.Invalid("api key", "", "invalid API key")
+}
+
+func (s *APIKeyService) sign(accountID string) string {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(apiKeyPrefix + accountID))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,197 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Ananth-NQI/truckpe-backend/internal/events"
+	"github.com/Ananth-NQI/truckpe-backend/internal/models"
+	"github.com/Ananth-NQI/truckpe-backend/internal/storage"
+)
+
+const (
+	// backhaulEventBuffer is how many store events may queue up before the
+	// outbox has to publish them again
+	backhaulEventBuffer = 1024
+
+	// backhaulConsumer records the booking events the service acted on
+	backhaulConsumer = "backhaul"
+)
+
+// BackhaulService suggests return loads to truckers who have just delivered
+type BackhaulService struct {
+	store  storage.Store
+	bus    *events.Bus
+	twilio *TwilioService // nil when Twilio is not configured, see TwilioService.Notify
+}
+
+// NewBackhaulService creates a new backhaul service
+func NewBackhaulService(store storage.Store, bus *events.Bus, twilio *TwilioService) *BackhaulService {
+	return &BackhaulService{
+		store:  store,
+		bus:    bus,
+		twilio: twilio,
+	}
+}
+
+// BackhaulLoad is a suggested return load and how far its pickup is from the trucker
+type BackhaulLoad struct {
+	Load       *models.Load `json:"load"`
+	DistanceKm float64      `json:"distance_km"`
+}
+
+// Suggest finds available loads starting within BackhaulRadiusKm of the city,
+// loading in the next BackhaulWindowDays, that the trucker's truck can carry.
+// The nearest are listed first, then the best paid.
+func (b *BackhaulService) Suggest(ctx context.Context, trucker *models.Trucker, city string) ([]*BackhaulLoad, error) {
+	today := time.Now()
+	search := &models.LoadSearch{
+		NearCity: city,
+		RadiusKm: models.BackhaulRadiusKm,
+		DateFrom: today.Format("2006-01-02"),
+		DateTo:   today.AddDate(0, 0, models.BackhaulWindowDays).Format("2006-01-02"),
+	}
+	search.FitCapacity(trucker.Capacity)
+
+	loads, err := b.store.SearchLoads(ctx, search)
+	if err != nil {
+		return nil, err
+	}
+
+	origin, known := models.CityCoordinates(city)
+	var suggestions []*BackhaulLoad
+	for _, load := range loads {
+		if !load.AcceptsVehicle(trucker.VehicleType) {
+			continue
+		}
+
+		distance := 0.0
+		if pickup, ok := models.CityCoordinates(load.FromCity); known && ok {
+			distance = math.Round(models.DistanceKm(origin, pickup))
+		}
+		suggestions = append(suggestions, &BackhaulLoad{Load: load, DistanceKm: distance})
+	}
+
+	sort.SliceStable(suggestions, func(i, j int) bool {
+		if suggestions[i].DistanceKm != suggestions[j].DistanceKm {
+			return suggestions[i].DistanceKm < suggestions[j].DistanceKm
+		}
+		return suggestions[i].Load.Price > suggestions[j].Load.Price
+	})
+
+	if len(suggestions) > models.BackhaulMaxSuggestions {
+		suggestions = suggestions[:models.BackhaulMaxSuggestions]
+	}
+	return suggestions, nil
+}
+
+// Stats reports how many suggestions were sent since a time and how many were booked
+func (b *BackhaulService) Stats(ctx context.Context, since time.Time) (*models.BackhaulStats, error) {
+	return b.store.GetBackhaulStats(ctx, since)
+}
+
+// Start subscribes to store events: deliveries trigger suggestions,
+// and bookings of a suggested load count as conversions
+func (b *BackhaulService) Start() {
+	sub := b.bus.SubscribeDurable(backhaulEventBuffer)
+	go func() {
+		ctx := context.Background()
+		for event := range sub.C {
+			if event.EntityType != models.EntityBooking {
+				continue
+			}
+
+			switch {
+			case event.EventType == models.EventBookingStatusChanged && event.After["status"] == models.BookingStatusDelivered:
+				handleOnce(ctx, b.store, backhaulConsumer, event, func() {
+					b.handleDelivery(ctx, event.EntityID)
+				})
+			case event.EventType == models.EventBookingCreated:
+				handleOnce(ctx, b.store, backhaulConsumer, event, func() {
+					b.handleBooking(ctx, event.EntityID)
+				})
+			}
+		}
+	}()
+}
+
+// handleDelivery messages the trucker of a delivered booking their top return loads
+func (b *BackhaulService) handleDelivery(ctx context.Context, bookingID string) {
+	booking, err := b.store.GetBooking(ctx, bookingID)
+	if err != nil {
+		log.Printf("❌ Backhaul event for unknown booking %s: %v", bookingID, err)
+		return
+	}
+	load, err := b.store.GetLoad(ctx, booking.LoadID)
+	if err != nil {
+		return
+	}
+	trucker, err := b.store.GetTrucker(ctx, booking.TruckerID)
+	if err != nil {
+		return
+	}
+
+	suggestions, err := b.Suggest(ctx, trucker, load.ToCity)
+	if err != nil {
+		log.Printf("❌ Failed to find return loads for %s: %v", booking.BookingID, err)
+		return
+	}
+	if len(suggestions) == 0 {
+		return
+	}
+
+	if err := b.twilio.Notify(ctx, trucker.Phone, backhaulMessage(load.ToCity, suggestions)); err != nil {
+		return
+	}
+
+	now := time.Now()
+	for i, suggestion := range suggestions {
+		err := b.store.CreateBackhaulSuggestion(ctx, &models.BackhaulSuggestion{
+			TruckerID:          trucker.TruckerID,
+			DeliveredBookingID: booking.BookingID,
+			LoadID:             suggestion.Load.LoadID,
+			Rank:               i + 1,
+			DistanceKm:         suggestion.DistanceKm,
+			SentAt:             now,
+		})
+		if err != nil {
+			log.Printf("❌ Failed to record backhaul suggestion for %s: %v", booking.BookingID, err)
+		}
+	}
+}
+
+// handleBooking marks a suggestion as converted when the trucker books the suggested load
+func (b *BackhaulService) handleBooking(ctx context.Context, bookingID string) {
+	booking, err := b.store.GetBooking(ctx, bookingID)
+	if err != nil {
+		return
+	}
+	if err := b.store.ConvertBackhaulSuggestion(ctx, booking.TruckerID, booking.LoadID, booking.BookingID); err == nil {
+		log.Printf("🔁 Backhaul suggestion converted: %s booked %s", booking.TruckerID, booking.LoadID)
+	}
+}
+
+// backhaulMessage is the WhatsApp text listing return loads after a delivery
+func backhaulMessage(city string, suggestions []*BackhaulLoad) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("🏁 *Delivered!* Need a return load from %s?\n\n", city))
+
+	for i, suggestion := range suggestions {
+		load := suggestion.Load
+		sb.WriteString(fmt.Sprintf("%d. *%s* %s → %s\n", i+1, load.LoadID, load.FromCity, load.ToCity))
+		sb.WriteString(fmt.Sprintf("   📦 %s, %s 💰 ₹%.0f\n", load.Material, load.WeightText(), load.Price))
+		if suggestion.DistanceKm > 0 {
+			sb.WriteString(fmt.Sprintf("   📍 %.0f km from %s, loading %s\n", suggestion.DistanceKm, city, load.LoadingDate.Format("02 Jan")))
+		} else {
+			sb.WriteString(fmt.Sprintf("   📅 Loading %s\n", load.LoadingDate.Format("02 Jan")))
+		}
+		sb.WriteString(fmt.Sprintf("   👉 Reply *BOOK %s*\n\n", load.LoadID))
+	}
+
+	return strings.TrimRight(sb.String(), "\n")
+}
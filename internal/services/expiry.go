@@ -0,0 +1,138 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/Ananth-NQI/truckpe-backend/internal/models"
+	"github.com/Ananth-NQI/truckpe-backend/internal/storage"
+)
+
+const (
+	// loadExpiryPollInterval is how often the sweeper looks for stale loads
+	loadExpiryPollInterval = 10 * time.Minute
+
+	// DefaultExpiryReminder is how long before expiry the shipper is reminded,
+	// unless LOAD_EXPIRY_REMINDER_HOURS says otherwise
+	DefaultExpiryReminder = 6 * time.Hour
+)
+
+// LoadExpiryService unlists loads nobody booked before their expiry and
+// reminds shippers beforehand so they can RENEW them
+type LoadExpiryService struct {
+	store    storage.Store
+	twilio   *TwilioService // nil when Twilio is not configured, see TwilioService.Notify
+	reminder time.Duration
+}
+
+// NewLoadExpiryService creates a new load expiry service
+func NewLoadExpiryService(store storage.Store, twilio *TwilioService) *LoadExpiryService {
+	reminder := DefaultExpiryReminder
+	if hours, err := strconv.Atoi(os.Getenv("LOAD_EXPIRY_REMINDER_HOURS")); err == nil && hours > 0 {
+		reminder = time.Duration(hours) * time.Hour
+	}
+
+	return &LoadExpiryService{
+		store:    store,
+		twilio:   twilio,
+		reminder: reminder,
+	}
+}
+
+// Start launches the sweeper
+func (e *LoadExpiryService) Start() {
+	go func() {
+		ctx := context.Background()
+		ticker := time.NewTicker(loadExpiryPollInterval)
+		defer ticker.Stop()
+
+		for {
+			e.Sweep(ctx, time.Now())
+			<-ticker.C
+		}
+	}()
+}
+
+// Sweep reminds shippers of loads about to expire and expires the stale ones
+func (e *LoadExpiryService) Sweep(ctx context.Context, now time.Time) {
+	expiring, err := e.store.GetLoadsExpiringBefore(ctx, now.Add(e.reminder))
+	if err != nil {
+		log.Printf("❌ Failed to fetch expiring loads: %v", err)
+	}
+	for _, load := range expiring {
+		if !load.ExpiresAt.After(now) {
+			continue // expired below instead
+		}
+		if err := e.twilio.Notify(ctx, load.ShipperPhone, expiryReminderMessage(load)); err != nil {
+			continue
+		}
+		if err := e.store.MarkLoadExpiryReminded(ctx, load.LoadID, now); err != nil {
+			log.Printf("❌ Failed to record expiry reminder for %s: %v", load.LoadID, err)
+		}
+	}
+
+	expired, err := e.store.ExpireLoads(ctx, now, models.SystemActor)
+	if err != nil {
+		log.Printf("❌ Failed to expire loads: %v", err)
+		return
+	}
+	for _, load := range expired {
+		log.Printf("⌛ Load %s unlisted (%s)", load.LoadID, load.Status)
+		if load.Status == models.LoadStatusExpired {
+			e.twilio.Notify(ctx, load.ShipperPhone, expiredMessage(load))
+		}
+	}
+}
+
+// Renew moves one of the shipper's loads to a new loading date, optionally
+// repricing it, and relists it if it had expired. A price of 0 keeps the price.
+func (e *LoadExpiryService) Renew(ctx context.Context, shipperID, loadID string, loadingDate time.Time, price float64, actor models.Actor) (*models.Load, error) {
+	load, err := e.store.GetLoad(ctx, loadID)
+	if err != nil {
+		return nil, err
+	}
+	if load.ShipperID != shipperID {
+		return nil, models.NotFound("load")
+	}
+
+	if price < 0 {
+		return nil, models.Invalid("load", "price", "price cannot be negative")
+	}
+	if !loadingDate.After(time.Now()) {
+		return nil, models.Invalid("load", "loading_date", "loading date must be in the future")
+	}
+
+	return e.store.RenewLoad(ctx, load.LoadID, loadingDate, price, actor)
+}
+
+// expiryReminderMessage is the WhatsApp text warning a shipper that a load is about to be unlisted
+func expiryReminderMessage(load *models.Load) string {
+	return fmt.Sprintf(`⏳ *Load expiring soon*
+
+*Load ID:* %s
+📍 *Route:* %s → %s
+💰 *Price:* ₹%.0f
+
+Nobody has booked it yet. It will be unlisted at %s.
+
+To keep it listed for another day, type: RENEW %s
+To reprice it too, type: RENEW %s 1 <price>`,
+		load.LoadID, load.FromCity, load.ToCity, load.Price, load.ExpiresAt.Format("02 Jan 15:04"),
+		load.LoadID, load.LoadID)
+}
+
+// expiredMessage is the WhatsApp text telling a shipper a load was unlisted
+func expiredMessage(load *models.Load) string {
+	return fmt.Sprintf(`⌛ *Load expired*
+
+*Load ID:* %s
+📍 *Route:* %s → %s
+
+It was not booked and is no longer shown to truckers.
+To post it again, type: RENEW %s <days> [price]`,
+		load.LoadID, load.FromCity, load.ToCity, load.LoadID)
+}
@@ -0,0 +1,339 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Ananth-NQI/truckpe-backend/internal/models"
+	"github.com/Ananth-NQI/truckpe-backend/internal/storage"
+	"github.com/xuri/excelize/v2"
+)
+
+// StatementLinkTTL is how long a monthly statement download link stays valid
+const StatementLinkTTL = 7 * 24 * time.Hour
+
+// exportTimeLayout formats timestamps in CSV and XLSX exports
+const exportTimeLayout = "2006-01-02 15:04:05"
+
+// exportColumns are the column headers of each dataset, in order
+var exportColumns = map[string][]string{
+	models.ExportLoads: {
+		"load_id", "shipper_id", "shipper_name", "from_city", "to_city", "material", "weight",
+		"vehicle_type", "price", "payment_terms", "loading_date", "status", "created_at",
+	},
+	models.ExportBookings: {
+		"booking_id", "load_id", "shipper_id", "trucker_id", "agreed_price", "commission", "net_amount",
+		"status", "payment_status", "confirmed_at", "picked_up_at", "delivered_at", "created_at",
+	},
+	models.ExportPayouts: {
+		"booking_id", "load_id", "trucker_id", "gross_amount", "commission", "net_amount",
+		"payment_status", "payment_id", "delivered_at",
+	},
+	models.ExportInvoices: {
+		"invoice_no", "booking_id", "load_id", "shipper_id", "amount", "payment_status", "issued_at",
+	},
+}
+
+// ExportContentTypes maps export formats to their MIME types
+var ExportContentTypes = map[string]string{
+	models.ExportFormatCSV:   "text/csv; charset=utf-8",
+	models.ExportFormatXLSX:  "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	models.ExportFormatJSONL: "application/x-ndjson",
+}
+
+// ExportService streams loads, bookings, payouts and invoices as files
+type ExportService struct {
+	store   storage.Store
+	secret  []byte
+	baseURL string
+}
+
+// NewExportService creates a new export service
+func NewExportService(store storage.Store) *ExportService {
+	secret := os.Getenv("EXPORT_SECRET")
+	if secret == "" {
+		// Statement links will stop verifying after a restart, fine for local testing
+		log.Println("⚠️  EXPORT_SECRET not set - using a random secret")
+		buf := make([]byte, 32)
+		rand.Read(buf)
+		secret = hex.EncodeToString(buf)
+	}
+
+	baseURL := os.Getenv("PUBLIC_BASE_URL")
+	if baseURL == "" {
+		baseURL = "http://localhost:8080"
+	}
+
+	return &ExportService{
+		store:   store,
+		secret:  []byte(secret),
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+	}
+}
+
+// Validate checks an export request before any output is written
+func (e *ExportService) Validate(dataset, format string, filter *models.ExportFilter) error {
+	if _, ok := exportColumns[dataset]; !ok {
+		return models.Invalid("export", "dataset", "unknown export dataset: "+dataset)
+	}
+	if _, ok := ExportContentTypes[format]; !ok {
+		return models.Invalid("export", "format", "unknown export format: "+format)
+	}
+	if dataset == models.ExportLoads && filter.TruckerID != "" {
+		return models.Invalid("export", "trucker_id", "trucker filter is not supported for loads")
+	}
+	if filter.From != nil && filter.To != nil && !filter.From.Before(*filter.To) {
+		return models.Invalid("export", "from", "from must be before to")
+	}
+	return nil
+}
+
+// Export writes every row of the dataset matching the filter to w.
+// Rows are written as they are read from the store, so memory use does not grow with the export.
+func (e *ExportService) Export(ctx context.Context, w io.Writer, dataset, format string, filter *models.ExportFilter) error {
+	if err := e.Validate(dataset, format, filter); err != nil {
+		return err
+	}
+
+	out, err := newExportWriter(w, format, exportColumns[dataset])
+	if err != nil {
+		return err
+	}
+
+	switch dataset {
+	case models.ExportLoads:
+		err = e.store.EachLoad(ctx, filter, func(l *models.Load) error {
+			return out.Write(l, []interface{}{
+				l.LoadID, l.ShipperID, l.ShipperName, l.FromCity, l.ToCity, l.Material, l.Weight,
+				l.VehicleType, l.Price, l.PaymentTerms, exportTime(&l.LoadingDate), l.Status, exportTime(&l.CreatedAt),
+			})
+		})
+	case models.ExportBookings:
+		err = e.store.EachBooking(ctx, filter, func(b *models.Booking) error {
+			return out.Write(b, []interface{}{
+				b.BookingID, b.LoadID, b.ShipperID, b.TruckerID, b.AgreedPrice, b.Commission, b.NetAmount,
+				b.Status, b.PaymentStatus, exportTime(b.ConfirmedAt), exportTime(b.PickedUpAt), exportTime(b.DeliveredAt), exportTime(&b.CreatedAt),
+			})
+		})
+	case models.ExportPayouts:
+		filter.ByDeliveryDate = true
+		err = e.store.EachBooking(ctx, filter, func(b *models.Booking) error {
+			p := models.NewPayout(b)
+			return out.Write(p, []interface{}{
+				p.BookingID, p.LoadID, p.TruckerID, p.GrossAmount, p.Commission, p.NetAmount,
+				p.PaymentStatus, p.PaymentID, exportTime(p.DeliveredAt),
+			})
+		})
+	case models.ExportInvoices:
+		filter.ByDeliveryDate = true
+		err = e.store.EachBooking(ctx, filter, func(b *models.Booking) error {
+			inv := models.NewInvoice(b)
+			return out.Write(inv, []interface{}{
+				inv.InvoiceNo, inv.BookingID, inv.LoadID, inv.ShipperID, inv.Amount, inv.PaymentStatus, exportTime(inv.IssuedAt),
+			})
+		})
+	}
+	if err != nil {
+		return err
+	}
+
+	return out.Close()
+}
+
+// Filename suggests a download name such as "bookings-20261018.csv"
+func (e *ExportService) Filename(dataset, format string) string {
+	return fmt.Sprintf("%s-%s.%s", dataset, time.Now().Format("20060102"), format)
+}
+
+// StatementURL returns a signed download link for a shipper's monthly statement
+func (e *ExportService) StatementURL(shipperID string, month time.Time) string {
+	expiresAt := time.Now().Add(StatementLinkTTL)
+	payload := fmt.Sprintf("%s|%s|%d", shipperID, month.Format("2006-01"), expiresAt.Unix())
+	encoded := base64.RawURLEncoding.EncodeToString([]byte(payload))
+
+	return e.baseURL + "/r/" + encoded + "." + e.sign(encoded)
+}
+
+// ResolveStatement verifies a statement token and returns the shipper and month it covers
+func (e *ExportService) ResolveStatement(token string) (string, time.Time, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 2 || !hmac.Equal([]byte(parts[1]), []byte(e.sign(parts[0]))) {
+		return "", time.Time{}, models.NotFound("statement link")
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return "", time.Time{}, models.NotFound("statement link")
+	}
+
+	fields := strings.Split(string(raw), "|")
+	if len(fields) != 3 {
+		return "", time.Time{}, models.NotFound("statement link")
+	}
+
+	month, err := time.Parse("2006-01", fields[1])
+	if err != nil {
+		return "", time.Time{}, models.NotFound("statement link")
+	}
+
+	unix, err := strconv.ParseInt(fields[2], 10, 64)
+	if err != nil {
+		return "", time.Time{}, models.NotFound("statement link")
+	}
+	if time.Now().After(time.Unix(unix, 0)) {
+		return "", time.Time{}, models.Expired("statement link", "statement link expired")
+	}
+
+	return fields[0], month, nil
+}
+
+// StatementFilter selects a shipper's invoices delivered in a calendar month
+func StatementFilter(shipperID string, month time.Time) *models.ExportFilter {
+	from := time.Date(month.Year(), month.Month(), 1, 0, 0, 0, 0, time.Local)
+	to := from.AddDate(0, 1, 0)
+	return &models.ExportFilter{
+		From:           &from,
+		To:             &to,
+		ShipperID:      shipperID,
+		ByDeliveryDate: true,
+	}
+}
+
+func (e *ExportService) sign(payload string) string {
+	mac := hmac.New(sha256.New, e.secret)
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// exportTime formats an optional timestamp for tabular exports
+func exportTime(t *time.Time) string {
+	if t == nil || t.IsZero() {
+		return ""
+	}
+	return t.Format(exportTimeLayout)
+}
+
+// exportWriter writes one row at a time in a specific file format.
+// record is the original value (for JSONL); values are the tabular cells.
+type exportWriter interface {
+	Write(record interface{}, values []interface{}) error
+	Close() error
+}
+
+func newExportWriter(w io.Writer, format string, columns []string) (exportWriter, error) {
+	switch format {
+	case models.ExportFormatCSV:
+		cw := csv.NewWriter(w)
+		if err := cw.Write(columns); err != nil {
+			return nil, err
+		}
+		return &csvExportWriter{w: cw}, nil
+
+	case models.ExportFormatJSONL:
+		return &jsonlExportWriter{enc: json.NewEncoder(w)}, nil
+
+	case models.ExportFormatXLSX:
+		f := excelize.NewFile()
+		sw, err := f.NewStreamWriter("Sheet1")
+		if err != nil {
+			return nil, err
+		}
+		header := make([]interface{}, len(columns))
+		for i, column := range columns {
+			header[i] = column
+		}
+		if err := sw.SetRow("A1", header); err != nil {
+			return nil, err
+		}
+		return &xlsxExportWriter{w: w, file: f, sheet: sw, row: 1}, nil
+	}
+
+	return nil, fmt.Errorf("unknown export format: %s", format)
+}
+
+// csvExportWriter writes comma-separated rows with a header
+type csvExportWriter struct {
+	w    *csv.Writer
+	rows int
+}
+
+func (c *csvExportWriter) Write(record interface{}, values []interface{}) error {
+	row := make([]string, len(values))
+	for i, v := range values {
+		switch v := v.(type) {
+		case float64:
+			row[i] = strconv.FormatFloat(v, 'f', -1, 64)
+		default:
+			row[i] = fmt.Sprint(v)
+		}
+	}
+	if err := c.w.Write(row); err != nil {
+		return err
+	}
+
+	// Push rows out periodically so clients see progress on large exports
+	c.rows++
+	if c.rows%100 == 0 {
+		c.w.Flush()
+		return c.w.Error()
+	}
+	return nil
+}
+
+func (c *csvExportWriter) Close() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// jsonlExportWriter writes one JSON object per line
+type jsonlExportWriter struct {
+	enc *json.Encoder
+}
+
+func (j *jsonlExportWriter) Write(record interface{}, values []interface{}) error {
+	return j.enc.Encode(record)
+}
+
+func (j *jsonlExportWriter) Close() error {
+	return nil
+}
+
+// xlsxExportWriter streams rows into a worksheet; excelize spills large sheets
+// to a temporary file, and the workbook is written out on Close
+type xlsxExportWriter struct {
+	w     io.Writer
+	file  *excelize.File
+	sheet *excelize.StreamWriter
+	row   int
+}
+
+func (x *xlsxExportWriter) Write(record interface{}, values []interface{}) error {
+	x.row++
+	cell, err := excelize.CoordinatesToCellName(1, x.row)
+	if err != nil {
+		return err
+	}
+	return x.sheet.SetRow(cell, values)
+}
+
+func (x *xlsxExportWriter) Close() error {
+	defer x.file.Close()
+
+	if err := x.sheet.Flush(); err != nil {
+		return err
+	}
+	return x.file.Write(x.w)
+}
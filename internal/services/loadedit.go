@@ -0,0 +1,187 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/Ananth-NQI/truckpe-backend/internal/events"
+	"github.com/Ananth-NQI/truckpe-backend/internal/models"
+	"github.com/Ananth-NQI/truckpe-backend/internal/storage"
+)
+
+const (
+	// loadEditEventBuffer is how many store events may queue up before the
+	// outbox has to publish them again
+	loadEditEventBuffer = 1024
+
+	// loadEditConsumer records the load events truckers were notified of
+	loadEditConsumer = "load_edits"
+)
+
+// LoadEditService lets shippers edit or withdraw loads nobody has booked yet,
+// and tells the truckers who were offered the load about the change
+type LoadEditService struct {
+	store  storage.Store
+	bus    *events.Bus
+	twilio *TwilioService // nil when Twilio is not configured, see TwilioService.Notify
+}
+
+// NewLoadEditService creates a new load edit service
+func NewLoadEditService(store storage.Store, bus *events.Bus, twilio *TwilioService) *LoadEditService {
+	return &LoadEditService{
+		store:  store,
+		bus:    bus,
+		twilio: twilio,
+	}
+}
+
+// Edit changes the price, loading date or vehicle type of one of the shipper's
+// loads. The previous values are kept in the load's timeline.
+func (e *LoadEditService) Edit(ctx context.Context, shipperID, loadID string, changes *models.LoadChanges, actor models.Actor) (*models.Load, error) {
+	load, err := e.store.GetLoad(ctx, loadID)
+	if err != nil {
+		return nil, err
+	}
+	if load.ShipperID != shipperID {
+		return nil, models.NotFound("load")
+	}
+
+	if err := changes.Validate(); err != nil {
+		return nil, err
+	}
+
+	return e.store.UpdateLoad(ctx, load.LoadID, changes, actor)
+}
+
+// Withdraw takes one of the shipper's loads off the marketplace
+func (e *LoadEditService) Withdraw(ctx context.Context, shipperID, loadID string, actor models.Actor) (*models.Load, error) {
+	load, err := e.store.GetLoad(ctx, loadID)
+	if err != nil {
+		return nil, err
+	}
+	if load.ShipperID != shipperID {
+		return nil, models.NotFound("load")
+	}
+
+	return e.store.WithdrawLoad(ctx, load.LoadID, actor)
+}
+
+// Start subscribes to store events and notifies truckers of edited and withdrawn loads
+func (e *LoadEditService) Start() {
+	sub := e.bus.SubscribeDurable(loadEditEventBuffer)
+	go func() {
+		ctx := context.Background()
+		for event := range sub.C {
+			if event.EntityType != models.EntityLoad {
+				continue
+			}
+
+			switch {
+			case event.EventType == models.EventLoadUpdated:
+				handleOnce(ctx, e.store, loadEditConsumer, event, func() {
+					e.notify(ctx, event.EntityID, func(load *models.Load) string {
+						return loadUpdatedMessage(load, event.Before, event.After)
+					})
+				})
+			case event.EventType == models.EventLoadStatusChanged && event.After["status"] == models.LoadStatusWithdrawn:
+				handleOnce(ctx, e.store, loadEditConsumer, event, func() {
+					e.notify(ctx, event.EntityID, loadWithdrawnMessage)
+				})
+			}
+		}
+	}()
+}
+
+// notify messages every trucker who was alerted about the load, suggested it
+// as a return load or booked it, once each
+func (e *LoadEditService) notify(ctx context.Context, loadID string, message func(load *models.Load) string) {
+	load, err := e.store.GetLoad(ctx, loadID)
+	if err != nil {
+		log.Printf("❌ Load edit event for unknown load %s: %v", loadID, err)
+		return
+	}
+
+	truckerIDs, err := e.interestedTruckers(ctx, load.LoadID)
+	if err != nil {
+		log.Printf("❌ Failed to find truckers offered %s: %v", load.LoadID, err)
+		return
+	}
+
+	text := message(load)
+	for _, truckerID := range truckerIDs {
+		trucker, err := e.store.GetTrucker(ctx, truckerID)
+		if err != nil {
+			continue
+		}
+		e.twilio.Notify(ctx, trucker.Phone, text)
+	}
+}
+
+// interestedTruckers lists the truckers who were offered or booked the load
+func (e *LoadEditService) interestedTruckers(ctx context.Context, loadID string) ([]string, error) {
+	seen := make(map[string]bool)
+	var truckerIDs []string
+	add := func(truckerID string) {
+		if truckerID != "" && !seen[truckerID] {
+			seen[truckerID] = true
+			truckerIDs = append(truckerIDs, truckerID)
+		}
+	}
+
+	notifications, err := e.store.GetAlertNotificationsByLoad(ctx, loadID)
+	if err != nil {
+		return nil, err
+	}
+	for _, notification := range notifications {
+		add(notification.TruckerID)
+	}
+
+	suggestions, err := e.store.GetBackhaulSuggestionsByLoad(ctx, loadID)
+	if err != nil {
+		return nil, err
+	}
+	for _, suggestion := range suggestions {
+		add(suggestion.TruckerID)
+	}
+
+	bookings, err := e.store.GetBookingsByLoad(ctx, loadID)
+	if err != nil {
+		return nil, err
+	}
+	for _, booking := range bookings {
+		add(booking.TruckerID)
+	}
+
+	return truckerIDs, nil
+}
+
+// loadUpdatedMessage is the WhatsApp text telling a trucker what changed on a load
+func loadUpdatedMessage(load *models.Load, before, after models.EventData) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("✏️ *Load updated:* %s %s → %s\n\n", load.LoadID, load.FromCity, load.ToCity))
+
+	if _, ok := after["price"]; ok {
+		sb.WriteString(fmt.Sprintf("💰 Price: ₹%.0f → ₹%.0f\n", before["price"], after["price"]))
+	}
+	if _, ok := after["loading_date"]; ok {
+		previous, _ := before["loading_date"].(time.Time)
+		sb.WriteString(fmt.Sprintf("📅 Loading: %s → %s\n", previous.Format("02 Jan 15:04"), load.LoadingDate.Format("02 Jan 15:04")))
+	}
+	if _, ok := after["vehicle_type"]; ok {
+		sb.WriteString(fmt.Sprintf("🚛 Vehicle: %s → %s\n", before["vehicle_type"], after["vehicle_type"]))
+	}
+
+	if load.IsEditable() {
+		sb.WriteString(fmt.Sprintf("\n👉 Reply *BOOK %s*", load.LoadID))
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
+
+// loadWithdrawnMessage is the WhatsApp text telling a trucker a load is no longer offered
+func loadWithdrawnMessage(load *models.Load) string {
+	return fmt.Sprintf("🚫 *Load withdrawn:* %s %s → %s\n\nThe shipper has taken this load off TruckPe.",
+		load.LoadID, load.FromCity, load.ToCity)
+}
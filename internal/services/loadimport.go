@@ -0,0 +1,388 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Ananth-NQI/truckpe-backend/internal/models"
+	"github.com/Ananth-NQI/truckpe-backend/internal/storage"
+	"github.com/xuri/excelize/v2"
+)
+
+// Bulk import commit modes
+const (
+	ImportModeAtomic     = "atomic"      // every row is created, or none if any row is invalid
+	ImportModeBestEffort = "best_effort" // valid rows are created, invalid rows are reported
+)
+
+const (
+	// MaxImportRows caps the number of loads in a single file
+	MaxImportRows = 500
+
+	// MaxImportFileSize caps the size of an uploaded file in bytes
+	MaxImportFileSize = 5 << 20
+)
+
+// LoadImportColumns is the column layout of bulk load files.
+// The first row must be a header naming the columns; order does not matter,
+// names are case-insensitive and unknown columns are ignored.
+//
+//	from_city      required
+//	to_city        required
+//	material       required
+//	weight         required, tons
+//	price          required, rupees
+//	vehicle_type   optional, defaults to "Any"
+//	loading_date   optional, YYYY-MM-DD or DD/MM/YYYY, defaults to tomorrow
+//	payment_terms  optional, Advance, To-Pay or POD
+//	pickup_point   optional
+//	drop_point     optional
+//	distance       optional, km
+//	truck_count    optional, trucks needed for the weight, defaults to 1
+//	splittable     optional, yes for a part load booked by weight
+var LoadImportColumns = []string{
+	"from_city", "to_city", "material", "weight", "price",
+	"vehicle_type", "loading_date", "payment_terms", "pickup_point", "drop_point", "distance",
+	"truck_count", "splittable",
+}
+
+// spreadsheetContentTypes are the attachment types accepted for bulk upload
+var spreadsheetContentTypes = []string{
+	"text/csv",
+	"text/comma-separated-values",
+	"text/plain",
+	"application/csv",
+	"application/vnd.ms-excel",
+	"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+}
+
+// IsSpreadsheetContentType reports whether an attachment looks like a CSV or Excel file
+func IsSpreadsheetContentType(contentType string) bool {
+	contentType = strings.ToLower(strings.TrimSpace(strings.Split(contentType, ";")[0]))
+	if contentType == "" {
+		return true // Let the parser decide
+	}
+	for _, t := range spreadsheetContentTypes {
+		if contentType == t {
+			return true
+		}
+	}
+	return false
+}
+
+// requiredImportColumns must be present in the header row
+var requiredImportColumns = []string{"from_city", "to_city", "material", "weight", "price"}
+
+// loadingDateLayouts are the accepted loading_date formats
+var loadingDateLayouts = []string{"2006-01-02", "02/01/2006", "02-01-2006", "2/1/2006"}
+
+// LoadImportRowError describes why a row was rejected.
+// Row is the line number in the file, counting the header as row 1.
+type LoadImportRowError struct {
+	Row   int    `json:"row"`
+	Error string `json:"error"`
+}
+
+// LoadImportResult summarises a bulk import
+type LoadImportResult struct {
+	Mode      string               `json:"mode"`
+	DryRun    bool                 `json:"dry_run"`
+	TotalRows int                  `json:"total_rows"`
+	ValidRows int                  `json:"valid_rows"`
+	Created   []*models.Load       `json:"created"`
+	Errors    []LoadImportRowError `json:"errors"`
+}
+
+// LoadImportService creates loads in bulk from CSV and Excel files
+type LoadImportService struct {
+	store storage.Store
+}
+
+// NewLoadImportService creates a new load import service
+func NewLoadImportService(store storage.Store) *LoadImportService {
+	return &LoadImportService{
+		store: store,
+	}
+}
+
+// importRow is a parsed data row with its line number
+type importRow struct {
+	line int
+	load *models.Load
+}
+
+// Import parses a CSV or XLSX file and creates its loads for the shipper.
+// With dryRun set, rows are only validated and nothing is created.
+func (s *LoadImportService) Import(ctx context.Context, shipperID string, data []byte, mode string, dryRun bool, actor models.Actor) (*LoadImportResult, error) {
+	if mode == "" {
+		mode = ImportModeAtomic
+	}
+	if mode != ImportModeAtomic && mode != ImportModeBestEffort {
+		return nil, models.Invalid("import", "mode", "invalid import mode: "+mode)
+	}
+
+	shipper, err := s.store.GetShipper(ctx, shipperID)
+	if err != nil {
+		return nil, err
+	}
+
+	records, err := readSpreadsheet(data)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &LoadImportResult{
+		Mode:    mode,
+		DryRun:  dryRun,
+		Created: []*models.Load{},
+		Errors:  []LoadImportRowError{},
+	}
+
+	rows, err := parseLoadRows(records, shipper, result)
+	if err != nil {
+		return nil, err
+	}
+	result.ValidRows = len(rows)
+
+	if dryRun || len(rows) == 0 {
+		return result, nil
+	}
+
+	if mode == ImportModeAtomic {
+		// One bad row rejects the whole file
+		if len(result.Errors) > 0 {
+			return result, nil
+		}
+
+		loads := make([]*models.Load, len(rows))
+		for i, row := range rows {
+			loads[i] = row.load
+		}
+		created, err := s.store.CreateLoads(ctx, loads, actor)
+		if err != nil {
+			return nil, err
+		}
+		result.Created = created
+		return result, nil
+	}
+
+	for _, row := range rows {
+		created, err := s.store.CreateLoad(ctx, row.load, actor)
+		if err != nil {
+			result.Errors = append(result.Errors, LoadImportRowError{Row: row.line, Error: "Failed to create load"})
+			continue
+		}
+		result.Created = append(result.Created, created)
+	}
+
+	return result, nil
+}
+
+// readSpreadsheet returns the rows of an XLSX workbook's first sheet or of a CSV file
+func readSpreadsheet(data []byte) ([][]string, error) {
+	if len(data) == 0 {
+		return nil, models.Invalid("import", "file", "file is empty")
+	}
+	if len(data) > MaxImportFileSize {
+		return nil, models.Invalid("import", "file", fmt.Sprintf("file is larger than %d MB", MaxImportFileSize>>20))
+	}
+
+	// XLSX files are zip archives
+	if bytes.HasPrefix(data, []byte("PK\x03\x04")) {
+		f, err := excelize.OpenReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, models.Invalid("import", "file", "unsupported file format")
+		}
+		defer f.Close()
+
+		sheets := f.GetSheetList()
+		if len(sheets) == 0 {
+			return nil, models.Invalid("import", "file", "file has no rows")
+		}
+		// Raw values keep dates as serial numbers rather than locale formatted text
+		return f.GetRows(sheets[0], excelize.Options{RawCellValue: true})
+	}
+
+	reader := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))))
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	var records [][]string
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, models.Invalid("import", "file", "unsupported file format")
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// parseLoadRows maps the header and data rows to loads, recording per-row errors in result
+func parseLoadRows(records [][]string, shipper *models.Shipper, result *LoadImportResult) ([]importRow, error) {
+	if len(records) == 0 {
+		return nil, models.Invalid("import", "file", "file has no rows")
+	}
+
+	columns := make(map[string]int)
+	for i, name := range records[0] {
+		name = strings.ToLower(strings.TrimSpace(name))
+		name = strings.ReplaceAll(name, " ", "_")
+		columns[name] = i
+	}
+	for _, name := range requiredImportColumns {
+		if _, ok := columns[name]; !ok {
+			return nil, models.Invalid("import", "file", "missing required column: "+name)
+		}
+	}
+
+	var rows []importRow
+	for i, record := range records[1:] {
+		line := i + 2
+
+		cell := func(name string) string {
+			if idx, ok := columns[name]; ok && idx < len(record) {
+				return strings.TrimSpace(record[idx])
+			}
+			return ""
+		}
+
+		if strings.TrimSpace(strings.Join(record, "")) == "" {
+			continue
+		}
+
+		result.TotalRows++
+		if result.TotalRows > MaxImportRows {
+			return nil, models.Invalid("import", "file", fmt.Sprintf("file has more than %d rows", MaxImportRows))
+		}
+
+		load, err := parseLoadRow(cell, shipper)
+		if err != nil {
+			result.Errors = append(result.Errors, LoadImportRowError{Row: line, Error: err.Error()})
+			continue
+		}
+		rows = append(rows, importRow{line: line, load: load})
+	}
+
+	if result.TotalRows == 0 {
+		return nil, models.Invalid("import", "file", "file has no rows")
+	}
+
+	return rows, nil
+}
+
+// parseLoadRow builds and validates one load from a row
+func parseLoadRow(cell func(name string) string, shipper *models.Shipper) (*models.Load, error) {
+	load := &models.Load{
+		ShipperID:    shipper.ShipperID,
+		ShipperName:  shipper.CompanyName,
+		ShipperPhone: shipper.Phone,
+		FromCity:     cell("from_city"),
+		ToCity:       cell("to_city"),
+		Material:     strings.Title(strings.ToLower(cell("material"))),
+		VehicleType:  cell("vehicle_type"),
+		PickupPoint:  cell("pickup_point"),
+		DropPoint:    cell("drop_point"),
+		LoadingDate:  time.Now().Add(24 * time.Hour), // Tomorrow, as for WhatsApp POST
+		Status:       models.LoadStatusAvailable,
+	}
+
+	if load.VehicleType == "" {
+		load.VehicleType = "Any"
+	}
+
+	var err error
+	if load.Weight, err = parseImportNumber(cell("weight")); err != nil {
+		return nil, fmt.Errorf("invalid weight: %s", cell("weight"))
+	}
+	if load.Price, err = parseImportNumber(cell("price")); err != nil {
+		return nil, fmt.Errorf("invalid price: %s", cell("price"))
+	}
+	if value := cell("distance"); value != "" {
+		if load.Distance, err = parseImportNumber(value); err != nil {
+			return nil, fmt.Errorf("invalid distance: %s", value)
+		}
+	}
+
+	if value := cell("truck_count"); value != "" {
+		if load.TruckCount, err = strconv.Atoi(value); err != nil || load.TruckCount < 1 {
+			return nil, fmt.Errorf("invalid truck count: %s", value)
+		}
+	}
+	if value := cell("splittable"); value != "" {
+		switch strings.ToLower(value) {
+		case "yes", "y", "true", "1":
+			load.Splittable = true
+		case "no", "n", "false", "0":
+		default:
+			return nil, fmt.Errorf("invalid splittable: %s", value)
+		}
+	}
+
+	if value := cell("loading_date"); value != "" {
+		date, err := parseLoadingDate(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid loading date: %s", value)
+		}
+		load.LoadingDate = date
+	}
+
+	if value := cell("payment_terms"); value != "" {
+		terms, ok := parsePaymentTerms(value)
+		if !ok {
+			return nil, fmt.Errorf("invalid payment terms: %s", value)
+		}
+		load.PaymentTerms = terms
+	}
+
+	if err := load.Validate(); err != nil {
+		return nil, err
+	}
+	if err := load.ResolveCities(); err != nil {
+		return nil, err
+	}
+
+	return load, nil
+}
+
+// parseImportNumber parses numbers written with thousands separators or a currency sign
+func parseImportNumber(value string) (float64, error) {
+	value = strings.TrimPrefix(strings.TrimSpace(value), "₹")
+	value = strings.ReplaceAll(value, ",", "")
+	if value == "" {
+		return 0, nil
+	}
+	return strconv.ParseFloat(value, 64)
+}
+
+// parseLoadingDate accepts text dates and Excel serial dates
+func parseLoadingDate(value string) (time.Time, error) {
+	for _, layout := range loadingDateLayouts {
+		if date, err := time.Parse(layout, value); err == nil {
+			return date, nil
+		}
+	}
+	if serial, err := strconv.ParseFloat(value, 64); err == nil && serial > 0 {
+		return excelize.ExcelDateToTime(serial, false)
+	}
+	return time.Time{}, fmt.Errorf("invalid date")
+}
+
+// parsePaymentTerms matches payment terms case-insensitively
+func parsePaymentTerms(value string) (string, bool) {
+	for _, terms := range []string{models.PaymentTermsAdvance, models.PaymentTermsToPay, models.PaymentTermsPOD} {
+		if strings.EqualFold(value, terms) {
+			return terms, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,113 @@
+package services
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/Ananth-NQI/truckpe-backend/internal/events"
+	"github.com/Ananth-NQI/truckpe-backend/internal/models"
+	"github.com/Ananth-NQI/truckpe-backend/internal/storage"
+)
+
+const (
+	// outboxPollInterval is how often the dispatcher looks for unpublished events
+	outboxPollInterval = 15 * time.Second
+
+	// outboxGrace is how long after its commit an event is left to the store
+	// to publish before the dispatcher takes over
+	outboxGrace = 30 * time.Second
+
+	// outboxBatch is how many events are published per store query
+	outboxBatch = 100
+)
+
+// OutboxDispatcher publishes events the store committed but never got to
+// publish, such as those of a process that stopped right after a commit or
+// those a busy durable subscriber could not take. Events are published at
+// least once: subscribers that must act on an event only once should check
+// its IdempotencyKey.
+type OutboxDispatcher struct {
+	store storage.Store
+	bus   *events.Bus
+}
+
+// NewOutboxDispatcher creates a new outbox dispatcher
+func NewOutboxDispatcher(store storage.Store, bus *events.Bus) *OutboxDispatcher {
+	return &OutboxDispatcher{
+		store: store,
+		bus:   bus,
+	}
+}
+
+// Start launches the dispatcher; start it after the services that subscribe
+// to the bus so they see the events left over from before a restart
+func (o *OutboxDispatcher) Start() {
+	go func() {
+		ctx := context.Background()
+		ticker := time.NewTicker(outboxPollInterval)
+		defer ticker.Stop()
+
+		for {
+			o.Dispatch(ctx, time.Now().Add(-outboxGrace))
+			<-ticker.C
+		}
+	}()
+}
+
+// Dispatch publishes the events committed before the given time that are
+// still in the outbox, and returns how many it published. Events a durable
+// subscriber could not take stay in the outbox for the next round.
+func (o *OutboxDispatcher) Dispatch(ctx context.Context, before time.Time) int {
+	published := 0
+	for {
+		messages, err := o.store.GetPendingOutboxMessages(ctx, before, outboxBatch)
+		if err != nil {
+			log.Printf("❌ Failed to fetch outbox messages: %v", err)
+			return published
+		}
+		if len(messages) == 0 {
+			return published
+		}
+
+		ids := make([]uint, 0, len(messages))
+		for _, message := range messages {
+			if message.Event != nil && !o.bus.Publish(message.Event) {
+				continue
+			}
+			ids = append(ids, message.ID)
+		}
+		if len(ids) > 0 {
+			if err := o.store.DeleteOutboxMessages(ctx, ids); err != nil {
+				log.Printf("❌ Failed to clear outbox messages: %v", err)
+				return published
+			}
+			published += len(ids)
+			log.Printf("📤 Published %d events left in the outbox", len(ids))
+		}
+		if len(ids) < len(messages) {
+			// Subscribers are busy; give them until the next round
+			return published
+		}
+	}
+}
+
+// handleOnce runs handle unless consumer already handled the event, as it
+// may have before the outbox published the event again, and then records
+// the event as handled. An event whose handling a crash cut short is
+// handled again.
+func handleOnce(ctx context.Context, store storage.Store, consumer string, event *models.Event, handle func()) {
+	key := event.IdempotencyKey()
+	handled, err := store.IsEventHandled(ctx, consumer, key)
+	if err != nil {
+		log.Printf("⚠️  Could not check whether %s handled event %d: %v", consumer, event.ID, err)
+	} else if handled {
+		return
+	}
+
+	handle()
+
+	if err := store.MarkEventHandled(ctx, consumer, key); err != nil {
+		log.Printf("❌ Failed to record event %d as handled by %s: %v", event.ID, consumer, err)
+	}
+}
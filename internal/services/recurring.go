@@ -0,0 +1,230 @@
+package services
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/Ananth-NQI/truckpe-backend/internal/models"
+	"github.com/Ananth-NQI/truckpe-backend/internal/storage"
+)
+
+// recurringPollInterval is how often the scheduler posts upcoming occurrences
+const recurringPollInterval = 15 * time.Minute
+
+// RecurringLoadService manages shippers' recurring loads and posts their
+// occurrences as loads ahead of time
+type RecurringLoadService struct {
+	store storage.Store
+	mu    sync.Mutex // serialises scheduling and changes in this process; the store's versions guard against other instances
+}
+
+// NewRecurringLoadService creates a new recurring load service
+func NewRecurringLoadService(store storage.Store) *RecurringLoadService {
+	return &RecurringLoadService{
+		store: store,
+	}
+}
+
+// Create saves a recurring load for a shipper and posts its first occurrences.
+// Days may be a preset such as "weekdays" or a list of day names.
+func (r *RecurringLoadService) Create(ctx context.Context, shipperID string, recurring *models.RecurringLoad) (*models.RecurringLoad, error) {
+	shipper, err := r.store.GetShipper(ctx, shipperID)
+	if err != nil {
+		return nil, err
+	}
+
+	recurring.ShipperID = shipper.ShipperID
+	recurring.ShipperName = shipper.CompanyName
+	recurring.ShipperPhone = shipper.Phone
+	if recurring.VehicleType == "" {
+		recurring.VehicleType = "Any"
+	}
+	if recurring.Time == "" {
+		recurring.Time = models.DefaultRecurringTime
+	}
+	if recurring.StartDate.IsZero() {
+		recurring.StartDate = time.Now()
+	}
+	if recurring.Days != "" {
+		if recurring.Days, err = models.ParseScheduleDays(recurring.Days); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := recurring.Validate(); err != nil {
+		return nil, err
+	}
+	if err := recurring.ResolveCities(); err != nil {
+		return nil, err
+	}
+
+	if recurring.ContractTruckerID != "" {
+		trucker, err := r.store.GetTrucker(ctx, recurring.ContractTruckerID)
+		if err != nil {
+			return nil, err
+		}
+		recurring.ContractTruckerID = trucker.TruckerID
+	}
+
+	created, err := r.store.CreateRecurringLoad(ctx, recurring)
+	if err != nil {
+		return nil, err
+	}
+
+	r.schedule(ctx, created.RecurringID, time.Now())
+	return created, nil
+}
+
+// Repeat turns an existing load into a recurring one on the given schedule,
+// loading at the same time of day. The load itself is the first occurrence.
+func (r *RecurringLoadService) Repeat(ctx context.Context, shipperID, loadID, days string) (*models.RecurringLoad, error) {
+	load, err := r.store.GetLoad(ctx, loadID)
+	if err != nil {
+		return nil, err
+	}
+	if load.ShipperID != shipperID {
+		return nil, models.NotFound("load")
+	}
+
+	return r.Create(ctx, shipperID, &models.RecurringLoad{
+		FromCity:       load.FromCity,
+		ToCity:         load.ToCity,
+		PickupPoint:    load.PickupPoint,
+		DropPoint:      load.DropPoint,
+		Distance:       load.Distance,
+		Material:       load.Material,
+		Weight:         load.Weight,
+		VehicleType:    load.VehicleType,
+		TruckCount:     load.TruckCount,
+		Price:          load.Price,
+		PaymentTerms:   load.PaymentTerms,
+		Days:           days,
+		Time:           load.LoadingDate.Format("15:04"),
+		StartDate:      load.LoadingDate,
+		ScheduledUntil: load.LoadingDate,
+	})
+}
+
+// RecurringLoads lists a shipper's recurring loads
+func (r *RecurringLoadService) RecurringLoads(ctx context.Context, shipperID string) ([]*models.RecurringLoad, error) {
+	return r.store.GetRecurringLoadsByShipper(ctx, shipperID)
+}
+
+// Pause stops posting occurrences until the recurring load is resumed
+func (r *RecurringLoadService) Pause(ctx context.Context, shipperID, id string) (*models.RecurringLoad, error) {
+	return r.update(ctx, shipperID, id, func(recurring *models.RecurringLoad) {
+		recurring.Paused = true
+	})
+}
+
+// Resume restarts a paused recurring load from now on; occurrences missed
+// while it was paused are not posted
+func (r *RecurringLoadService) Resume(ctx context.Context, shipperID, id string) (*models.RecurringLoad, error) {
+	recurring, err := r.update(ctx, shipperID, id, func(recurring *models.RecurringLoad) {
+		recurring.Paused = false
+		if now := time.Now(); recurring.ScheduledUntil.Before(now) {
+			recurring.ScheduledUntil = now
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	r.schedule(ctx, recurring.RecurringID, time.Now())
+	return recurring, nil
+}
+
+// Skip cancels the occurrence on one date. An occurrence already posted
+// stays on the marketplace and must be withdrawn separately.
+func (r *RecurringLoadService) Skip(ctx context.Context, shipperID, id string, date time.Time) (*models.RecurringLoad, error) {
+	return r.update(ctx, shipperID, id, func(recurring *models.RecurringLoad) {
+		recurring.Skip(date)
+	})
+}
+
+// update applies a change to the current state of one of the shipper's
+// recurring loads and saves it
+func (r *RecurringLoadService) update(ctx context.Context, shipperID, id string, change func(recurring *models.RecurringLoad)) (*models.RecurringLoad, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	recurring, err := r.store.GetRecurringLoad(ctx, id)
+	if err != nil || recurring.ShipperID != shipperID {
+		return nil, models.NotFound("recurring load")
+	}
+
+	change(recurring)
+	if err := r.store.UpdateRecurringLoad(ctx, recurring); err != nil {
+		return nil, err
+	}
+	return recurring, nil
+}
+
+// Start launches the scheduler, which posts occurrences up to RecurringHorizon ahead
+func (r *RecurringLoadService) Start() {
+	go func() {
+		ctx := context.Background()
+		ticker := time.NewTicker(recurringPollInterval)
+		defer ticker.Stop()
+
+		for {
+			r.Run(ctx, time.Now())
+			<-ticker.C
+		}
+	}()
+}
+
+// Run posts every active recurring load's occurrences due within RecurringHorizon of now
+func (r *RecurringLoadService) Run(ctx context.Context, now time.Time) {
+	recurringLoads, err := r.store.GetActiveRecurringLoads(ctx)
+	if err != nil {
+		log.Printf("❌ Failed to fetch recurring loads: %v", err)
+		return
+	}
+
+	for _, recurring := range recurringLoads {
+		r.schedule(ctx, recurring.RecurringID, now)
+	}
+}
+
+// schedule posts the occurrences of one recurring load that are not posted yet,
+// booking each to the contracted trucker if there is one. A contracted
+// trucker who is busy leaves the load on the open market.
+func (r *RecurringLoadService) schedule(ctx context.Context, recurringID string, now time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// Read the row afresh: it may have been paused or skipped since it was
+	// listed, and another instance may have posted some occurrences
+	recurring, err := r.store.GetRecurringLoad(ctx, recurringID)
+	if err != nil {
+		log.Printf("❌ Failed to fetch recurring load %s: %v", recurringID, err)
+		return
+	}
+	if recurring.Paused {
+		return
+	}
+
+	after := recurring.ScheduledUntil
+	if after.Before(now) {
+		after = now
+	}
+
+	for _, loadingDate := range recurring.Occurrences(after, now.Add(models.RecurringHorizon)) {
+		// Posts the load and advances ScheduledUntil together, unless the row changed meanwhile
+		load, err := r.store.PostRecurringOccurrence(ctx, recurring, loadingDate, models.SystemActor)
+		if err != nil {
+			log.Printf("❌ Failed to post recurring load %s: %v", recurring.RecurringID, err)
+			return
+		}
+
+		if recurring.ContractTruckerID != "" {
+			if _, err := r.store.CreateBooking(ctx, load.LoadID, recurring.ContractTruckerID, 0, models.SystemActor); err != nil {
+				log.Printf("⚠️  Contracted trucker %s could not take %s: %v", recurring.ContractTruckerID, load.LoadID, err)
+			}
+		}
+		log.Printf("🔁 Posted %s from recurring load %s for %s", load.LoadID, recurring.RecurringID, loadingDate.Format("02 Jan 15:04"))
+	}
+}
@@ -0,0 +1,222 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Ananth-NQI/truckpe-backend/internal/models"
+	"github.com/Ananth-NQI/truckpe-backend/internal/storage"
+)
+
+const (
+	// DefaultTrackingLinkTTL is how long a shared tracking link stays valid
+	DefaultTrackingLinkTTL = 72 * time.Hour
+
+	// averageTruckSpeedKmph is used for rough ETA estimates
+	averageTruckSpeedKmph = 40.0
+)
+
+// TrackingService issues and resolves public tracking links for consignees
+type TrackingService struct {
+	store   storage.Store
+	secret  []byte
+	baseURL string
+}
+
+// NewTrackingService creates a new tracking service
+func NewTrackingService(store storage.Store) *TrackingService {
+	secret := os.Getenv("TRACKING_SECRET")
+	if secret == "" {
+		// Links will stop verifying after a restart, fine for local testing
+		log.Println("⚠️  TRACKING_SECRET not set - using a random secret")
+		buf := make([]byte, 32)
+		rand.Read(buf)
+		secret = hex.EncodeToString(buf)
+	}
+
+	baseURL := os.Getenv("PUBLIC_BASE_URL")
+	if baseURL == "" {
+		baseURL = "http://localhost:8080"
+	}
+
+	return &TrackingService{
+		store:   store,
+		secret:  []byte(secret),
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+	}
+}
+
+// CreateLink issues a new tracking link for a booking owned by the shipper
+func (t *TrackingService) CreateLink(ctx context.Context, bookingID, shipperID string, ttl time.Duration, actor models.Actor) (*models.TrackingLink, error) {
+	booking, err := t.store.GetBooking(ctx, bookingID)
+	if err != nil {
+		return nil, err
+	}
+	if booking.ShipperID != shipperID {
+		return nil, models.Forbidden("booking", "booking does not belong to shipper")
+	}
+
+	if ttl <= 0 {
+		ttl = DefaultTrackingLinkTTL
+	}
+	expiresAt := time.Now().Add(ttl)
+
+	link := &models.TrackingLink{
+		Token:     t.signToken(booking.BookingID, expiresAt),
+		BookingID: booking.BookingID,
+		ShipperID: shipperID,
+		ExpiresAt: expiresAt,
+	}
+
+	return t.store.CreateTrackingLink(ctx, link, actor)
+}
+
+// RevokeLinks revokes every tracking link the shipper has shared for a booking
+func (t *TrackingService) RevokeLinks(ctx context.Context, bookingID, shipperID string, actor models.Actor) error {
+	booking, err := t.store.GetBooking(ctx, bookingID)
+	if err != nil {
+		return err
+	}
+	if booking.ShipperID != shipperID {
+		return models.Forbidden("booking", "booking does not belong to shipper")
+	}
+
+	return t.store.RevokeTrackingLinks(ctx, booking.BookingID, actor)
+}
+
+// Resolve validates a token and returns the public view of its booking
+func (t *TrackingService) Resolve(ctx context.Context, token string) (*models.PublicTracking, error) {
+	if _, _, err := t.verifyToken(token); err != nil {
+		return nil, err
+	}
+
+	link, err := t.store.GetTrackingLink(ctx, token)
+	if err != nil {
+		return nil, models.NotFound("tracking link")
+	}
+	if !link.IsActive() {
+		return nil, models.Expired("tracking link", "tracking link expired")
+	}
+
+	booking, err := t.store.GetBooking(ctx, link.BookingID)
+	if err != nil {
+		return nil, err
+	}
+	load, err := t.store.GetLoad(ctx, booking.LoadID)
+	if err != nil {
+		return nil, err
+	}
+
+	view := &models.PublicTracking{
+		BookingID:    booking.BookingID,
+		Status:       booking.Status,
+		FromCity:     load.FromCity,
+		ToCity:       load.ToCity,
+		LastLocation: load.FromCity,
+		LastUpdated:  booking.UpdatedAt,
+		ETA:          estimateArrival(booking, load),
+	}
+
+	if booking.Status == models.BookingStatusDelivered || booking.Status == models.BookingStatusCompleted {
+		view.LastLocation = load.ToCity
+	}
+
+	if trucker, err := t.store.GetTrucker(ctx, booking.TruckerID); err == nil {
+		view.TruckerName = models.MaskName(trucker.Name)
+		view.VehicleNo = models.MaskVehicleNo(trucker.VehicleNo)
+		if trucker.CurrentCity != "" && booking.Status == models.BookingStatusInTransit {
+			view.LastLocation = trucker.CurrentCity
+		}
+	}
+
+	return view, nil
+}
+
+// LinkURL returns the public URL for a tracking token
+func (t *TrackingService) LinkURL(token string) string {
+	return t.baseURL + "/t/" + token
+}
+
+// signToken builds "<payload>.<signature>" where payload is bookingID|expiry|nonce
+func (t *TrackingService) signToken(bookingID string, expiresAt time.Time) string {
+	nonce := make([]byte, 8)
+	rand.Read(nonce)
+
+	payload := fmt.Sprintf("%s|%d|%s", bookingID, expiresAt.Unix(), hex.EncodeToString(nonce))
+	encoded := base64.RawURLEncoding.EncodeToString([]byte(payload))
+
+	return encoded + "." + t.sign(encoded)
+}
+
+// verifyToken checks the signature and expiry embedded in a token
+func (t *TrackingService) verifyToken(token string) (string, time.Time, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 2 {
+		return "", time.Time{}, models.NotFound("tracking link")
+	}
+
+	if !hmac.Equal([]byte(parts[1]), []byte(t.sign(parts[0]))) {
+		return "", time.Time{}, models.NotFound("tracking link")
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return "", time.Time{}, models.NotFound("tracking link")
+	}
+
+	fields := strings.Split(string(raw), "|")
+	if len(fields) != 3 {
+		return "", time.Time{}, models.NotFound("tracking link")
+	}
+
+	unix, err := strconv.ParseInt(fields[1], 10, 64)
+	if err != nil {
+		return "", time.Time{}, models.NotFound("tracking link")
+	}
+
+	expiresAt := time.Unix(unix, 0)
+	if time.Now().After(expiresAt) {
+		return "", time.Time{}, models.Expired("tracking link", "tracking link expired")
+	}
+
+	return fields[0], expiresAt, nil
+}
+
+func (t *TrackingService) sign(payload string) string {
+	mac := hmac.New(sha256.New, t.secret)
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// estimateArrival gives a rough ETA from distance and an average truck speed
+func estimateArrival(booking *models.Booking, load *models.Load) *time.Time {
+	switch booking.Status {
+	case models.BookingStatusDelivered, models.BookingStatusCompleted:
+		return booking.DeliveredAt
+	}
+
+	if load.Distance <= 0 {
+		return nil
+	}
+
+	start := load.LoadingDate
+	if booking.PickedUpAt != nil {
+		start = *booking.PickedUpAt
+	}
+	if start.IsZero() {
+		return nil
+	}
+
+	eta := start.Add(time.Duration(load.Distance / averageTruckSpeedKmph * float64(time.Hour)))
+	return &eta
+}
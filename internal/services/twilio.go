@@ -1,17 +1,53 @@
 package services
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"log"
+	"net"
+	"net/http"
+	"net/url"
 	"os"
+	"time"
 
+	"github.com/Ananth-NQI/truckpe-backend/internal/models"
 	"github.com/twilio/twilio-go"
+	twilioclient "github.com/twilio/twilio-go/client"
 	api "github.com/twilio/twilio-go/rest/api/v2010"
 )
 
+// twilioMediaHost serves the attachments of messages sent to us. Only
+// this host is sent the account credentials.
+const twilioMediaHost = "api.twilio.com"
+
+// mediaClient downloads attachments. Twilio redirects media to its CDN; the
+// redirect must stay on https and never reaches an internal address.
+var mediaClient = &http.Client{
+	Timeout: 30 * time.Second,
+	Transport: &http.Transport{
+		DialContext: (&net.Dialer{Timeout: 30 * time.Second, Control: dialPublicOnly}).DialContext,
+	},
+	CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		if len(via) >= 5 {
+			return fmt.Errorf("media download redirected too often")
+		}
+		if req.URL.Scheme != "https" {
+			return fmt.Errorf("media download redirected to %s", req.URL.Scheme)
+		}
+		if req.URL.Hostname() != twilioMediaHost {
+			req.Header.Del("Authorization")
+		}
+		return nil
+	},
+}
+
 type TwilioService struct {
-	client *twilio.RestClient
-	from   string // Your Twilio WhatsApp number
+	client     *twilio.RestClient
+	validator  twilioclient.RequestValidator
+	from       string // Your Twilio WhatsApp number
+	accountSid string
+	authToken  string
 }
 
 // NewTwilioService creates a new Twilio service instance
@@ -30,24 +66,95 @@ func NewTwilioService() (*TwilioService, error) {
 	})
 
 	return &TwilioService{
-		client: client,
-		from:   from,
+		client:     client,
+		validator:  twilioclient.NewRequestValidator(authToken),
+		from:       from,
+		accountSid: accountSid,
+		authToken:  authToken,
 	}, nil
 }
 
-// SendWhatsAppMessage sends a WhatsApp message via Twilio
-func (t *TwilioService) SendWhatsAppMessage(to string, message string) error {
+// SendWhatsAppMessage sends a WhatsApp message via Twilio. The Twilio SDK takes
+// no context, so cancelling ctx stops the wait for Twilio's reply but cannot
+// recall a message already handed over.
+func (t *TwilioService) SendWhatsAppMessage(ctx context.Context, to string, message string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	params := &api.CreateMessageParams{}
 	params.SetFrom(t.from)
 	params.SetTo(fmt.Sprintf("whatsapp:%s", to))
 	params.SetBody(message)
 
-	resp, err := t.client.Api.CreateMessage(params)
+	type result struct {
+		resp *api.ApiV2010Message
+		err  error
+	}
+	done := make(chan result, 1)
+	go func() {
+		resp, err := t.client.Api.CreateMessage(params)
+		done <- result{resp, err}
+	}()
+
+	select {
+	case <-ctx.Done():
+		log.Printf("❌ Gave up waiting for WhatsApp message to %s: %v", to, ctx.Err())
+		return ctx.Err()
+	case r := <-done:
+		if r.err != nil {
+			log.Printf("❌ Failed to send WhatsApp message: %v", r.err)
+			return r.err
+		}
+		log.Printf("✅ WhatsApp message sent! SID: %s", *r.resp.Sid)
+		return nil
+	}
+}
+
+// Notify sends a proactive WhatsApp message. Without Twilio configured (nil
+// service, as in local testing) the message is only logged.
+func (t *TwilioService) Notify(ctx context.Context, to string, message string) error {
+	if t == nil {
+		log.Printf("📤 Message to %s (not sent - Twilio not configured): %s", to, message)
+		return nil
+	}
+	return t.SendWhatsAppMessage(ctx, to, message)
+}
+
+// ValidateWebhook checks the X-Twilio-Signature Twilio sends with a webhook
+// POSTed to webhookURL with the form params. Without Twilio configured nothing is valid.
+func (t *TwilioService) ValidateWebhook(webhookURL string, params map[string]string, signature string) bool {
+	if t == nil || signature == "" {
+		return false
+	}
+	return t.validator.Validate(webhookURL, params, signature)
+}
+
+// DownloadMedia fetches an attachment sent to us from Twilio's media host;
+// Twilio media URLs require account credentials
+func (t *TwilioService) DownloadMedia(ctx context.Context, mediaURL string, maxBytes int64) ([]byte, error) {
+	parsed, err := url.Parse(mediaURL)
+	if err != nil || parsed.Scheme != "https" || parsed.Host != twilioMediaHost {
+		return nil, models.Invalid("media", "url", "media must be downloaded from "+twilioMediaHost)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, parsed.String(), nil)
 	if err != nil {
-		log.Printf("❌ Failed to send WhatsApp message: %v", err)
-		return err
+		return nil, err
+	}
+	if t != nil {
+		req.SetBasicAuth(t.accountSid, t.authToken)
+	}
+
+	resp, err := mediaClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("media download returned status %d", resp.StatusCode)
 	}
 
-	log.Printf("✅ WhatsApp message sent! SID: %s", *resp.Sid)
-	return nil
+	return io.ReadAll(io.LimitReader(resp.Body, maxBytes+1))
 }
@@ -0,0 +1,412 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/Ananth-NQI/truckpe-backend/internal/events"
+	"github.com/Ananth-NQI/truckpe-backend/internal/models"
+	"github.com/Ananth-NQI/truckpe-backend/internal/storage"
+)
+
+const (
+	// webhookPollInterval is how often the retry worker looks for due deliveries
+	webhookPollInterval = 10 * time.Second
+
+	// webhookEventBuffer is how many store events may queue up before the
+	// outbox has to publish them again
+	webhookEventBuffer = 1024
+
+	// webhookConsumer records the booking events deliveries were queued for
+	webhookConsumer = "webhooks"
+)
+
+// WebhookService pushes booking updates to shipper-registered endpoints
+type WebhookService struct {
+	store  storage.Store
+	bus    *events.Bus
+	client *http.Client
+
+	// requireHTTPS rejects plain http endpoints; only local development allows them
+	requireHTTPS bool
+
+	// Deliveries currently being attempted, so the worker and the
+	// immediate attempt after Publish never send the same delivery twice
+	mu       sync.Mutex
+	inFlight map[string]bool
+}
+
+// NewWebhookService creates a new webhook service
+func NewWebhookService(store storage.Store, bus *events.Bus) *WebhookService {
+	// The dialer checks every address it connects to, so a host that resolves
+	// to an internal address after registration, or a redirect to one, is
+	// still refused. No proxy, or the check would only see the proxy.
+	dialer := &net.Dialer{Timeout: models.WebhookDeliveryTimeout, Control: dialPublicOnly}
+	transport := &http.Transport{
+		DialContext:         dialer.DialContext,
+		TLSHandshakeTimeout: models.WebhookDeliveryTimeout,
+	}
+
+	return &WebhookService{
+		store:        store,
+		bus:          bus,
+		client:       &http.Client{Timeout: models.WebhookDeliveryTimeout, Transport: transport},
+		requireHTTPS: os.Getenv("INSTANCE_CONNECTION_NAME") != "",
+		inFlight:     make(map[string]bool),
+	}
+}
+
+// WebhookPayload is the JSON body POSTed to shipper endpoints
+type WebhookPayload struct {
+	ID        string      `json:"id"`
+	Type      string      `json:"type"`
+	EventKey  string      `json:"event_key,omitempty"` // same for every delivery of one store event, even if published again
+	CreatedAt time.Time   `json:"created_at"`
+	Data      interface{} `json:"data"`
+}
+
+// RegisterEndpoint registers a new endpoint for a shipper and generates its signing secret
+func (w *WebhookService) RegisterEndpoint(ctx context.Context, shipperID, endpointURL string, eventTypes []string) (*models.WebhookEndpoint, error) {
+	if _, err := w.store.GetShipper(ctx, shipperID); err != nil {
+		return nil, err
+	}
+
+	parsed, err := url.Parse(endpointURL)
+	if err != nil || (parsed.Scheme != "https" && parsed.Scheme != "http") || parsed.Hostname() == "" {
+		return nil, models.Invalid("webhook endpoint", "url", "invalid webhook url")
+	}
+	if parsed.Scheme != "https" && w.requireHTTPS {
+		return nil, models.Invalid("webhook endpoint", "url", "webhook url must use https")
+	}
+	if err := checkPublicHost(ctx, parsed.Hostname()); err != nil {
+		return nil, err
+	}
+
+	if len(eventTypes) == 0 {
+		eventTypes = models.WebhookEventTypes
+	}
+	for _, t := range eventTypes {
+		if !models.IsValidWebhookEventType(t) {
+			return nil, models.Invalid("webhook endpoint", "event_types", "invalid event type: "+t)
+		}
+	}
+
+	secret := make([]byte, 24)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, fmt.Errorf("generating webhook secret: %w", err)
+	}
+
+	endpoint := &models.WebhookEndpoint{
+		ShipperID:  shipperID,
+		URL:        endpointURL,
+		Secret:     "whsec_" + hex.EncodeToString(secret),
+		EventTypes: strings.Join(eventTypes, ","),
+	}
+
+	return w.store.CreateWebhookEndpoint(ctx, endpoint)
+}
+
+// Publish queues an event for every active endpoint of the shipper subscribed
+// to it; eventKey is the idempotency key of the store event behind it, if any
+func (w *WebhookService) Publish(ctx context.Context, shipperID, eventType, eventKey string, data interface{}) {
+	endpoints, err := w.store.GetWebhookEndpointsByShipper(ctx, shipperID)
+	if err != nil {
+		log.Printf("❌ Failed to load webhook endpoints for %s: %v", shipperID, err)
+		return
+	}
+
+	for _, endpoint := range endpoints {
+		if !endpoint.Active || !endpoint.Subscribes(eventType) {
+			continue
+		}
+
+		// Sent right away below; the worker only picks it up if that attempt never happens
+		retryAt := time.Now().Add(models.WebhookDeliveryInitialDelay)
+		delivery := &models.WebhookDelivery{
+			EndpointID:    endpoint.EndpointID,
+			ShipperID:     shipperID,
+			EventType:     eventType,
+			Status:        models.WebhookDeliveryPending,
+			NextAttemptAt: &retryAt,
+		}
+
+		delivery, err = w.store.CreateWebhookDelivery(ctx, delivery)
+		if err != nil {
+			log.Printf("❌ Failed to queue webhook delivery to %s: %v", endpoint.EndpointID, err)
+			continue
+		}
+
+		// The payload embeds the delivery ID so receivers can deduplicate retries,
+		// and the event key for events the outbox published more than once
+		body, err := json.Marshal(WebhookPayload{
+			ID:        delivery.DeliveryID,
+			Type:      eventType,
+			EventKey:  eventKey,
+			CreatedAt: delivery.CreatedAt,
+			Data:      data,
+		})
+		if err != nil {
+			log.Printf("❌ Failed to encode webhook payload: %v", err)
+			continue
+		}
+		delivery.Payload = string(body)
+		if err := w.store.UpdateWebhookDelivery(ctx, delivery); err != nil {
+			log.Printf("❌ Failed to save webhook payload: %v", err)
+			continue
+		}
+
+		go w.attempt(ctx, delivery)
+	}
+}
+
+// PublishBookingEvent publishes a booking update to the booking's shipper
+func (w *WebhookService) PublishBookingEvent(ctx context.Context, eventType, eventKey string, booking *models.Booking) {
+	data := map[string]interface{}{
+		"booking": booking,
+	}
+	if load, err := w.store.GetLoad(ctx, booking.LoadID); err == nil {
+		data["load"] = load
+	}
+
+	w.Publish(ctx, booking.ShipperID, eventType, eventKey, data)
+}
+
+// Replay resets a delivery of the shipper (typically from the dead-letter list) and sends it again
+func (w *WebhookService) Replay(ctx context.Context, deliveryID, shipperID string) (*models.WebhookDelivery, error) {
+	delivery, err := w.store.GetWebhookDelivery(ctx, deliveryID)
+	if err != nil {
+		return nil, err
+	}
+	if delivery.ShipperID != shipperID {
+		return nil, models.Forbidden("webhook delivery", "delivery does not belong to shipper")
+	}
+
+	now := time.Now()
+	delivery.Status = models.WebhookDeliveryPending
+	delivery.Attempts = 0
+	delivery.LastError = ""
+	delivery.NextAttemptAt = &now
+
+	if err := w.store.UpdateWebhookDelivery(ctx, delivery); err != nil {
+		return nil, err
+	}
+
+	// The retry outlives the request that asked for it
+	go w.attempt(context.WithoutCancel(ctx), delivery)
+	return delivery, nil
+}
+
+// Start subscribes to store events and launches the worker that retries due deliveries
+func (w *WebhookService) Start() {
+	ctx := context.Background()
+	sub := w.bus.SubscribeDurable(webhookEventBuffer)
+	go func() {
+		for event := range sub.C {
+			w.handleEvent(ctx, event)
+		}
+	}()
+
+	go func() {
+		ticker := time.NewTicker(webhookPollInterval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			deliveries, err := w.store.GetDueWebhookDeliveries(ctx, time.Now())
+			if err != nil {
+				log.Printf("❌ Failed to fetch due webhook deliveries: %v", err)
+				continue
+			}
+			for _, delivery := range deliveries {
+				w.attempt(ctx, delivery)
+			}
+		}
+	}()
+}
+
+// handleEvent turns booking events from the store into webhook deliveries
+func (w *WebhookService) handleEvent(ctx context.Context, event *models.Event) {
+	if event.EntityType != models.EntityBooking {
+		return
+	}
+
+	var eventType string
+	switch event.EventType {
+	case models.EventBookingCreated:
+		eventType = models.WebhookEventLoadBooked
+	case models.EventPODUploaded:
+		eventType = models.WebhookEventPODUploaded
+	case models.EventBookingStatusChanged:
+		switch event.After["status"] {
+		case models.BookingStatusInTransit:
+			eventType = models.WebhookEventPickedUp
+		case models.BookingStatusDelivered:
+			eventType = models.WebhookEventDelivered
+		case models.BookingStatusCancelled:
+			eventType = models.WebhookEventCancelled
+		}
+	}
+	if eventType == "" {
+		return
+	}
+
+	handleOnce(ctx, w.store, webhookConsumer, event, func() {
+		booking, err := w.store.GetBooking(ctx, event.EntityID)
+		if err != nil {
+			log.Printf("❌ Webhook event for unknown booking %s: %v", event.EntityID, err)
+			return
+		}
+
+		w.PublishBookingEvent(ctx, eventType, event.IdempotencyKey(), booking)
+	})
+}
+
+// attempt sends one delivery and schedules a retry with exponential backoff on failure
+func (w *WebhookService) attempt(ctx context.Context, delivery *models.WebhookDelivery) {
+	w.mu.Lock()
+	if w.inFlight[delivery.DeliveryID] {
+		w.mu.Unlock()
+		return
+	}
+	w.inFlight[delivery.DeliveryID] = true
+	w.mu.Unlock()
+
+	defer func() {
+		w.mu.Lock()
+		delete(w.inFlight, delivery.DeliveryID)
+		w.mu.Unlock()
+	}()
+
+	endpoint, err := w.store.GetWebhookEndpoint(ctx, delivery.EndpointID)
+	if err != nil {
+		log.Printf("❌ Webhook endpoint %s missing for delivery %s", delivery.EndpointID, delivery.DeliveryID)
+		return
+	}
+
+	delivery.Attempts++
+	code, err := w.send(ctx, endpoint, delivery)
+	delivery.ResponseCode = code
+
+	now := time.Now()
+	if err == nil {
+		delivery.Status = models.WebhookDeliverySucceeded
+		delivery.DeliveredAt = &now
+		delivery.NextAttemptAt = nil
+		delivery.LastError = ""
+		log.Printf("✅ Webhook %s delivered to %s", delivery.DeliveryID, endpoint.URL)
+	} else {
+		delivery.LastError = err.Error()
+		if delivery.Attempts >= models.WebhookDeliveryMaxAttempts {
+			delivery.Status = models.WebhookDeliveryDead
+			delivery.NextAttemptAt = nil
+			log.Printf("💀 Webhook %s moved to dead-letter list: %v", delivery.DeliveryID, err)
+		} else {
+			next := now.Add(models.WebhookDeliveryInitialDelay << (delivery.Attempts - 1))
+			delivery.NextAttemptAt = &next
+			log.Printf("⚠️  Webhook %s failed (attempt %d): %v", delivery.DeliveryID, delivery.Attempts, err)
+		}
+	}
+
+	if err := w.store.UpdateWebhookDelivery(ctx, delivery); err != nil {
+		log.Printf("❌ Failed to record webhook delivery %s: %v", delivery.DeliveryID, err)
+	}
+}
+
+// send POSTs the payload signed with the endpoint secret
+func (w *WebhookService) send(ctx context.Context, endpoint *models.WebhookEndpoint, delivery *models.WebhookDelivery) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint.URL, bytes.NewBufferString(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+
+	timestamp := fmt.Sprintf("%d", time.Now().Unix())
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "TruckPe-Webhooks/1.0")
+	req.Header.Set("X-TruckPe-Event", delivery.EventType)
+	req.Header.Set("X-TruckPe-Delivery", delivery.DeliveryID)
+	req.Header.Set("X-TruckPe-Signature", fmt.Sprintf("t=%s,v1=%s", timestamp, SignWebhookPayload(endpoint.Secret, timestamp, delivery.Payload)))
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("endpoint returned status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// checkPublicHost rejects a webhook host that is, or resolves to, an address
+// inside our network: loopback, private, link-local (which includes the cloud
+// metadata server at 169.254.169.254) and the like
+func checkPublicHost(ctx context.Context, host string) error {
+	var ips []net.IP
+	if ip := net.ParseIP(host); ip != nil {
+		ips = []net.IP{ip}
+	} else {
+		addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+		if err != nil {
+			return models.Invalid("webhook endpoint", "url", "webhook host does not resolve: "+host)
+		}
+		for _, addr := range addrs {
+			ips = append(ips, addr.IP)
+		}
+	}
+
+	for _, ip := range ips {
+		if !isPublicIP(ip) {
+			return models.Invalid("webhook endpoint", "url", "webhook host must be a public address")
+		}
+	}
+	return nil
+}
+
+// dialPublicOnly is the webhook dialer's Control hook. It runs after DNS
+// resolution with the address about to be connected to, so it cannot be
+// bypassed by changing what the endpoint's name resolves to.
+func dialPublicOnly(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); ip == nil || !isPublicIP(ip) {
+		return fmt.Errorf("webhook address %s is not public", host)
+	}
+	return nil
+}
+
+// isPublicIP reports whether ip is reachable on the public internet
+func isPublicIP(ip net.IP) bool {
+	return !(ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() ||
+		ip.IsMulticast() || sharedAddressSpace.Contains(ip))
+}
+
+// sharedAddressSpace is the carrier-grade NAT range, internal like the private ranges
+var sharedAddressSpace = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// SignWebhookPayload computes the hex HMAC-SHA256 of "<timestamp>.<payload>".
+// Receivers recompute it with their secret to verify X-TruckPe-Signature.
+func SignWebhookPayload(secret, timestamp, payload string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "." + payload))
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -1,28 +1,77 @@
 package services
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"strings"
 	"time"
 
+	"github.com/Ananth-NQI/truckpe-backend/internal/cities"
 	"github.com/Ananth-NQI/truckpe-backend/internal/models"
 	"github.com/Ananth-NQI/truckpe-backend/internal/storage"
 )
 
+const (
+	// whatsappPageSize is how many items a WhatsApp list reply shows
+	whatsappPageSize = 5
+
+	// listSessionTTL is how long MORE can continue the last list
+	listSessionTTL = 30 * time.Minute
+
+	// deleteAccountTTL is how long CONFIRM DELETE is accepted after DELETE MY ACCOUNT
+	deleteAccountTTL = 10 * time.Minute
+)
+
+// Lists that MORE can continue
+const (
+	listMyLoads    = "my_loads"
+	listLoadSearch = "load_search"
+	listStatus     = "status"
+)
+
+// sessionDeleteAccount marks a session waiting for CONFIRM DELETE
+const sessionDeleteAccount = "delete_account"
+
+// listSession is the WhatsApp session context recording where the last list stopped
+type listSession struct {
+	List     string `json:"list"`
+	Cursor   string `json:"cursor"`
+	FromCity string `json:"from_city,omitempty"`
+	ToCity   string `json:"to_city,omitempty"`
+}
+
 // WhatsAppService handles WhatsApp message processing
 type WhatsAppService struct {
-	store storage.Store
+	store             storage.Store
+	trackingService   *TrackingService
+	loadImportService *LoadImportService
+	exportService     *ExportService
+	alertService      *AlertService
+	recurringService  *RecurringLoadService
+	expiryService     *LoadExpiryService
+	editService       *LoadEditService
+	keyService        *APIKeyService
 }
 
 // NewWhatsAppService creates a new WhatsApp service
-func NewWhatsAppService(store storage.Store) *WhatsAppService {
+func NewWhatsAppService(store storage.Store, trackingService *TrackingService, loadImportService *LoadImportService, exportService *ExportService, alertService *AlertService, recurringService *RecurringLoadService, expiryService *LoadExpiryService, editService *LoadEditService, keyService *APIKeyService) *WhatsAppService {
 	return &WhatsAppService{
-		store: store,
+		store:             store,
+		trackingService:   trackingService,
+		loadImportService: loadImportService,
+		exportService:     exportService,
+		alertService:      alertService,
+		recurringService:  recurringService,
+		expiryService:     expiryService,
+		editService:       editService,
+		keyService:        keyService,
 	}
 }
 
 // ProcessMessage processes incoming WhatsApp messages
-func (w *WhatsAppService) ProcessMessage(from, message string) (string, error) {
+func (w *WhatsAppService) ProcessMessage(ctx context.Context, from, message string) (string, error) {
 	// Convert to uppercase and trim
 	msg := strings.TrimSpace(strings.ToUpper(message))
 
@@ -35,28 +84,79 @@ func (w *WhatsAppService) ProcessMessage(from, message string) (string, error) {
 		return w.getHelpMessage(), nil
 
 	case strings.HasPrefix(msg, "REGISTER SHIPPER"):
-		return w.handleShipperRegistration(phone, msg)
+		return w.handleShipperRegistration(ctx, phone, msg)
 
 	case strings.HasPrefix(msg, "REGISTER"):
-		return w.handleRegistration(phone, msg)
+		return w.handleRegistration(ctx, phone, msg)
 
 	case strings.HasPrefix(msg, "POST"):
-		return w.handlePostLoad(phone, msg)
+		return w.handlePostLoad(ctx, phone, msg)
+
+	case msg == "MORE":
+		return w.handleMore(ctx, phone)
 
 	case msg == "MY LOADS":
-		return w.handleMyLoads(phone)
+		return w.handleMyLoads(ctx, phone)
+
+	case msg == "REPEATS":
+		return w.handleListRecurring(ctx, phone)
+
+	case strings.HasPrefix(msg, "REPEAT"):
+		return w.handleRepeat(ctx, phone, msg)
+
+	case strings.HasPrefix(msg, "RENEW"):
+		return w.handleRenew(ctx, phone, msg)
+
+	case strings.HasPrefix(msg, "EDIT"):
+		return w.handleEditLoad(ctx, phone, msg)
+
+	case strings.HasPrefix(msg, "WITHDRAW"):
+		return w.handleWithdrawLoad(ctx, phone, msg)
 
 	case strings.HasPrefix(msg, "LOAD"):
-		return w.handleLoadSearch(phone, msg)
+		return w.handleLoadSearch(ctx, phone, msg)
+
+	case strings.HasPrefix(msg, "LOCATION"):
+		return w.handleLocation(ctx, phone, msg)
+
+	case msg == "ALERTS":
+		return w.handleListAlerts(ctx, phone)
+
+	case strings.HasPrefix(msg, "ALERT"):
+		return w.handleCreateAlert(ctx, phone, msg)
+
+	case strings.HasPrefix(msg, "UNALERT"):
+		return w.handleDeleteAlert(ctx, phone, msg)
 
 	case strings.HasPrefix(msg, "BOOK"):
-		return w.handleBooking(phone, msg)
+		return w.handleBooking(ctx, phone, msg)
 
 	case msg == "STATUS":
-		return w.handleStatus(phone)
+		return w.handleStatus(ctx, phone)
 
 	case strings.HasPrefix(msg, "TRACK"):
-		return w.handleTrackBooking(phone, msg)
+		return w.handleTrackBooking(ctx, phone, msg)
+
+	case strings.HasPrefix(msg, "HISTORY"):
+		return w.handleHistory(ctx, phone, msg)
+
+	case strings.HasPrefix(msg, "REPORT"):
+		return w.handleReport(ctx, phone, msg)
+
+	case strings.HasPrefix(msg, "UNSHARE"):
+		return w.handleUnshareTracking(ctx, phone, msg)
+
+	case strings.HasPrefix(msg, "SHARE"):
+		return w.handleShareTracking(ctx, phone, msg)
+
+	case msg == "API KEY":
+		return w.handleAPIKey(ctx, phone)
+
+	case msg == "DELETE MY ACCOUNT":
+		return w.handleDeleteAccount(ctx, phone)
+
+	case msg == "CONFIRM DELETE":
+		return w.handleConfirmDelete(ctx, phone)
 
 	default:
 		return "❌ Invalid command. Type HELP to see available commands.", nil
@@ -70,14 +170,31 @@ func (w *WhatsAppService) getHelpMessage() string {
 *For Truckers:*
 📝 *REGISTER* - Register as a trucker
 🔍 *LOAD <from> <to>* - Search loads
-📦 *BOOK <load_id>* - Book a load
+📍 *LOCATION <city>* - Set your current city
+🔔 *ALERT <from> <to>* - Get alerts for new loads
+📋 *ALERTS* - Your alerts
+📦 *BOOK <load_id> [tons]* - Book a load (tons for part loads)
 📊 *STATUS* - Check your bookings
+🕘 *HISTORY <booking_id>* - Booking timeline
 
 *For Shippers:*
 🏭 *REGISTER SHIPPER* - Register as shipper
 📦 *POST* - Post a new load
+📎 *Send a CSV/Excel file* - Post many loads at once
 📋 *MY LOADS* - View your posted loads
+🔁 *REPEAT <load_id> daily* - Post a load on a schedule
+🗓️ *REPEATS* - Your repeating loads
+♻️ *RENEW <load_id> [days] [price]* - Relist an expiring load
+✏️ *EDIT <load_id> PRICE 40000* - Change price, DATE or VEHICLE
+🗑️ *WITHDRAW <load_id>* - Take a load off TruckPe
 🔍 *TRACK <booking_id>* - Track a booking
+🔗 *SHARE <booking_id>* - Tracking link for consignee
+🚫 *UNSHARE <booking_id>* - Revoke tracking links
+🧾 *REPORT <month>* - Monthly statement
+
+➡️ *MORE* - Show more results
+🔑 *API KEY* - Key for the TruckPe API
+❌ *DELETE MY ACCOUNT* - Delete your TruckPe account
 
 💰 *48-hour payment guarantee!*
 🔒 *100% safe with escrow*
@@ -86,9 +203,9 @@ Type any command to start!`
 }
 
 // Handle shipper registration
-func (w *WhatsAppService) handleShipperRegistration(phone, msg string) (string, error) {
+func (w *WhatsAppService) handleShipperRegistration(ctx context.Context, phone, msg string) (string, error) {
 	// Check if already registered as shipper
-	existingShipper, _ := w.store.GetShipperByPhone(phone)
+	existingShipper, _ := w.store.GetShipperByPhone(ctx, phone)
 	if existingShipper != nil {
 		return fmt.Sprintf(`✅ *Already Registered as Shipper!*
 
@@ -102,7 +219,7 @@ Type: POST to start posting`,
 	}
 
 	// Check if registered as trucker
-	existingTrucker, _ := w.store.GetTruckerByPhone(phone)
+	existingTrucker, _ := w.store.GetTruckerByPhone(ctx, phone)
 	if existingTrucker != nil {
 		return "❌ This number is registered as a trucker. Use a different number for shipper account.", nil
 	}
@@ -136,12 +253,12 @@ REGISTER SHIPPER ABC Industries, 29ABCDE1234F1Z5`, nil
 		Phone:       phone,
 	}
 
-	createdShipper, err := w.store.CreateShipper(shipper)
+	createdShipper, err := w.store.CreateShipper(ctx, shipper, whatsappActor(phone))
 	if err != nil {
-		if strings.Contains(err.Error(), "phone") {
+		if isError(err, models.ErrConflict, "shipper", "phone") {
 			return "❌ This phone number is already registered!", nil
 		}
-		if strings.Contains(err.Error(), "GST") {
+		if isError(err, models.ErrConflict, "shipper", "gst_number") {
 			return "❌ This GST number is already registered!", nil
 		}
 		return "❌ Registration failed. Please try again.", err
@@ -160,9 +277,9 @@ Type POST to start posting loads.`,
 }
 
 // Handle post load - guided flow
-func (w *WhatsAppService) handlePostLoad(phone, msg string) (string, error) {
+func (w *WhatsAppService) handlePostLoad(ctx context.Context, phone, msg string) (string, error) {
 	// Check if registered as shipper
-	shipper, err := w.store.GetShipperByPhone(phone)
+	shipper, err := w.store.GetShipperByPhone(ctx, phone)
 	if err != nil {
 		return "❌ Please register as shipper first!\n\nType: REGISTER SHIPPER CompanyName, GSTNumber", nil
 	}
@@ -178,6 +295,9 @@ POST <From> <To> <Material> <Weight> <Price>
 Example:
 POST Chennai Bangalore Electronics 15 35000
 
+Needs several trucks? Add the count: ... 100 300000 4 TRUCKS
+Part load (LTL)? Add PART: ... 40 60000 PART
+
 Or type each detail:
 From City: ?`, nil
 	}
@@ -192,9 +312,7 @@ Format: POST <From> <To> <Material> <Weight> <Price>
 Example: POST Chennai Bangalore Electronics 15 35000`, nil
 	}
 
-	// Extract details (convert cities to proper case for display)
-	fromCity := strings.Title(strings.ToLower(parts[1]))
-	toCity := strings.Title(strings.ToLower(parts[2]))
+	// Extract details (cities are resolved to their canonical names below)
 	material := strings.Title(strings.ToLower(parts[3]))
 
 	var weight float64
@@ -202,22 +320,40 @@ Example: POST Chennai Bangalore Electronics 15 35000`, nil
 	fmt.Sscanf(parts[4], "%f", &weight)
 	fmt.Sscanf(parts[5], "%f", &price)
 
+	// Optional split: "<n> TRUCKS" or "PART"
+	truckCount := 1
+	splittable := false
+	switch {
+	case len(parts) > 7 && strings.HasPrefix(parts[7], "TRUCK"):
+		if _, err := fmt.Sscanf(parts[6], "%d", &truckCount); err != nil || truckCount < 1 {
+			return "❌ Invalid truck count\n\nExample: POST Chennai Bangalore Steel 100 300000 4 TRUCKS", nil
+		}
+	case len(parts) > 6 && parts[6] == "PART":
+		splittable = true
+	}
+
 	// Create load
 	load := &models.Load{
 		ShipperID:    shipper.ShipperID,
 		ShipperName:  shipper.CompanyName,
 		ShipperPhone: shipper.Phone,
-		FromCity:     fromCity,
-		ToCity:       toCity,
+		FromCity:     parts[1],
+		ToCity:       parts[2],
 		Material:     material,
 		Weight:       weight,
+		TruckCount:   truckCount,
+		Splittable:   splittable,
 		Price:        price,
 		VehicleType:  "Any",                          // Default
 		LoadingDate:  time.Now().Add(24 * time.Hour), // Tomorrow
 		Status:       "available",
 	}
 
-	createdLoad, err := w.store.CreateLoad(load)
+	if err := load.ResolveCities(); err != nil {
+		return didYouMean(err), nil
+	}
+
+	createdLoad, err := w.store.CreateLoad(ctx, load, whatsappActor(shipper.ShipperID))
 	if err != nil {
 		return "❌ Failed to post load. Please try again.", err
 	}
@@ -230,43 +366,486 @@ Example: POST Chennai Bangalore Electronics 15 35000`, nil
 *Load ID:* %s
 📍 *Route:* %s → %s
 📦 *Material:* %s
-⚖️ *Weight:* %.1f tons
+⚖️ *Weight:* %s
 💰 *Price:* ₹%.0f
 
 🔔 Notifying nearby truckers...
 
 Type MY LOADS to see all your loads.`,
 		createdLoad.LoadID, createdLoad.FromCity, createdLoad.ToCity,
-		createdLoad.Material, createdLoad.Weight, createdLoad.Price), nil
+		createdLoad.Material, createdLoad.WeightText(), createdLoad.Price), nil
+}
+
+// Handle REPEAT: make a load recurring, or pause, resume or skip a recurring load
+func (w *WhatsAppService) handleRepeat(ctx context.Context, phone, msg string) (string, error) {
+	shipper, err := w.store.GetShipperByPhone(ctx, phone)
+	if err != nil {
+		return "❌ Please register as shipper first!\n\nType: REGISTER SHIPPER CompanyName, GSTNumber", nil
+	}
+
+	parts := strings.Fields(msg)
+	if len(parts) < 2 {
+		return `❌ Please specify Load ID and schedule
+
+Format: REPEAT <Load_ID> <daily|weekdays|MON,WED,FRI>
+
+Example: REPEAT LD00001Y weekdays`, nil
+	}
+
+	switch parts[1] {
+	case "PAUSE", "RESUME", "SKIP":
+		return w.handleRecurringChange(ctx, shipper, parts)
+	}
+
+	id, err := models.ParseID(models.PrefixLoad, parts[1])
+	if err != nil {
+		return "❌ " + err.Error(), nil
+	}
+	parts[1] = id
+
+	schedule := "daily"
+	if len(parts) > 2 {
+		schedule = strings.Join(parts[2:], ",")
+	}
+
+	recurring, err := w.recurringService.Repeat(ctx, shipper.ShipperID, parts[1], schedule)
+	if err != nil {
+		if isError(err, models.ErrNotFound, "load", "") {
+			return "❌ Load not found. Type MY LOADS to see your loads.", nil
+		}
+		if isError(err, models.ErrInvalid, "recurring load", "days") {
+			return "❌ Invalid schedule. Use daily, weekdays, weekends or days like MON,WED,FRI", nil
+		}
+		return "❌ " + err.Error(), nil
+	}
+
+	return fmt.Sprintf(`🔁 *Repeating Load Created!*
+
+*ID:* %s
+📍 *Route:* %s → %s
+📦 *Material:* %s, %.1f tons
+💰 *Price:* ₹%.0f
+🗓️ *Schedule:* %s
+
+Loads are posted %.0f hours ahead.
+To pause: REPEAT PAUSE %s
+To skip a day: REPEAT SKIP %s <date>`,
+		recurring.RecurringID, recurring.FromCity, recurring.ToCity, recurring.Material, recurring.Weight,
+		recurring.Price, recurring.Schedule(), models.RecurringHorizon.Hours(), recurring.RecurringID, recurring.RecurringID), nil
+}
+
+// Handle RENEW: move a load's loading date forward, optionally repricing it, and relist it if expired
+func (w *WhatsAppService) handleRenew(ctx context.Context, phone, msg string) (string, error) {
+	shipper, err := w.store.GetShipperByPhone(ctx, phone)
+	if err != nil {
+		return "❌ Please register as shipper first!\n\nType: REGISTER SHIPPER CompanyName, GSTNumber", nil
+	}
+
+	parts := strings.Fields(msg)
+	if len(parts) < 2 {
+		return `❌ Please specify Load ID
+
+Format: RENEW <Load_ID> [days] [price]
+
+Example: RENEW LD00001Y 2 42000`, nil
+	}
+
+	id, err := models.ParseID(models.PrefixLoad, parts[1])
+	if err != nil {
+		return "❌ " + err.Error(), nil
+	}
+	parts[1] = id
+
+	days := 1
+	if len(parts) > 2 {
+		if _, err := fmt.Sscanf(parts[2], "%d", &days); err != nil || days < 1 {
+			return "❌ Invalid number of days\n\nExample: RENEW " + parts[1] + " 2", nil
+		}
+	}
+	var price float64
+	if len(parts) > 3 {
+		if _, err := fmt.Sscanf(parts[3], "%f", &price); err != nil || price <= 0 {
+			return "❌ Invalid price\n\nExample: RENEW " + parts[1] + " 1 42000", nil
+		}
+	}
+
+	load, err := w.store.GetLoad(ctx, parts[1])
+	if err != nil || load.ShipperID != shipper.ShipperID {
+		return "❌ Load not found. Type MY LOADS to see your loads.", nil
+	}
+
+	// Same time of day, days from today
+	now := time.Now()
+	loadingDate := time.Date(now.Year(), now.Month(), now.Day()+days,
+		load.LoadingDate.Hour(), load.LoadingDate.Minute(), 0, 0, now.Location())
+
+	renewed, err := w.expiryService.Renew(ctx, shipper.ShipperID, load.LoadID, loadingDate, price, whatsappActor(shipper.ShipperID))
+	if err != nil {
+		if isError(err, models.ErrUnavailable, "load", "") {
+			return "❌ This load has already been booked.", nil
+		}
+		return "❌ " + err.Error(), nil
+	}
+
+	return fmt.Sprintf(`♻️ *Load Renewed!*
+
+*Load ID:* %s
+📍 *Route:* %s → %s
+💰 *Price:* ₹%.0f
+📅 *Loading:* %s
+⏳ *Listed until:* %s`,
+		renewed.LoadID, renewed.FromCity, renewed.ToCity, renewed.Price,
+		renewed.LoadingDate.Format("02 Jan 15:04"), renewed.ExpiresAt.Format("02 Jan 15:04")), nil
+}
+
+// Handle EDIT: change the price, loading date or vehicle type of a load nobody has booked
+func (w *WhatsAppService) handleEditLoad(ctx context.Context, phone, msg string) (string, error) {
+	shipper, err := w.store.GetShipperByPhone(ctx, phone)
+	if err != nil {
+		return "❌ Please register as shipper first!\n\nType: REGISTER SHIPPER CompanyName, GSTNumber", nil
+	}
+
+	parts := strings.Fields(msg)
+	if len(parts) < 4 {
+		return `❌ Please specify what to change
+
+Format: EDIT <Load_ID> PRICE <amount>
+        EDIT <Load_ID> DATE <YYYY-MM-DD> [HH:MM]
+        EDIT <Load_ID> VEHICLE <type>
+
+Example: EDIT LD00001Y PRICE 40000`, nil
+	}
+
+	id, err := models.ParseID(models.PrefixLoad, parts[1])
+	if err != nil {
+		return "❌ " + err.Error(), nil
+	}
+	parts[1] = id
+
+	changes := &models.LoadChanges{}
+	value := strings.Join(parts[3:], " ")
+	switch parts[2] {
+	case "PRICE":
+		var price float64
+		if _, err := fmt.Sscanf(value, "%f", &price); err != nil {
+			return "❌ Invalid price\n\nExample: EDIT " + parts[1] + " PRICE 40000", nil
+		}
+		changes.Price = &price
+	case "DATE":
+		load, err := w.store.GetLoad(ctx, parts[1])
+		if err != nil || load.ShipperID != shipper.ShipperID {
+			return "❌ Load not found. Type MY LOADS to see your loads.", nil
+		}
+		// Keep the time of day unless a new one is given
+		if len(parts) == 4 {
+			value += load.LoadingDate.Format(" 15:04")
+		}
+		loadingDate, err := time.ParseInLocation("2006-01-02 15:04", value, time.Local)
+		if err != nil {
+			return "❌ Invalid date\n\nExample: EDIT " + parts[1] + " DATE 2026-10-25 09:00", nil
+		}
+		changes.LoadingDate = &loadingDate
+	case "VEHICLE":
+		vehicleType := strings.ToLower(value)
+		changes.VehicleType = &vehicleType
+	default:
+		return "❌ You can change PRICE, DATE or VEHICLE\n\nExample: EDIT " + parts[1] + " PRICE 40000", nil
+	}
+
+	load, err := w.editService.Edit(ctx, shipper.ShipperID, parts[1], changes, whatsappActor(shipper.ShipperID))
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrNotFound):
+			return "❌ Load not found. Type MY LOADS to see your loads.", nil
+		case errors.Is(err, models.ErrUnavailable):
+			return "❌ This load is no longer open for booking and can't be changed.", nil
+		}
+		return "❌ " + err.Error(), nil
+	}
+
+	return fmt.Sprintf(`✏️ *Load Updated!*
+
+*Load ID:* %s
+📍 *Route:* %s → %s
+💰 *Price:* ₹%.0f
+📅 *Loading:* %s
+🚛 *Vehicle:* %s
+
+Truckers who were sent this load have been told about the change.`,
+		load.LoadID, load.FromCity, load.ToCity, load.Price,
+		load.LoadingDate.Format("02 Jan 15:04"), load.VehicleType), nil
+}
+
+// Handle WITHDRAW: take a load nobody has booked off the marketplace
+func (w *WhatsAppService) handleWithdrawLoad(ctx context.Context, phone, msg string) (string, error) {
+	shipper, err := w.store.GetShipperByPhone(ctx, phone)
+	if err != nil {
+		return "❌ Please register as shipper first!\n\nType: REGISTER SHIPPER CompanyName, GSTNumber", nil
+	}
+
+	parts := strings.Fields(msg)
+	if len(parts) < 2 {
+		return `❌ Please specify Load ID
+
+Format: WITHDRAW <Load_ID>
+
+Example: WITHDRAW LD00001Y`, nil
+	}
+
+	id, err := models.ParseID(models.PrefixLoad, parts[1])
+	if err != nil {
+		return "❌ " + err.Error(), nil
+	}
+	parts[1] = id
+
+	load, err := w.editService.Withdraw(ctx, shipper.ShipperID, parts[1], whatsappActor(shipper.ShipperID))
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrNotFound):
+			return "❌ Load not found. Type MY LOADS to see your loads.", nil
+		case errors.Is(err, models.ErrUnavailable):
+			return "❌ This load is no longer open for booking and can't be withdrawn.", nil
+		}
+		return "❌ " + err.Error(), nil
+	}
+
+	return fmt.Sprintf(`🗑️ *Load Withdrawn*
+
+*Load ID:* %s
+📍 *Route:* %s → %s
+
+It is no longer shown to truckers.`,
+		load.LoadID, load.FromCity, load.ToCity), nil
+}
+
+// handleRecurringChange handles REPEAT PAUSE, REPEAT RESUME and REPEAT SKIP
+func (w *WhatsAppService) handleRecurringChange(ctx context.Context, shipper *models.Shipper, parts []string) (string, error) {
+	if len(parts) < 3 {
+		return fmt.Sprintf("❌ Please specify the repeating load ID\n\nExample: REPEAT %s RL00001Y", parts[1]), nil
+	}
+	id := parts[2]
+
+	var err error
+	var reply string
+	switch parts[1] {
+	case "PAUSE":
+		_, err = w.recurringService.Pause(ctx, shipper.ShipperID, id)
+		reply = fmt.Sprintf("⏸️ %s paused. No new loads will be posted.\n\nTo restart: REPEAT RESUME %s", id, id)
+	case "RESUME":
+		_, err = w.recurringService.Resume(ctx, shipper.ShipperID, id)
+		reply = fmt.Sprintf("▶️ %s resumed.", id)
+	case "SKIP":
+		if len(parts) < 4 {
+			return fmt.Sprintf("❌ Please specify the date to skip\n\nExample: REPEAT SKIP %s %s", id, time.Now().AddDate(0, 0, 1).Format("2006-01-02")), nil
+		}
+		date, dateErr := parseLoadingDate(parts[3])
+		if dateErr != nil {
+			return "❌ Invalid date. Use YYYY-MM-DD or DD/MM/YYYY", nil
+		}
+		_, err = w.recurringService.Skip(ctx, shipper.ShipperID, id, date)
+		reply = fmt.Sprintf("⏭️ %s will not be posted on %s.", id, date.Format("02 Jan"))
+	}
+	if err != nil {
+		return "❌ Repeating load not found. Type REPEATS to see yours.", nil
+	}
+	return reply, nil
+}
+
+// Handle REPEATS: list the shipper's recurring loads
+func (w *WhatsAppService) handleListRecurring(ctx context.Context, phone string) (string, error) {
+	shipper, err := w.store.GetShipperByPhone(ctx, phone)
+	if err != nil {
+		return "❌ Please register as shipper first!\n\nType: REGISTER SHIPPER CompanyName, GSTNumber", nil
+	}
+
+	recurringLoads, err := w.recurringService.RecurringLoads(ctx, shipper.ShipperID)
+	if err != nil {
+		return "❌ Error fetching repeating loads. Please try again.", err
+	}
+
+	if len(recurringLoads) == 0 {
+		return "🗓️ You have no repeating loads.\n\nType: REPEAT <Load_ID> daily", nil
+	}
+
+	response := "🗓️ *Your Repeating Loads*\n\n"
+	for _, recurring := range recurringLoads {
+		status := ""
+		if recurring.Paused {
+			status = " ⏸️ paused"
+		}
+		response += fmt.Sprintf("*%s* %s → %s, %s%s\n", recurring.RecurringID, recurring.FromCity, recurring.ToCity, recurring.Schedule(), status)
+	}
+	response += "\nTo pause: REPEAT PAUSE <ID>\nTo skip a day: REPEAT SKIP <ID> <date>"
+	return response, nil
+}
+
+// ProcessDocument imports a CSV or Excel file sent by a shipper as loads.
+// The caption may say CHECK for a dry run or PARTIAL to keep the valid rows
+// of a file with errors; by default the file is rejected if any row is invalid.
+func (w *WhatsAppService) ProcessDocument(ctx context.Context, from string, data []byte, caption string) (string, error) {
+	phone := strings.TrimPrefix(from, "whatsapp:")
+
+	shipper, err := w.store.GetShipperByPhone(ctx, phone)
+	if err != nil {
+		return "❌ Please register as shipper first!\n\nType: REGISTER SHIPPER CompanyName, GSTNumber", nil
+	}
+
+	caption = strings.TrimSpace(strings.ToUpper(caption))
+	dryRun := caption == "CHECK" || caption == "DRY RUN"
+	mode := ImportModeAtomic
+	if caption == "PARTIAL" {
+		mode = ImportModeBestEffort
+	}
+
+	result, err := w.loadImportService.Import(ctx, shipper.ShipperID, data, mode, dryRun, whatsappActor(shipper.ShipperID))
+	if err != nil {
+		if !errors.Is(err, models.ErrInvalid) {
+			return "❌ Failed to post loads. Please try again.", err
+		}
+		return fmt.Sprintf(`❌ Could not read your file: %s
+
+Columns: %s
+Required: from_city, to_city, material, weight, price`, err.Error(), strings.Join(LoadImportColumns, ", ")), nil
+	}
+
+	var response strings.Builder
+	switch {
+	case dryRun:
+		response.WriteString(fmt.Sprintf("🔎 *File Checked*\n\n%d of %d rows are valid. Nothing was posted.\n", result.ValidRows, result.TotalRows))
+	case len(result.Created) == 0:
+		response.WriteString(fmt.Sprintf("❌ *No Loads Posted*\n\n%d of %d rows have errors. Fix them and send the file again", len(result.Errors), result.TotalRows))
+		if mode == ImportModeAtomic {
+			response.WriteString(", or add the caption PARTIAL to post only the valid rows")
+		}
+		response.WriteString(".\n")
+	default:
+		response.WriteString(fmt.Sprintf("✅ *%d Loads Posted!*\n\n", len(result.Created)))
+		for i, load := range result.Created {
+			if i == 10 {
+				response.WriteString(fmt.Sprintf("...and %d more\n", len(result.Created)-10))
+				break
+			}
+			response.WriteString(fmt.Sprintf("*%s* %s → %s ₹%.0f\n", load.LoadID, load.FromCity, load.ToCity, load.Price))
+		}
+	}
+
+	if len(result.Errors) > 0 {
+		response.WriteString("\n*Errors:*\n")
+		for i, rowErr := range result.Errors {
+			if i == 10 {
+				response.WriteString(fmt.Sprintf("...and %d more\n", len(result.Errors)-10))
+				break
+			}
+			response.WriteString(fmt.Sprintf("Row %d: %s\n", rowErr.Row, rowErr.Error))
+		}
+	}
+
+	response.WriteString("\nType MY LOADS to see all your loads.")
+	return response.String(), nil
+}
+
+// Handle monthly statement requests from shippers
+func (w *WhatsAppService) handleReport(ctx context.Context, phone, msg string) (string, error) {
+	shipper, err := w.store.GetShipperByPhone(ctx, phone)
+	if err != nil {
+		return "❌ Please register as shipper first!\n\nType: REGISTER SHIPPER CompanyName, GSTNumber", nil
+	}
+
+	month, ok := parseReportMonth(strings.TrimSpace(strings.TrimPrefix(msg, "REPORT")), time.Now())
+	if !ok {
+		return `❌ Invalid month!
+
+Format: REPORT <month>
+
+Examples:
+REPORT SEP
+REPORT SEPTEMBER 2026
+REPORT 2026-09`, nil
+	}
+
+	// Summarise the statement in the chat; the file itself is behind a signed link
+	var count int
+	var total float64
+	err = w.store.EachBooking(ctx, StatementFilter(shipper.ShipperID, month), func(b *models.Booking) error {
+		count++
+		total += b.AgreedPrice
+		return nil
+	})
+	if err != nil {
+		return "❌ Failed to prepare your statement. Please try again.", err
+	}
+
+	if count == 0 {
+		return fmt.Sprintf("🧾 No deliveries in %s.", month.Format("January 2006")), nil
+	}
+
+	return fmt.Sprintf(`🧾 *Statement for %s*
+
+*Deliveries:* %d
+*Total billed:* ₹%.0f
+
+📥 Download (Excel):
+%s
+
+Link valid for 7 days.`,
+		month.Format("January 2006"), count, total,
+		w.exportService.StatementURL(shipper.ShipperID, month)), nil
+}
+
+// parseReportMonth accepts "SEP", "SEPTEMBER", "SEP 2026", "2026-09" or "09/2026";
+// an empty month means the current one and a month without a year the latest past one
+func parseReportMonth(value string, now time.Time) (time.Time, bool) {
+	current := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.Local)
+	if value == "" {
+		return current, true
+	}
+
+	for _, layout := range []string{"2006-01", "01/2006", "1/2006", "Jan 2006", "January 2006"} {
+		if month, err := time.ParseInLocation(layout, strings.Title(strings.ToLower(value)), time.Local); err == nil {
+			return month, true
+		}
+	}
+
+	for _, layout := range []string{"Jan", "January"} {
+		if month, err := time.Parse(layout, strings.Title(strings.ToLower(value))); err == nil {
+			result := time.Date(now.Year(), month.Month(), 1, 0, 0, 0, 0, time.Local)
+			if result.After(current) {
+				result = result.AddDate(-1, 0, 0)
+			}
+			return result, true
+		}
+	}
+
+	return time.Time{}, false
 }
 
 // Handle my loads for shippers
-func (w *WhatsAppService) handleMyLoads(phone string) (string, error) {
+func (w *WhatsAppService) handleMyLoads(ctx context.Context, phone string) (string, error) {
 	// Check if shipper
-	shipper, err := w.store.GetShipperByPhone(phone)
+	shipper, err := w.store.GetShipperByPhone(ctx, phone)
 	if err != nil {
 		return "❌ Please register as shipper first!\n\nType: REGISTER SHIPPER CompanyName, GSTNumber", nil
 	}
 
-	// Get loads
-	loads, err := w.store.GetLoadsByShipper(shipper.ShipperID)
+	return w.listMyLoads(ctx, phone, shipper, "")
+}
+
+// listMyLoads shows one page of a shipper's loads
+func (w *WhatsAppService) listMyLoads(ctx context.Context, phone string, shipper *models.Shipper, cursor string) (string, error) {
+	page, err := w.store.ListLoads(ctx, &models.LoadFilter{ShipperID: shipper.ShipperID},
+		&models.ListOptions{Cursor: cursor, Limit: whatsappPageSize})
 	if err != nil {
 		return "❌ Error fetching loads. Please try again.", err
 	}
 
-	if len(loads) == 0 {
+	if len(page.Loads) == 0 {
 		return "📋 *Your Loads*\n\nNo loads posted yet.\n\nType POST to create a new load.", nil
 	}
 
 	// Format response
 	response := fmt.Sprintf("📋 *Your Posted Loads*\n🏭 %s\n\n", shipper.CompanyName)
 
-	for i, load := range loads {
-		if i > 4 { // Limit display
-			response += fmt.Sprintf("\n... and %d more loads", len(loads)-5)
-			break
-		}
-
+	for _, load := range page.Loads {
 		statusEmoji := "🟢" // available
 		if load.Status == "booked" {
 			statusEmoji = "🟡"
@@ -283,29 +862,41 @@ func (w *WhatsAppService) handleMyLoads(phone string) (string, error) {
 			load.Price, load.Status)
 	}
 
+	response += w.saveListSession(ctx, phone, &listSession{List: listMyLoads, Cursor: page.NextCursor}, "loads")
 	response += "Type TRACK <LoadID> to see booking details."
 	return response, nil
 }
 
 // Handle track booking for shippers
-func (w *WhatsAppService) handleTrackBooking(phone, msg string) (string, error) {
+func (w *WhatsAppService) handleTrackBooking(ctx context.Context, phone, msg string) (string, error) {
 	// Can be used by both shippers and truckers
 	parts := strings.Fields(msg)
 	if len(parts) < 2 {
-		return "❌ Please specify Booking or Load ID\n\nExample: TRACK BK00001 or TRACK LD00001", nil
+		return "❌ Please specify Booking or Load ID\n\nExample: TRACK BK00001Y or TRACK LD00001Y", nil
 	}
 
 	trackID := parts[1]
 
 	// Check if it's a booking ID
-	if strings.HasPrefix(trackID, "BK") {
-		booking, err := w.store.GetBooking(trackID)
+	if strings.HasPrefix(trackID, models.PrefixBooking) {
+		bookingID, err := models.ParseID(models.PrefixBooking, trackID)
+		if err != nil {
+			return "❌ " + err.Error(), nil
+		}
+
+		booking, err := w.store.GetBooking(ctx, bookingID)
 		if err != nil {
 			return "❌ Booking not found. Please check the ID.", nil
 		}
 
 		// Get load details
-		load, _ := w.store.GetLoad(booking.LoadID)
+		load, err := w.store.GetLoad(ctx, booking.LoadID)
+		if err != nil {
+			if isError(err, models.ErrNotFound, "", "") {
+				return "❌ The load of this booking is no longer listed.", nil
+			}
+			return "❌ Tracking failed. Please try again.", err
+		}
 
 		return fmt.Sprintf(`📍 *Tracking Details*
 
@@ -321,8 +912,13 @@ Last Update: Just now`,
 	}
 
 	// If it's a load ID, show bookings for that load
-	if strings.HasPrefix(trackID, "LD") {
-		bookings, err := w.store.GetBookingsByLoad(trackID)
+	if strings.HasPrefix(trackID, models.PrefixLoad) {
+		loadID, err := models.ParseID(models.PrefixLoad, trackID)
+		if err != nil {
+			return "❌ " + err.Error(), nil
+		}
+
+		bookings, err := w.store.GetBookingsByLoad(ctx, loadID)
 		if err != nil || len(bookings) == 0 {
 			return "❌ No bookings found for this load.", nil
 		}
@@ -336,16 +932,195 @@ Last Update: Just now`,
 *Trucker:* %s
 
 Type STATUS for more details.`,
-			trackID, booking.BookingID, booking.Status, booking.TruckerID), nil
+			loadID, booking.BookingID, booking.Status, booking.TruckerID), nil
+	}
+
+	return "❌ Invalid ID format. Use booking ID (BK00001Y) or load ID (LD00001Y).", nil
+}
+
+// Handle booking history for the trucker or shipper on the booking
+func (w *WhatsAppService) handleHistory(ctx context.Context, phone, msg string) (string, error) {
+	parts := strings.Fields(msg)
+	if len(parts) < 2 {
+		return "❌ Please specify Booking ID\n\nExample: HISTORY BK00001Y", nil
+	}
+
+	id, err := models.ParseID(models.PrefixBooking, parts[1])
+	if err != nil {
+		return "❌ " + err.Error(), nil
+	}
+	parts[1] = id
+
+	booking, err := w.store.GetBooking(ctx, parts[1])
+	if err != nil {
+		return "❌ Booking not found. Please check the ID.", nil
 	}
 
-	return "❌ Invalid ID format. Use booking ID (BK00001) or load ID (LD00001).", nil
+	// Only the trucker and shipper on the booking may see its history
+	allowed := false
+	if trucker, err := w.store.GetTruckerByPhone(ctx, phone); err == nil && trucker.TruckerID == booking.TruckerID {
+		allowed = true
+	}
+	if shipper, err := w.store.GetShipperByPhone(ctx, phone); err == nil && shipper.ShipperID == booking.ShipperID {
+		allowed = true
+	}
+	if !allowed {
+		return "❌ Booking not found. Please check the ID.", nil
+	}
+
+	events, err := w.store.GetEvents(ctx, models.EntityBooking, booking.BookingID)
+	if err != nil {
+		return "❌ Error fetching history. Please try again.", err
+	}
+
+	response := fmt.Sprintf("🕘 *Booking History*\n*Booking ID:* %s\n\n", booking.BookingID)
+	for _, event := range events {
+		response += fmt.Sprintf("📅 %s - %s\n", event.OccurredAt.Format("02 Jan 3:04 PM"), describeEvent(event))
+	}
+
+	return response, nil
+}
+
+// describeEvent renders an event as a short human readable line
+func describeEvent(event *models.Event) string {
+	via := ""
+	switch event.Channel {
+	case models.ChannelWhatsApp:
+		via = " (via WhatsApp)"
+	case models.ChannelREST:
+		via = " (via API)"
+	}
+
+	switch event.EventType {
+	case models.EventBookingCreated:
+		return "Booking confirmed" + via
+	case models.EventBookingStatusChanged:
+		return fmt.Sprintf("Status %v → %v%s", event.Before["status"], event.After["status"], via)
+	case models.EventTrackingLinkCreated:
+		return "Tracking link shared" + via
+	case models.EventTrackingLinkRevoked:
+		return "Tracking links revoked" + via
+	}
+	return event.EventType + via
+}
+
+// whatsappActor identifies a WhatsApp user in the audit log
+func whatsappActor(id string) models.Actor {
+	return models.Actor{ID: id, Channel: models.ChannelWhatsApp}
+}
+
+// isError reports whether err is a domain error of the given kind about the
+// entity and field; an empty entity or field matches any
+func isError(err error, kind error, entity, field string) bool {
+	var domainErr *models.Error
+	if !errors.As(err, &domainErr) || !errors.Is(err, kind) {
+		return false
+	}
+	return (entity == "" || domainErr.Entity == entity) && (field == "" || domainErr.Field == field)
+}
+
+// didYouMean turns a city resolution error into a reply asking the user to pick a city
+func didYouMean(err error) string {
+	var ambiguous *cities.AmbiguousError
+	if errors.As(err, &ambiguous) {
+		return fmt.Sprintf("🤔 *%s* could be more than one city.\n\nDid you mean %s?\n\nPlease send the command again with the full city name.",
+			ambiguous.Input, ambiguous.Suggestion())
+	}
+	return "❌ " + err.Error()
+}
+
+// Handle share tracking link for shippers
+func (w *WhatsAppService) handleShareTracking(ctx context.Context, phone, msg string) (string, error) {
+	shipper, err := w.store.GetShipperByPhone(ctx, phone)
+	if err != nil {
+		return "❌ Please register as shipper first!\n\nType: REGISTER SHIPPER CompanyName, GSTNumber", nil
+	}
+
+	parts := strings.Fields(msg)
+	if len(parts) < 2 {
+		return "❌ Please specify Booking ID\n\nExample: SHARE BK00001Y", nil
+	}
+
+	id, err := models.ParseID(models.PrefixBooking, parts[1])
+	if err != nil {
+		return "❌ " + err.Error(), nil
+	}
+	parts[1] = id
+
+	link, err := w.trackingService.CreateLink(ctx, parts[1], shipper.ShipperID, DefaultTrackingLinkTTL, whatsappActor(shipper.ShipperID))
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) || errors.Is(err, models.ErrForbidden) {
+			return "❌ Booking not found. Please check the ID.", nil
+		}
+		return "❌ Failed to create tracking link. Please try again.", err
+	}
+
+	return fmt.Sprintf(`🔗 *Tracking Link Created*
+
+*Booking ID:* %s
+*Link:* %s
+*Valid until:* %s
+
+Share this link with your consignee.
+Type UNSHARE %s to revoke it.`,
+		link.BookingID, w.trackingService.LinkURL(link.Token),
+		link.ExpiresAt.Format("02 Jan 3:04 PM"), link.BookingID), nil
+}
+
+// Handle revoke tracking links for shippers
+func (w *WhatsAppService) handleUnshareTracking(ctx context.Context, phone, msg string) (string, error) {
+	shipper, err := w.store.GetShipperByPhone(ctx, phone)
+	if err != nil {
+		return "❌ Please register as shipper first!\n\nType: REGISTER SHIPPER CompanyName, GSTNumber", nil
+	}
+
+	parts := strings.Fields(msg)
+	if len(parts) < 2 {
+		return "❌ Please specify Booking ID\n\nExample: UNSHARE BK00001Y", nil
+	}
+
+	id, err := models.ParseID(models.PrefixBooking, parts[1])
+	if err != nil {
+		return "❌ " + err.Error(), nil
+	}
+	parts[1] = id
+
+	if err := w.trackingService.RevokeLinks(ctx, parts[1], shipper.ShipperID, whatsappActor(shipper.ShipperID)); err != nil {
+		if errors.Is(err, models.ErrNotFound) || errors.Is(err, models.ErrForbidden) {
+			return "❌ Booking not found. Please check the ID.", nil
+		}
+		return "❌ Failed to revoke tracking link. Please try again.", err
+	}
+
+	return fmt.Sprintf("✅ All tracking links for %s have been revoked.", parts[1]), nil
+}
+
+// Handle API key request; keys are sent only to the account's own number,
+// one for each of the sender's shipper and trucker registrations
+func (w *WhatsAppService) handleAPIKey(ctx context.Context, phone string) (string, error) {
+	var keys []string
+	if shipper, err := w.store.GetShipperByPhone(ctx, phone); err == nil {
+		keys = append(keys, fmt.Sprintf("*Shipper %s:*\n%s", shipper.ShipperID, w.keyService.Issue(shipper.ShipperID)))
+	}
+	if trucker, err := w.store.GetTruckerByPhone(ctx, phone); err == nil {
+		keys = append(keys, fmt.Sprintf("*Trucker %s:*\n%s", trucker.TruckerID, w.keyService.Issue(trucker.TruckerID)))
+	}
+	if len(keys) == 0 {
+		return "❌ Please register first!\n\nType: REGISTER or REGISTER SHIPPER CompanyName, GSTNumber", nil
+	}
+
+	return fmt.Sprintf(`🔑 *Your TruckPe API Key*
+
+%s
+
+Send it as *Authorization: Bearer <key>* to use the TruckPe API from your systems.
+Keep it secret - anyone with it can act as you.`, strings.Join(keys, "\n\n")), nil
 }
 
 // Handle trucker registration (existing code)
-func (w *WhatsAppService) handleRegistration(phone, msg string) (string, error) {
+func (w *WhatsAppService) handleRegistration(ctx context.Context, phone, msg string) (string, error) {
 	// Check if already registered
-	existingTrucker, _ := w.store.GetTruckerByPhone(phone)
+	existingTrucker, _ := w.store.GetTruckerByPhone(ctx, phone)
 	if existingTrucker != nil {
 		return fmt.Sprintf(`✅ *Already Registered!*
 
@@ -359,7 +1134,7 @@ Type: LOAD <from> <to>`,
 	}
 
 	// Check if registered as shipper
-	existingShipper, _ := w.store.GetShipperByPhone(phone)
+	existingShipper, _ := w.store.GetShipperByPhone(ctx, phone)
 	if existingShipper != nil {
 		return "❌ This number is registered as a shipper. Use a different number for trucker account.", nil
 	}
@@ -389,12 +1164,12 @@ Type: LOAD <from> <to>`,
 		Capacity:    capacity,
 	}
 
-	trucker, err := w.store.CreateTrucker(reg)
+	trucker, err := w.store.CreateTrucker(ctx, reg, whatsappActor(phone))
 	if err != nil {
-		if strings.Contains(err.Error(), "phone number already registered") {
+		if isError(err, models.ErrConflict, "trucker", "phone") {
 			return "❌ This phone number is already registered!", nil
 		}
-		if strings.Contains(err.Error(), "vehicle already registered") {
+		if isError(err, models.ErrConflict, "trucker", "vehicle_no") {
 			return "❌ This vehicle is already registered with another trucker!", nil
 		}
 		return "❌ Registration failed. Please try again.", err
@@ -416,9 +1191,9 @@ Example: LOAD Delhi Mumbai`,
 }
 
 // Handle load search (existing code)
-func (w *WhatsAppService) handleLoadSearch(phone, msg string) (string, error) {
+func (w *WhatsAppService) handleLoadSearch(ctx context.Context, phone, msg string) (string, error) {
 	// Check if trucker is registered
-	trucker, err := w.store.GetTruckerByPhone(phone)
+	trucker, err := w.store.GetTruckerByPhone(ctx, phone)
 	if err != nil {
 		return "❌ Please register first!\n\nType: REGISTER Name, VehicleNo, Type, Capacity", nil
 	}
@@ -430,54 +1205,188 @@ func (w *WhatsAppService) handleLoadSearch(phone, msg string) (string, error) {
 		return "❌ Please specify at least origin city\n\nExample: LOAD Delhi or LOAD Delhi Mumbai", nil
 	}
 
-	search := &models.LoadSearch{
-		FromCity: parts[1],
+	session := &listSession{List: listLoadSearch, FromCity: parts[1]}
+	if len(parts) > 2 {
+		session.ToCity = parts[2]
 	}
 
-	if len(parts) > 2 {
-		search.ToCity = parts[2]
+	filter := &models.LoadFilter{FromCity: session.FromCity, ToCity: session.ToCity}
+	if err := filter.ResolveCities(); err != nil {
+		return didYouMean(err), nil
 	}
+	session.FromCity, session.ToCity = filter.FromCity, filter.ToCity
+
+	return w.listLoadSearch(ctx, phone, trucker, session)
+}
 
-	// Search loads
-	loads, err := w.store.SearchLoads(search)
+// listLoadSearch shows one page of available loads on a route
+func (w *WhatsAppService) listLoadSearch(ctx context.Context, phone string, trucker *models.Trucker, session *listSession) (string, error) {
+	filter := &models.LoadFilter{
+		Status:   models.LoadStatusAvailable,
+		FromCity: session.FromCity,
+		ToCity:   session.ToCity,
+	}
+
+	page, err := w.store.ListLoads(ctx, filter, &models.ListOptions{Cursor: session.Cursor, Limit: whatsappPageSize})
 	if err != nil {
 		return "❌ Error searching loads. Please try again.", err
 	}
 
-	if len(loads) == 0 {
-		return fmt.Sprintf("😔 No loads found from %s\n\nTry searching other routes or check back later!", search.FromCity), nil
+	if len(page.Loads) == 0 {
+		return fmt.Sprintf("😔 No loads found from %s\n\nTry searching other routes or check back later!", session.FromCity), nil
 	}
 
 	// Format response
-	response := fmt.Sprintf("🚛 *Available Loads from %s*\n", search.FromCity)
+	response := fmt.Sprintf("🚛 *Available Loads from %s*\n", session.FromCity)
 	response += fmt.Sprintf("👤 *For:* %s (%s)\n\n", trucker.Name, trucker.VehicleNo)
 
-	for i, load := range loads {
-		if i > 4 { // Limit to 5 loads in WhatsApp
-			response += fmt.Sprintf("\n... and %d more loads\n", len(loads)-5)
-			break
-		}
-
+	for _, load := range page.Loads {
 		response += fmt.Sprintf(`📦 *Load ID:* %s
 📍 *Route:* %s → %s
 📦 *Material:* %s
-⚖️ *Weight:* %.1f tons
+⚖️ *Weight:* %s
 💰 *Price:* ₹%.0f
 🚛 *Vehicle:* %s
 📅 *Loading:* Today
 
 `, load.LoadID, load.FromCity, load.ToCity, load.Material,
-			load.Weight, load.Price, load.VehicleType)
+			load.WeightText(), load.Price, load.VehicleType)
 	}
 
-	response += "To book, type: BOOK <Load_ID>\nExample: BOOK " + loads[0].LoadID
+	session.Cursor = page.NextCursor
+	response += w.saveListSession(ctx, phone, session, "loads")
+	response += "To book, type: BOOK <Load_ID>\nExample: BOOK " + page.Loads[0].LoadID
 	return response, nil
 }
 
+// Handle LOCATION: set or show the trucker's current city
+func (w *WhatsAppService) handleLocation(ctx context.Context, phone, msg string) (string, error) {
+	trucker, err := w.store.GetTruckerByPhone(ctx, phone)
+	if err != nil {
+		return "❌ Please register first!\n\nType: REGISTER Name, VehicleNo, Type, Capacity", nil
+	}
+
+	input := strings.TrimSpace(strings.TrimPrefix(msg, "LOCATION"))
+	if input == "" {
+		if trucker.CurrentCity == "" {
+			return "📍 No location set.\n\nType: LOCATION <city>\nExample: LOCATION Chennai", nil
+		}
+		return fmt.Sprintf("📍 Your current city: *%s*\n\nType LOCATION <city> to change it.", trucker.CurrentCity), nil
+	}
+
+	city, err := models.ResolveCity(input)
+	if err != nil {
+		return didYouMean(err), nil
+	}
+
+	if err := w.store.UpdateTruckerLocation(ctx, trucker.TruckerID, city.Name, city.ID, whatsappActor(trucker.TruckerID)); err != nil {
+		return "❌ Failed to update location. Please try again.", err
+	}
+
+	return fmt.Sprintf("📍 Location updated to *%s*\n\nType LOAD <from> <to> to search loads.", city.Name), nil
+}
+
+// Handle ALERT: subscribe to new loads on a lane.
+// Format: ALERT <from> [<to>|ANY] [vehicle type] [MIN <price>]
+func (w *WhatsAppService) handleCreateAlert(ctx context.Context, phone, msg string) (string, error) {
+	trucker, err := w.store.GetTruckerByPhone(ctx, phone)
+	if err != nil {
+		return "❌ Please register first!\n\nType: REGISTER Name, VehicleNo, Type, Capacity", nil
+	}
+
+	usage := `❌ Please specify the lane
+
+Format: ALERT <From> <To> <Vehicle> MIN <Price>
+To and vehicle are optional; use ANY for any destination.
+
+Example: ALERT Delhi Mumbai 32ft MIN 40000`
+
+	parts := strings.Fields(msg)[1:]
+	alert := &models.LaneAlert{}
+	for i := 0; i < len(parts); i++ {
+		if parts[i] == "MIN" && i+1 < len(parts) {
+			price, err := parseImportNumber(parts[i+1])
+			if err != nil {
+				return usage, nil
+			}
+			alert.MinPrice = price
+			parts = append(parts[:i], parts[i+2:]...)
+			break
+		}
+	}
+	if len(parts) == 0 {
+		return usage, nil
+	}
+
+	alert.FromCity = parts[0]
+	if len(parts) > 1 && parts[1] != "ANY" {
+		alert.ToCity = parts[1]
+	}
+	if len(parts) > 2 {
+		alert.VehicleType = strings.ToLower(strings.Join(parts[2:], " "))
+	}
+
+	alert, err = w.alertService.Subscribe(ctx, trucker.TruckerID, alert)
+	if err != nil {
+		return didYouMean(err), nil
+	}
+
+	return fmt.Sprintf(`🔔 *Alert saved!*
+
+*Alert ID:* %s
+📍 *Lane:* %s
+
+We'll message you as soon as a matching load is posted.
+Type ALERTS to see your alerts.`, alert.AlertID, alert.Lane()), nil
+}
+
+// Handle ALERTS: list the trucker's lane alerts
+func (w *WhatsAppService) handleListAlerts(ctx context.Context, phone string) (string, error) {
+	trucker, err := w.store.GetTruckerByPhone(ctx, phone)
+	if err != nil {
+		return "❌ Please register first!\n\nType: REGISTER Name, VehicleNo, Type, Capacity", nil
+	}
+
+	alerts, err := w.alertService.Alerts(ctx, trucker.TruckerID)
+	if err != nil {
+		return "❌ Error fetching alerts. Please try again.", err
+	}
+
+	if len(alerts) == 0 {
+		return "🔕 You have no alerts.\n\nType: ALERT <from> <to>\nExample: ALERT Delhi Mumbai", nil
+	}
+
+	response := "🔔 *Your Alerts*\n\n"
+	for _, alert := range alerts {
+		response += fmt.Sprintf("*%s* %s\n", alert.AlertID, alert.Lane())
+	}
+	response += "\nTo stop an alert, type: UNALERT <Alert_ID>"
+	return response, nil
+}
+
+// Handle UNALERT: delete one of the trucker's lane alerts
+func (w *WhatsAppService) handleDeleteAlert(ctx context.Context, phone, msg string) (string, error) {
+	trucker, err := w.store.GetTruckerByPhone(ctx, phone)
+	if err != nil {
+		return "❌ Please register first!\n\nType: REGISTER Name, VehicleNo, Type, Capacity", nil
+	}
+
+	parts := strings.Fields(msg)
+	if len(parts) < 2 {
+		return "❌ Please specify alert ID\n\nExample: UNALERT AL00001Y", nil
+	}
+
+	if err := w.alertService.Unsubscribe(ctx, trucker.TruckerID, parts[1]); err != nil {
+		return "❌ Alert not found. Type ALERTS to see your alerts.", nil
+	}
+
+	return fmt.Sprintf("🔕 Alert %s stopped.", parts[1]), nil
+}
+
 // Handle booking (existing code)
-func (w *WhatsAppService) handleBooking(phone, msg string) (string, error) {
+func (w *WhatsAppService) handleBooking(ctx context.Context, phone, msg string) (string, error) {
 	// Check if trucker is registered
-	trucker, err := w.store.GetTruckerByPhone(phone)
+	trucker, err := w.store.GetTruckerByPhone(ctx, phone)
 	if err != nil {
 		return "❌ Please register first!\n\nType: REGISTER Name, VehicleNo, Type, Capacity", nil
 	}
@@ -485,28 +1394,47 @@ func (w *WhatsAppService) handleBooking(phone, msg string) (string, error) {
 	// Extract load ID
 	parts := strings.Fields(msg)
 	if len(parts) < 2 {
-		return "❌ Please specify Load ID\n\nExample: BOOK LD00001", nil
+		return "❌ Please specify Load ID\n\nExample: BOOK LD00001Y", nil
 	}
 
-	loadID := parts[1]
+	loadID, err := models.ParseID(models.PrefixLoad, parts[1])
+	if err != nil {
+		return "❌ " + err.Error(), nil
+	}
+
+	// Part loads may be booked by weight: BOOK LD00001Y 5
+	var weight float64
+	if len(parts) > 2 {
+		if _, err := fmt.Sscanf(parts[2], "%f", &weight); err != nil || weight <= 0 {
+			return "❌ Invalid weight\n\nExample: BOOK LD00001Y 5 (tons of a part load)", nil
+		}
+	}
 
 	// Create booking
-	booking, err := w.store.CreateBooking(loadID, trucker.TruckerID)
+	booking, err := w.store.CreateBooking(ctx, loadID, trucker.TruckerID, weight, whatsappActor(trucker.TruckerID))
 	if err != nil {
-		if strings.Contains(err.Error(), "load not found") {
+		if isError(err, models.ErrNotFound, "load", "") {
 			return "❌ Load not found. Please check the Load ID.", nil
 		}
-		if strings.Contains(err.Error(), "load not available") {
+		if isError(err, models.ErrUnavailable, "load", "weight") {
+			if load, err := w.store.GetLoad(ctx, loadID); err == nil {
+				return fmt.Sprintf("❌ Sorry! Only %.1f tons are left on this load.\n\nBook less: BOOK %s <tons>", load.RemainingWeight(), load.LoadID), nil
+			}
+		}
+		if isError(err, models.ErrUnavailable, "trucker", "capacity") {
+			return fmt.Sprintf("❌ This load does not fit in your truck (%.1f tons capacity).", trucker.Capacity), nil
+		}
+		if isError(err, models.ErrUnavailable, "load", "") {
 			return "❌ Sorry! This load has already been booked.", nil
 		}
-		if strings.Contains(err.Error(), "trucker not available") {
+		if isError(err, models.ErrUnavailable, "trucker", "") {
 			return "❌ You already have an active booking. Complete it first!", nil
 		}
 		return "❌ Booking failed. Please try again.", err
 	}
 
 	// Get load details
-	load, _ := w.store.GetLoad(loadID)
+	load, _ := w.store.GetLoad(ctx, loadID)
 
 	return fmt.Sprintf(`✅ *Booking Confirmed!*
 
@@ -514,6 +1442,7 @@ func (w *WhatsAppService) handleBooking(phone, msg string) (string, error) {
 *Load ID:* %s
 *Route:* %s → %s
 *Material:* %s
+*Weight:* %.1f tons
 *Amount:* ₹%.0f
 *Your earnings:* ₹%.0f (after 5%% commission)
 
@@ -525,38 +1454,38 @@ Show this OTP at pickup point.
 
 Type STATUS to check your bookings.`,
 		booking.BookingID, load.LoadID, load.FromCity, load.ToCity,
-		load.Material, booking.AgreedPrice, booking.NetAmount, booking.OTP), nil
+		load.Material, booking.Weight, booking.AgreedPrice, booking.NetAmount, booking.OTP), nil
 }
 
 // Handle status check (existing code)
-func (w *WhatsAppService) handleStatus(phone string) (string, error) {
+func (w *WhatsAppService) handleStatus(ctx context.Context, phone string) (string, error) {
 	// Check if trucker is registered
-	trucker, err := w.store.GetTruckerByPhone(phone)
+	trucker, err := w.store.GetTruckerByPhone(ctx, phone)
 	if err != nil {
 		return "❌ Please register first!\n\nType: REGISTER Name, VehicleNo, Type, Capacity", nil
 	}
 
-	// Get bookings
-	bookings, err := w.store.GetBookingsByTrucker(trucker.TruckerID)
+	return w.listStatus(ctx, phone, trucker, "")
+}
+
+// listStatus shows one page of a trucker's bookings
+func (w *WhatsAppService) listStatus(ctx context.Context, phone string, trucker *models.Trucker, cursor string) (string, error) {
+	page, err := w.store.ListBookings(ctx, &models.BookingFilter{TruckerID: trucker.TruckerID},
+		&models.ListOptions{Cursor: cursor, Limit: whatsappPageSize})
 	if err != nil {
 		return "❌ Error fetching bookings. Please try again.", err
 	}
 
-	if len(bookings) == 0 {
+	if len(page.Bookings) == 0 {
 		return "📊 *Your Status*\n\nNo active bookings.\n\nSearch for loads: LOAD <from> <to>", nil
 	}
 
 	// Format response
 	response := fmt.Sprintf("📊 *Your Bookings*\n👤 %s (%s)\n\n", trucker.Name, trucker.VehicleNo)
 
-	for i, booking := range bookings {
-		if i > 4 { // Limit display
-			response += fmt.Sprintf("\n... and %d more bookings", len(bookings)-5)
-			break
-		}
-
+	for _, booking := range page.Bookings {
 		// Get load details
-		load, _ := w.store.GetLoad(booking.LoadID)
+		load, _ := w.store.GetLoad(ctx, booking.LoadID)
 		if load != nil {
 			response += fmt.Sprintf(`🚛 *Booking:* %s
 📍 *Route:* %s → %s
@@ -568,5 +1497,131 @@ func (w *WhatsAppService) handleStatus(phone string) (string, error) {
 		}
 	}
 
+	response += w.saveListSession(ctx, phone, &listSession{List: listStatus, Cursor: page.NextCursor}, "bookings")
+	return strings.TrimRight(response, "\n"), nil
+}
+
+// Handle DELETE MY ACCOUNT: explain what deleting does and ask for confirmation
+func (w *WhatsAppService) handleDeleteAccount(ctx context.Context, phone string) (string, error) {
+	trucker, _ := w.store.GetTruckerByPhone(ctx, phone)
+	shipper, _ := w.store.GetShipperByPhone(ctx, phone)
+	if trucker == nil && shipper == nil {
+		return "❌ No TruckPe account is registered to this number.", nil
+	}
+
+	response := "⚠️ *Delete your TruckPe account?*\n\n"
+	if trucker != nil {
+		response += fmt.Sprintf("🚛 *Trucker:* %s (%s)\n", trucker.TruckerID, trucker.VehicleNo)
+	}
+	if shipper != nil {
+		response += fmt.Sprintf("🏭 *Shipper:* %s (%s)\n", shipper.ShipperID, shipper.CompanyName)
+	}
+	response += `
+• Bookings not yet picked up will be cancelled
+• Your open loads will be withdrawn
+• Past bookings and loads are kept for our records
+
+`
+
+	err := w.store.SaveSession(ctx, &models.WhatsAppSession{
+		PhoneNumber: phone,
+		LastCommand: sessionDeleteAccount,
+		ExpiresAt:   time.Now().Add(deleteAccountTTL),
+	})
+	if err != nil {
+		return "❌ Error starting account deletion. Please try again.", err
+	}
+
+	response += fmt.Sprintf("Reply *CONFIRM DELETE* within %d minutes to delete your account.", int(deleteAccountTTL.Minutes()))
 	return response, nil
 }
+
+// Handle CONFIRM DELETE: delete the trucker and shipper accounts of this
+// number if DELETE MY ACCOUNT was sent recently
+func (w *WhatsAppService) handleConfirmDelete(ctx context.Context, phone string) (string, error) {
+	session, err := w.store.GetSession(ctx, phone)
+	if err != nil || session.LastCommand != sessionDeleteAccount {
+		return "❌ Nothing to confirm.\n\nTo delete your account, type: DELETE MY ACCOUNT", nil
+	}
+
+	trucker, _ := w.store.GetTruckerByPhone(ctx, phone)
+	shipper, _ := w.store.GetShipperByPhone(ctx, phone)
+	if trucker == nil && shipper == nil {
+		return "❌ No TruckPe account is registered to this number.", nil
+	}
+
+	// Both accounts of the number go together, or neither does
+	var truckerID, shipperID string
+	if trucker != nil {
+		truckerID = trucker.TruckerID
+	}
+	if shipper != nil {
+		shipperID = shipper.ShipperID
+	}
+	actorID := truckerID
+	if actorID == "" {
+		actorID = shipperID
+	}
+	if err := w.store.DeleteAccount(ctx, truckerID, shipperID, whatsappActor(actorID)); err != nil {
+		if isError(err, models.ErrUnavailable, "", "") {
+			return fmt.Sprintf("❌ Your account cannot be deleted yet: %s", err), nil
+		}
+		return "❌ Account deletion failed. Please try again.", err
+	}
+
+	// The confirmation is used up
+	w.store.SaveSession(ctx, &models.WhatsAppSession{PhoneNumber: phone, ExpiresAt: time.Now()})
+
+	return `✅ *Your TruckPe account has been deleted.*
+
+Thank you for using TruckPe. You can register again at any time.`, nil
+}
+
+// Handle MORE: continue the last list shown to this number
+func (w *WhatsAppService) handleMore(ctx context.Context, phone string) (string, error) {
+	var session listSession
+	stored, err := w.store.GetSession(ctx, phone)
+	if err == nil {
+		json.Unmarshal([]byte(stored.Context), &session)
+	}
+	if session.List == "" || session.Cursor == "" {
+		return "📭 Nothing more to show.\n\nType HELP to see available commands.", nil
+	}
+
+	switch session.List {
+	case listMyLoads:
+		shipper, err := w.store.GetShipperByPhone(ctx, phone)
+		if err != nil {
+			return "❌ Please register as shipper first!\n\nType: REGISTER SHIPPER CompanyName, GSTNumber", nil
+		}
+		return w.listMyLoads(ctx, phone, shipper, session.Cursor)
+
+	case listLoadSearch, listStatus:
+		trucker, err := w.store.GetTruckerByPhone(ctx, phone)
+		if err != nil {
+			return "❌ Please register first!\n\nType: REGISTER Name, VehicleNo, Type, Capacity", nil
+		}
+		if session.List == listStatus {
+			return w.listStatus(ctx, phone, trucker, session.Cursor)
+		}
+		return w.listLoadSearch(ctx, phone, trucker, &session)
+	}
+
+	return "📭 Nothing more to show.\n\nType HELP to see available commands.", nil
+}
+
+// saveListSession remembers where a list stopped so MORE can continue it,
+// and returns the hint to append to the reply
+func (w *WhatsAppService) saveListSession(ctx context.Context, phone string, session *listSession, noun string) string {
+	context, _ := json.Marshal(session)
+	err := w.store.SaveSession(ctx, &models.WhatsAppSession{
+		PhoneNumber: phone,
+		LastCommand: session.List,
+		Context:     string(context),
+		ExpiresAt:   time.Now().Add(listSessionTTL),
+	})
+	if err != nil || session.Cursor == "" {
+		return ""
+	}
+	return fmt.Sprintf("➡️ Type MORE to see more %s\n\n", noun)
+}
@@ -1,35 +1,43 @@
 package storage
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"log"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/Ananth-NQI/truckpe-backend/internal/events"
 	"github.com/Ananth-NQI/truckpe-backend/internal/models"
+	"github.com/jackc/pgx/v5/pgconn"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // DatabaseStore implements Store interface using PostgreSQL
 type DatabaseStore struct {
-	db *gorm.DB
+	db  *gorm.DB
+	bus *events.Bus
 }
 
-// NewDatabaseStore creates a new database storage
-func NewDatabaseStore(db *gorm.DB) Store {
-	return &DatabaseStore{db: db}
+// NewDatabaseStore creates a new database storage that publishes its events to bus
+func NewDatabaseStore(db *gorm.DB, bus *events.Bus) Store {
+	return &DatabaseStore{db: db, bus: bus}
 }
 
 // Trucker operations
-func (d *DatabaseStore) CreateTrucker(reg *models.TruckerRegistration) (*models.Trucker, error) {
+func (d *DatabaseStore) CreateTrucker(ctx context.Context, reg *models.TruckerRegistration, actor models.Actor) (*models.Trucker, error) {
 	// Check if phone already exists
 	var existing models.Trucker
-	if err := d.db.Where("phone = ?", reg.Phone).First(&existing).Error; err == nil {
-		return nil, fmt.Errorf("phone number already registered")
+	if err := d.db.WithContext(ctx).Where("phone = ?", reg.Phone).First(&existing).Error; err == nil {
+		return nil, models.Conflict("trucker", "phone", "phone number already registered")
 	}
 
 	// Check if vehicle already exists
-	if err := d.db.Where("vehicle_no = ?", reg.VehicleNo).First(&existing).Error; err == nil {
-		return nil, fmt.Errorf("vehicle already registered")
+	if err := d.db.WithContext(ctx).Where("vehicle_no = ?", reg.VehicleNo).First(&existing).Error; err == nil {
+		return nil, models.Conflict("trucker", "vehicle_no", "vehicle already registered")
 	}
 
 	trucker := &models.Trucker{
@@ -42,107 +50,142 @@ func (d *DatabaseStore) CreateTrucker(reg *models.TruckerRegistration) (*models.
 		Rating:      5.0, // Start with 5 stars
 		TotalTrips:  0,
 		Available:   true,
+		Active:      true,
 	}
 
-	if err := d.db.Create(trucker).Error; err != nil {
+	err := d.transaction(ctx, func(tx *gorm.DB, rec *eventRecorder) error {
+		if err := tx.Create(trucker).Error; err != nil {
+			// Registered concurrently since the checks above
+			return uniqueConflict(err, "trucker", map[string]string{
+				"phone":      "phone number already registered",
+				"vehicle_no": "vehicle already registered",
+			})
+		}
+		return rec.record(tx, models.NewEvent(models.EntityTrucker, trucker.TruckerID, models.EventTruckerRegistered, actor, nil,
+			models.EventData{"name": trucker.Name, "vehicle_no": trucker.VehicleNo, "vehicle_type": trucker.VehicleType, "capacity": trucker.Capacity}).For("", trucker.TruckerID))
+	})
+	if err != nil {
 		return nil, fmt.Errorf("failed to create trucker: %w", err)
 	}
 
 	return trucker, nil
 }
 
-func (d *DatabaseStore) GetTrucker(id string) (*models.Trucker, error) {
+func (d *DatabaseStore) GetTrucker(ctx context.Context, id string) (*models.Trucker, error) {
 	var trucker models.Trucker
-
-	// Check if it's a TruckerID (starts with "TR") or numeric ID
-	if strings.HasPrefix(id, "TR") {
-		// Search by TruckerID only
-		if err := d.db.Where("trucker_id = ?", id).First(&trucker).Error; err != nil {
-			if err == gorm.ErrRecordNotFound {
-				return nil, fmt.Errorf("trucker not found")
-			}
-			return nil, fmt.Errorf("database error: %w", err)
-		}
-	} else {
-		// Try to parse as numeric ID
-		if err := d.db.Where("id = ?", id).First(&trucker).Error; err != nil {
-			if err == gorm.ErrRecordNotFound {
-				return nil, fmt.Errorf("trucker not found")
-			}
-			return nil, fmt.Errorf("database error: %w", err)
-		}
+	if err := findByID(d.db.WithContext(ctx), &trucker, "trucker_id", id, "trucker"); err != nil {
+		return nil, err
 	}
-
 	return &trucker, nil
 }
 
-func (d *DatabaseStore) GetTruckerByPhone(phone string) (*models.Trucker, error) {
+func (d *DatabaseStore) GetTruckerByPhone(ctx context.Context, phone string) (*models.Trucker, error) {
 	var trucker models.Trucker
-	if err := d.db.Where("phone = ?", phone).First(&trucker).Error; err != nil {
+	if err := d.db.WithContext(ctx).Where("phone = ?", phone).First(&trucker).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
-			return nil, fmt.Errorf("trucker not found")
+			return nil, models.NotFound("trucker")
 		}
 		return nil, fmt.Errorf("database error: %w", err)
 	}
 	return &trucker, nil
 }
 
+// UpdateTruckerLocation sets the city a trucker is currently in
+func (d *DatabaseStore) UpdateTruckerLocation(ctx context.Context, id, city, cityID string, actor models.Actor) error {
+	return d.transaction(ctx, func(tx *gorm.DB, rec *eventRecorder) error {
+		var trucker models.Trucker
+		if err := tx.Where("trucker_id = ?", id).First(&trucker).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return models.NotFound("trucker")
+			}
+			return fmt.Errorf("database error: %w", err)
+		}
+
+		if err := models.CheckVersion(ctx, "trucker", trucker.Version); err != nil {
+			return err
+		}
+
+		previous := trucker.CurrentCity
+		if err := updateVersioned(tx, &trucker, &trucker.Version, "trucker", map[string]interface{}{"current_city": city, "current_city_id": cityID}); err != nil {
+			return fmt.Errorf("failed to update trucker location: %w", err)
+		}
+
+		return rec.record(tx, models.NewEvent(models.EntityTrucker, trucker.TruckerID, models.EventTruckerUpdated, actor,
+			models.EventData{"current_city": previous}, models.EventData{"current_city": city}).For("", trucker.TruckerID))
+	})
+}
+
 // Load operations
-func (d *DatabaseStore) CreateLoad(load *models.Load) (*models.Load, error) {
+func (d *DatabaseStore) CreateLoad(ctx context.Context, load *models.Load, actor models.Actor) (*models.Load, error) {
 	// LoadID will be auto-generated by BeforeCreate hook
 	load.Status = "available"
 
-	if err := d.db.Create(load).Error; err != nil {
+	err := d.transaction(ctx, func(tx *gorm.DB, rec *eventRecorder) error {
+		return insertLoad(tx, rec, load, actor)
+	})
+	if err != nil {
 		return nil, fmt.Errorf("failed to create load: %w", err)
 	}
 
 	return load, nil
 }
 
-func (d *DatabaseStore) GetLoad(id string) (*models.Load, error) {
-	var load models.Load
-
-	// Check if it's a LoadID (starts with "LD") or numeric ID
-	if strings.HasPrefix(id, "LD") {
-		// Search by LoadID only
-		if err := d.db.Where("load_id = ?", id).First(&load).Error; err != nil {
-			if err == gorm.ErrRecordNotFound {
-				return nil, fmt.Errorf("load not found")
-			}
-			return nil, fmt.Errorf("database error: %w", err)
-		}
-	} else {
-		// Try to parse as numeric ID
-		if err := d.db.Where("id = ?", id).First(&load).Error; err != nil {
-			if err == gorm.ErrRecordNotFound {
-				return nil, fmt.Errorf("load not found")
+// CreateLoads creates several loads in one transaction; if any insert fails none are kept
+func (d *DatabaseStore) CreateLoads(ctx context.Context, loads []*models.Load, actor models.Actor) ([]*models.Load, error) {
+	err := d.transaction(ctx, func(tx *gorm.DB, rec *eventRecorder) error {
+		for _, load := range loads {
+			load.Status = "available"
+			if err := insertLoad(tx, rec, load, actor); err != nil {
+				return err
 			}
-			return nil, fmt.Errorf("database error: %w", err)
 		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create loads: %w", err)
 	}
 
-	return &load, nil
+	return loads, nil
+}
+
+// insertLoad inserts a load and records its creation event inside a transaction
+func insertLoad(tx *gorm.DB, rec *eventRecorder, load *models.Load, actor models.Actor) error {
+	if err := checkShipperActive(tx, load.ShipperID); err != nil {
+		return err
+	}
+	if err := tx.Create(load).Error; err != nil {
+		return err
+	}
+	return rec.record(tx, models.NewEvent(models.EntityLoad, load.LoadID, models.EventLoadCreated, actor, nil,
+		models.EventData{"status": load.Status, "from_city": load.FromCity, "to_city": load.ToCity, "price": load.Price, "weight": load.Weight,
+			"truck_count": load.TruckCount, "splittable": load.Splittable}).For(load.ShipperID, ""))
+}
+
+func (d *DatabaseStore) GetLoad(ctx context.Context, id string) (*models.Load, error) {
+	return findLoad(d.db.WithContext(ctx), id)
 }
 
-func (d *DatabaseStore) GetAvailableLoads() ([]*models.Load, error) {
+func (d *DatabaseStore) GetAvailableLoads(ctx context.Context) ([]*models.Load, error) {
 	var loads []*models.Load
-	if err := d.db.Where("status = ?", "available").
-		Order("created_at DESC").
+	if err := d.db.WithContext(ctx).Where("status = ?", "available").
+		Where("expires_at IS NULL OR expires_at > ?", time.Now()).
+		Order("created_at DESC, id DESC").
 		Find(&loads).Error; err != nil {
 		return nil, fmt.Errorf("failed to fetch loads: %w", err)
 	}
 	return loads, nil
 }
 
-func (d *DatabaseStore) SearchLoads(search *models.LoadSearch) ([]*models.Load, error) {
-	query := d.db.Where("status = ?", "available")
+// SearchLoads applies the rules of Load.MatchesSearch in SQL
+func (d *DatabaseStore) SearchLoads(ctx context.Context, search *models.LoadSearch) ([]*models.Load, error) {
+	query := d.db.WithContext(ctx).Where("status = ?", "available").
+		Where("expires_at IS NULL OR expires_at > ?", time.Now())
 
 	if search.FromCity != "" {
-		// Use ILIKE for case-insensitive search in PostgreSQL
-		query = query.Where("LOWER(from_city) = LOWER(?)", search.FromCity)
+		query = query.Where("LOWER(from_city) IN ?", models.CityNames(search.FromCity))
 	}
 	if search.ToCity != "" {
-		query = query.Where("LOWER(to_city) = LOWER(?)", search.ToCity)
+		query = query.Where("LOWER(to_city) IN ?", models.CityNames(search.ToCity))
 	}
 	if search.VehicleType != "" {
 		query = query.Where("LOWER(vehicle_type) LIKE LOWER(?)", "%"+search.VehicleType+"%")
@@ -152,260 +195,1796 @@ func (d *DatabaseStore) SearchLoads(search *models.LoadSearch) ([]*models.Load,
 			query = query.Where("loading_date >= ?", date)
 		}
 	}
+	if search.DateTo != "" {
+		if date, err := time.Parse("2006-01-02", search.DateTo); err == nil {
+			query = query.Where("loading_date < ?", date.AddDate(0, 0, 1))
+		}
+	}
+	if search.MinPrice > 0 {
+		query = query.Where("price >= ?", search.MinPrice)
+	}
+	if search.MaxPrice > 0 {
+		query = query.Where("price <= ?", search.MaxPrice)
+	}
+	if search.MinRatePerTon > 0 {
+		query = query.Where(loadSortColumns[models.SortRatePerTon]+" >= ?", search.MinRatePerTon)
+	}
+	if search.MinRatePerKm > 0 {
+		query = query.Where(loadSortColumns[models.SortRatePerKm]+" >= ?", search.MinRatePerKm)
+	}
+	if search.MinWeight > 0 {
+		query = query.Where("weight >= ?", search.MinWeight)
+	}
+	if search.MaxWeight > 0 {
+		query = query.Where("(splittable OR weight / COALESCE(NULLIF(truck_count, 0), 1) <= ?)", search.MaxWeight)
+	}
+	if search.Material != "" {
+		query = query.Where("LOWER(material) LIKE LOWER(?)", "%"+search.Material+"%")
+	}
+	if search.MaterialCategory != "" {
+		materials := models.MaterialsInCategory(search.MaterialCategory)
+		if len(materials) == 0 {
+			return []*models.Load{}, nil
+		}
+		query = query.Where("LOWER(material) IN ?", materials)
+	}
+	if search.PaymentTerms != "" {
+		query = query.Where("LOWER(payment_terms) = LOWER(?)", search.PaymentTerms)
+	}
+	if search.NearCity != "" {
+		query = query.Where("LOWER(from_city) IN ?", search.NearbyCities())
+	}
 
 	var loads []*models.Load
-	if err := query.Order("created_at DESC").Find(&loads).Error; err != nil {
+	if err := query.Order("created_at DESC, id DESC").Find(&loads).Error; err != nil {
 		return nil, fmt.Errorf("failed to search loads: %w", err)
 	}
 	return loads, nil
 }
 
-func (d *DatabaseStore) UpdateLoadStatus(id string, status string) error {
-	var result *gorm.DB
+// loadSortColumns maps sort options to SQL expressions; rates of loads
+// without weight or distance sort as zero, like Load.CalculateRate
+var loadSortColumns = map[string]string{
+	models.SortPrice:       "price",
+	models.SortRatePerTon:  "COALESCE(price / NULLIF(weight, 0), 0)",
+	models.SortRatePerKm:   "COALESCE(price / NULLIF(distance, 0), 0)",
+	models.SortLoadingDate: "loading_date",
+	models.SortCreatedAt:   "created_at",
+}
 
-	// Check if it's a LoadID (starts with "LD") or numeric ID
-	if strings.HasPrefix(id, "LD") {
-		result = d.db.Model(&models.Load{}).
-			Where("load_id = ?", id).
-			Update("status", status)
-	} else {
-		result = d.db.Model(&models.Load{}).
-			Where("id = ?", id).
-			Update("status", status)
+func (d *DatabaseStore) ListLoads(ctx context.Context, filter *models.LoadFilter, opts *models.ListOptions) (*models.LoadPage, error) {
+	if err := opts.Normalize(models.LoadSorts); err != nil {
+		return nil, err
 	}
 
-	if result.Error != nil {
-		return fmt.Errorf("failed to update load status: %w", result.Error)
+	query := d.db.WithContext(ctx).Model(&models.Load{})
+	if filter.ShipperID != "" {
+		query = query.Where("shipper_id = ?", filter.ShipperID)
 	}
-	if result.RowsAffected == 0 {
-		return fmt.Errorf("load not found")
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
 	}
-	return nil
+	if filter.Status == models.LoadStatusAvailable {
+		query = query.Where("expires_at IS NULL OR expires_at > ?", time.Now())
+	}
+	if filter.FromCity != "" {
+		query = query.Where("LOWER(from_city) IN ?", models.CityNames(filter.FromCity))
+	}
+	if filter.ToCity != "" {
+		query = query.Where("LOWER(to_city) IN ?", models.CityNames(filter.ToCity))
+	}
+	if filter.VehicleType != "" {
+		query = query.Where("LOWER(vehicle_type) LIKE LOWER(?)", "%"+filter.VehicleType+"%")
+	}
+
+	var loads []*models.Load
+	if err := paginate(query, opts, loadSortColumns[opts.Sort]).Find(&loads).Error; err != nil {
+		return nil, fmt.Errorf("failed to list loads: %w", err)
+	}
+
+	page := &models.LoadPage{Loads: loads}
+	if len(loads) > opts.Limit {
+		page.Loads = loads[:opts.Limit]
+		page.NextCursor = page.Loads[opts.Limit-1].SortKey(opts.Sort).Cursor()
+	}
+	return page, nil
 }
 
-// Booking operations
-func (d *DatabaseStore) CreateBooking(loadID, truckerID string) (*models.Booking, error) {
-	// Start transaction
-	tx := d.db.Begin()
-	defer func() {
-		if r := recover(); r != nil {
-			tx.Rollback()
+func (d *DatabaseStore) UpdateLoadStatus(ctx context.Context, id string, status string, actor models.Actor) error {
+	return d.transaction(ctx, func(tx *gorm.DB, rec *eventRecorder) error {
+		load, err := findLoad(tx, id)
+		if err != nil {
+			return err
 		}
-	}()
 
-	// Get load with proper ID handling
-	var load models.Load
-	if strings.HasPrefix(loadID, "LD") {
-		if err := tx.Where("load_id = ?", loadID).First(&load).Error; err != nil {
-			tx.Rollback()
-			return nil, fmt.Errorf("load not found")
+		if err := models.CheckVersion(ctx, "load", load.Version); err != nil {
+			return err
 		}
-	} else {
-		if err := tx.Where("id = ?", loadID).First(&load).Error; err != nil {
-			tx.Rollback()
-			return nil, fmt.Errorf("load not found")
+
+		previous := load.Status
+		if err := updateVersioned(tx, load, &load.Version, "load", map[string]interface{}{"status": status}); err != nil {
+			return fmt.Errorf("failed to update load status: %w", err)
 		}
+
+		return rec.record(tx, models.NewEvent(models.EntityLoad, load.LoadID, models.EventLoadStatusChanged, actor,
+			models.EventData{"status": previous}, models.EventData{"status": status}).For(load.ShipperID, ""))
+	})
+}
+
+// findLoad loads a load by LoadID or numeric ID inside a transaction
+func findLoad(tx *gorm.DB, id string) (*models.Load, error) {
+	var load models.Load
+	if err := findByID(tx, &load, "load_id", id, "load"); err != nil {
+		return nil, err
 	}
+	return &load, nil
+}
 
-	if load.Status != "available" {
-		tx.Rollback()
-		return nil, fmt.Errorf("load not available")
+// findBooking loads a booking by BookingID or numeric ID inside a transaction
+func findBooking(tx *gorm.DB, id string) (*models.Booking, error) {
+	var booking models.Booking
+	if err := findByID(tx, &booking, "booking_id", id, "booking"); err != nil {
+		return nil, err
 	}
+	return &booking, nil
+}
 
-	// Get trucker with proper ID handling
-	var trucker models.Trucker
-	if strings.HasPrefix(truckerID, "TR") {
-		if err := tx.Where("trucker_id = ?", truckerID).First(&trucker).Error; err != nil {
-			tx.Rollback()
-			return nil, fmt.Errorf("trucker not found")
+// findByID loads a row by its string ID column (load_id, trucker_id, ...) and
+// falls back to the numeric primary key, matching the memory store's lookups
+func findByID(tx *gorm.DB, dest interface{}, column, id, entity string) error {
+	err := tx.Where(column+" = ?", id).First(dest).Error
+	if err == gorm.ErrRecordNotFound {
+		if numericID, parseErr := strconv.ParseUint(id, 10, 64); parseErr == nil {
+			err = tx.Where("id = ?", numericID).First(dest).Error
 		}
+	}
+	if err == gorm.ErrRecordNotFound {
+		return models.NotFound(entity)
+	}
+	if err != nil {
+		return fmt.Errorf("database error: %w", err)
+	}
+	return nil
+}
+
+// versionChanged reports a row updated by another transaction since it was
+// read; transaction retries on it
+type versionChanged struct {
+	entity string
+}
+
+func (e *versionChanged) Error() string {
+	return e.entity + " was changed by another request"
+}
+
+// recurringLoadChanged reports an update of a recurring load made from a stale copy
+func recurringLoadChanged() error {
+	return models.Conflict("recurring load", "version", "recurring load was changed by someone else, please try again")
+}
+
+// updateVersioned applies updates to the row model was read from only if the
+// row is still at the version read, and increments the version. This is what
+// stops two bookings racing for the last truck of a load from both winning.
+func updateVersioned(tx *gorm.DB, model interface{}, version *uint, entity string, updates map[string]interface{}) error {
+	next := *version + 1
+	updates["version"] = next
+	result := tx.Model(model).Where("version = ?", *version).Updates(updates)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return &versionChanged{entity: entity}
+	}
+	*version = next
+	return nil
+}
+
+// uniqueConflict turns a violation of the unique index on one of the
+// columns into the Conflict the store reports for it, with its message,
+// and returns any other error unchanged
+func uniqueConflict(err error, entity string, columns map[string]string) error {
+	var column string
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" { // unique_violation
+		column = pgErr.ConstraintName // e.g. idx_truckers_phone
+	} else if _, failed, found := strings.Cut(err.Error(), "UNIQUE constraint failed: "); found {
+		// SQLite: UNIQUE constraint failed: truckers.phone (2067)
+		column, _, _ = strings.Cut(failed, " ")
 	} else {
-		if err := tx.Where("id = ?", truckerID).First(&trucker).Error; err != nil {
-			tx.Rollback()
-			return nil, fmt.Errorf("trucker not found")
+		return err
+	}
+	for name, message := range columns {
+		if strings.HasSuffix(column, "_"+name) || strings.HasSuffix(column, "."+name) {
+			return models.Conflict(entity, name, message)
 		}
 	}
+	return err
+}
 
-	// Create booking using the actual model IDs (not the input parameters)
-	now := time.Now()
-	booking := &models.Booking{
-		LoadID:        load.LoadID,       // Use the actual LoadID from the model
-		TruckerID:     trucker.TruckerID, // Use the actual TruckerID from the model
-		ShipperID:     load.ShipperID,
-		AgreedPrice:   load.Price,
-		Commission:    load.Price * 0.05, // 5% commission
-		NetAmount:     load.Price * 0.95,
-		Status:        models.BookingStatusConfirmed,
-		PaymentStatus: models.PaymentStatusPending,
-		ConfirmedAt:   &now,
+// Load expiry operations
+func (d *DatabaseStore) ExpireLoads(ctx context.Context, now time.Time, actor models.Actor) ([]*models.Load, error) {
+	var expired []*models.Load
+	err := d.transaction(ctx, func(tx *gorm.DB, rec *eventRecorder) error {
+		if err := tx.Where("status = ? AND expires_at <= ?", models.LoadStatusAvailable, now).
+			Order("id ASC").
+			Find(&expired).Error; err != nil {
+			return fmt.Errorf("failed to fetch expired loads: %w", err)
+		}
+
+		for _, load := range expired {
+			// A partly booked load keeps its bookings; only the unbooked share is unlisted
+			status := models.LoadStatusExpired
+			if load.TrucksBooked > 0 {
+				status = models.LoadStatusBooked
+			}
+
+			// Only the version read above is expired, so a booking committed
+			// since, which changed TrucksBooked, leaves the load alone
+			err := updateVersioned(tx, load, &load.Version, "load", map[string]interface{}{"status": status})
+			var changed *versionChanged
+			if errors.As(err, &changed) {
+				continue
+			}
+			if err != nil {
+				return fmt.Errorf("failed to expire load: %w", err)
+			}
+			load.Status = status
+
+			if err := rec.record(tx, models.NewEvent(models.EntityLoad, load.LoadID, models.EventLoadStatusChanged, actor,
+				models.EventData{"status": models.LoadStatusAvailable}, models.EventData{"status": status, "expires_at": load.ExpiresAt}).For(load.ShipperID, "")); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	// BookingID and OTP will be auto-generated by BeforeCreate hook
-	if err := tx.Create(booking).Error; err != nil {
-		tx.Rollback()
-		return nil, fmt.Errorf("failed to create booking: %w", err)
+	// Drop the loads changed between the select and the update
+	var changed []*models.Load
+	for _, load := range expired {
+		if !load.IsAvailable() {
+			changed = append(changed, load)
+		}
 	}
+	return changed, nil
+}
 
-	// Update load status
-	if err := tx.Model(&load).Update("status", "booked").Error; err != nil {
-		tx.Rollback()
-		return nil, fmt.Errorf("failed to update load status: %w", err)
+func (d *DatabaseStore) GetLoadsExpiringBefore(ctx context.Context, before time.Time) ([]*models.Load, error) {
+	var loads []*models.Load
+	if err := d.db.WithContext(ctx).Where("status = ? AND expires_at < ? AND expiry_reminded_at IS NULL", models.LoadStatusAvailable, before).
+		Order("id ASC").
+		Find(&loads).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch expiring loads: %w", err)
 	}
+	return loads, nil
+}
 
-	// Update trucker availability
-	if err := tx.Model(&trucker).Update("available", false).Error; err != nil {
-		tx.Rollback()
-		return nil, fmt.Errorf("failed to update trucker availability: %w", err)
+func (d *DatabaseStore) MarkLoadExpiryReminded(ctx context.Context, id string, at time.Time) error {
+	load, err := findLoad(d.db.WithContext(ctx), id)
+	if err != nil {
+		return err
+	}
+	if err := d.db.WithContext(ctx).Model(load).Update("expiry_reminded_at", at).Error; err != nil {
+		return fmt.Errorf("failed to update load: %w", err)
 	}
+	return nil
+}
+
+func (d *DatabaseStore) RenewLoad(ctx context.Context, id string, loadingDate time.Time, price float64, actor models.Actor) (*models.Load, error) {
+	var load *models.Load
+	err := d.transaction(ctx, func(tx *gorm.DB, rec *eventRecorder) error {
+		var err error
+		if load, err = findLoad(tx, id); err != nil {
+			return err
+		}
+		if !load.IsAvailable() && load.Status != models.LoadStatusExpired {
+			return models.Unavailable("load", "", "load not available")
+		}
+		if err := models.CheckVersion(ctx, "load", load.Version); err != nil {
+			return err
+		}
 
-	// Commit transaction
-	if err := tx.Commit().Error; err != nil {
-		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+		before := models.EventData{"status": load.Status, "loading_date": load.LoadingDate, "expires_at": load.ExpiresAt, "price": load.Price}
+		load.Renew(loadingDate, price)
+		if err := updateVersioned(tx, load, &load.Version, "load", map[string]interface{}{
+			"status":             load.Status,
+			"loading_date":       load.LoadingDate,
+			"expires_at":         load.ExpiresAt,
+			"expiry_reminded_at": nil,
+			"price":              load.Price,
+		}); err != nil {
+			return fmt.Errorf("failed to renew load: %w", err)
+		}
+
+		return rec.record(tx, models.NewEvent(models.EntityLoad, load.LoadID, models.EventLoadRenewed, actor, before,
+			models.EventData{"status": load.Status, "loading_date": load.LoadingDate, "expires_at": load.ExpiresAt, "price": load.Price}).For(load.ShipperID, ""))
+	})
+	if err != nil {
+		return nil, err
 	}
+	return load, nil
+}
 
-	return booking, nil
+// Load editing operations
+func (d *DatabaseStore) UpdateLoad(ctx context.Context, id string, changes *models.LoadChanges, actor models.Actor) (*models.Load, error) {
+	var load *models.Load
+	err := d.transaction(ctx, func(tx *gorm.DB, rec *eventRecorder) error {
+		var err error
+		if load, err = findLoad(tx, id); err != nil {
+			return err
+		}
+		if !load.IsEditable() {
+			return models.Unavailable("load", "", "load not available")
+		}
+		if err := models.CheckVersion(ctx, "load", load.Version); err != nil {
+			return err
+		}
+
+		before, after := changes.Apply(load)
+		if len(after) == 0 {
+			return nil
+		}
+		// A booking made since the load was read changes its version, so the
+		// retry finds it no longer editable
+		if err := updateVersioned(tx, load, &load.Version, "load", map[string]interface{}{
+			"price":              load.Price,
+			"loading_date":       load.LoadingDate,
+			"expires_at":         load.ExpiresAt,
+			"expiry_reminded_at": load.ExpiryRemindedAt,
+			"vehicle_type":       load.VehicleType,
+		}); err != nil {
+			return fmt.Errorf("failed to update load: %w", err)
+		}
+
+		return rec.record(tx, models.NewEvent(models.EntityLoad, load.LoadID, models.EventLoadUpdated, actor, before, after).For(load.ShipperID, ""))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return load, nil
 }
 
-func (d *DatabaseStore) GetBooking(id string) (*models.Booking, error) {
-	var booking models.Booking
+func (d *DatabaseStore) WithdrawLoad(ctx context.Context, id string, actor models.Actor) (*models.Load, error) {
+	var load *models.Load
+	err := d.transaction(ctx, func(tx *gorm.DB, rec *eventRecorder) error {
+		var err error
+		if load, err = findLoad(tx, id); err != nil {
+			return err
+		}
+		if !load.IsEditable() {
+			return models.Unavailable("load", "", "load not available")
+		}
+		if err := models.CheckVersion(ctx, "load", load.Version); err != nil {
+			return err
+		}
 
-	// Check if it's a BookingID (starts with "BK") or numeric ID
-	if strings.HasPrefix(id, "BK") {
-		// Search by BookingID only
-		if err := d.db.Where("booking_id = ?", id).First(&booking).Error; err != nil {
-			if err == gorm.ErrRecordNotFound {
-				return nil, fmt.Errorf("booking not found")
-			}
-			return nil, fmt.Errorf("database error: %w", err)
+		// A booking made since the load was read changes its version, so the
+		// retry finds it no longer editable
+		previous := load.Status
+		if err := updateVersioned(tx, load, &load.Version, "load", map[string]interface{}{"status": models.LoadStatusWithdrawn}); err != nil {
+			return fmt.Errorf("failed to withdraw load: %w", err)
 		}
-	} else {
-		// Try to parse as numeric ID
-		if err := d.db.Where("id = ?", id).First(&booking).Error; err != nil {
-			if err == gorm.ErrRecordNotFound {
-				return nil, fmt.Errorf("booking not found")
+		load.Status = models.LoadStatusWithdrawn
+		return rec.record(tx, models.NewEvent(models.EntityLoad, load.LoadID, models.EventLoadStatusChanged, actor,
+			models.EventData{"status": previous}, models.EventData{"status": load.Status}).For(load.ShipperID, ""))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return load, nil
+}
+
+// Booking operations
+func (d *DatabaseStore) CreateBooking(ctx context.Context, loadID, truckerID string, weight float64, actor models.Actor) (*models.Booking, error) {
+	var booking *models.Booking
+	err := d.transaction(ctx, func(tx *gorm.DB, rec *eventRecorder) error {
+		load, err := findLoad(tx, loadID)
+		if err != nil {
+			return err
+		}
+		var trucker models.Trucker
+		if err := findByID(tx, &trucker, "trucker_id", truckerID, "trucker"); err != nil {
+			return err
+		}
+
+		var active []*models.Booking
+		if err := tx.Where("trucker_id = ? AND status IN ?", trucker.TruckerID, models.ActiveBookingStatuses).Find(&active).Error; err != nil {
+			return fmt.Errorf("database error: %w", err)
+		}
+
+		weight, price, err := load.Allocate(weight, &trucker, active)
+		if err != nil {
+			return err
+		}
+
+		// Create booking using the actual model IDs (not the input parameters)
+		now := time.Now()
+		booking = &models.Booking{
+			LoadID:        load.LoadID,       // Use the actual LoadID from the model
+			TruckerID:     trucker.TruckerID, // Use the actual TruckerID from the model
+			ShipperID:     load.ShipperID,
+			AgreedPrice:   price,
+			Commission:    price * 0.05, // 5% commission
+			NetAmount:     price * 0.95,
+			Weight:        weight,
+			PartLoad:      load.Splittable,
+			Status:        models.BookingStatusConfirmed,
+			PaymentStatus: models.PaymentStatusPending,
+			ConfirmedAt:   &now,
+		}
+
+		// BookingID and OTP will be auto-generated by BeforeCreate hook
+		if err := tx.Create(booking).Error; err != nil {
+			return fmt.Errorf("failed to create booking: %w", err)
+		}
+
+		// Allocate the share, booking the load once fully allocated. If
+		// another booking took a share since the load was read, the versioned
+		// update fails and the retry sees what is left.
+		before := models.EventData{"status": load.Status, "trucks_booked": load.TrucksBooked, "booked_weight": load.BookedWeight}
+		load.AddBooking(weight)
+		if err := updateVersioned(tx, load, &load.Version, "load", map[string]interface{}{
+			"status":        load.Status,
+			"trucks_booked": load.TrucksBooked,
+			"booked_weight": load.BookedWeight,
+		}); err != nil {
+			return fmt.Errorf("failed to update load status: %w", err)
+		}
+		after := models.EventData{"status": load.Status, "trucks_booked": load.TrucksBooked, "booked_weight": load.BookedWeight, "booking_id": booking.BookingID}
+
+		// Update trucker availability, guarded the same way against the
+		// trucker booking another load at the same moment
+		wasAvailable := trucker.Available
+		if err := updateVersioned(tx, &trucker, &trucker.Version, "trucker", map[string]interface{}{"available": false}); err != nil {
+			return fmt.Errorf("failed to update trucker availability: %w", err)
+		}
+
+		// Record events for every entity touched by the booking
+		loadEvent := models.EventLoadAllocated
+		if load.Status == models.LoadStatusBooked {
+			loadEvent = models.EventLoadStatusChanged
+		}
+		bookingEvents := []*models.Event{
+			models.NewEvent(models.EntityBooking, booking.BookingID, models.EventBookingCreated, actor, nil,
+				models.EventData{"status": booking.Status, "load_id": booking.LoadID, "trucker_id": booking.TruckerID, "agreed_price": booking.AgreedPrice, "weight": booking.Weight}).For(booking.ShipperID, booking.TruckerID),
+			models.NewEvent(models.EntityLoad, load.LoadID, loadEvent, actor, before, after).For(load.ShipperID, trucker.TruckerID),
+		}
+		if wasAvailable {
+			bookingEvents = append(bookingEvents, models.NewEvent(models.EntityTrucker, trucker.TruckerID, models.EventTruckerUpdated, actor,
+				models.EventData{"available": true}, models.EventData{"available": false, "booking_id": booking.BookingID}).For("", trucker.TruckerID))
+		}
+		for _, event := range bookingEvents {
+			if err := rec.record(tx, event); err != nil {
+				return err
 			}
-			return nil, fmt.Errorf("database error: %w", err)
 		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
+	return booking, nil
+}
 
-	return &booking, nil
+func (d *DatabaseStore) GetBooking(ctx context.Context, id string) (*models.Booking, error) {
+	return findBooking(d.db.WithContext(ctx), id)
 }
 
-func (d *DatabaseStore) GetBookingsByTrucker(truckerID string) ([]*models.Booking, error) {
+func (d *DatabaseStore) GetBookingsByTrucker(ctx context.Context, truckerID string) ([]*models.Booking, error) {
 	var bookings []*models.Booking
-	if err := d.db.Where("trucker_id = ?", truckerID).
-		Order("created_at DESC").
+	if err := d.db.WithContext(ctx).Where("trucker_id = ?", truckerID).
+		Order("created_at DESC, id DESC").
 		Find(&bookings).Error; err != nil {
 		return nil, fmt.Errorf("failed to fetch bookings: %w", err)
 	}
 	return bookings, nil
 }
 
-func (d *DatabaseStore) GetBookingsByLoad(loadID string) ([]*models.Booking, error) {
+func (d *DatabaseStore) GetBookingsByLoad(ctx context.Context, loadID string) ([]*models.Booking, error) {
 	var bookings []*models.Booking
-	if err := d.db.Where("load_id = ?", loadID).
-		Order("created_at DESC").
+	if err := d.db.WithContext(ctx).Where("load_id = ?", loadID).
+		Order("created_at DESC, id DESC").
 		Find(&bookings).Error; err != nil {
 		return nil, fmt.Errorf("failed to fetch bookings: %w", err)
 	}
 	return bookings, nil
 }
 
-func (d *DatabaseStore) UpdateBookingStatus(id string, status string) error {
-	var result *gorm.DB
+func (d *DatabaseStore) UpdateBookingStatus(ctx context.Context, id string, status string, actor models.Actor) error {
+	return d.transaction(ctx, func(tx *gorm.DB, rec *eventRecorder) error {
+		booking, err := findBooking(tx, id)
+		if err != nil {
+			return err
+		}
+		if err := models.CheckVersion(ctx, "booking", booking.Version); err != nil {
+			return err
+		}
 
-	// Check if it's a BookingID (starts with "BK") or numeric ID
-	if strings.HasPrefix(id, "BK") {
-		result = d.db.Model(&models.Booking{}).
-			Where("booking_id = ?", id).
-			Update("status", status)
-	} else {
-		result = d.db.Model(&models.Booking{}).
-			Where("id = ?", id).
-			Update("status", status)
+		// Update timestamps based on status
+		now := time.Now()
+		updates := map[string]interface{}{"status": status}
+		switch status {
+		case models.BookingStatusInTransit:
+			updates["picked_up_at"] = now
+		case models.BookingStatusDelivered:
+			updates["delivered_at"] = now
+		case models.BookingStatusCompleted:
+			updates["completed_at"] = now
+			updates["payment_status"] = models.PaymentStatusCompleted
+		}
+
+		previous := booking.Status
+		if err := updateVersioned(tx, booking, &booking.Version, "booking", updates); err != nil {
+			return fmt.Errorf("failed to update booking status: %w", err)
+		}
+
+		if err := rec.record(tx, models.NewEvent(models.EntityBooking, booking.BookingID, models.EventBookingStatusChanged, actor,
+			models.EventData{"status": previous}, models.EventData{"status": status}).For(booking.ShipperID, booking.TruckerID)); err != nil {
+			return err
+		}
+
+		if status == models.BookingStatusDelivered {
+			return deliverBooking(tx, rec, booking, actor)
+		}
+		return nil
+	})
+}
+
+// deliverBooking marks the load delivered once it is closed to bookings and
+// every truck on it has delivered, and makes the trucker available again at
+// the load's destination unless the truck is still carrying other part loads
+func deliverBooking(tx *gorm.DB, rec *eventRecorder, booking *models.Booking, actor models.Actor) error {
+	var destination *models.Load
+	if load, err := findLoad(tx, booking.LoadID); err == nil {
+		destination = load
+
+		var inProgress int64
+		if err := tx.Model(&models.Booking{}).
+			Where("load_id = ? AND status IN ?", load.LoadID, models.ActiveBookingStatuses).
+			Count(&inProgress).Error; err != nil {
+			return fmt.Errorf("database error: %w", err)
+		}
+		if !load.IsAvailable() && inProgress == 0 {
+			previous := load.Status
+			if err := updateVersioned(tx, load, &load.Version, "load", map[string]interface{}{"status": models.LoadStatusDelivered}); err != nil {
+				return fmt.Errorf("failed to update load status: %w", err)
+			}
+			if err := rec.record(tx, models.NewEvent(models.EntityLoad, load.LoadID, models.EventLoadStatusChanged, actor,
+				models.EventData{"status": previous}, models.EventData{"status": models.LoadStatusDelivered}).For(load.ShipperID, booking.TruckerID)); err != nil {
+				return err
+			}
+		}
+	} else if !errors.Is(err, models.ErrNotFound) {
+		return err
 	}
 
-	if result.Error != nil {
-		return fmt.Errorf("failed to update booking status: %w", result.Error)
+	var trucker models.Trucker
+	if err := tx.Where("trucker_id = ?", booking.TruckerID).First(&trucker).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil
+		}
+		return fmt.Errorf("database error: %w", err)
 	}
-	if result.RowsAffected == 0 {
-		return fmt.Errorf("booking not found")
+
+	var active int64
+	if err := tx.Model(&models.Booking{}).
+		Where("trucker_id = ? AND status IN ?", trucker.TruckerID, models.ActiveBookingStatuses).
+		Count(&active).Error; err != nil {
+		return fmt.Errorf("database error: %w", err)
 	}
-	return nil
+
+	before := models.EventData{"available": trucker.Available, "total_trips": trucker.TotalTrips, "current_city": trucker.CurrentCity}
+	trucker.Available = active == 0
+	trucker.TotalTrips++
+	if destination != nil {
+		trucker.UpdateLocation(destination.ToCity, destination.ToCityID)
+	}
+	if err := updateVersioned(tx, &trucker, &trucker.Version, "trucker", map[string]interface{}{
+		"available":       trucker.Available,
+		"total_trips":     trucker.TotalTrips,
+		"current_city":    trucker.CurrentCity,
+		"current_city_id": trucker.CurrentCityID,
+	}); err != nil {
+		return fmt.Errorf("failed to update trucker: %w", err)
+	}
+
+	return rec.record(tx, models.NewEvent(models.EntityTrucker, trucker.TruckerID, models.EventTruckerUpdated, actor, before,
+		models.EventData{"available": trucker.Available, "total_trips": trucker.TotalTrips, "current_city": trucker.CurrentCity}).For("", trucker.TruckerID))
+}
+
+func (d *DatabaseStore) UpdateBookingPOD(ctx context.Context, id string, podURL string, actor models.Actor) error {
+	return d.transaction(ctx, func(tx *gorm.DB, rec *eventRecorder) error {
+		booking, err := findBooking(tx, id)
+		if err != nil {
+			return err
+		}
+		if err := models.CheckVersion(ctx, "booking", booking.Version); err != nil {
+			return err
+		}
+
+		previous := booking.PodURL
+		if err := updateVersioned(tx, booking, &booking.Version, "booking", map[string]interface{}{"pod_url": podURL}); err != nil {
+			return fmt.Errorf("failed to update proof of delivery: %w", err)
+		}
+
+		return rec.record(tx, models.NewEvent(models.EntityBooking, booking.BookingID, models.EventPODUploaded, actor,
+			models.EventData{"pod_url": previous}, models.EventData{"pod_url": podURL}).For(booking.ShipperID, booking.TruckerID))
+	})
+}
+
+// bookingSortColumns maps sort options to SQL expressions
+var bookingSortColumns = map[string]string{
+	models.SortPrice:     "agreed_price",
+	models.SortCreatedAt: "created_at",
+}
+
+func (d *DatabaseStore) ListBookings(ctx context.Context, filter *models.BookingFilter, opts *models.ListOptions) (*models.BookingPage, error) {
+	if err := opts.Normalize(models.BookingSorts); err != nil {
+		return nil, err
+	}
+
+	query := d.db.WithContext(ctx).Model(&models.Booking{})
+	if filter.TruckerID != "" {
+		query = query.Where("trucker_id = ?", filter.TruckerID)
+	}
+	if filter.ShipperID != "" {
+		query = query.Where("shipper_id = ?", filter.ShipperID)
+	}
+	if filter.LoadID != "" {
+		query = query.Where("load_id = ?", filter.LoadID)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+
+	var bookings []*models.Booking
+	if err := paginate(query, opts, bookingSortColumns[opts.Sort]).Find(&bookings).Error; err != nil {
+		return nil, fmt.Errorf("failed to list bookings: %w", err)
+	}
+
+	page := &models.BookingPage{Bookings: bookings}
+	if len(bookings) > opts.Limit {
+		page.Bookings = bookings[:opts.Limit]
+		page.NextCursor = page.Bookings[opts.Limit-1].SortKey(opts.Sort).Cursor()
+	}
+	return page, nil
+}
+
+// paginate orders a query by the sort expression (ID breaking ties), seeks past
+// the cursor and fetches one extra row to tell whether another page follows
+func paginate(query *gorm.DB, opts *models.ListOptions, column string) *gorm.DB {
+	dir, cmp := "ASC", ">"
+	if opts.Order == models.SortDesc {
+		dir, cmp = "DESC", "<"
+	}
+
+	if opts.Cursor != "" {
+		cursor, _ := models.ParseCursor(opts.Cursor)
+		var value interface{} = cursor.Value
+		if models.IsTimeSort(opts.Sort) {
+			value = cursor.Time
+		}
+		query = query.Where(fmt.Sprintf("(%s %s ? OR (%s = ? AND id %s ?))", column, cmp, column, cmp), value, value, cursor.ID)
+	}
+
+	return query.Order(fmt.Sprintf("%s %s, id %s", column, dir, dir)).Limit(opts.Limit + 1)
 }
 
 // Shipper operations
-func (d *DatabaseStore) CreateShipper(shipper *models.Shipper) (*models.Shipper, error) {
+func (d *DatabaseStore) CreateShipper(ctx context.Context, shipper *models.Shipper, actor models.Actor) (*models.Shipper, error) {
 	// Check if phone already exists
 	var existing models.Shipper
-	if err := d.db.Where("phone = ?", shipper.Phone).First(&existing).Error; err == nil {
-		return nil, fmt.Errorf("phone number already registered")
+	if err := d.db.WithContext(ctx).Where("phone = ?", shipper.Phone).First(&existing).Error; err == nil {
+		return nil, models.Conflict("shipper", "phone", "phone number already registered")
 	}
 
 	// Check if GST already exists
-	if err := d.db.Where("gst_number = ?", shipper.GSTNumber).First(&existing).Error; err == nil {
-		return nil, fmt.Errorf("GST number already registered")
+	if err := d.db.WithContext(ctx).Where("gst_number = ?", shipper.GSTNumber).First(&existing).Error; err == nil {
+		return nil, models.Conflict("shipper", "gst_number", "GST number already registered")
 	}
 
 	// ShipperID will be auto-generated by BeforeCreate hook
-	if err := d.db.Create(shipper).Error; err != nil {
+	shipper.Active = true
+	err := d.transaction(ctx, func(tx *gorm.DB, rec *eventRecorder) error {
+		if err := tx.Create(shipper).Error; err != nil {
+			// Registered concurrently since the checks above
+			return uniqueConflict(err, "shipper", map[string]string{
+				"phone":      "phone number already registered",
+				"gst_number": "GST number already registered",
+			})
+		}
+		return rec.record(tx, models.NewEvent(models.EntityShipper, shipper.ShipperID, models.EventShipperRegistered, actor, nil,
+			models.EventData{"company_name": shipper.CompanyName, "gst_number": shipper.GSTNumber}).For(shipper.ShipperID, ""))
+	})
+	if err != nil {
 		return nil, fmt.Errorf("failed to create shipper: %w", err)
 	}
 
 	return shipper, nil
 }
 
-func (d *DatabaseStore) GetShipper(id string) (*models.Shipper, error) {
+func (d *DatabaseStore) GetShipper(ctx context.Context, id string) (*models.Shipper, error) {
 	var shipper models.Shipper
-
-	// Check if it's a ShipperID (starts with "SH") or numeric ID
-	if strings.HasPrefix(id, "SH") {
-		if err := d.db.Where("shipper_id = ?", id).First(&shipper).Error; err != nil {
-			if err == gorm.ErrRecordNotFound {
-				return nil, fmt.Errorf("shipper not found")
-			}
-			return nil, fmt.Errorf("database error: %w", err)
-		}
-	} else {
-		if err := d.db.Where("id = ?", id).First(&shipper).Error; err != nil {
-			if err == gorm.ErrRecordNotFound {
-				return nil, fmt.Errorf("shipper not found")
-			}
-			return nil, fmt.Errorf("database error: %w", err)
-		}
+	if err := findByID(d.db.WithContext(ctx), &shipper, "shipper_id", id, "shipper"); err != nil {
+		return nil, err
 	}
-
 	return &shipper, nil
 }
 
-func (d *DatabaseStore) GetShipperByPhone(phone string) (*models.Shipper, error) {
+func (d *DatabaseStore) GetShipperByPhone(ctx context.Context, phone string) (*models.Shipper, error) {
 	var shipper models.Shipper
-	if err := d.db.Where("phone = ?", phone).First(&shipper).Error; err != nil {
+	if err := d.db.WithContext(ctx).Where("phone = ?", phone).First(&shipper).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
-			return nil, fmt.Errorf("shipper not found")
+			return nil, models.NotFound("shipper")
 		}
 		return nil, fmt.Errorf("database error: %w", err)
 	}
 	return &shipper, nil
 }
 
-func (d *DatabaseStore) GetShipperByGST(gst string) (*models.Shipper, error) {
+func (d *DatabaseStore) GetShipperByGST(ctx context.Context, gst string) (*models.Shipper, error) {
 	var shipper models.Shipper
-	if err := d.db.Where("gst_number = ?", gst).First(&shipper).Error; err != nil {
+	if err := d.db.WithContext(ctx).Where("gst_number = ?", gst).First(&shipper).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
-			return nil, fmt.Errorf("shipper not found")
+			return nil, models.NotFound("shipper")
 		}
 		return nil, fmt.Errorf("database error: %w", err)
 	}
 	return &shipper, nil
 }
 
-func (d *DatabaseStore) GetLoadsByShipper(shipperID string) ([]*models.Load, error) {
+func (d *DatabaseStore) GetLoadsByShipper(ctx context.Context, shipperID string) ([]*models.Load, error) {
 	var loads []*models.Load
-	if err := d.db.Where("shipper_id = ?", shipperID).
-		Order("created_at DESC").
+	if err := d.db.WithContext(ctx).Where("shipper_id = ?", shipperID).
+		Order("created_at DESC, id DESC").
 		Find(&loads).Error; err != nil {
 		return nil, fmt.Errorf("failed to fetch loads: %w", err)
 	}
 	return loads, nil
 }
+
+// Account and listing administration
+
+// DeactivateTrucker takes a trucker off the platform, cancelling the
+// bookings they have not picked up yet
+func (d *DatabaseStore) DeactivateTrucker(ctx context.Context, id string, actor models.Actor) (*models.Trucker, error) {
+	return d.closeTrucker(ctx, id, false, actor)
+}
+
+// DeleteTrucker deactivates a trucker and soft-deletes them
+func (d *DatabaseStore) DeleteTrucker(ctx context.Context, id string, actor models.Actor) error {
+	_, err := d.closeTrucker(ctx, id, true, actor)
+	return err
+}
+
+func (d *DatabaseStore) closeTrucker(ctx context.Context, id string, remove bool, actor models.Actor) (*models.Trucker, error) {
+	var trucker models.Trucker
+	err := d.transaction(ctx, func(tx *gorm.DB, rec *eventRecorder) error {
+		return closeTrucker(ctx, tx, rec, &trucker, id, remove, actor)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &trucker, nil
+}
+
+// closeTrucker deactivates the trucker with the given ID inside a
+// transaction, deleting it too if remove is set, and reads it into trucker
+func closeTrucker(ctx context.Context, tx *gorm.DB, rec *eventRecorder, trucker *models.Trucker, id string, remove bool, actor models.Actor) error {
+	if err := findByID(tx, trucker, "trucker_id", id, "trucker"); err != nil {
+		return err
+	}
+	if !remove && !trucker.Active {
+		return nil
+	}
+	if err := models.CheckVersion(ctx, "trucker", trucker.Version); err != nil {
+		return err
+	}
+	open, err := openBookings(tx, "trucker", "trucker_id", trucker.TruckerID)
+	if err != nil {
+		return err
+	}
+
+	// Deactivate first so cancelling the bookings leaves the truck unavailable
+	before := models.EventData{"active": trucker.Active, "available": trucker.Available}
+	updates := map[string]interface{}{"active": false, "available": false}
+	eventType := models.EventTruckerDeactivated
+	if remove {
+		trucker.DeletedAt = gorm.DeletedAt{Time: time.Now(), Valid: true}
+		updates["deleted_at"] = trucker.DeletedAt
+		eventType = models.EventTruckerDeleted
+	}
+	if err := updateVersioned(tx, trucker, &trucker.Version, "trucker", updates); err != nil {
+		return fmt.Errorf("failed to deactivate trucker: %w", err)
+	}
+	trucker.Active, trucker.Available = false, false
+
+	if err := cancelBookings(tx, rec, open, actor); err != nil {
+		return err
+	}
+	return rec.record(tx, models.NewEvent(models.EntityTrucker, trucker.TruckerID, eventType, actor, before,
+		models.EventData{"active": false, "available": false, "bookings_cancelled": len(open)}).For("", trucker.TruckerID))
+}
+
+// RestoreTrucker undeletes and reactivates a trucker
+func (d *DatabaseStore) RestoreTrucker(ctx context.Context, id string, actor models.Actor) (*models.Trucker, error) {
+	var trucker models.Trucker
+	err := d.transaction(ctx, func(tx *gorm.DB, rec *eventRecorder) error {
+		if err := findByID(tx.Unscoped(), &trucker, "trucker_id", id, "trucker"); err != nil {
+			return err
+		}
+		deleted := trucker.DeletedAt.Valid
+		if !deleted && trucker.Active {
+			return nil
+		}
+
+		var existing models.Trucker
+		if err := tx.Where("phone = ? AND id <> ?", trucker.Phone, trucker.ID).First(&existing).Error; err == nil {
+			return models.Conflict("trucker", "phone", "phone number registered again since")
+		}
+		if err := tx.Where("vehicle_no = ? AND id <> ?", trucker.VehicleNo, trucker.ID).First(&existing).Error; err == nil {
+			return models.Conflict("trucker", "vehicle_no", "vehicle registered again since")
+		}
+
+		// Deactivation cancelled every booking, so the truck is free
+		if err := updateVersioned(tx.Unscoped(), &trucker, &trucker.Version, "trucker", map[string]interface{}{
+			"deleted_at": nil,
+			"active":     true,
+			"available":  true,
+		}); err != nil {
+			return fmt.Errorf("failed to restore trucker: %w", uniqueConflict(err, "trucker", map[string]string{
+				"phone":      "phone number registered again since",
+				"vehicle_no": "vehicle registered again since",
+			}))
+		}
+		trucker.DeletedAt = gorm.DeletedAt{}
+		trucker.Active, trucker.Available = true, true
+
+		return rec.record(tx, models.NewEvent(models.EntityTrucker, trucker.TruckerID, models.EventTruckerRestored, actor,
+			models.EventData{"active": false, "deleted": deleted}, models.EventData{"active": true, "available": true}).For("", trucker.TruckerID))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &trucker, nil
+}
+
+// DeactivateShipper stops a shipper posting loads, withdrawing their open
+// loads and cancelling the bookings on them not picked up yet
+func (d *DatabaseStore) DeactivateShipper(ctx context.Context, id string, actor models.Actor) (*models.Shipper, error) {
+	return d.closeShipper(ctx, id, false, actor)
+}
+
+// DeleteShipper deactivates a shipper and soft-deletes them; their loads
+// stay, withdrawn, for the bookings made on them
+func (d *DatabaseStore) DeleteShipper(ctx context.Context, id string, actor models.Actor) error {
+	_, err := d.closeShipper(ctx, id, true, actor)
+	return err
+}
+
+func (d *DatabaseStore) closeShipper(ctx context.Context, id string, remove bool, actor models.Actor) (*models.Shipper, error) {
+	var shipper models.Shipper
+	err := d.transaction(ctx, func(tx *gorm.DB, rec *eventRecorder) error {
+		return closeShipper(tx, rec, &shipper, id, remove, actor)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &shipper, nil
+}
+
+// closeShipper deactivates the shipper with the given ID inside a
+// transaction, deleting it too if remove is set, and reads it into shipper
+func closeShipper(tx *gorm.DB, rec *eventRecorder, shipper *models.Shipper, id string, remove bool, actor models.Actor) error {
+	if err := findByID(tx, shipper, "shipper_id", id, "shipper"); err != nil {
+		return err
+	}
+	if !remove && !shipper.Active {
+		return nil
+	}
+	open, err := openBookings(tx, "shipper", "shipper_id", shipper.ShipperID)
+	if err != nil {
+		return err
+	}
+
+	// Deactivate first so no load is posted while the open ones are withdrawn
+	if err := tx.Model(shipper).Update("active", false).Error; err != nil {
+		return fmt.Errorf("failed to deactivate shipper: %w", err)
+	}
+	if err := cancelBookings(tx, rec, open, actor); err != nil {
+		return err
+	}
+
+	var loads []*models.Load
+	if err := tx.Where("shipper_id = ? AND status = ?", shipper.ShipperID, models.LoadStatusAvailable).
+		Order("id ASC").
+		Find(&loads).Error; err != nil {
+		return fmt.Errorf("failed to fetch loads: %w", err)
+	}
+	for _, load := range loads {
+		if err := withdrawOpenLoad(tx, rec, load, actor); err != nil {
+			return err
+		}
+	}
+
+	eventType := models.EventShipperDeactivated
+	if remove {
+		if err := tx.Delete(shipper).Error; err != nil {
+			return fmt.Errorf("failed to delete shipper: %w", err)
+		}
+		eventType = models.EventShipperDeleted
+	}
+	return rec.record(tx, models.NewEvent(models.EntityShipper, shipper.ShipperID, eventType, actor, models.EventData{"active": true},
+		models.EventData{"active": false, "bookings_cancelled": len(open), "loads_withdrawn": len(loads)}).For(shipper.ShipperID, ""))
+}
+
+func (d *DatabaseStore) DeleteAccount(ctx context.Context, truckerID, shipperID string, actor models.Actor) error {
+	return d.transaction(ctx, func(tx *gorm.DB, rec *eventRecorder) error {
+		if truckerID != "" {
+			var trucker models.Trucker
+			if err := closeTrucker(ctx, tx, rec, &trucker, truckerID, true, actor); err != nil {
+				return err
+			}
+		}
+		if shipperID != "" {
+			var shipper models.Shipper
+			if err := closeShipper(tx, rec, &shipper, shipperID, true, actor); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// RestoreShipper undeletes and reactivates a shipper. Their withdrawn loads
+// stay withdrawn; they can post or renew them again.
+func (d *DatabaseStore) RestoreShipper(ctx context.Context, id string, actor models.Actor) (*models.Shipper, error) {
+	var shipper models.Shipper
+	err := d.transaction(ctx, func(tx *gorm.DB, rec *eventRecorder) error {
+		if err := findByID(tx.Unscoped(), &shipper, "shipper_id", id, "shipper"); err != nil {
+			return err
+		}
+		deleted := shipper.DeletedAt.Valid
+		if !deleted && shipper.Active {
+			return nil
+		}
+
+		var existing models.Shipper
+		if err := tx.Where("phone = ? AND id <> ?", shipper.Phone, shipper.ID).First(&existing).Error; err == nil {
+			return models.Conflict("shipper", "phone", "phone number registered again since")
+		}
+		if err := tx.Where("gst_number = ? AND id <> ?", shipper.GSTNumber, shipper.ID).First(&existing).Error; err == nil {
+			return models.Conflict("shipper", "gst_number", "GST number registered again since")
+		}
+
+		if err := tx.Unscoped().Model(&shipper).Updates(map[string]interface{}{"deleted_at": nil, "active": true}).Error; err != nil {
+			return fmt.Errorf("failed to restore shipper: %w", uniqueConflict(err, "shipper", map[string]string{
+				"phone":      "phone number registered again since",
+				"gst_number": "GST number registered again since",
+			}))
+		}
+		shipper.DeletedAt = gorm.DeletedAt{}
+		shipper.Active = true
+
+		return rec.record(tx, models.NewEvent(models.EntityShipper, shipper.ShipperID, models.EventShipperRestored, actor,
+			models.EventData{"active": false, "deleted": deleted}, models.EventData{"active": true}).For(shipper.ShipperID, ""))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &shipper, nil
+}
+
+// DeactivateLoad withdraws a load, cancelling the bookings on it not picked
+// up yet. A load that is no longer open is returned unchanged.
+func (d *DatabaseStore) DeactivateLoad(ctx context.Context, id string, actor models.Actor) (*models.Load, error) {
+	return d.closeLoad(ctx, id, false, actor)
+}
+
+// DeleteLoad withdraws a load if still open and soft-deletes it
+func (d *DatabaseStore) DeleteLoad(ctx context.Context, id string, actor models.Actor) error {
+	_, err := d.closeLoad(ctx, id, true, actor)
+	return err
+}
+
+func (d *DatabaseStore) closeLoad(ctx context.Context, id string, remove bool, actor models.Actor) (*models.Load, error) {
+	var load *models.Load
+	err := d.transaction(ctx, func(tx *gorm.DB, rec *eventRecorder) error {
+		var err error
+		if load, err = findLoad(tx, id); err != nil {
+			return err
+		}
+		if !remove && !load.IsOpen() {
+			return nil
+		}
+		if err := models.CheckVersion(ctx, "load", load.Version); err != nil {
+			return err
+		}
+		open, err := openBookings(tx, "load", "load_id", load.LoadID)
+		if err != nil {
+			return err
+		}
+		if err := cancelBookings(tx, rec, open, actor); err != nil {
+			return err
+		}
+
+		// Cancelling updated the load; withdraw what it left
+		if load, err = findLoad(tx, load.LoadID); err != nil {
+			return err
+		}
+		if err := withdrawOpenLoad(tx, rec, load, actor); err != nil {
+			return err
+		}
+
+		if !remove {
+			return nil
+		}
+		load.DeletedAt = gorm.DeletedAt{Time: time.Now(), Valid: true}
+		if err := updateVersioned(tx, load, &load.Version, "load", map[string]interface{}{"deleted_at": load.DeletedAt}); err != nil {
+			return fmt.Errorf("failed to delete load: %w", err)
+		}
+		return rec.record(tx, models.NewEvent(models.EntityLoad, load.LoadID, models.EventLoadDeleted, actor, nil,
+			models.EventData{"status": load.Status, "bookings_cancelled": len(open)}).For(load.ShipperID, ""))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return load, nil
+}
+
+// RestoreLoad undeletes a load and relists it if it was withdrawn. The
+// shipper must still be active.
+func (d *DatabaseStore) RestoreLoad(ctx context.Context, id string, actor models.Actor) (*models.Load, error) {
+	var load models.Load
+	err := d.transaction(ctx, func(tx *gorm.DB, rec *eventRecorder) error {
+		if err := findByID(tx.Unscoped(), &load, "load_id", id, "load"); err != nil {
+			return err
+		}
+		deleted := load.DeletedAt.Valid
+		if !deleted && load.Status != models.LoadStatusWithdrawn {
+			return nil
+		}
+		if err := checkShipperActive(tx, load.ShipperID); err != nil {
+			return err
+		}
+
+		before := models.EventData{"status": load.Status, "deleted": deleted}
+		if load.Status == models.LoadStatusWithdrawn {
+			load.Status = models.LoadStatusAvailable
+		}
+		if err := updateVersioned(tx.Unscoped(), &load, &load.Version, "load", map[string]interface{}{
+			"deleted_at": nil,
+			"status":     load.Status,
+		}); err != nil {
+			return fmt.Errorf("failed to restore load: %w", err)
+		}
+		load.DeletedAt = gorm.DeletedAt{}
+
+		return rec.record(tx, models.NewEvent(models.EntityLoad, load.LoadID, models.EventLoadRestored, actor, before,
+			models.EventData{"status": load.Status}).For(load.ShipperID, ""))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &load, nil
+}
+
+// checkShipperActive refuses loads of a deactivated or deleted shipper.
+// Loads of shippers not registered here pass.
+func checkShipperActive(tx *gorm.DB, shipperID string) error {
+	var shipper models.Shipper
+	err := tx.Unscoped().Where("shipper_id = ?", shipperID).First(&shipper).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("database error: %w", err)
+	}
+	if !shipper.Active || shipper.DeletedAt.Valid {
+		return models.Unavailable("shipper", "", "shipper account is deactivated")
+	}
+	return nil
+}
+
+// openBookings lists the active bookings whose column matches value, oldest
+// first, or fails if one of them is in transit
+func openBookings(tx *gorm.DB, entity, column, value string) ([]*models.Booking, error) {
+	var open []*models.Booking
+	if err := tx.Where(column+" = ? AND status IN ?", value, models.ActiveBookingStatuses).
+		Order("id ASC").
+		Find(&open).Error; err != nil {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	for _, booking := range open {
+		if !booking.IsCancellable() {
+			return nil, models.Unavailable(entity, "", fmt.Sprintf("booking %s is in transit; wait for its delivery", booking.BookingID))
+		}
+	}
+	return open, nil
+}
+
+// cancelBookings cancels bookings, handing their share back to the load and
+// freeing trucks left without work
+func cancelBookings(tx *gorm.DB, rec *eventRecorder, bookings []*models.Booking, actor models.Actor) error {
+	for _, booking := range bookings {
+		previous := booking.Status
+		if err := updateVersioned(tx, booking, &booking.Version, "booking", map[string]interface{}{"status": models.BookingStatusCancelled}); err != nil {
+			return fmt.Errorf("failed to cancel booking: %w", err)
+		}
+		booking.Status = models.BookingStatusCancelled
+		if err := rec.record(tx, models.NewEvent(models.EntityBooking, booking.BookingID, models.EventBookingStatusChanged, actor,
+			models.EventData{"status": previous}, models.EventData{"status": booking.Status}).For(booking.ShipperID, booking.TruckerID)); err != nil {
+			return err
+		}
+
+		if load, err := findLoad(tx, booking.LoadID); err == nil {
+			before := models.EventData{"status": load.Status, "trucks_booked": load.TrucksBooked, "booked_weight": load.BookedWeight}
+			load.RemoveBooking(booking.Weight)
+			if err := updateVersioned(tx, load, &load.Version, "load", map[string]interface{}{
+				"status":        load.Status,
+				"trucks_booked": load.TrucksBooked,
+				"booked_weight": load.BookedWeight,
+			}); err != nil {
+				return fmt.Errorf("failed to update load: %w", err)
+			}
+			if err := rec.record(tx, models.NewEvent(models.EntityLoad, load.LoadID, models.EventLoadReleased, actor, before,
+				models.EventData{"status": load.Status, "trucks_booked": load.TrucksBooked, "booked_weight": load.BookedWeight, "booking_id": booking.BookingID}).For(load.ShipperID, booking.TruckerID)); err != nil {
+				return err
+			}
+		} else if !errors.Is(err, models.ErrNotFound) {
+			return err
+		}
+
+		var trucker models.Trucker
+		if err := tx.Where("trucker_id = ?", booking.TruckerID).First(&trucker).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				continue
+			}
+			return fmt.Errorf("database error: %w", err)
+		}
+		if !trucker.Active || trucker.Available {
+			continue
+		}
+		var active int64
+		if err := tx.Model(&models.Booking{}).
+			Where("trucker_id = ? AND status IN ?", trucker.TruckerID, models.ActiveBookingStatuses).
+			Count(&active).Error; err != nil {
+			return fmt.Errorf("database error: %w", err)
+		}
+		if active > 0 {
+			continue
+		}
+		if err := updateVersioned(tx, &trucker, &trucker.Version, "trucker", map[string]interface{}{"available": true}); err != nil {
+			return fmt.Errorf("failed to update trucker availability: %w", err)
+		}
+		if err := rec.record(tx, models.NewEvent(models.EntityTrucker, trucker.TruckerID, models.EventTruckerUpdated, actor,
+			models.EventData{"available": false}, models.EventData{"available": true, "booking_id": booking.BookingID}).For("", trucker.TruckerID)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// withdrawOpenLoad takes an available load off the marketplace
+func withdrawOpenLoad(tx *gorm.DB, rec *eventRecorder, load *models.Load, actor models.Actor) error {
+	if !load.IsAvailable() {
+		return nil
+	}
+	if err := updateVersioned(tx, load, &load.Version, "load", map[string]interface{}{"status": models.LoadStatusWithdrawn}); err != nil {
+		return fmt.Errorf("failed to withdraw load: %w", err)
+	}
+	load.Status = models.LoadStatusWithdrawn
+	return rec.record(tx, models.NewEvent(models.EntityLoad, load.LoadID, models.EventLoadStatusChanged, actor,
+		models.EventData{"status": models.LoadStatusAvailable}, models.EventData{"status": load.Status}).For(load.ShipperID, ""))
+}
+
+// WhatsApp session operations
+func (d *DatabaseStore) GetSession(ctx context.Context, phone string) (*models.WhatsAppSession, error) {
+	var session models.WhatsAppSession
+	if err := d.db.WithContext(ctx).Where("phone_number = ? AND expires_at > ?", phone, time.Now()).First(&session).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, models.NotFound("session")
+		}
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	return &session, nil
+}
+
+func (d *DatabaseStore) SaveSession(ctx context.Context, session *models.WhatsAppSession) error {
+	// One session per phone number: overwrite whatever was there
+	var existing models.WhatsAppSession
+	if err := d.db.WithContext(ctx).Unscoped().Where("phone_number = ?", session.PhoneNumber).First(&existing).Error; err == nil {
+		session.ID = existing.ID
+		session.CreatedAt = existing.CreatedAt
+	}
+
+	if err := d.db.WithContext(ctx).Save(session).Error; err != nil {
+		return fmt.Errorf("failed to save session: %w", err)
+	}
+	return nil
+}
+
+// Tracking link operations
+func (d *DatabaseStore) CreateTrackingLink(ctx context.Context, link *models.TrackingLink, actor models.Actor) (*models.TrackingLink, error) {
+	err := d.transaction(ctx, func(tx *gorm.DB, rec *eventRecorder) error {
+		if err := tx.Create(link).Error; err != nil {
+			return err
+		}
+		return rec.record(tx, models.NewEvent(models.EntityBooking, link.BookingID, models.EventTrackingLinkCreated, actor, nil,
+			models.EventData{"expires_at": link.ExpiresAt}).For(link.ShipperID, ""))
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create tracking link: %w", err)
+	}
+	return link, nil
+}
+
+func (d *DatabaseStore) GetTrackingLink(ctx context.Context, token string) (*models.TrackingLink, error) {
+	var link models.TrackingLink
+	if err := d.db.WithContext(ctx).Where("token = ?", token).First(&link).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, models.NotFound("tracking link")
+		}
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	return &link, nil
+}
+
+func (d *DatabaseStore) RevokeTrackingLinks(ctx context.Context, bookingID string, actor models.Actor) error {
+	return d.transaction(ctx, func(tx *gorm.DB, rec *eventRecorder) error {
+		var link models.TrackingLink
+		tx.Where("booking_id = ?", bookingID).First(&link)
+
+		result := tx.Model(&models.TrackingLink{}).
+			Where("booking_id = ? AND revoked_at IS NULL", bookingID).
+			Update("revoked_at", time.Now())
+		if result.Error != nil {
+			return fmt.Errorf("failed to revoke tracking links: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+		return rec.record(tx, models.NewEvent(models.EntityBooking, bookingID, models.EventTrackingLinkRevoked, actor, nil,
+			models.EventData{"revoked": result.RowsAffected}).For(link.ShipperID, ""))
+	})
+}
+
+// Webhook operations
+func (d *DatabaseStore) CreateWebhookEndpoint(ctx context.Context, endpoint *models.WebhookEndpoint) (*models.WebhookEndpoint, error) {
+	// EndpointID will be auto-generated by BeforeCreate hook
+	endpoint.Active = true
+
+	if err := d.db.WithContext(ctx).Create(endpoint).Error; err != nil {
+		return nil, fmt.Errorf("failed to create webhook endpoint: %w", err)
+	}
+	return endpoint, nil
+}
+
+func (d *DatabaseStore) GetWebhookEndpoint(ctx context.Context, id string) (*models.WebhookEndpoint, error) {
+	var endpoint models.WebhookEndpoint
+	if err := d.db.WithContext(ctx).Where("endpoint_id = ?", id).First(&endpoint).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, models.NotFound("webhook endpoint")
+		}
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	return &endpoint, nil
+}
+
+func (d *DatabaseStore) GetWebhookEndpointsByShipper(ctx context.Context, shipperID string) ([]*models.WebhookEndpoint, error) {
+	var endpoints []*models.WebhookEndpoint
+	if err := d.db.WithContext(ctx).Where("shipper_id = ?", shipperID).
+		Order("id ASC").
+		Find(&endpoints).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch webhook endpoints: %w", err)
+	}
+	return endpoints, nil
+}
+
+func (d *DatabaseStore) DeactivateWebhookEndpoint(ctx context.Context, id string) error {
+	result := d.db.WithContext(ctx).Model(&models.WebhookEndpoint{}).
+		Where("endpoint_id = ?", id).
+		Update("active", false)
+
+	if result.Error != nil {
+		return fmt.Errorf("failed to deactivate webhook endpoint: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return models.NotFound("webhook endpoint")
+	}
+	return nil
+}
+
+func (d *DatabaseStore) CreateWebhookDelivery(ctx context.Context, delivery *models.WebhookDelivery) (*models.WebhookDelivery, error) {
+	// DeliveryID will be auto-generated by BeforeCreate hook
+	if err := d.db.WithContext(ctx).Create(delivery).Error; err != nil {
+		return nil, fmt.Errorf("failed to create webhook delivery: %w", err)
+	}
+	return delivery, nil
+}
+
+func (d *DatabaseStore) GetWebhookDelivery(ctx context.Context, id string) (*models.WebhookDelivery, error) {
+	var delivery models.WebhookDelivery
+	if err := d.db.WithContext(ctx).Where("delivery_id = ?", id).First(&delivery).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, models.NotFound("webhook delivery")
+		}
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	return &delivery, nil
+}
+
+func (d *DatabaseStore) GetWebhookDeliveries(ctx context.Context, endpointID string, status string) ([]*models.WebhookDelivery, error) {
+	query := d.db.WithContext(ctx).Where("endpoint_id = ?", endpointID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var deliveries []*models.WebhookDelivery
+	if err := query.Order("id DESC").Find(&deliveries).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch webhook deliveries: %w", err)
+	}
+	return deliveries, nil
+}
+
+func (d *DatabaseStore) GetDueWebhookDeliveries(ctx context.Context, now time.Time) ([]*models.WebhookDelivery, error) {
+	var deliveries []*models.WebhookDelivery
+	if err := d.db.WithContext(ctx).Where("status = ?", models.WebhookDeliveryPending).
+		Where("next_attempt_at IS NULL OR next_attempt_at <= ?", now).
+		Order("id ASC").
+		Find(&deliveries).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch due webhook deliveries: %w", err)
+	}
+	return deliveries, nil
+}
+
+func (d *DatabaseStore) UpdateWebhookDelivery(ctx context.Context, delivery *models.WebhookDelivery) error {
+	// Save inserts rows it does not know, so check the delivery exists first
+	var existing models.WebhookDelivery
+	if err := findByID(d.db.WithContext(ctx), &existing, "delivery_id", delivery.DeliveryID, "webhook delivery"); err != nil {
+		return err
+	}
+
+	delivery.ID = existing.ID
+	if err := d.db.WithContext(ctx).Save(delivery).Error; err != nil {
+		return fmt.Errorf("failed to update webhook delivery: %w", err)
+	}
+	return nil
+}
+
+// Lane alert operations
+func (d *DatabaseStore) CreateLaneAlert(ctx context.Context, alert *models.LaneAlert) (*models.LaneAlert, error) {
+	// AlertID will be auto-generated by BeforeCreate hook
+	alert.Active = true
+
+	if err := d.db.WithContext(ctx).Create(alert).Error; err != nil {
+		return nil, fmt.Errorf("failed to create lane alert: %w", err)
+	}
+	return alert, nil
+}
+
+func (d *DatabaseStore) GetLaneAlert(ctx context.Context, id string) (*models.LaneAlert, error) {
+	var alert models.LaneAlert
+	if err := d.db.WithContext(ctx).Where("alert_id = ?", id).First(&alert).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, models.NotFound("lane alert")
+		}
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	return &alert, nil
+}
+
+func (d *DatabaseStore) GetLaneAlertsByTrucker(ctx context.Context, truckerID string) ([]*models.LaneAlert, error) {
+	var alerts []*models.LaneAlert
+	if err := d.db.WithContext(ctx).Where("trucker_id = ? AND active = ?", truckerID, true).
+		Order("id ASC").
+		Find(&alerts).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch lane alerts: %w", err)
+	}
+	return alerts, nil
+}
+
+func (d *DatabaseStore) GetLaneAlertsByOrigin(ctx context.Context, fromCity string) ([]*models.LaneAlert, error) {
+	var alerts []*models.LaneAlert
+	if err := d.db.WithContext(ctx).Where("LOWER(from_city) IN ? AND active = ?", models.CityNames(fromCity), true).
+		Order("id ASC").
+		Find(&alerts).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch lane alerts: %w", err)
+	}
+	return alerts, nil
+}
+
+func (d *DatabaseStore) DeactivateLaneAlert(ctx context.Context, id string) error {
+	result := d.db.WithContext(ctx).Model(&models.LaneAlert{}).
+		Where("alert_id = ?", id).
+		Update("active", false)
+
+	if result.Error != nil {
+		return fmt.Errorf("failed to deactivate lane alert: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return models.NotFound("lane alert")
+	}
+	return nil
+}
+
+func (d *DatabaseStore) CreateAlertNotification(ctx context.Context, notification *models.AlertNotification) error {
+	if err := d.db.WithContext(ctx).Create(notification).Error; err != nil {
+		return fmt.Errorf("failed to record alert notification: %w", err)
+	}
+	return nil
+}
+
+func (d *DatabaseStore) CountAlertNotifications(ctx context.Context, truckerID string, since time.Time) (int, error) {
+	var count int64
+	if err := d.db.WithContext(ctx).Model(&models.AlertNotification{}).
+		Where("trucker_id = ? AND sent_at >= ?", truckerID, since).
+		Count(&count).Error; err != nil {
+		return 0, fmt.Errorf("failed to count alert notifications: %w", err)
+	}
+	return int(count), nil
+}
+
+func (d *DatabaseStore) GetAlertNotificationsByLoad(ctx context.Context, loadID string) ([]*models.AlertNotification, error) {
+	var notifications []*models.AlertNotification
+	if err := d.db.WithContext(ctx).Where("load_id = ?", loadID).Order("id ASC").Find(&notifications).Error; err != nil {
+		return nil, fmt.Errorf("failed to get alert notifications: %w", err)
+	}
+	return notifications, nil
+}
+
+// Backhaul suggestion operations
+func (d *DatabaseStore) CreateBackhaulSuggestion(ctx context.Context, suggestion *models.BackhaulSuggestion) error {
+	if err := d.db.WithContext(ctx).Create(suggestion).Error; err != nil {
+		return fmt.Errorf("failed to record backhaul suggestion: %w", err)
+	}
+	return nil
+}
+
+func (d *DatabaseStore) ConvertBackhaulSuggestion(ctx context.Context, truckerID, loadID, bookingID string) error {
+	var suggestion models.BackhaulSuggestion
+	if err := d.db.WithContext(ctx).Where("trucker_id = ? AND load_id = ? AND converted_booking_id = ?", truckerID, loadID, "").
+		Order("id DESC").
+		First(&suggestion).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return models.NotFound("backhaul suggestion")
+		}
+		return fmt.Errorf("database error: %w", err)
+	}
+
+	now := time.Now()
+	if err := d.db.WithContext(ctx).Model(&suggestion).Updates(map[string]interface{}{
+		"converted_booking_id": bookingID,
+		"converted_at":         now,
+	}).Error; err != nil {
+		return fmt.Errorf("failed to update backhaul suggestion: %w", err)
+	}
+	return nil
+}
+
+func (d *DatabaseStore) GetBackhaulStats(ctx context.Context, since time.Time) (*models.BackhaulStats, error) {
+	var suggested, converted int64
+	if err := d.db.WithContext(ctx).Model(&models.BackhaulSuggestion{}).
+		Where("sent_at >= ?", since).
+		Count(&suggested).Error; err != nil {
+		return nil, fmt.Errorf("failed to count backhaul suggestions: %w", err)
+	}
+	if err := d.db.WithContext(ctx).Model(&models.BackhaulSuggestion{}).
+		Where("sent_at >= ? AND converted_booking_id <> ?", since, "").
+		Count(&converted).Error; err != nil {
+		return nil, fmt.Errorf("failed to count backhaul suggestions: %w", err)
+	}
+
+	stats := &models.BackhaulStats{Suggested: int(suggested), Converted: int(converted)}
+	if suggested > 0 {
+		stats.ConversionRate = float64(converted) / float64(suggested)
+	}
+	return stats, nil
+}
+
+func (d *DatabaseStore) GetBackhaulSuggestionsByLoad(ctx context.Context, loadID string) ([]*models.BackhaulSuggestion, error) {
+	var suggestions []*models.BackhaulSuggestion
+	if err := d.db.WithContext(ctx).Where("load_id = ?", loadID).Order("id ASC").Find(&suggestions).Error; err != nil {
+		return nil, fmt.Errorf("failed to get backhaul suggestions: %w", err)
+	}
+	return suggestions, nil
+}
+
+// Recurring load operations
+func (d *DatabaseStore) CreateRecurringLoad(ctx context.Context, recurring *models.RecurringLoad) (*models.RecurringLoad, error) {
+	// RecurringID will be auto-generated by BeforeCreate hook
+	if err := d.db.WithContext(ctx).Create(recurring).Error; err != nil {
+		return nil, fmt.Errorf("failed to create recurring load: %w", err)
+	}
+	return recurring, nil
+}
+
+func (d *DatabaseStore) GetRecurringLoad(ctx context.Context, id string) (*models.RecurringLoad, error) {
+	var recurring models.RecurringLoad
+	if err := d.db.WithContext(ctx).Where("recurring_id = ?", id).First(&recurring).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, models.NotFound("recurring load")
+		}
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	return &recurring, nil
+}
+
+func (d *DatabaseStore) GetRecurringLoadsByShipper(ctx context.Context, shipperID string) ([]*models.RecurringLoad, error) {
+	var recurringLoads []*models.RecurringLoad
+	if err := d.db.WithContext(ctx).Where("shipper_id = ?", shipperID).
+		Order("id ASC").
+		Find(&recurringLoads).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch recurring loads: %w", err)
+	}
+	return recurringLoads, nil
+}
+
+func (d *DatabaseStore) GetActiveRecurringLoads(ctx context.Context) ([]*models.RecurringLoad, error) {
+	var recurringLoads []*models.RecurringLoad
+	if err := d.db.WithContext(ctx).Where("paused = ?", false).
+		Order("id ASC").
+		Find(&recurringLoads).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch recurring loads: %w", err)
+	}
+	return recurringLoads, nil
+}
+
+func (d *DatabaseStore) UpdateRecurringLoad(ctx context.Context, recurring *models.RecurringLoad) error {
+	var existing models.RecurringLoad
+	if err := findByID(d.db.WithContext(ctx), &existing, "recurring_id", recurring.RecurringID, "recurring load"); err != nil {
+		return err
+	}
+
+	// Write every column, but only over the version recurring was read at
+	updated := *recurring
+	updated.ID = existing.ID
+	updated.CreatedAt = existing.CreatedAt
+	updated.Version = recurring.Version + 1
+	result := d.db.WithContext(ctx).Model(&existing).
+		Where("version = ?", recurring.Version).
+		Select("*").Omit("id", "created_at", "deleted_at").
+		Updates(&updated)
+	if result.Error != nil {
+		return fmt.Errorf("failed to update recurring load: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return recurringLoadChanged()
+	}
+
+	recurring.ID, recurring.Version, recurring.UpdatedAt = updated.ID, updated.Version, updated.UpdatedAt
+	return nil
+}
+
+func (d *DatabaseStore) PostRecurringOccurrence(ctx context.Context, recurring *models.RecurringLoad, loadingDate time.Time, actor models.Actor) (*models.Load, error) {
+	load := recurring.NewLoad(loadingDate)
+	load.Status = "available"
+
+	err := d.transaction(ctx, func(tx *gorm.DB, rec *eventRecorder) error {
+		result := tx.Model(&models.RecurringLoad{}).
+			Where("recurring_id = ? AND version = ?", recurring.RecurringID, recurring.Version).
+			Updates(map[string]interface{}{"scheduled_until": loadingDate, "version": recurring.Version + 1})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return recurringLoadChanged()
+		}
+		return insertLoad(tx, rec, load, actor)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to post recurring load: %w", err)
+	}
+
+	recurring.ScheduledUntil = loadingDate
+	recurring.Version++
+	return load, nil
+}
+
+// Event log operations
+// Outbox operations
+func (d *DatabaseStore) GetPendingOutboxMessages(ctx context.Context, before time.Time, limit int) ([]*models.OutboxMessage, error) {
+	query := d.db.WithContext(ctx).Preload("Event").
+		Where("created_at < ?", before).
+		Order("id ASC")
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+
+	var messages []*models.OutboxMessage
+	if err := query.Find(&messages).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch outbox messages: %w", err)
+	}
+	return messages, nil
+}
+
+func (d *DatabaseStore) DeleteOutboxMessages(ctx context.Context, ids []uint) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	if err := d.db.WithContext(ctx).Unscoped().Delete(&models.OutboxMessage{}, ids).Error; err != nil {
+		return fmt.Errorf("failed to clear outbox messages: %w", err)
+	}
+	return nil
+}
+
+func (d *DatabaseStore) IsEventHandled(ctx context.Context, consumer, idempotencyKey string) (bool, error) {
+	var count int64
+	if err := d.db.WithContext(ctx).Model(&models.HandledEvent{}).
+		Where("consumer = ? AND idempotency_key = ?", consumer, idempotencyKey).
+		Count(&count).Error; err != nil {
+		return false, fmt.Errorf("failed to check handled event: %w", err)
+	}
+	return count > 0, nil
+}
+
+func (d *DatabaseStore) MarkEventHandled(ctx context.Context, consumer, idempotencyKey string) error {
+	handled := &models.HandledEvent{Consumer: consumer, IdempotencyKey: idempotencyKey}
+	if err := d.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(handled).Error; err != nil {
+		return fmt.Errorf("failed to mark event handled: %w", err)
+	}
+	return nil
+}
+
+func (d *DatabaseStore) GetEvents(ctx context.Context, entityType, entityID string) ([]*models.Event, error) {
+	var events []*models.Event
+	if err := d.db.WithContext(ctx).Where("entity_type = ? AND entity_id = ?", entityType, entityID).
+		Order("occurred_at ASC, id ASC").
+		Find(&events).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch events: %w", err)
+	}
+	return events, nil
+}
+
+// exportBatchSize is how many rows are read per query while exporting
+const exportBatchSize = 500
+
+// Export operations
+func (d *DatabaseStore) EachLoad(ctx context.Context, filter *models.ExportFilter, fn func(load *models.Load) error) error {
+	query := d.db.WithContext(ctx).Model(&models.Load{})
+	if filter.ShipperID != "" {
+		query = query.Where("shipper_id = ?", filter.ShipperID)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	query = exportDateRange(query, filter, "created_at")
+
+	var loads []*models.Load
+	var fnErr error
+	result := query.FindInBatches(&loads, exportBatchSize, func(tx *gorm.DB, batch int) error {
+		for _, load := range loads {
+			if fnErr = fn(load); fnErr != nil {
+				return fnErr
+			}
+		}
+		return nil
+	})
+	if fnErr != nil {
+		return fnErr
+	}
+	if result.Error != nil {
+		return fmt.Errorf("failed to export loads: %w", result.Error)
+	}
+	return nil
+}
+
+func (d *DatabaseStore) EachBooking(ctx context.Context, filter *models.ExportFilter, fn func(booking *models.Booking) error) error {
+	query := d.db.WithContext(ctx).Model(&models.Booking{})
+	if filter.ShipperID != "" {
+		query = query.Where("shipper_id = ?", filter.ShipperID)
+	}
+	if filter.TruckerID != "" {
+		query = query.Where("trucker_id = ?", filter.TruckerID)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.ByDeliveryDate {
+		query = exportDateRange(query.Where("delivered_at IS NOT NULL"), filter, "delivered_at")
+	} else {
+		query = exportDateRange(query, filter, "created_at")
+	}
+
+	var bookings []*models.Booking
+	var fnErr error
+	result := query.FindInBatches(&bookings, exportBatchSize, func(tx *gorm.DB, batch int) error {
+		for _, booking := range bookings {
+			if fnErr = fn(booking); fnErr != nil {
+				return fnErr
+			}
+		}
+		return nil
+	})
+	if fnErr != nil {
+		return fnErr
+	}
+	if result.Error != nil {
+		return fmt.Errorf("failed to export bookings: %w", result.Error)
+	}
+	return nil
+}
+
+// exportDateRange applies the filter's date range to a timestamp column
+func exportDateRange(query *gorm.DB, filter *models.ExportFilter, column string) *gorm.DB {
+	if filter.From != nil {
+		query = query.Where(column+" >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where(column+" < ?", *filter.To)
+	}
+	return query
+}
+
+// eventRecorder collects the events written inside a transaction
+type eventRecorder struct {
+	messages []*models.OutboxMessage
+}
+
+// record appends an event and queues it in the outbox using the caller's transaction
+func (r *eventRecorder) record(tx *gorm.DB, event *models.Event) error {
+	if err := tx.Create(event).Error; err != nil {
+		return fmt.Errorf("failed to record event: %w", err)
+	}
+	message := models.NewOutboxMessage(event)
+	if err := tx.Omit("Event").Create(message).Error; err != nil {
+		return fmt.Errorf("failed to queue event: %w", err)
+	}
+	r.messages = append(r.messages, message)
+	return nil
+}
+
+// versionRetries is how many times a transaction is run again after losing
+// a race to update a row
+const versionRetries = 10
+
+// transaction runs fn in a database transaction and publishes the events
+// it recorded once the transaction has committed. If another transaction
+// updated a row fn read (see updateVersioned), fn is run again on fresh rows.
+func (d *DatabaseStore) transaction(ctx context.Context, fn func(tx *gorm.DB, rec *eventRecorder) error) error {
+	for attempt := 0; ; attempt++ {
+		rec := &eventRecorder{}
+		err := d.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			return fn(tx, rec)
+		})
+
+		var changed *versionChanged
+		if errors.As(err, &changed) {
+			if attempt < versionRetries {
+				continue
+			}
+			return models.Conflict(changed.entity, "version", changed.entity+" is being changed by someone else, please try again")
+		}
+		if err != nil {
+			return err
+		}
+
+		d.publish(ctx, rec.messages)
+		return nil
+	}
+}
+
+// publish sends committed events to the event bus and clears those every
+// durable subscriber took from the outbox. Should the process stop in
+// between, or a subscriber be too busy, the outbox dispatcher publishes
+// them again.
+func (d *DatabaseStore) publish(ctx context.Context, committed []*models.OutboxMessage) {
+	if len(committed) == 0 {
+		return
+	}
+	ids := make([]uint, 0, len(committed))
+	for _, message := range committed {
+		if d.bus != nil && !d.bus.Publish(message.Event) {
+			continue
+		}
+		ids = append(ids, message.ID)
+	}
+	if len(ids) == 0 {
+		return
+	}
+	if err := d.DeleteOutboxMessages(context.WithoutCancel(ctx), ids); err != nil {
+		log.Printf("⚠️  %v; the outbox dispatcher will publish them again", err)
+	}
+}
@@ -0,0 +1,146 @@
+package storage_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Ananth-NQI/truckpe-backend/database"
+	"github.com/Ananth-NQI/truckpe-backend/internal/events"
+	"github.com/Ananth-NQI/truckpe-backend/internal/models"
+	"github.com/Ananth-NQI/truckpe-backend/internal/services"
+	"github.com/Ananth-NQI/truckpe-backend/internal/storage"
+	"github.com/Ananth-NQI/truckpe-backend/internal/storage/storetest"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+var quiet = &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)}
+
+// TestDatabaseStore runs the suite on a throwaway SQLite file per subtest,
+// opened and migrated exactly as DB_DRIVER=sqlite does at startup
+func TestDatabaseStore(t *testing.T) {
+	storetest.Run(t, func(t *testing.T, bus *events.Bus) storage.Store {
+		db, err := database.OpenSQLite(filepath.Join(t.TempDir(), "truckpe.db"), quiet)
+		if err != nil {
+			t.Fatalf("open sqlite: %v", err)
+		}
+		return migratedStore(t, db, bus)
+	})
+}
+
+// TestDatabaseStorePostgres runs the suite on the throwaway database in
+// TEST_POSTGRES_DSN, which CI provides, in a storetest schema recreated for
+// each subtest. SQLite runs one write transaction at a time, so this is
+// where simultaneous bookings really race.
+func TestDatabaseStorePostgres(t *testing.T) {
+	dsn := os.Getenv("TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("TEST_POSTGRES_DSN not set")
+	}
+
+	storetest.Run(t, func(t *testing.T, bus *events.Bus) storage.Store {
+		admin, err := gorm.Open(postgres.Open(dsn), quiet)
+		if err != nil {
+			t.Fatalf("open postgres: %v", err)
+		}
+		err = admin.Exec("DROP SCHEMA IF EXISTS storetest CASCADE; CREATE SCHEMA storetest").Error
+		if sqlDB, dbErr := admin.DB(); dbErr == nil {
+			sqlDB.Close()
+		}
+		if err != nil {
+			t.Fatalf("reset schema: %v", err)
+		}
+
+		db, err := gorm.Open(postgres.Open(withSearchPath(dsn, "storetest")), quiet)
+		if err != nil {
+			t.Fatalf("open postgres: %v", err)
+		}
+		// Stay well under the server's connection limit when hundreds of bookings race
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.SetMaxOpenConns(20)
+		}
+		return migratedStore(t, db, bus)
+	})
+}
+
+// TestDatabaseStoreOutboxRecovery commits an event the way a process that
+// stopped before publishing it would leave it, and checks the dispatcher
+// publishes it exactly once
+func TestDatabaseStoreOutboxRecovery(t *testing.T) {
+	db, err := database.OpenSQLite(filepath.Join(t.TempDir(), "truckpe.db"), quiet)
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	bus := events.NewBus()
+	store := migratedStore(t, db, bus)
+	ctx := context.Background()
+
+	event := models.NewEvent(models.EntityLoad, "LD00001Y", models.EventLoadCreated, models.SystemActor, nil, models.EventData{"status": "available"})
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(event).Error; err != nil {
+			return err
+		}
+		return tx.Omit("Event").Create(models.NewOutboxMessage(event)).Error
+	})
+	if err != nil {
+		t.Fatalf("commit event: %v", err)
+	}
+
+	sub := bus.Subscribe(10)
+	defer sub.Close()
+	dispatcher := services.NewOutboxDispatcher(store, bus)
+	if n := dispatcher.Dispatch(ctx, time.Now().Add(-time.Hour)); n != 0 {
+		t.Fatalf("dispatcher published %d events within their grace period, want 0", n)
+	}
+	if n := dispatcher.Dispatch(ctx, time.Now().Add(time.Second)); n != 1 {
+		t.Fatalf("dispatcher published %d events, want 1", n)
+	}
+	select {
+	case published := <-sub.C:
+		if published.IdempotencyKey() != event.IdempotencyKey() || published.EntityID != event.EntityID {
+			t.Fatalf("published %s for %s, want %s", published.IdempotencyKey(), published.EntityID, event.IdempotencyKey())
+		}
+	case <-time.After(time.Second):
+		t.Fatal("left over event was not published")
+	}
+
+	// Published events leave the outbox, and the bus drops a second publish
+	if n := dispatcher.Dispatch(ctx, time.Now().Add(time.Second)); n != 0 {
+		t.Fatalf("dispatcher published %d events again, want 0", n)
+	}
+	bus.Publish(event)
+	select {
+	case again := <-sub.C:
+		t.Fatalf("bus delivered %s twice", again.IdempotencyKey())
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+// migratedStore migrates db and returns a store on it, closed when the test ends
+func migratedStore(t *testing.T, db *gorm.DB, bus *events.Bus) storage.Store {
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	if err := database.Migrate(context.Background(), db); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	return storage.NewDatabaseStore(db, bus)
+}
+
+// withSearchPath adds a search_path to a key=value or URL PostgreSQL DSN
+func withSearchPath(dsn, schema string) string {
+	if !strings.Contains(dsn, "://") {
+		return dsn + " search_path=" + schema
+	}
+	if strings.Contains(dsn, "?") {
+		return dsn + "&search_path=" + schema
+	}
+	return dsn + "?search_path=" + schema
+}
@@ -1,13 +1,16 @@
 package storage
 
 import (
+	"context"
 	"fmt"
 	"sort"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/Ananth-NQI/truckpe-backend/internal/events"
 	"github.com/Ananth-NQI/truckpe-backend/internal/models"
+	"gorm.io/gorm"
 )
 
 // MemoryStore holds all data in memory for MVP
@@ -18,29 +21,98 @@ type MemoryStore struct {
 	bookings map[uint]*models.Booking // Changed from string to uint
 	shippers map[string]*models.Shipper
 
+	// Tracking links keyed by token
+	trackingLinks map[string]*models.TrackingLink
+
+	// WhatsApp sessions keyed by phone number
+	sessions map[string]*models.WhatsAppSession
+
+	// Append-only event log, also published to the event bus
+	events []*models.Event
+	bus    *events.Bus
+
+	// Events not yet published, keyed by message ID
+	outbox map[uint]*models.OutboxMessage
+
+	// Events subscribers acted on, keyed by handledEventKey
+	handledEvents map[string]*models.HandledEvent
+
+	// Webhook endpoints and deliveries keyed by their string IDs
+	webhookEndpoints  map[string]*models.WebhookEndpoint
+	webhookDeliveries map[string]*models.WebhookDelivery
+
+	// Lane alerts keyed by AlertID, and the alerts sent for them
+	laneAlerts         map[string]*models.LaneAlert
+	alertNotifications []*models.AlertNotification
+
+	// Return loads suggested after deliveries, oldest first
+	backhaulSuggestions []*models.BackhaulSuggestion
+
+	// Recurring load templates keyed by RecurringID
+	recurringLoads map[string]*models.RecurringLoad
+
+	// Soft-deleted rows, kept apart so lookups miss them as the database's do
+	deletedTruckers map[uint]*models.Trucker
+	deletedShippers map[string]*models.Shipper
+	deletedLoads    map[uint]*models.Load
+
 	// Maps for lookup by string IDs
 	truckersByTruckerID map[string]*models.Trucker
 	loadsByLoadID       map[string]*models.Load
 	bookingsByBookingID map[string]*models.Booking
 
 	// Mutexes for thread safety
-	truckerMu sync.RWMutex
-	loadMu    sync.RWMutex
-	bookingMu sync.RWMutex
+	truckerMu   sync.RWMutex
+	loadMu      sync.RWMutex
+	bookingMu   sync.RWMutex
+	trackingMu  sync.RWMutex
+	eventMu     sync.RWMutex
+	webhookMu   sync.RWMutex
+	sessionMu   sync.RWMutex
+	alertMu     sync.RWMutex
+	backhaulMu  sync.RWMutex
+	recurringMu sync.RWMutex
+
+	// Held shared by every mutation and exclusively by snapshots, and the
+	// log mutations are written to when the store is persisted
+	persistMu sync.RWMutex
+	wal       *memoryWAL
 
 	// Counters for ID generation
-	truckerCounter uint
-	loadCounter    uint
-	bookingCounter uint
+	truckerCounter   uint
+	shipperCounter   uint
+	loadCounter      uint
+	bookingCounter   uint
+	trackingCounter  uint
+	eventCounter     uint
+	outboxCounter    uint
+	handledCounter   uint
+	endpointCounter  uint
+	deliveryCounter  uint
+	sessionCounter   uint
+	alertCounter     uint
+	recurringCounter uint
 }
 
-// NewMemoryStore creates a new in-memory storage
-func NewMemoryStore() *MemoryStore {
+// NewMemoryStore creates a new in-memory storage that publishes its events to bus
+func NewMemoryStore(bus *events.Bus) *MemoryStore {
 	return &MemoryStore{
+		bus:                 bus,
 		truckers:            make(map[uint]*models.Trucker),
 		loads:               make(map[uint]*models.Load),
 		bookings:            make(map[uint]*models.Booking),
 		shippers:            make(map[string]*models.Shipper),
+		trackingLinks:       make(map[string]*models.TrackingLink),
+		sessions:            make(map[string]*models.WhatsAppSession),
+		outbox:              make(map[uint]*models.OutboxMessage),
+		handledEvents:       make(map[string]*models.HandledEvent),
+		webhookEndpoints:    make(map[string]*models.WebhookEndpoint),
+		webhookDeliveries:   make(map[string]*models.WebhookDelivery),
+		laneAlerts:          make(map[string]*models.LaneAlert),
+		recurringLoads:      make(map[string]*models.RecurringLoad),
+		deletedTruckers:     make(map[uint]*models.Trucker),
+		deletedShippers:     make(map[string]*models.Shipper),
+		deletedLoads:        make(map[uint]*models.Load),
 		truckersByTruckerID: make(map[string]*models.Trucker),
 		loadsByLoadID:       make(map[string]*models.Load),
 		bookingsByBookingID: make(map[string]*models.Booking),
@@ -48,17 +120,19 @@ func NewMemoryStore() *MemoryStore {
 }
 
 // Trucker operations
-func (m *MemoryStore) CreateTrucker(reg *models.TruckerRegistration) (*models.Trucker, error) {
+func (m *MemoryStore) CreateTrucker(ctx context.Context, reg *models.TruckerRegistration, actor models.Actor) (*models.Trucker, error) {
+	j := m.journal()
 	m.truckerMu.Lock()
 	defer m.truckerMu.Unlock()
+	defer j.commit()
 
 	// Check if phone already exists
 	for _, t := range m.truckers {
 		if t.Phone == reg.Phone {
-			return nil, fmt.Errorf("phone number already registered")
+			return nil, models.Conflict("trucker", "phone", "phone number already registered")
 		}
 		if t.VehicleNo == reg.VehicleNo {
-			return nil, fmt.Errorf("vehicle already registered")
+			return nil, models.Conflict("trucker", "vehicle_no", "vehicle already registered")
 		}
 	}
 
@@ -66,7 +140,7 @@ func (m *MemoryStore) CreateTrucker(reg *models.TruckerRegistration) (*models.Tr
 	now := time.Now()
 
 	trucker := &models.Trucker{
-		TruckerID:   fmt.Sprintf("TRK%05d", m.truckerCounter),
+		TruckerID:   models.FormatID(models.PrefixTrucker, uint64(m.truckerCounter)),
 		Name:        reg.Name,
 		Phone:       reg.Phone,
 		VehicleNo:   reg.VehicleNo,
@@ -76,6 +150,8 @@ func (m *MemoryStore) CreateTrucker(reg *models.TruckerRegistration) (*models.Tr
 		Rating:      5.0,
 		TotalTrips:  0,
 		Available:   true,
+		Active:      true,
+		Version:     1,
 	}
 
 	// Set ID and timestamps (simulating GORM behavior)
@@ -85,11 +161,15 @@ func (m *MemoryStore) CreateTrucker(reg *models.TruckerRegistration) (*models.Tr
 
 	m.truckers[trucker.ID] = trucker
 	m.truckersByTruckerID[trucker.TruckerID] = trucker
+	j.put(trucker)
+
+	m.recordEvent(j, models.NewEvent(models.EntityTrucker, trucker.TruckerID, models.EventTruckerRegistered, actor, nil,
+		models.EventData{"name": trucker.Name, "vehicle_no": trucker.VehicleNo, "vehicle_type": trucker.VehicleType, "capacity": trucker.Capacity}).For("", trucker.TruckerID))
 
 	return trucker, nil
 }
 
-func (m *MemoryStore) GetTrucker(id string) (*models.Trucker, error) {
+func (m *MemoryStore) GetTrucker(ctx context.Context, id string) (*models.Trucker, error) {
 	m.truckerMu.RLock()
 	defer m.truckerMu.RUnlock()
 
@@ -106,10 +186,10 @@ func (m *MemoryStore) GetTrucker(id string) (*models.Trucker, error) {
 		}
 	}
 
-	return nil, fmt.Errorf("trucker not found")
+	return nil, models.NotFound("trucker")
 }
 
-func (m *MemoryStore) GetTruckerByPhone(phone string) (*models.Trucker, error) {
+func (m *MemoryStore) GetTruckerByPhone(ctx context.Context, phone string) (*models.Trucker, error) {
 	m.truckerMu.RLock()
 	defer m.truckerMu.RUnlock()
 
@@ -118,30 +198,109 @@ func (m *MemoryStore) GetTruckerByPhone(phone string) (*models.Trucker, error) {
 			return trucker, nil
 		}
 	}
-	return nil, fmt.Errorf("trucker not found")
+	return nil, models.NotFound("trucker")
+}
+
+// UpdateTruckerLocation sets the city a trucker is currently in
+func (m *MemoryStore) UpdateTruckerLocation(ctx context.Context, id, city, cityID string, actor models.Actor) error {
+	j := m.journal()
+	m.truckerMu.Lock()
+	defer m.truckerMu.Unlock()
+	defer j.commit()
+
+	trucker, exists := m.truckersByTruckerID[id]
+	if !exists {
+		return models.NotFound("trucker")
+	}
+	if err := models.CheckVersion(ctx, "trucker", trucker.Version); err != nil {
+		return err
+	}
+
+	previous := trucker.CurrentCity
+	trucker.UpdateLocation(city, cityID)
+	trucker.Version++
+	trucker.UpdatedAt = time.Now()
+	j.put(trucker)
+
+	m.recordEvent(j, models.NewEvent(models.EntityTrucker, trucker.TruckerID, models.EventTruckerUpdated, actor,
+		models.EventData{"current_city": previous}, models.EventData{"current_city": city}).For("", trucker.TruckerID))
+	return nil
 }
 
 // Load operations
-func (m *MemoryStore) CreateLoad(load *models.Load) (*models.Load, error) {
+func (m *MemoryStore) CreateLoad(ctx context.Context, load *models.Load, actor models.Actor) (*models.Load, error) {
+	j := m.journal()
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	m.loadMu.Lock()
+	defer m.loadMu.Unlock()
+	defer j.commit()
+
+	if err := m.checkShipperActive(load.ShipperID); err != nil {
+		return nil, err
+	}
+	m.insertLoad(j, load, actor)
+	return load, nil
+}
+
+// CreateLoads creates several loads under one lock so no reader sees a partial batch
+func (m *MemoryStore) CreateLoads(ctx context.Context, loads []*models.Load, actor models.Actor) ([]*models.Load, error) {
+	j := m.journal()
+	m.mu.RLock()
+	defer m.mu.RUnlock()
 	m.loadMu.Lock()
 	defer m.loadMu.Unlock()
+	defer j.commit()
+
+	for _, load := range loads {
+		if err := m.checkShipperActive(load.ShipperID); err != nil {
+			return nil, err
+		}
+	}
+	for _, load := range loads {
+		m.insertLoad(j, load, actor)
+	}
+	return loads, nil
+}
+
+// checkShipperActive refuses loads of a deactivated or deleted shipper; the
+// caller must hold mu. Loads of shippers not registered here pass.
+func (m *MemoryStore) checkShipperActive(shipperID string) error {
+	if _, deleted := m.deletedShippers[shipperID]; deleted {
+		return models.Unavailable("shipper", "", "shipper account is deactivated")
+	}
+	if shipper, exists := m.shippers[shipperID]; exists && !shipper.Active {
+		return models.Unavailable("shipper", "", "shipper account is deactivated")
+	}
+	return nil
+}
 
+// insertLoad assigns IDs and stores a load; the caller must hold loadMu
+func (m *MemoryStore) insertLoad(j *journal, load *models.Load, actor models.Actor) {
 	m.loadCounter++
 	now := time.Now()
 
 	load.ID = m.loadCounter
-	load.LoadID = fmt.Sprintf("LD%05d", m.loadCounter)
+	load.LoadID = models.FormatID(models.PrefixLoad, uint64(m.loadCounter))
 	load.Status = "available"
+	if load.TruckCount <= 0 {
+		load.TruckCount = 1
+	}
+	load.SetDefaultExpiry()
+	load.Version = 1
 	load.CreatedAt = now
 	load.UpdatedAt = now
 
 	m.loads[load.ID] = load
 	m.loadsByLoadID[load.LoadID] = load
+	j.put(load)
 
-	return load, nil
+	m.recordEvent(j, models.NewEvent(models.EntityLoad, load.LoadID, models.EventLoadCreated, actor, nil,
+		models.EventData{"status": load.Status, "from_city": load.FromCity, "to_city": load.ToCity, "price": load.Price, "weight": load.Weight,
+			"truck_count": load.TruckCount, "splittable": load.Splittable}).For(load.ShipperID, ""))
 }
 
-func (m *MemoryStore) GetLoad(id string) (*models.Load, error) {
+func (m *MemoryStore) GetLoad(ctx context.Context, id string) (*models.Load, error) {
 	m.loadMu.RLock()
 	defer m.loadMu.RUnlock()
 
@@ -158,117 +317,317 @@ func (m *MemoryStore) GetLoad(id string) (*models.Load, error) {
 		}
 	}
 
-	return nil, fmt.Errorf("load not found")
+	return nil, models.NotFound("load")
 }
 
-func (m *MemoryStore) GetAvailableLoads() ([]*models.Load, error) {
+func (m *MemoryStore) GetAvailableLoads(ctx context.Context) ([]*models.Load, error) {
 	m.loadMu.RLock()
 	defer m.loadMu.RUnlock()
 
+	now := time.Now()
 	var loads []*models.Load
 	for _, load := range m.loads {
-		if load.Status == "available" {
+		if load.IsListed(now) {
 			loads = append(loads, load)
 		}
 	}
+	newestLoadsFirst(loads)
 	return loads, nil
 }
 
-func (m *MemoryStore) SearchLoads(search *models.LoadSearch) ([]*models.Load, error) {
+func (m *MemoryStore) SearchLoads(ctx context.Context, search *models.LoadSearch) ([]*models.Load, error) {
 	m.loadMu.RLock()
 	defer m.loadMu.RUnlock()
 
+	now := time.Now()
 	var results []*models.Load
 	for _, load := range m.loads {
-		if load.Status != "available" {
+		if !load.IsListed(now) {
 			continue
 		}
 
-		// Case-insensitive city matching
-		if search.FromCity != "" && !strings.EqualFold(load.FromCity, search.FromCity) {
+		if !load.MatchesSearch(*search) {
 			continue
 		}
-		if search.ToCity != "" && !strings.EqualFold(load.ToCity, search.ToCity) {
-			continue
-		}
-		if search.VehicleType != "" && !strings.Contains(strings.ToLower(load.VehicleType), strings.ToLower(search.VehicleType)) {
-			continue
-		}
-		if search.DateFrom != "" {
-			if date, err := time.Parse("2006-01-02", search.DateFrom); err == nil {
-				if load.LoadingDate.Before(date) {
-					continue
-				}
-			}
-		}
 
 		results = append(results, load)
 	}
+	newestLoadsFirst(results)
 	return results, nil
 }
 
-func (m *MemoryStore) UpdateLoadStatus(id string, status string) error {
+func (m *MemoryStore) UpdateLoadStatus(ctx context.Context, id string, status string, actor models.Actor) error {
+	j := m.journal()
 	m.loadMu.Lock()
 	defer m.loadMu.Unlock()
+	defer j.commit()
 
 	// Try LoadID first
-	if load, exists := m.loadsByLoadID[id]; exists {
-		load.Status = status
-		load.UpdatedAt = time.Now()
-		return nil
+	load, exists := m.loadsByLoadID[id]
+	if !exists {
+		// Try uint ID
+		var uintID uint
+		if _, err := fmt.Sscanf(id, "%d", &uintID); err == nil {
+			load, exists = m.loads[uintID]
+		}
+	}
+
+	if !exists {
+		return models.NotFound("load")
+	}
+	if err := models.CheckVersion(ctx, "load", load.Version); err != nil {
+		return err
 	}
 
-	// Try uint ID
+	previous := load.Status
+	load.Status = status
+	load.Version++
+	load.UpdatedAt = time.Now()
+	j.put(load)
+
+	m.recordEvent(j, models.NewEvent(models.EntityLoad, load.LoadID, models.EventLoadStatusChanged, actor,
+		models.EventData{"status": previous}, models.EventData{"status": status}).For(load.ShipperID, ""))
+
+	return nil
+}
+
+// lookupLoad finds a load by LoadID or numeric ID; the caller must hold loadMu
+func (m *MemoryStore) lookupLoad(id string) (*models.Load, error) {
+	if load, exists := m.loadsByLoadID[id]; exists {
+		return load, nil
+	}
 	var uintID uint
 	if _, err := fmt.Sscanf(id, "%d", &uintID); err == nil {
 		if load, exists := m.loads[uintID]; exists {
-			load.Status = status
-			load.UpdatedAt = time.Now()
-			return nil
+			return load, nil
+		}
+	}
+	return nil, models.NotFound("load")
+}
+
+// Load expiry operations
+func (m *MemoryStore) ExpireLoads(ctx context.Context, now time.Time, actor models.Actor) ([]*models.Load, error) {
+	j := m.journal()
+	m.loadMu.Lock()
+	defer m.loadMu.Unlock()
+	defer j.commit()
+
+	var expired []*models.Load
+	for _, load := range m.loads {
+		if !load.IsAvailable() || load.IsListed(now) {
+			continue
+		}
+
+		// A partly booked load keeps its bookings; only the unbooked share is unlisted
+		status := models.LoadStatusExpired
+		if load.TrucksBooked > 0 {
+			status = models.LoadStatusBooked
 		}
+
+		load.Status = status
+		load.Version++
+		load.UpdatedAt = now
+		j.put(load)
+		m.recordEvent(j, models.NewEvent(models.EntityLoad, load.LoadID, models.EventLoadStatusChanged, actor,
+			models.EventData{"status": models.LoadStatusAvailable}, models.EventData{"status": status, "expires_at": load.ExpiresAt}).For(load.ShipperID, ""))
+		expired = append(expired, load)
 	}
 
-	return fmt.Errorf("load not found")
+	sort.Slice(expired, func(i, j int) bool {
+		return expired[i].ID < expired[j].ID
+	})
+	return expired, nil
 }
 
-// Booking operations
-func (m *MemoryStore) CreateBooking(loadID, truckerID string) (*models.Booking, error) {
-	// First check if load exists and is available
-	load, err := m.GetLoad(loadID)
+func (m *MemoryStore) GetLoadsExpiringBefore(ctx context.Context, before time.Time) ([]*models.Load, error) {
+	m.loadMu.RLock()
+	defer m.loadMu.RUnlock()
+
+	var loads []*models.Load
+	for _, load := range m.loads {
+		if load.IsAvailable() && load.ExpiresAt != nil && load.ExpiresAt.Before(before) && load.ExpiryRemindedAt == nil {
+			loads = append(loads, load)
+		}
+	}
+
+	sort.Slice(loads, func(i, j int) bool {
+		return loads[i].ID < loads[j].ID
+	})
+	return loads, nil
+}
+
+func (m *MemoryStore) MarkLoadExpiryReminded(ctx context.Context, id string, at time.Time) error {
+	j := m.journal()
+	m.loadMu.Lock()
+	defer m.loadMu.Unlock()
+	defer j.commit()
+
+	load, err := m.lookupLoad(id)
+	if err != nil {
+		return err
+	}
+
+	load.ExpiryRemindedAt = &at
+	j.put(load)
+	return nil
+}
+
+func (m *MemoryStore) RenewLoad(ctx context.Context, id string, loadingDate time.Time, price float64, actor models.Actor) (*models.Load, error) {
+	j := m.journal()
+	m.loadMu.Lock()
+	defer m.loadMu.Unlock()
+	defer j.commit()
+
+	load, err := m.lookupLoad(id)
+	if err != nil {
+		return nil, err
+	}
+	if !load.IsAvailable() && load.Status != models.LoadStatusExpired {
+		return nil, models.Unavailable("load", "", "load not available")
+	}
+	if err := models.CheckVersion(ctx, "load", load.Version); err != nil {
+		return nil, err
+	}
+
+	before := models.EventData{"status": load.Status, "loading_date": load.LoadingDate, "expires_at": load.ExpiresAt, "price": load.Price}
+	load.Renew(loadingDate, price)
+	load.Version++
+	load.UpdatedAt = time.Now()
+	j.put(load)
+
+	m.recordEvent(j, models.NewEvent(models.EntityLoad, load.LoadID, models.EventLoadRenewed, actor, before,
+		models.EventData{"status": load.Status, "loading_date": load.LoadingDate, "expires_at": load.ExpiresAt, "price": load.Price}).For(load.ShipperID, ""))
+	return load, nil
+}
+
+// Load editing operations
+func (m *MemoryStore) UpdateLoad(ctx context.Context, id string, changes *models.LoadChanges, actor models.Actor) (*models.Load, error) {
+	j := m.journal()
+	m.loadMu.Lock()
+	defer m.loadMu.Unlock()
+	defer j.commit()
+
+	load, err := m.lookupLoad(id)
+	if err != nil {
+		return nil, err
+	}
+	if !load.IsEditable() {
+		return nil, models.Unavailable("load", "", "load not available")
+	}
+	if err := models.CheckVersion(ctx, "load", load.Version); err != nil {
+		return nil, err
+	}
+
+	before, after := changes.Apply(load)
+	if len(after) == 0 {
+		return load, nil
+	}
+	load.Version++
+	load.UpdatedAt = time.Now()
+	j.put(load)
+
+	m.recordEvent(j, models.NewEvent(models.EntityLoad, load.LoadID, models.EventLoadUpdated, actor, before, after).For(load.ShipperID, ""))
+	return load, nil
+}
+
+func (m *MemoryStore) WithdrawLoad(ctx context.Context, id string, actor models.Actor) (*models.Load, error) {
+	j := m.journal()
+	m.loadMu.Lock()
+	defer m.loadMu.Unlock()
+	defer j.commit()
+
+	load, err := m.lookupLoad(id)
 	if err != nil {
 		return nil, err
 	}
-	if load.Status != "available" {
-		return nil, fmt.Errorf("load not available")
+	if !load.IsEditable() {
+		return nil, models.Unavailable("load", "", "load not available")
+	}
+	if err := models.CheckVersion(ctx, "load", load.Version); err != nil {
+		return nil, err
+	}
+
+	previous := load.Status
+	load.Status = models.LoadStatusWithdrawn
+	load.Version++
+	load.UpdatedAt = time.Now()
+	j.put(load)
+
+	m.recordEvent(j, models.NewEvent(models.EntityLoad, load.LoadID, models.EventLoadStatusChanged, actor,
+		models.EventData{"status": previous}, models.EventData{"status": load.Status}).For(load.ShipperID, ""))
+	return load, nil
+}
+
+// Booking operations
+func (m *MemoryStore) ListLoads(ctx context.Context, filter *models.LoadFilter, opts *models.ListOptions) (*models.LoadPage, error) {
+	if err := opts.Normalize(models.LoadSorts); err != nil {
+		return nil, err
+	}
+
+	m.loadMu.RLock()
+	var loads []*models.Load
+	var keys []models.SortKey
+	for _, load := range m.loads {
+		if filter.Matches(load) {
+			loads = append(loads, load)
+			keys = append(keys, load.SortKey(opts.Sort))
+		}
+	}
+	m.loadMu.RUnlock()
+
+	page := &models.LoadPage{Loads: []*models.Load{}}
+	indexes, next := pageIndexes(keys, opts)
+	for _, i := range indexes {
+		page.Loads = append(page.Loads, loads[i])
 	}
+	page.NextCursor = next
+	return page, nil
+}
 
-	// Check if trucker exists
-	trucker, err := m.GetTrucker(truckerID)
+func (m *MemoryStore) CreateBooking(ctx context.Context, loadID, truckerID string, weight float64, actor models.Actor) (*models.Booking, error) {
+	// First check if load and trucker exist
+	load, err := m.GetLoad(ctx, loadID)
 	if err != nil {
 		return nil, err
 	}
-	if !trucker.Available {
-		return nil, fmt.Errorf("trucker not available")
+	trucker, err := m.GetTrucker(ctx, truckerID)
+	if err != nil {
+		return nil, err
 	}
 
+	j := m.journal()
 	m.bookingMu.Lock()
 	defer m.bookingMu.Unlock()
+	m.loadMu.Lock()
+	defer m.loadMu.Unlock()
+	m.truckerMu.Lock()
+	defer m.truckerMu.Unlock()
+	defer j.commit()
+
+	// Allocate under the booking lock so two truckers cannot take the last share of a load
+	weight, price, err := load.Allocate(weight, trucker, m.activeBookings(trucker.TruckerID))
+	if err != nil {
+		return nil, err
+	}
 
 	m.bookingCounter++
 	now := time.Now()
 
 	booking := &models.Booking{
-		BookingID:     fmt.Sprintf("BK%05d", m.bookingCounter),
-		LoadID:        loadID,
-		TruckerID:     truckerID,
+		BookingID:     models.FormatID(models.PrefixBooking, uint64(m.bookingCounter)),
+		LoadID:        load.LoadID,
+		TruckerID:     trucker.TruckerID,
 		ShipperID:     load.ShipperID,
-		AgreedPrice:   load.Price,
-		Commission:    load.Price * 0.05, // 5% commission
-		NetAmount:     load.Price * 0.95,
+		AgreedPrice:   price,
+		Commission:    price * 0.05, // 5% commission
+		NetAmount:     price * 0.95,
+		Weight:        weight,
+		PartLoad:      load.Splittable,
 		Status:        models.BookingStatusConfirmed,
 		PaymentStatus: models.PaymentStatusPending,
 		ConfirmedAt:   &now,
 		OTP:           fmt.Sprintf("%06d", time.Now().Unix()%1000000), // Generate 6-digit OTP
+		Version:       1,
 	}
 
 	// Set ID and timestamps
@@ -276,25 +635,52 @@ func (m *MemoryStore) CreateBooking(loadID, truckerID string) (*models.Booking,
 	booking.CreatedAt = now
 	booking.UpdatedAt = now
 
-	// Update load status
-	m.loadMu.Lock()
-	load.Status = "booked"
+	// Allocate the share, booking the load once fully allocated
+	before := models.EventData{"status": load.Status, "trucks_booked": load.TrucksBooked, "booked_weight": load.BookedWeight}
+	load.AddBooking(weight)
+	load.Version++
 	load.UpdatedAt = now
-	m.loadMu.Unlock()
+	after := models.EventData{"status": load.Status, "trucks_booked": load.TrucksBooked, "booked_weight": load.BookedWeight, "booking_id": booking.BookingID}
+	j.put(load)
 
 	// Update trucker availability
-	m.truckerMu.Lock()
+	wasAvailable := trucker.Available
 	trucker.Available = false
+	trucker.Version++
 	trucker.UpdatedAt = now
-	m.truckerMu.Unlock()
+	j.put(trucker)
 
 	m.bookings[booking.ID] = booking
 	m.bookingsByBookingID[booking.BookingID] = booking
+	j.put(booking)
+
+	m.recordEvent(j, models.NewEvent(models.EntityBooking, booking.BookingID, models.EventBookingCreated, actor, nil,
+		models.EventData{"status": booking.Status, "load_id": booking.LoadID, "trucker_id": booking.TruckerID, "agreed_price": booking.AgreedPrice, "weight": booking.Weight}).For(booking.ShipperID, booking.TruckerID))
+	loadEvent := models.EventLoadAllocated
+	if load.Status == models.LoadStatusBooked {
+		loadEvent = models.EventLoadStatusChanged
+	}
+	m.recordEvent(j, models.NewEvent(models.EntityLoad, load.LoadID, loadEvent, actor, before, after).For(load.ShipperID, booking.TruckerID))
+	if wasAvailable {
+		m.recordEvent(j, models.NewEvent(models.EntityTrucker, trucker.TruckerID, models.EventTruckerUpdated, actor,
+			models.EventData{"available": true}, models.EventData{"available": false, "booking_id": booking.BookingID}).For("", trucker.TruckerID))
+	}
 
 	return booking, nil
 }
 
-func (m *MemoryStore) GetBooking(id string) (*models.Booking, error) {
+// activeBookings lists a trucker's undelivered bookings; the caller must hold bookingMu
+func (m *MemoryStore) activeBookings(truckerID string) []*models.Booking {
+	var active []*models.Booking
+	for _, booking := range m.bookings {
+		if booking.TruckerID == truckerID && booking.IsActive() {
+			active = append(active, booking)
+		}
+	}
+	return active
+}
+
+func (m *MemoryStore) GetBooking(ctx context.Context, id string) (*models.Booking, error) {
 	m.bookingMu.RLock()
 	defer m.bookingMu.RUnlock()
 
@@ -311,10 +697,10 @@ func (m *MemoryStore) GetBooking(id string) (*models.Booking, error) {
 		}
 	}
 
-	return nil, fmt.Errorf("booking not found")
+	return nil, models.NotFound("booking")
 }
 
-func (m *MemoryStore) GetBookingsByTrucker(truckerID string) ([]*models.Booking, error) {
+func (m *MemoryStore) GetBookingsByTrucker(ctx context.Context, truckerID string) ([]*models.Booking, error) {
 	m.bookingMu.RLock()
 	defer m.bookingMu.RUnlock()
 
@@ -324,10 +710,11 @@ func (m *MemoryStore) GetBookingsByTrucker(truckerID string) ([]*models.Booking,
 			bookings = append(bookings, booking)
 		}
 	}
+	newestBookingsFirst(bookings)
 	return bookings, nil
 }
 
-func (m *MemoryStore) GetBookingsByLoad(loadID string) ([]*models.Booking, error) {
+func (m *MemoryStore) GetBookingsByLoad(ctx context.Context, loadID string) ([]*models.Booking, error) {
 	m.bookingMu.RLock()
 	defer m.bookingMu.RUnlock()
 
@@ -337,12 +724,19 @@ func (m *MemoryStore) GetBookingsByLoad(loadID string) ([]*models.Booking, error
 			bookings = append(bookings, booking)
 		}
 	}
+	newestBookingsFirst(bookings)
 	return bookings, nil
 }
 
-func (m *MemoryStore) UpdateBookingStatus(id string, status string) error {
+func (m *MemoryStore) UpdateBookingStatus(ctx context.Context, id string, status string, actor models.Actor) error {
+	j := m.journal()
 	m.bookingMu.Lock()
 	defer m.bookingMu.Unlock()
+	m.loadMu.Lock()
+	defer m.loadMu.Unlock()
+	m.truckerMu.Lock()
+	defer m.truckerMu.Unlock()
+	defer j.commit()
 
 	var booking *models.Booking
 
@@ -360,12 +754,20 @@ func (m *MemoryStore) UpdateBookingStatus(id string, status string) error {
 	}
 
 	if booking == nil {
-		return fmt.Errorf("booking not found")
+		return models.NotFound("booking")
+	}
+	if err := models.CheckVersion(ctx, "booking", booking.Version); err != nil {
+		return err
 	}
 
+	previous := booking.Status
 	booking.Status = status
+	booking.Version++
 	booking.UpdatedAt = time.Now()
 
+	m.recordEvent(j, models.NewEvent(models.EntityBooking, booking.BookingID, models.EventBookingStatusChanged, actor,
+		models.EventData{"status": previous}, models.EventData{"status": status}).For(booking.ShipperID, booking.TruckerID))
+
 	// Update timestamps based on status
 	now := time.Now()
 	switch status {
@@ -373,55 +775,183 @@ func (m *MemoryStore) UpdateBookingStatus(id string, status string) error {
 		booking.PickedUpAt = &now
 	case models.BookingStatusDelivered:
 		booking.DeliveredAt = &now
-		// Also mark load as delivered
-		m.loadMu.Lock()
-		if load, err := m.GetLoad(booking.LoadID); err == nil {
-			load.Status = "delivered"
-			load.UpdatedAt = now
+		// Also mark load as delivered once it is closed to bookings and every truck on it has delivered
+		var destination *models.Load
+		if load, exists := m.loadsByLoadID[booking.LoadID]; exists {
+			destination = load
+			if !load.IsAvailable() && !m.loadInProgress(load.LoadID) {
+				m.recordEvent(j, models.NewEvent(models.EntityLoad, load.LoadID, models.EventLoadStatusChanged, actor,
+					models.EventData{"status": load.Status}, models.EventData{"status": models.LoadStatusDelivered}).For(load.ShipperID, booking.TruckerID))
+				load.Status = models.LoadStatusDelivered
+				load.Version++
+				load.UpdatedAt = now
+				j.put(load)
+			}
 		}
-		m.loadMu.Unlock()
-		// Mark trucker as available again
-		m.truckerMu.Lock()
-		if trucker, err := m.GetTrucker(booking.TruckerID); err == nil {
-			trucker.Available = true
+		// Mark trucker as available again, at the load's destination, unless
+		// the truck is still carrying other part loads
+		if trucker, exists := m.truckersByTruckerID[booking.TruckerID]; exists {
+			before := models.EventData{"available": trucker.Available, "total_trips": trucker.TotalTrips, "current_city": trucker.CurrentCity}
+			trucker.Available = len(m.activeBookings(trucker.TruckerID)) == 0
 			trucker.TotalTrips++
+			if destination != nil {
+				trucker.UpdateLocation(destination.ToCity, destination.ToCityID)
+			}
+			trucker.Version++
 			trucker.UpdatedAt = now
+			j.put(trucker)
+			m.recordEvent(j, models.NewEvent(models.EntityTrucker, trucker.TruckerID, models.EventTruckerUpdated, actor, before,
+				models.EventData{"available": trucker.Available, "total_trips": trucker.TotalTrips, "current_city": trucker.CurrentCity}).For("", trucker.TruckerID))
 		}
-		m.truckerMu.Unlock()
 	case models.BookingStatusCompleted:
 		booking.CompletedAt = &now
 		booking.PaymentStatus = models.PaymentStatusCompleted
 	}
+	j.put(booking)
+
+	return nil
+}
+
+// loadInProgress reports whether any booking of a load is undelivered; the caller must hold bookingMu
+func (m *MemoryStore) loadInProgress(loadID string) bool {
+	for _, booking := range m.bookings {
+		if booking.LoadID == loadID && booking.IsActive() {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *MemoryStore) UpdateBookingPOD(ctx context.Context, id string, podURL string, actor models.Actor) error {
+	j := m.journal()
+	m.bookingMu.Lock()
+	defer m.bookingMu.Unlock()
+	defer j.commit()
+
+	booking, exists := m.bookingsByBookingID[id]
+	if !exists {
+		var uintID uint
+		if _, err := fmt.Sscanf(id, "%d", &uintID); err == nil {
+			booking, exists = m.bookings[uintID]
+		}
+	}
+
+	if !exists {
+		return models.NotFound("booking")
+	}
+	if err := models.CheckVersion(ctx, "booking", booking.Version); err != nil {
+		return err
+	}
+
+	previous := booking.PodURL
+	booking.PodURL = podURL
+	booking.Version++
+	booking.UpdatedAt = time.Now()
+	j.put(booking)
+
+	m.recordEvent(j, models.NewEvent(models.EntityBooking, booking.BookingID, models.EventPODUploaded, actor,
+		models.EventData{"pod_url": previous}, models.EventData{"pod_url": podURL}).For(booking.ShipperID, booking.TruckerID))
 
 	return nil
 }
 
+func (m *MemoryStore) ListBookings(ctx context.Context, filter *models.BookingFilter, opts *models.ListOptions) (*models.BookingPage, error) {
+	if err := opts.Normalize(models.BookingSorts); err != nil {
+		return nil, err
+	}
+
+	m.bookingMu.RLock()
+	var bookings []*models.Booking
+	var keys []models.SortKey
+	for _, booking := range m.bookings {
+		if filter.Matches(booking) {
+			bookings = append(bookings, booking)
+			keys = append(keys, booking.SortKey(opts.Sort))
+		}
+	}
+	m.bookingMu.RUnlock()
+
+	page := &models.BookingPage{Bookings: []*models.Booking{}}
+	indexes, next := pageIndexes(keys, opts)
+	for _, i := range indexes {
+		page.Bookings = append(page.Bookings, bookings[i])
+	}
+	page.NextCursor = next
+	return page, nil
+}
+
+// pageIndexes sorts rows by their keys and returns the indexes of the page
+// after opts.Cursor, with the cursor of the following page if there is one
+func pageIndexes(keys []models.SortKey, opts *models.ListOptions) ([]int, string) {
+	indexes := make([]int, len(keys))
+	for i := range indexes {
+		indexes[i] = i
+	}
+
+	desc := opts.Order == models.SortDesc
+	sort.Slice(indexes, func(a, b int) bool {
+		cmp := keys[indexes[a]].Compare(keys[indexes[b]])
+		if desc {
+			return cmp > 0
+		}
+		return cmp < 0
+	})
+
+	start := 0
+	if opts.Cursor != "" {
+		cursor, _ := models.ParseCursor(opts.Cursor)
+		start = len(indexes)
+		for pos, i := range indexes {
+			cmp := keys[i].Compare(*cursor)
+			if (desc && cmp < 0) || (!desc && cmp > 0) {
+				start = pos
+				break
+			}
+		}
+	}
+
+	end := start + opts.Limit
+	if end >= len(indexes) {
+		return indexes[start:], ""
+	}
+	return indexes[start:end], keys[indexes[end-1]].Cursor()
+}
+
 // Shipper operations
-func (m *MemoryStore) CreateShipper(shipper *models.Shipper) (*models.Shipper, error) {
+func (m *MemoryStore) CreateShipper(ctx context.Context, shipper *models.Shipper, actor models.Actor) (*models.Shipper, error) {
+	j := m.journal()
 	m.mu.Lock()
 	defer m.mu.Unlock()
+	defer j.commit()
 
 	// Check if phone already exists
 	for _, s := range m.shippers {
 		if s.Phone == shipper.Phone {
-			return nil, fmt.Errorf("phone number already registered")
+			return nil, models.Conflict("shipper", "phone", "phone number already registered")
 		}
 		if s.GSTNumber == shipper.GSTNumber {
-			return nil, fmt.Errorf("GST number already registered")
+			return nil, models.Conflict("shipper", "gst_number", "GST number already registered")
 		}
 	}
 
 	// Generate ShipperID
-	shipper.ID = uint(len(m.shippers) + 1)
-	shipper.ShipperID = fmt.Sprintf("SH%05d", shipper.ID)
+	m.shipperCounter++
+	shipper.ID = m.shipperCounter
+	shipper.ShipperID = models.FormatID(models.PrefixShipper, uint64(m.shipperCounter))
+	shipper.Active = true
 	shipper.CreatedAt = time.Now()
 	shipper.UpdatedAt = time.Now()
 
 	m.shippers[shipper.ShipperID] = shipper
+	j.put(shipper)
+
+	m.recordEvent(j, models.NewEvent(models.EntityShipper, shipper.ShipperID, models.EventShipperRegistered, actor, nil,
+		models.EventData{"company_name": shipper.CompanyName, "gst_number": shipper.GSTNumber}).For(shipper.ShipperID, ""))
+
 	return shipper, nil
 }
 
-func (m *MemoryStore) GetShipper(id string) (*models.Shipper, error) {
+func (m *MemoryStore) GetShipper(ctx context.Context, id string) (*models.Shipper, error) {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
@@ -436,10 +966,10 @@ func (m *MemoryStore) GetShipper(id string) (*models.Shipper, error) {
 		}
 	}
 
-	return nil, fmt.Errorf("shipper not found")
+	return nil, models.NotFound("shipper")
 }
 
-func (m *MemoryStore) GetShipperByPhone(phone string) (*models.Shipper, error) {
+func (m *MemoryStore) GetShipperByPhone(ctx context.Context, phone string) (*models.Shipper, error) {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
@@ -448,10 +978,10 @@ func (m *MemoryStore) GetShipperByPhone(phone string) (*models.Shipper, error) {
 			return shipper, nil
 		}
 	}
-	return nil, fmt.Errorf("shipper not found")
+	return nil, models.NotFound("shipper")
 }
 
-func (m *MemoryStore) GetShipperByGST(gst string) (*models.Shipper, error) {
+func (m *MemoryStore) GetShipperByGST(ctx context.Context, gst string) (*models.Shipper, error) {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
@@ -460,12 +990,12 @@ func (m *MemoryStore) GetShipperByGST(gst string) (*models.Shipper, error) {
 			return shipper, nil
 		}
 	}
-	return nil, fmt.Errorf("shipper not found")
+	return nil, models.NotFound("shipper")
 }
 
-func (m *MemoryStore) GetLoadsByShipper(shipperID string) ([]*models.Load, error) {
-	m.mu.RLock()
-	defer m.mu.RUnlock()
+func (m *MemoryStore) GetLoadsByShipper(ctx context.Context, shipperID string) ([]*models.Load, error) {
+	m.loadMu.RLock()
+	defer m.loadMu.RUnlock()
 
 	var loads []*models.Load
 	for _, load := range m.loads {
@@ -474,10 +1004,1267 @@ func (m *MemoryStore) GetLoadsByShipper(shipperID string) ([]*models.Load, error
 		}
 	}
 
-	// Sort by created date (newest first)
-	sort.Slice(loads, func(i, j int) bool {
-		return loads[i].CreatedAt.After(loads[j].CreatedAt)
-	})
-
+	newestLoadsFirst(loads)
 	return loads, nil
 }
+
+// Account and listing administration
+
+// DeactivateTrucker takes a trucker off the platform, cancelling the
+// bookings they have not picked up yet
+func (m *MemoryStore) DeactivateTrucker(ctx context.Context, id string, actor models.Actor) (*models.Trucker, error) {
+	return m.closeTrucker(ctx, id, false, actor)
+}
+
+// DeleteTrucker deactivates a trucker and soft-deletes them
+func (m *MemoryStore) DeleteTrucker(ctx context.Context, id string, actor models.Actor) error {
+	_, err := m.closeTrucker(ctx, id, true, actor)
+	return err
+}
+
+func (m *MemoryStore) closeTrucker(ctx context.Context, id string, remove bool, actor models.Actor) (*models.Trucker, error) {
+	j := m.journal()
+	m.bookingMu.Lock()
+	defer m.bookingMu.Unlock()
+	m.loadMu.Lock()
+	defer m.loadMu.Unlock()
+	m.truckerMu.Lock()
+	defer m.truckerMu.Unlock()
+	defer j.commit()
+
+	return m.closeTruckerLocked(ctx, j, id, remove, actor)
+}
+
+// closeTruckerLocked deactivates a trucker, deleting it too if remove is
+// set; the caller must hold bookingMu, loadMu and truckerMu
+func (m *MemoryStore) closeTruckerLocked(ctx context.Context, j *journal, id string, remove bool, actor models.Actor) (*models.Trucker, error) {
+	trucker, err := m.lookupTrucker(id)
+	if err != nil {
+		return nil, err
+	}
+	if !remove && !trucker.Active {
+		return trucker, nil
+	}
+	if err := models.CheckVersion(ctx, "trucker", trucker.Version); err != nil {
+		return nil, err
+	}
+	open, err := m.openBookings("trucker", func(booking *models.Booking) bool { return booking.TruckerID == trucker.TruckerID })
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	before := models.EventData{"active": trucker.Active, "available": trucker.Available}
+	eventType := models.EventTruckerDeactivated
+	trucker.Active = false
+	trucker.Available = false
+	if remove {
+		trucker.DeletedAt = gorm.DeletedAt{Time: now, Valid: true}
+		delete(m.truckers, trucker.ID)
+		delete(m.truckersByTruckerID, trucker.TruckerID)
+		m.deletedTruckers[trucker.ID] = trucker
+		eventType = models.EventTruckerDeleted
+	}
+	trucker.Version++
+	trucker.UpdatedAt = now
+	j.put(trucker)
+
+	m.cancelBookings(j, open, actor)
+	m.recordEvent(j, models.NewEvent(models.EntityTrucker, trucker.TruckerID, eventType, actor, before,
+		models.EventData{"active": false, "available": false, "bookings_cancelled": len(open)}).For("", trucker.TruckerID))
+	return trucker, nil
+}
+
+// RestoreTrucker undeletes and reactivates a trucker
+func (m *MemoryStore) RestoreTrucker(ctx context.Context, id string, actor models.Actor) (*models.Trucker, error) {
+	j := m.journal()
+	m.truckerMu.Lock()
+	defer m.truckerMu.Unlock()
+	defer j.commit()
+
+	trucker, deleted := findDeleted(m.deletedTruckers, func(t *models.Trucker) bool { return t.TruckerID == id || fmt.Sprint(t.ID) == id })
+	if !deleted {
+		var err error
+		if trucker, err = m.lookupTrucker(id); err != nil {
+			return nil, err
+		}
+		if trucker.Active {
+			return trucker, nil
+		}
+	}
+	for _, t := range m.truckers {
+		if t == trucker {
+			continue
+		}
+		if t.Phone == trucker.Phone {
+			return nil, models.Conflict("trucker", "phone", "phone number registered again since")
+		}
+		if t.VehicleNo == trucker.VehicleNo {
+			return nil, models.Conflict("trucker", "vehicle_no", "vehicle registered again since")
+		}
+	}
+
+	if deleted {
+		trucker.DeletedAt = gorm.DeletedAt{}
+		delete(m.deletedTruckers, trucker.ID)
+		m.truckers[trucker.ID] = trucker
+		m.truckersByTruckerID[trucker.TruckerID] = trucker
+	}
+	// Deactivation cancelled every booking, so the truck is free
+	trucker.Active = true
+	trucker.Available = true
+	trucker.Version++
+	trucker.UpdatedAt = time.Now()
+	j.put(trucker)
+
+	m.recordEvent(j, models.NewEvent(models.EntityTrucker, trucker.TruckerID, models.EventTruckerRestored, actor,
+		models.EventData{"active": false, "deleted": deleted}, models.EventData{"active": true, "available": true}).For("", trucker.TruckerID))
+	return trucker, nil
+}
+
+// lookupTrucker finds a trucker by TruckerID or numeric ID; the caller must hold truckerMu
+func (m *MemoryStore) lookupTrucker(id string) (*models.Trucker, error) {
+	if trucker, exists := m.truckersByTruckerID[id]; exists {
+		return trucker, nil
+	}
+	var uintID uint
+	if _, err := fmt.Sscanf(id, "%d", &uintID); err == nil {
+		if trucker, exists := m.truckers[uintID]; exists {
+			return trucker, nil
+		}
+	}
+	return nil, models.NotFound("trucker")
+}
+
+// DeactivateShipper stops a shipper posting loads, withdrawing their open
+// loads and cancelling the bookings on them not picked up yet
+func (m *MemoryStore) DeactivateShipper(ctx context.Context, id string, actor models.Actor) (*models.Shipper, error) {
+	return m.closeShipper(ctx, id, false, actor)
+}
+
+// DeleteShipper deactivates a shipper and soft-deletes them; their loads
+// stay, withdrawn, for the bookings made on them
+func (m *MemoryStore) DeleteShipper(ctx context.Context, id string, actor models.Actor) error {
+	_, err := m.closeShipper(ctx, id, true, actor)
+	return err
+}
+
+func (m *MemoryStore) closeShipper(ctx context.Context, id string, remove bool, actor models.Actor) (*models.Shipper, error) {
+	j := m.journal()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.bookingMu.Lock()
+	defer m.bookingMu.Unlock()
+	m.loadMu.Lock()
+	defer m.loadMu.Unlock()
+	m.truckerMu.Lock()
+	defer m.truckerMu.Unlock()
+	defer j.commit()
+
+	return m.closeShipperLocked(j, id, remove, actor)
+}
+
+// closeShipperLocked deactivates a shipper, deleting it too if remove is
+// set; the caller must hold mu, bookingMu, loadMu and truckerMu
+func (m *MemoryStore) closeShipperLocked(j *journal, id string, remove bool, actor models.Actor) (*models.Shipper, error) {
+	shipper, err := m.lookupShipper(id)
+	if err != nil {
+		return nil, err
+	}
+	if !remove && !shipper.Active {
+		return shipper, nil
+	}
+	open, err := m.openBookings("shipper", func(booking *models.Booking) bool { return booking.ShipperID == shipper.ShipperID })
+	if err != nil {
+		return nil, err
+	}
+	m.cancelBookings(j, open, actor)
+
+	withdrawn := 0
+	for _, load := range sortedByID(m.loads) {
+		if load.ShipperID == shipper.ShipperID && m.withdrawOpenLoad(j, load, actor) {
+			withdrawn++
+		}
+	}
+
+	now := time.Now()
+	eventType := models.EventShipperDeactivated
+	shipper.Active = false
+	if remove {
+		shipper.DeletedAt = gorm.DeletedAt{Time: now, Valid: true}
+		delete(m.shippers, shipper.ShipperID)
+		m.deletedShippers[shipper.ShipperID] = shipper
+		eventType = models.EventShipperDeleted
+	}
+	shipper.UpdatedAt = now
+	j.put(shipper)
+
+	m.recordEvent(j, models.NewEvent(models.EntityShipper, shipper.ShipperID, eventType, actor, models.EventData{"active": true},
+		models.EventData{"active": false, "bookings_cancelled": len(open), "loads_withdrawn": withdrawn}).For(shipper.ShipperID, ""))
+	return shipper, nil
+}
+
+func (m *MemoryStore) DeleteAccount(ctx context.Context, truckerID, shipperID string, actor models.Actor) error {
+	j := m.journal()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.bookingMu.Lock()
+	defer m.bookingMu.Unlock()
+	m.loadMu.Lock()
+	defer m.loadMu.Unlock()
+	m.truckerMu.Lock()
+	defer m.truckerMu.Unlock()
+	defer j.commit()
+
+	// Nothing is rolled back here, so check both can go before deleting either
+	if truckerID != "" {
+		trucker, err := m.lookupTrucker(truckerID)
+		if err != nil {
+			return err
+		}
+		if err := models.CheckVersion(ctx, "trucker", trucker.Version); err != nil {
+			return err
+		}
+		if _, err := m.openBookings("trucker", func(booking *models.Booking) bool { return booking.TruckerID == trucker.TruckerID }); err != nil {
+			return err
+		}
+	}
+	if shipperID != "" {
+		shipper, err := m.lookupShipper(shipperID)
+		if err != nil {
+			return err
+		}
+		if _, err := m.openBookings("shipper", func(booking *models.Booking) bool { return booking.ShipperID == shipper.ShipperID }); err != nil {
+			return err
+		}
+	}
+
+	if truckerID != "" {
+		if _, err := m.closeTruckerLocked(ctx, j, truckerID, true, actor); err != nil {
+			return err
+		}
+	}
+	if shipperID != "" {
+		if _, err := m.closeShipperLocked(j, shipperID, true, actor); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RestoreShipper undeletes and reactivates a shipper. Their withdrawn loads
+// stay withdrawn; they can post or renew them again.
+func (m *MemoryStore) RestoreShipper(ctx context.Context, id string, actor models.Actor) (*models.Shipper, error) {
+	j := m.journal()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	defer j.commit()
+
+	shipper, deleted := findDeleted(m.deletedShippers, func(s *models.Shipper) bool { return s.ShipperID == id || fmt.Sprint(s.ID) == id })
+	if !deleted {
+		var err error
+		if shipper, err = m.lookupShipper(id); err != nil {
+			return nil, err
+		}
+		if shipper.Active {
+			return shipper, nil
+		}
+	}
+	for _, s := range m.shippers {
+		if s == shipper {
+			continue
+		}
+		if s.Phone == shipper.Phone {
+			return nil, models.Conflict("shipper", "phone", "phone number registered again since")
+		}
+		if s.GSTNumber == shipper.GSTNumber {
+			return nil, models.Conflict("shipper", "gst_number", "GST number registered again since")
+		}
+	}
+
+	if deleted {
+		shipper.DeletedAt = gorm.DeletedAt{}
+		delete(m.deletedShippers, shipper.ShipperID)
+		m.shippers[shipper.ShipperID] = shipper
+	}
+	shipper.Active = true
+	shipper.UpdatedAt = time.Now()
+	j.put(shipper)
+
+	m.recordEvent(j, models.NewEvent(models.EntityShipper, shipper.ShipperID, models.EventShipperRestored, actor,
+		models.EventData{"active": false, "deleted": deleted}, models.EventData{"active": true}).For(shipper.ShipperID, ""))
+	return shipper, nil
+}
+
+// lookupShipper finds a shipper by ShipperID or numeric ID; the caller must hold mu
+func (m *MemoryStore) lookupShipper(id string) (*models.Shipper, error) {
+	if shipper, exists := m.shippers[id]; exists {
+		return shipper, nil
+	}
+	for _, shipper := range m.shippers {
+		if fmt.Sprintf("%d", shipper.ID) == id {
+			return shipper, nil
+		}
+	}
+	return nil, models.NotFound("shipper")
+}
+
+// DeactivateLoad withdraws a load, cancelling the bookings on it not picked
+// up yet. A load that is no longer open is returned unchanged.
+func (m *MemoryStore) DeactivateLoad(ctx context.Context, id string, actor models.Actor) (*models.Load, error) {
+	return m.closeLoad(ctx, id, false, actor)
+}
+
+// DeleteLoad withdraws a load if still open and soft-deletes it
+func (m *MemoryStore) DeleteLoad(ctx context.Context, id string, actor models.Actor) error {
+	_, err := m.closeLoad(ctx, id, true, actor)
+	return err
+}
+
+func (m *MemoryStore) closeLoad(ctx context.Context, id string, remove bool, actor models.Actor) (*models.Load, error) {
+	j := m.journal()
+	m.bookingMu.Lock()
+	defer m.bookingMu.Unlock()
+	m.loadMu.Lock()
+	defer m.loadMu.Unlock()
+	m.truckerMu.Lock()
+	defer m.truckerMu.Unlock()
+	defer j.commit()
+
+	load, err := m.lookupLoad(id)
+	if err != nil {
+		return nil, err
+	}
+	if !remove && !load.IsOpen() {
+		return load, nil
+	}
+	if err := models.CheckVersion(ctx, "load", load.Version); err != nil {
+		return nil, err
+	}
+	open, err := m.openBookings("load", func(booking *models.Booking) bool { return booking.LoadID == load.LoadID })
+	if err != nil {
+		return nil, err
+	}
+	m.cancelBookings(j, open, actor)
+	m.withdrawOpenLoad(j, load, actor)
+
+	if remove {
+		now := time.Now()
+		load.DeletedAt = gorm.DeletedAt{Time: now, Valid: true}
+		load.Version++
+		load.UpdatedAt = now
+		delete(m.loads, load.ID)
+		delete(m.loadsByLoadID, load.LoadID)
+		m.deletedLoads[load.ID] = load
+		j.put(load)
+
+		m.recordEvent(j, models.NewEvent(models.EntityLoad, load.LoadID, models.EventLoadDeleted, actor, nil,
+			models.EventData{"status": load.Status, "bookings_cancelled": len(open)}).For(load.ShipperID, ""))
+	}
+	return load, nil
+}
+
+// RestoreLoad undeletes a load and relists it if it was withdrawn. The
+// shipper must still be active.
+func (m *MemoryStore) RestoreLoad(ctx context.Context, id string, actor models.Actor) (*models.Load, error) {
+	j := m.journal()
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	m.loadMu.Lock()
+	defer m.loadMu.Unlock()
+	defer j.commit()
+
+	load, deleted := findDeleted(m.deletedLoads, func(l *models.Load) bool { return l.LoadID == id || fmt.Sprint(l.ID) == id })
+	if !deleted {
+		var err error
+		if load, err = m.lookupLoad(id); err != nil {
+			return nil, err
+		}
+		if load.Status != models.LoadStatusWithdrawn {
+			return load, nil
+		}
+	}
+	if err := m.checkShipperActive(load.ShipperID); err != nil {
+		return nil, err
+	}
+
+	if deleted {
+		load.DeletedAt = gorm.DeletedAt{}
+		delete(m.deletedLoads, load.ID)
+		m.loads[load.ID] = load
+		m.loadsByLoadID[load.LoadID] = load
+	}
+	before := models.EventData{"status": load.Status, "deleted": deleted}
+	if load.Status == models.LoadStatusWithdrawn {
+		load.Status = models.LoadStatusAvailable
+	}
+	load.Version++
+	load.UpdatedAt = time.Now()
+	j.put(load)
+
+	m.recordEvent(j, models.NewEvent(models.EntityLoad, load.LoadID, models.EventLoadRestored, actor, before,
+		models.EventData{"status": load.Status}).For(load.ShipperID, ""))
+	return load, nil
+}
+
+// openBookings lists the active bookings matched, oldest first, or fails if
+// one of them is in transit; the caller must hold bookingMu
+func (m *MemoryStore) openBookings(entity string, match func(booking *models.Booking) bool) ([]*models.Booking, error) {
+	var open []*models.Booking
+	for _, booking := range sortedByID(m.bookings) {
+		if !booking.IsActive() || !match(booking) {
+			continue
+		}
+		if !booking.IsCancellable() {
+			return nil, models.Unavailable(entity, "", fmt.Sprintf("booking %s is in transit; wait for its delivery", booking.BookingID))
+		}
+		open = append(open, booking)
+	}
+	return open, nil
+}
+
+// cancelBookings cancels bookings, handing their share back to the load and
+// freeing trucks left without work; the caller must hold bookingMu, loadMu
+// and truckerMu
+func (m *MemoryStore) cancelBookings(j *journal, bookings []*models.Booking, actor models.Actor) {
+	now := time.Now()
+	for _, booking := range bookings {
+		previous := booking.Status
+		booking.Status = models.BookingStatusCancelled
+		booking.Version++
+		booking.UpdatedAt = now
+		j.put(booking)
+		m.recordEvent(j, models.NewEvent(models.EntityBooking, booking.BookingID, models.EventBookingStatusChanged, actor,
+			models.EventData{"status": previous}, models.EventData{"status": booking.Status}).For(booking.ShipperID, booking.TruckerID))
+
+		if load, exists := m.loadsByLoadID[booking.LoadID]; exists {
+			before := models.EventData{"status": load.Status, "trucks_booked": load.TrucksBooked, "booked_weight": load.BookedWeight}
+			load.RemoveBooking(booking.Weight)
+			load.Version++
+			load.UpdatedAt = now
+			j.put(load)
+			m.recordEvent(j, models.NewEvent(models.EntityLoad, load.LoadID, models.EventLoadReleased, actor, before,
+				models.EventData{"status": load.Status, "trucks_booked": load.TrucksBooked, "booked_weight": load.BookedWeight, "booking_id": booking.BookingID}).For(load.ShipperID, booking.TruckerID))
+		}
+
+		if trucker, exists := m.truckersByTruckerID[booking.TruckerID]; exists && trucker.Active && !trucker.Available &&
+			len(m.activeBookings(trucker.TruckerID)) == 0 {
+			trucker.Available = true
+			trucker.Version++
+			trucker.UpdatedAt = now
+			j.put(trucker)
+			m.recordEvent(j, models.NewEvent(models.EntityTrucker, trucker.TruckerID, models.EventTruckerUpdated, actor,
+				models.EventData{"available": false}, models.EventData{"available": true, "booking_id": booking.BookingID}).For("", trucker.TruckerID))
+		}
+	}
+}
+
+// withdrawOpenLoad takes an available load off the marketplace and reports
+// whether it did; the caller must hold loadMu
+func (m *MemoryStore) withdrawOpenLoad(j *journal, load *models.Load, actor models.Actor) bool {
+	if !load.IsAvailable() {
+		return false
+	}
+	load.Status = models.LoadStatusWithdrawn
+	load.Version++
+	load.UpdatedAt = time.Now()
+	j.put(load)
+	m.recordEvent(j, models.NewEvent(models.EntityLoad, load.LoadID, models.EventLoadStatusChanged, actor,
+		models.EventData{"status": models.LoadStatusAvailable}, models.EventData{"status": load.Status}).For(load.ShipperID, ""))
+	return true
+}
+
+// findDeleted finds the soft-deleted row matched
+func findDeleted[K comparable, T any](rows map[K]*T, match func(row *T) bool) (*T, bool) {
+	for _, row := range rows {
+		if match(row) {
+			return row, true
+		}
+	}
+	return nil, false
+}
+
+// newestLoadsFirst sorts loads by creation time, newest first, like the database store
+func newestLoadsFirst(loads []*models.Load) {
+	sort.Slice(loads, func(i, j int) bool {
+		if !loads[i].CreatedAt.Equal(loads[j].CreatedAt) {
+			return loads[i].CreatedAt.After(loads[j].CreatedAt)
+		}
+		return loads[i].ID > loads[j].ID
+	})
+}
+
+// newestBookingsFirst sorts bookings by creation time, newest first, like the database store
+func newestBookingsFirst(bookings []*models.Booking) {
+	sort.Slice(bookings, func(i, j int) bool {
+		if !bookings[i].CreatedAt.Equal(bookings[j].CreatedAt) {
+			return bookings[i].CreatedAt.After(bookings[j].CreatedAt)
+		}
+		return bookings[i].ID > bookings[j].ID
+	})
+}
+
+// WhatsApp session operations
+func (m *MemoryStore) GetSession(ctx context.Context, phone string) (*models.WhatsAppSession, error) {
+	m.sessionMu.RLock()
+	defer m.sessionMu.RUnlock()
+
+	session, exists := m.sessions[phone]
+	if !exists || time.Now().After(session.ExpiresAt) {
+		return nil, models.NotFound("session")
+	}
+	return session, nil
+}
+
+func (m *MemoryStore) SaveSession(ctx context.Context, session *models.WhatsAppSession) error {
+	j := m.journal()
+	m.sessionMu.Lock()
+	defer m.sessionMu.Unlock()
+	defer j.commit()
+
+	now := time.Now()
+	if existing, exists := m.sessions[session.PhoneNumber]; exists {
+		session.ID = existing.ID
+		session.CreatedAt = existing.CreatedAt
+	} else {
+		m.sessionCounter++
+		session.ID = m.sessionCounter
+		session.CreatedAt = now
+	}
+	session.UpdatedAt = now
+
+	m.sessions[session.PhoneNumber] = session
+	j.put(session)
+	return nil
+}
+
+// Tracking link operations
+func (m *MemoryStore) CreateTrackingLink(ctx context.Context, link *models.TrackingLink, actor models.Actor) (*models.TrackingLink, error) {
+	j := m.journal()
+	m.trackingMu.Lock()
+	defer m.trackingMu.Unlock()
+	defer j.commit()
+
+	if _, exists := m.trackingLinks[link.Token]; exists {
+		return nil, models.Conflict("tracking link", "", "tracking link already exists")
+	}
+
+	m.trackingCounter++
+	now := time.Now()
+
+	link.ID = m.trackingCounter
+	link.CreatedAt = now
+	link.UpdatedAt = now
+
+	m.trackingLinks[link.Token] = link
+	j.put(link)
+
+	m.recordEvent(j, models.NewEvent(models.EntityBooking, link.BookingID, models.EventTrackingLinkCreated, actor, nil,
+		models.EventData{"expires_at": link.ExpiresAt}).For(link.ShipperID, ""))
+
+	return link, nil
+}
+
+func (m *MemoryStore) GetTrackingLink(ctx context.Context, token string) (*models.TrackingLink, error) {
+	m.trackingMu.RLock()
+	defer m.trackingMu.RUnlock()
+
+	if link, exists := m.trackingLinks[token]; exists {
+		return link, nil
+	}
+	return nil, models.NotFound("tracking link")
+}
+
+func (m *MemoryStore) RevokeTrackingLinks(ctx context.Context, bookingID string, actor models.Actor) error {
+	j := m.journal()
+	m.trackingMu.Lock()
+	defer m.trackingMu.Unlock()
+	defer j.commit()
+
+	now := time.Now()
+	revoked := 0
+	shipperID := ""
+	for _, link := range m.trackingLinks {
+		if link.BookingID == bookingID && link.RevokedAt == nil {
+			link.RevokedAt = &now
+			link.UpdatedAt = now
+			j.put(link)
+			shipperID = link.ShipperID
+			revoked++
+		}
+	}
+
+	if revoked > 0 {
+		m.recordEvent(j, models.NewEvent(models.EntityBooking, bookingID, models.EventTrackingLinkRevoked, actor, nil,
+			models.EventData{"revoked": revoked}).For(shipperID, ""))
+	}
+	return nil
+}
+
+// Webhook operations
+func (m *MemoryStore) CreateWebhookEndpoint(ctx context.Context, endpoint *models.WebhookEndpoint) (*models.WebhookEndpoint, error) {
+	j := m.journal()
+	m.webhookMu.Lock()
+	defer m.webhookMu.Unlock()
+	defer j.commit()
+
+	m.endpointCounter++
+	now := time.Now()
+
+	endpoint.ID = m.endpointCounter
+	endpoint.EndpointID = models.FormatID(models.PrefixWebhookEndpoint, uint64(m.endpointCounter))
+	endpoint.Active = true
+	endpoint.CreatedAt = now
+	endpoint.UpdatedAt = now
+
+	m.webhookEndpoints[endpoint.EndpointID] = endpoint
+	j.put(endpoint)
+	return endpoint, nil
+}
+
+func (m *MemoryStore) GetWebhookEndpoint(ctx context.Context, id string) (*models.WebhookEndpoint, error) {
+	m.webhookMu.RLock()
+	defer m.webhookMu.RUnlock()
+
+	if endpoint, exists := m.webhookEndpoints[id]; exists {
+		return endpoint, nil
+	}
+	return nil, models.NotFound("webhook endpoint")
+}
+
+func (m *MemoryStore) GetWebhookEndpointsByShipper(ctx context.Context, shipperID string) ([]*models.WebhookEndpoint, error) {
+	m.webhookMu.RLock()
+	defer m.webhookMu.RUnlock()
+
+	var endpoints []*models.WebhookEndpoint
+	for _, endpoint := range m.webhookEndpoints {
+		if endpoint.ShipperID == shipperID {
+			endpoints = append(endpoints, endpoint)
+		}
+	}
+
+	sort.Slice(endpoints, func(i, j int) bool {
+		return endpoints[i].ID < endpoints[j].ID
+	})
+
+	return endpoints, nil
+}
+
+func (m *MemoryStore) DeactivateWebhookEndpoint(ctx context.Context, id string) error {
+	j := m.journal()
+	m.webhookMu.Lock()
+	defer m.webhookMu.Unlock()
+	defer j.commit()
+
+	endpoint, exists := m.webhookEndpoints[id]
+	if !exists {
+		return models.NotFound("webhook endpoint")
+	}
+
+	endpoint.Active = false
+	endpoint.UpdatedAt = time.Now()
+	j.put(endpoint)
+	return nil
+}
+
+func (m *MemoryStore) CreateWebhookDelivery(ctx context.Context, delivery *models.WebhookDelivery) (*models.WebhookDelivery, error) {
+	j := m.journal()
+	m.webhookMu.Lock()
+	defer m.webhookMu.Unlock()
+	defer j.commit()
+
+	m.deliveryCounter++
+	now := time.Now()
+
+	delivery.ID = m.deliveryCounter
+	delivery.DeliveryID = models.FormatID(models.PrefixWebhookDelivery, uint64(m.deliveryCounter))
+	if delivery.Status == "" {
+		delivery.Status = models.WebhookDeliveryPending
+	}
+	delivery.CreatedAt = now
+	delivery.UpdatedAt = now
+
+	m.webhookDeliveries[delivery.DeliveryID] = delivery
+	j.put(delivery)
+	return delivery, nil
+}
+
+func (m *MemoryStore) GetWebhookDelivery(ctx context.Context, id string) (*models.WebhookDelivery, error) {
+	m.webhookMu.RLock()
+	defer m.webhookMu.RUnlock()
+
+	if delivery, exists := m.webhookDeliveries[id]; exists {
+		return delivery, nil
+	}
+	return nil, models.NotFound("webhook delivery")
+}
+
+func (m *MemoryStore) GetWebhookDeliveries(ctx context.Context, endpointID string, status string) ([]*models.WebhookDelivery, error) {
+	m.webhookMu.RLock()
+	defer m.webhookMu.RUnlock()
+
+	var deliveries []*models.WebhookDelivery
+	for _, delivery := range m.webhookDeliveries {
+		if delivery.EndpointID != endpointID {
+			continue
+		}
+		if status != "" && delivery.Status != status {
+			continue
+		}
+		deliveries = append(deliveries, delivery)
+	}
+
+	// Newest first, like the database store
+	sort.Slice(deliveries, func(i, j int) bool {
+		return deliveries[i].ID > deliveries[j].ID
+	})
+
+	return deliveries, nil
+}
+
+func (m *MemoryStore) GetDueWebhookDeliveries(ctx context.Context, now time.Time) ([]*models.WebhookDelivery, error) {
+	m.webhookMu.RLock()
+	defer m.webhookMu.RUnlock()
+
+	var deliveries []*models.WebhookDelivery
+	for _, delivery := range m.webhookDeliveries {
+		if delivery.Status != models.WebhookDeliveryPending {
+			continue
+		}
+		if delivery.NextAttemptAt != nil && delivery.NextAttemptAt.After(now) {
+			continue
+		}
+		deliveries = append(deliveries, delivery)
+	}
+
+	sort.Slice(deliveries, func(i, j int) bool {
+		return deliveries[i].ID < deliveries[j].ID
+	})
+
+	return deliveries, nil
+}
+
+func (m *MemoryStore) UpdateWebhookDelivery(ctx context.Context, delivery *models.WebhookDelivery) error {
+	j := m.journal()
+	m.webhookMu.Lock()
+	defer m.webhookMu.Unlock()
+	defer j.commit()
+
+	if _, exists := m.webhookDeliveries[delivery.DeliveryID]; !exists {
+		return models.NotFound("webhook delivery")
+	}
+
+	delivery.UpdatedAt = time.Now()
+	m.webhookDeliveries[delivery.DeliveryID] = delivery
+	j.put(delivery)
+	return nil
+}
+
+// Lane alert operations
+func (m *MemoryStore) CreateLaneAlert(ctx context.Context, alert *models.LaneAlert) (*models.LaneAlert, error) {
+	j := m.journal()
+	m.alertMu.Lock()
+	defer m.alertMu.Unlock()
+	defer j.commit()
+
+	m.alertCounter++
+	now := time.Now()
+
+	alert.ID = m.alertCounter
+	alert.AlertID = models.FormatID(models.PrefixLaneAlert, uint64(m.alertCounter))
+	alert.Active = true
+	alert.CreatedAt = now
+	alert.UpdatedAt = now
+
+	m.laneAlerts[alert.AlertID] = alert
+	j.put(alert)
+	return alert, nil
+}
+
+func (m *MemoryStore) GetLaneAlert(ctx context.Context, id string) (*models.LaneAlert, error) {
+	m.alertMu.RLock()
+	defer m.alertMu.RUnlock()
+
+	if alert, exists := m.laneAlerts[id]; exists {
+		return alert, nil
+	}
+	return nil, models.NotFound("lane alert")
+}
+
+func (m *MemoryStore) GetLaneAlertsByTrucker(ctx context.Context, truckerID string) ([]*models.LaneAlert, error) {
+	return m.findLaneAlerts(func(alert *models.LaneAlert) bool {
+		return alert.TruckerID == truckerID
+	}), nil
+}
+
+func (m *MemoryStore) GetLaneAlertsByOrigin(ctx context.Context, fromCity string) ([]*models.LaneAlert, error) {
+	names := models.CityNames(fromCity)
+	return m.findLaneAlerts(func(alert *models.LaneAlert) bool {
+		for _, name := range names {
+			if strings.EqualFold(alert.FromCity, name) {
+				return true
+			}
+		}
+		return false
+	}), nil
+}
+
+// findLaneAlerts returns the active alerts accepted by match, oldest first
+func (m *MemoryStore) findLaneAlerts(match func(alert *models.LaneAlert) bool) []*models.LaneAlert {
+	m.alertMu.RLock()
+	defer m.alertMu.RUnlock()
+
+	var alerts []*models.LaneAlert
+	for _, alert := range m.laneAlerts {
+		if alert.Active && match(alert) {
+			alerts = append(alerts, alert)
+		}
+	}
+
+	sort.Slice(alerts, func(i, j int) bool {
+		return alerts[i].ID < alerts[j].ID
+	})
+	return alerts
+}
+
+func (m *MemoryStore) DeactivateLaneAlert(ctx context.Context, id string) error {
+	j := m.journal()
+	m.alertMu.Lock()
+	defer m.alertMu.Unlock()
+	defer j.commit()
+
+	alert, exists := m.laneAlerts[id]
+	if !exists {
+		return models.NotFound("lane alert")
+	}
+
+	alert.Active = false
+	alert.UpdatedAt = time.Now()
+	j.put(alert)
+	return nil
+}
+
+func (m *MemoryStore) CreateAlertNotification(ctx context.Context, notification *models.AlertNotification) error {
+	j := m.journal()
+	m.alertMu.Lock()
+	defer m.alertMu.Unlock()
+	defer j.commit()
+
+	notification.ID = uint(len(m.alertNotifications) + 1)
+	notification.CreatedAt = time.Now()
+	notification.UpdatedAt = notification.CreatedAt
+
+	m.alertNotifications = append(m.alertNotifications, notification)
+	j.put(notification)
+	return nil
+}
+
+func (m *MemoryStore) CountAlertNotifications(ctx context.Context, truckerID string, since time.Time) (int, error) {
+	m.alertMu.RLock()
+	defer m.alertMu.RUnlock()
+
+	count := 0
+	for _, notification := range m.alertNotifications {
+		if notification.TruckerID == truckerID && !notification.SentAt.Before(since) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (m *MemoryStore) GetAlertNotificationsByLoad(ctx context.Context, loadID string) ([]*models.AlertNotification, error) {
+	m.alertMu.RLock()
+	defer m.alertMu.RUnlock()
+
+	var notifications []*models.AlertNotification
+	for _, notification := range m.alertNotifications {
+		if notification.LoadID == loadID {
+			notifications = append(notifications, notification)
+		}
+	}
+	return notifications, nil
+}
+
+// Backhaul suggestion operations
+func (m *MemoryStore) CreateBackhaulSuggestion(ctx context.Context, suggestion *models.BackhaulSuggestion) error {
+	j := m.journal()
+	m.backhaulMu.Lock()
+	defer m.backhaulMu.Unlock()
+	defer j.commit()
+
+	suggestion.ID = uint(len(m.backhaulSuggestions) + 1)
+	suggestion.CreatedAt = time.Now()
+	suggestion.UpdatedAt = suggestion.CreatedAt
+
+	m.backhaulSuggestions = append(m.backhaulSuggestions, suggestion)
+	j.put(suggestion)
+	return nil
+}
+
+func (m *MemoryStore) ConvertBackhaulSuggestion(ctx context.Context, truckerID, loadID, bookingID string) error {
+	j := m.journal()
+	m.backhaulMu.Lock()
+	defer m.backhaulMu.Unlock()
+	defer j.commit()
+
+	for i := len(m.backhaulSuggestions) - 1; i >= 0; i-- {
+		suggestion := m.backhaulSuggestions[i]
+		if suggestion.TruckerID == truckerID && suggestion.LoadID == loadID && suggestion.ConvertedBookingID == "" {
+			now := time.Now()
+			suggestion.ConvertedBookingID = bookingID
+			suggestion.ConvertedAt = &now
+			suggestion.UpdatedAt = now
+			j.put(suggestion)
+			return nil
+		}
+	}
+	return models.NotFound("backhaul suggestion")
+}
+
+func (m *MemoryStore) GetBackhaulStats(ctx context.Context, since time.Time) (*models.BackhaulStats, error) {
+	m.backhaulMu.RLock()
+	defer m.backhaulMu.RUnlock()
+
+	stats := &models.BackhaulStats{}
+	for _, suggestion := range m.backhaulSuggestions {
+		if suggestion.SentAt.Before(since) {
+			continue
+		}
+		stats.Suggested++
+		if suggestion.ConvertedBookingID != "" {
+			stats.Converted++
+		}
+	}
+	if stats.Suggested > 0 {
+		stats.ConversionRate = float64(stats.Converted) / float64(stats.Suggested)
+	}
+	return stats, nil
+}
+
+func (m *MemoryStore) GetBackhaulSuggestionsByLoad(ctx context.Context, loadID string) ([]*models.BackhaulSuggestion, error) {
+	m.backhaulMu.RLock()
+	defer m.backhaulMu.RUnlock()
+
+	var suggestions []*models.BackhaulSuggestion
+	for _, suggestion := range m.backhaulSuggestions {
+		if suggestion.LoadID == loadID {
+			suggestions = append(suggestions, suggestion)
+		}
+	}
+	return suggestions, nil
+}
+
+// Recurring load operations
+func (m *MemoryStore) CreateRecurringLoad(ctx context.Context, recurring *models.RecurringLoad) (*models.RecurringLoad, error) {
+	j := m.journal()
+	m.recurringMu.Lock()
+	defer m.recurringMu.Unlock()
+	defer j.commit()
+
+	m.recurringCounter++
+	now := time.Now()
+
+	recurring.ID = m.recurringCounter
+	recurring.RecurringID = models.FormatID(models.PrefixRecurringLoad, uint64(m.recurringCounter))
+	recurring.Version = 1
+	recurring.CreatedAt = now
+	recurring.UpdatedAt = now
+
+	// Callers get copies, so a change only takes effect through UpdateRecurringLoad
+	stored := *recurring
+	m.recurringLoads[recurring.RecurringID] = &stored
+	j.put(&stored)
+	return recurring, nil
+}
+
+func (m *MemoryStore) GetRecurringLoad(ctx context.Context, id string) (*models.RecurringLoad, error) {
+	m.recurringMu.RLock()
+	defer m.recurringMu.RUnlock()
+
+	if recurring, exists := m.recurringLoads[id]; exists {
+		copied := *recurring
+		return &copied, nil
+	}
+	return nil, models.NotFound("recurring load")
+}
+
+func (m *MemoryStore) GetRecurringLoadsByShipper(ctx context.Context, shipperID string) ([]*models.RecurringLoad, error) {
+	return m.findRecurringLoads(func(recurring *models.RecurringLoad) bool {
+		return recurring.ShipperID == shipperID
+	}), nil
+}
+
+func (m *MemoryStore) GetActiveRecurringLoads(ctx context.Context) ([]*models.RecurringLoad, error) {
+	return m.findRecurringLoads(func(recurring *models.RecurringLoad) bool {
+		return !recurring.Paused
+	}), nil
+}
+
+// findRecurringLoads returns the recurring loads accepted by match, oldest first
+func (m *MemoryStore) findRecurringLoads(match func(recurring *models.RecurringLoad) bool) []*models.RecurringLoad {
+	m.recurringMu.RLock()
+	defer m.recurringMu.RUnlock()
+
+	var recurringLoads []*models.RecurringLoad
+	for _, recurring := range m.recurringLoads {
+		if match(recurring) {
+			copied := *recurring
+			recurringLoads = append(recurringLoads, &copied)
+		}
+	}
+
+	sort.Slice(recurringLoads, func(i, j int) bool {
+		return recurringLoads[i].ID < recurringLoads[j].ID
+	})
+	return recurringLoads
+}
+
+func (m *MemoryStore) UpdateRecurringLoad(ctx context.Context, recurring *models.RecurringLoad) error {
+	j := m.journal()
+	m.recurringMu.Lock()
+	defer m.recurringMu.Unlock()
+	defer j.commit()
+
+	existing, exists := m.recurringLoads[recurring.RecurringID]
+	if !exists {
+		return models.NotFound("recurring load")
+	}
+	if existing.Version != recurring.Version {
+		return recurringLoadChanged()
+	}
+
+	recurring.ID = existing.ID
+	recurring.CreatedAt = existing.CreatedAt
+	recurring.Version++
+	recurring.UpdatedAt = time.Now()
+	stored := *recurring
+	m.recurringLoads[recurring.RecurringID] = &stored
+	j.put(&stored)
+	return nil
+}
+
+func (m *MemoryStore) PostRecurringOccurrence(ctx context.Context, recurring *models.RecurringLoad, loadingDate time.Time, actor models.Actor) (*models.Load, error) {
+	j := m.journal()
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	m.loadMu.Lock()
+	defer m.loadMu.Unlock()
+	m.recurringMu.Lock()
+	defer m.recurringMu.Unlock()
+	defer j.commit()
+
+	existing, exists := m.recurringLoads[recurring.RecurringID]
+	if !exists {
+		return nil, models.NotFound("recurring load")
+	}
+	if existing.Version != recurring.Version {
+		return nil, recurringLoadChanged()
+	}
+	load := recurring.NewLoad(loadingDate)
+	if err := m.checkShipperActive(load.ShipperID); err != nil {
+		return nil, err
+	}
+
+	m.insertLoad(j, load, actor)
+	existing.ScheduledUntil = loadingDate
+	existing.Version++
+	existing.UpdatedAt = time.Now()
+	j.put(existing)
+
+	recurring.ScheduledUntil, recurring.Version, recurring.UpdatedAt = existing.ScheduledUntil, existing.Version, existing.UpdatedAt
+	return load, nil
+}
+
+// Outbox operations
+func (m *MemoryStore) GetPendingOutboxMessages(ctx context.Context, before time.Time, limit int) ([]*models.OutboxMessage, error) {
+	m.eventMu.RLock()
+	defer m.eventMu.RUnlock()
+
+	var messages []*models.OutboxMessage
+	for _, message := range m.outbox {
+		if message.CreatedAt.Before(before) {
+			messages = append(messages, message)
+		}
+	}
+	sort.Slice(messages, func(i, j int) bool {
+		return messages[i].ID < messages[j].ID
+	})
+	if limit > 0 && len(messages) > limit {
+		messages = messages[:limit]
+	}
+	return messages, nil
+}
+
+func (m *MemoryStore) DeleteOutboxMessages(ctx context.Context, ids []uint) error {
+	j := m.journal()
+	m.eventMu.Lock()
+	defer m.eventMu.Unlock()
+	defer j.commit()
+
+	m.deleteOutboxMessages(j, ids)
+	return nil
+}
+
+// deleteOutboxMessages clears published messages; the caller must hold eventMu
+func (m *MemoryStore) deleteOutboxMessages(j *journal, ids []uint) {
+	for _, id := range ids {
+		if message, exists := m.outbox[id]; exists {
+			delete(m.outbox, id)
+			j.remove(message)
+		}
+	}
+}
+
+// Handled event operations
+func (m *MemoryStore) IsEventHandled(ctx context.Context, consumer, idempotencyKey string) (bool, error) {
+	m.eventMu.RLock()
+	defer m.eventMu.RUnlock()
+
+	_, handled := m.handledEvents[handledEventKey(consumer, idempotencyKey)]
+	return handled, nil
+}
+
+func (m *MemoryStore) MarkEventHandled(ctx context.Context, consumer, idempotencyKey string) error {
+	j := m.journal()
+	m.eventMu.Lock()
+	defer m.eventMu.Unlock()
+	defer j.commit()
+
+	key := handledEventKey(consumer, idempotencyKey)
+	if _, exists := m.handledEvents[key]; exists {
+		return nil
+	}
+	m.handledCounter++
+	handled := &models.HandledEvent{
+		ID:             m.handledCounter,
+		Consumer:       consumer,
+		IdempotencyKey: idempotencyKey,
+		CreatedAt:      time.Now(),
+	}
+	m.handledEvents[key] = handled
+	j.put(handled)
+	return nil
+}
+
+func handledEventKey(consumer, idempotencyKey string) string {
+	return consumer + "/" + idempotencyKey
+}
+
+// Event log operations
+func (m *MemoryStore) GetEvents(ctx context.Context, entityType, entityID string) ([]*models.Event, error) {
+	m.eventMu.RLock()
+	defer m.eventMu.RUnlock()
+
+	var events []*models.Event
+	for _, event := range m.events {
+		if event.EntityType == entityType && event.EntityID == entityID {
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
+
+// Export operations
+func (m *MemoryStore) EachLoad(ctx context.Context, filter *models.ExportFilter, fn func(load *models.Load) error) error {
+	// Collect under the lock, then call fn without it so slow writers don't block the store
+	m.loadMu.RLock()
+	var loads []*models.Load
+	for _, load := range m.loads {
+		if filter.ShipperID != "" && load.ShipperID != filter.ShipperID {
+			continue
+		}
+		if filter.Status != "" && load.Status != filter.Status {
+			continue
+		}
+		if !inExportRange(filter, &load.CreatedAt) {
+			continue
+		}
+		loads = append(loads, load)
+	}
+	m.loadMu.RUnlock()
+
+	sort.Slice(loads, func(i, j int) bool {
+		return loads[i].ID < loads[j].ID
+	})
+	for _, load := range loads {
+		if err := fn(load); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *MemoryStore) EachBooking(ctx context.Context, filter *models.ExportFilter, fn func(booking *models.Booking) error) error {
+	m.bookingMu.RLock()
+	var bookings []*models.Booking
+	for _, booking := range m.bookings {
+		if filter.ShipperID != "" && booking.ShipperID != filter.ShipperID {
+			continue
+		}
+		if filter.TruckerID != "" && booking.TruckerID != filter.TruckerID {
+			continue
+		}
+		if filter.Status != "" && booking.Status != filter.Status {
+			continue
+		}
+		when := &booking.CreatedAt
+		if filter.ByDeliveryDate {
+			when = booking.DeliveredAt
+		}
+		if !inExportRange(filter, when) {
+			continue
+		}
+		bookings = append(bookings, booking)
+	}
+	m.bookingMu.RUnlock()
+
+	sort.Slice(bookings, func(i, j int) bool {
+		return bookings[i].ID < bookings[j].ID
+	})
+	for _, booking := range bookings {
+		if err := fn(booking); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// inExportRange checks a timestamp against the filter's date range; delivery-date
+// filters never match rows that have not been delivered
+func inExportRange(filter *models.ExportFilter, when *time.Time) bool {
+	if when == nil {
+		return !filter.ByDeliveryDate
+	}
+	if filter.From != nil && when.Before(*filter.From) {
+		return false
+	}
+	if filter.To != nil && !when.Before(*filter.To) {
+		return false
+	}
+	return true
+}
+
+// recordEvent appends an event to the log and queues it in the outbox with
+// the mutation that caused it, to be published when the journal commits;
+// events are never modified afterwards
+func (m *MemoryStore) recordEvent(j *journal, event *models.Event) {
+	m.eventMu.Lock()
+	defer m.eventMu.Unlock()
+
+	m.eventCounter++
+	event.ID = m.eventCounter
+	event.CreatedAt = event.OccurredAt
+	event.UpdatedAt = event.OccurredAt
+
+	m.events = append(m.events, event)
+	j.put(event)
+
+	m.outboxCounter++
+	message := models.NewOutboxMessage(event)
+	message.ID = m.outboxCounter
+	message.CreatedAt = event.CreatedAt
+	message.UpdatedAt = event.CreatedAt
+	m.outbox[message.ID] = message
+	j.put(message)
+	j.outbox = append(j.outbox, message)
+}
@@ -0,0 +1,28 @@
+package storage_test
+
+import (
+	"testing"
+
+	"github.com/Ananth-NQI/truckpe-backend/internal/events"
+	"github.com/Ananth-NQI/truckpe-backend/internal/storage"
+	"github.com/Ananth-NQI/truckpe-backend/internal/storage/storetest"
+)
+
+func TestMemoryStore(t *testing.T) {
+	storetest.Run(t, func(t *testing.T, bus *events.Bus) storage.Store {
+		return storage.NewMemoryStore(bus)
+	})
+}
+
+// TestPersistedMemoryStore runs the suite on a store logging to disk, to
+// check journaling changes nothing about how it behaves
+func TestPersistedMemoryStore(t *testing.T) {
+	storetest.Run(t, func(t *testing.T, bus *events.Bus) storage.Store {
+		store, err := storage.OpenMemoryStore(t.TempDir(), bus)
+		if err != nil {
+			t.Fatalf("OpenMemoryStore: %v", err)
+		}
+		t.Cleanup(func() { store.Close() })
+		return store
+	})
+}
@@ -0,0 +1,822 @@
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Ananth-NQI/truckpe-backend/internal/events"
+	"github.com/Ananth-NQI/truckpe-backend/internal/models"
+)
+
+// A MemoryStore opened with OpenMemoryStore survives restarts. Every mutation
+// appends one line to wal.log holding the rows it changed, and snapshots
+// periodically write every row to snapshot.json and start a new log. On
+// startup the snapshot is loaded and the log replayed on top of it.
+//
+// A log line carries a checksum, so a line torn by a crash is detected and
+// dropped with anything after it: each mutation is recovered whole or not at
+// all. Rows carry a sequence number taken under their entity's lock, so the
+// newest state of a row wins even if two mutations' lines land out of order.
+
+const (
+	snapshotFileName = "snapshot.json"
+	walFileName      = "wal.log"
+)
+
+// DefaultSnapshotInterval is how often a persisted MemoryStore folds its log
+// into a snapshot unless MEMORY_SNAPSHOT_MINUTES says otherwise
+const DefaultSnapshotInterval = 5 * time.Minute
+
+// Tables of the log and snapshot
+const (
+	tableTruckers            = "truckers"
+	tableShippers            = "shippers"
+	tableLoads               = "loads"
+	tableBookings            = "bookings"
+	tableTrackingLinks       = "tracking_links"
+	tableSessions            = "sessions"
+	tableEvents              = "events"
+	tableWebhookEndpoints    = "webhook_endpoints"
+	tableWebhookDeliveries   = "webhook_deliveries"
+	tableLaneAlerts          = "lane_alerts"
+	tableAlertNotifications  = "alert_notifications"
+	tableBackhaulSuggestions = "backhaul_suggestions"
+	tableRecurringLoads      = "recurring_loads"
+	tableOutboxMessages      = "outbox_messages"
+	tableHandledEvents       = "handled_events"
+)
+
+// Rows are stored as the models' JSON plus the fields the API hides
+type (
+	loadRow struct {
+		*models.Load
+		ExpiryRemindedAt *time.Time `json:"expiry_reminded_at"`
+	}
+	bookingRow struct {
+		*models.Booking
+		OTP string `json:"otp"`
+	}
+	webhookEndpointRow struct {
+		*models.WebhookEndpoint
+		Secret string `json:"secret"`
+	}
+	// Outbox messages refer to their event by EventID only
+	outboxMessageRow struct {
+		*models.OutboxMessage
+		Event *models.Event `json:"event,omitempty"`
+	}
+)
+
+// walRow is the state of one row after a mutation, or the last state of a
+// row it deleted
+type walRow struct {
+	Seq     uint64          `json:"seq"`
+	Table   string          `json:"table"`
+	Row     json.RawMessage `json:"row"`
+	Deleted bool            `json:"deleted,omitempty"`
+}
+
+// walEntry is one line of the log: every row one mutation changed
+type walEntry struct {
+	Rows []walRow `json:"rows"`
+}
+
+// memorySnapshot is the content of snapshot.json: every row as of log
+// sequence number Seq, and the ID counters
+type memorySnapshot struct {
+	Seq      uint64                       `json:"seq"`
+	TakenAt  time.Time                    `json:"taken_at"`
+	Counters map[string]uint              `json:"counters"`
+	Tables   map[string][]json.RawMessage `json:"tables"`
+}
+
+// memoryWAL is the open log of a persisted MemoryStore
+type memoryWAL struct {
+	dir     string
+	mu      sync.Mutex // guards the fields below
+	file    *os.File
+	seq     uint64 // last sequence number handed out
+	entries int    // lines written since the last snapshot
+	failed  error  // first write error; mutations are no longer durable
+}
+
+// OpenMemoryStore creates a memory store kept durable in dir, creating the
+// directory if needed and restoring whatever it already holds. Call
+// StartSnapshots to fold the log into snapshots as it grows.
+func OpenMemoryStore(dir string, bus *events.Bus) (*MemoryStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("create memory store directory: %w", err)
+	}
+
+	m := NewMemoryStore(bus)
+	r := newRestorer(m)
+
+	snapshot, err := readSnapshot(filepath.Join(dir, snapshotFileName))
+	if err != nil {
+		return nil, err
+	}
+	if snapshot != nil {
+		if err := r.restoreSnapshot(snapshot); err != nil {
+			return nil, err
+		}
+	}
+
+	file, replayed, err := replayWAL(filepath.Join(dir, walFileName), r)
+	if err != nil {
+		return nil, err
+	}
+	r.finish()
+
+	m.wal = &memoryWAL{dir: dir, file: file, seq: r.seq, entries: replayed}
+	return m, nil
+}
+
+// readSnapshot reads snapshot.json, or returns nil if there is none yet
+func readSnapshot(path string) (*memorySnapshot, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read snapshot: %w", err)
+	}
+	var snapshot memorySnapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return nil, fmt.Errorf("read snapshot %s: %w", path, err)
+	}
+	return &snapshot, nil
+}
+
+// replayWAL applies every intact line of the log and truncates it after the
+// last one, returning the log open for appending and how many lines it holds
+func replayWAL(path string, r *restorer) (*os.File, int, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, 0, fmt.Errorf("open log: %w", err)
+	}
+
+	reader := bufio.NewReader(file)
+	var good int64
+	lines := 0
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			break // a final line without its newline was torn mid-write
+		}
+		if err != nil {
+			file.Close()
+			return nil, 0, fmt.Errorf("read log: %w", err)
+		}
+		entry, ok := decodeWALLine(line)
+		if !ok {
+			log.Printf("⚠️  Memory store log %s is damaged after byte %d, dropping the rest", path, good)
+			break
+		}
+		if err := r.restoreEntry(entry); err != nil {
+			file.Close()
+			return nil, 0, err
+		}
+		good += int64(len(line))
+		lines++
+	}
+
+	// Later appends must follow the last intact line, not the torn one
+	if err := file.Truncate(good); err != nil {
+		file.Close()
+		return nil, 0, fmt.Errorf("truncate log: %w", err)
+	}
+	if _, err := file.Seek(good, io.SeekStart); err != nil {
+		file.Close()
+		return nil, 0, fmt.Errorf("seek log: %w", err)
+	}
+	return file, lines, nil
+}
+
+// encodeWALLine frames an entry as "<crc32 of the JSON> <JSON>\n"
+func encodeWALLine(entry *walEntry) ([]byte, error) {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(fmt.Sprintf("%08x %s\n", crc32.ChecksumIEEE(data), data)), nil
+}
+
+// decodeWALLine checks a line's checksum and decodes it
+func decodeWALLine(line []byte) (*walEntry, bool) {
+	line = bytes.TrimSuffix(line, []byte("\n"))
+	checksum, data, found := bytes.Cut(line, []byte(" "))
+	if !found || fmt.Sprintf("%08x", crc32.ChecksumIEEE(data)) != string(checksum) {
+		return nil, false
+	}
+	var entry walEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, false
+	}
+	return &entry, true
+}
+
+// journal collects the rows one mutation changes, logged as one entry, and
+// the events it recorded
+type journal struct {
+	m      *MemoryStore
+	rows   []walRow
+	outbox []*models.OutboxMessage
+}
+
+// journal starts a mutation. It holds off snapshots until commit, which logs
+// the rows put in between. Take it before any entity lock and commit it
+// before releasing them:
+//
+//	j := m.journal()
+//	m.loadMu.Lock()
+//	defer m.loadMu.Unlock()
+//	defer j.commit()
+func (m *MemoryStore) journal() *journal {
+	m.persistMu.RLock()
+	return &journal{m: m}
+}
+
+// put records the state of a row; call it with the row's entity lock held
+func (j *journal) put(row interface{}) {
+	if j.m.wal == nil {
+		return
+	}
+	table, data, err := encodeRow(row)
+	if err != nil {
+		log.Printf("❌ Memory store could not encode a %T: %v", row, err)
+		return
+	}
+	j.rows = append(j.rows, walRow{Seq: j.m.wal.nextSeq(), Table: table, Row: data})
+}
+
+// remove records the deletion of a row; call it with the row's entity lock held
+func (j *journal) remove(row interface{}) {
+	j.put(row)
+	if len(j.rows) > 0 {
+		j.rows[len(j.rows)-1].Deleted = true
+	}
+}
+
+// commit appends the mutation's rows to the log, publishes the events it
+// recorded and lets snapshots proceed
+func (j *journal) commit() {
+	defer j.m.persistMu.RUnlock()
+	j.write()
+	if len(j.outbox) == 0 {
+		return
+	}
+
+	// Publish only what the log holds, then log that it was published; a
+	// crash in between, or a subscriber too busy to take an event, leaves
+	// the messages for the outbox dispatcher
+	ids := make([]uint, 0, len(j.outbox))
+	for _, message := range j.outbox {
+		if j.m.bus != nil && !j.m.bus.Publish(message.Event) {
+			continue
+		}
+		ids = append(ids, message.ID)
+	}
+	if len(ids) == 0 {
+		return
+	}
+	published := &journal{m: j.m}
+	j.m.eventMu.Lock()
+	j.m.deleteOutboxMessages(published, ids)
+	j.m.eventMu.Unlock()
+	published.write()
+}
+
+// write appends the journaled rows to the log as one entry
+func (j *journal) write() {
+	if j.m.wal == nil || len(j.rows) == 0 {
+		return
+	}
+	if err := j.m.wal.append(&walEntry{Rows: j.rows}); err != nil {
+		log.Printf("❌ Memory store could not write its log, changes since the last snapshot are at risk: %v", err)
+	}
+}
+
+func (w *memoryWAL) nextSeq() uint64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.seq++
+	return w.seq
+}
+
+// append writes one entry and syncs it to disk
+func (w *memoryWAL) append(entry *walEntry) error {
+	line, err := encodeWALLine(entry)
+	if err != nil {
+		return err
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.failed != nil {
+		return w.failed
+	}
+	if _, err := w.file.Write(line); err != nil {
+		w.failed = err
+		return err
+	}
+	if err := w.file.Sync(); err != nil {
+		w.failed = err
+		return err
+	}
+	w.entries++
+	return nil
+}
+
+// Snapshot writes every row to a new snapshot and starts an empty log.
+// Mutations wait while it runs; reads do not.
+func (m *MemoryStore) Snapshot() error {
+	if m.wal == nil {
+		return nil
+	}
+	m.persistMu.Lock()
+	defer m.persistMu.Unlock()
+	return m.snapshotLocked()
+}
+
+// snapshotLocked writes the snapshot; the caller must hold persistMu
+func (m *MemoryStore) snapshotLocked() error {
+	w := m.wal
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	snapshot, err := m.buildSnapshot(w.seq)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return fmt.Errorf("encode snapshot: %w", err)
+	}
+
+	// Write aside and rename, so a crash leaves either snapshot whole
+	path := filepath.Join(w.dir, snapshotFileName)
+	if err := writeFileSync(path+".tmp", data); err != nil {
+		return fmt.Errorf("write snapshot: %w", err)
+	}
+	if err := os.Rename(path+".tmp", path); err != nil {
+		return fmt.Errorf("write snapshot: %w", err)
+	}
+	if err := syncDir(w.dir); err != nil {
+		return fmt.Errorf("write snapshot: %w", err)
+	}
+
+	// Everything logged so far is in the snapshot. Should the process die
+	// before the log is emptied, replay skips rows the snapshot already has.
+	if err := w.file.Truncate(0); err != nil {
+		return fmt.Errorf("truncate log: %w", err)
+	}
+	if _, err := w.file.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("truncate log: %w", err)
+	}
+	if err := w.file.Sync(); err != nil {
+		return fmt.Errorf("truncate log: %w", err)
+	}
+	w.entries = 0
+	w.failed = nil
+	return nil
+}
+
+// StartSnapshots takes a snapshot every interval while the log has entries
+func (m *MemoryStore) StartSnapshots(interval time.Duration) {
+	if m.wal == nil {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			m.wal.mu.Lock()
+			pending := m.wal.entries > 0 || m.wal.failed != nil
+			m.wal.mu.Unlock()
+			if !pending {
+				continue
+			}
+			if err := m.Snapshot(); err != nil {
+				log.Printf("❌ Memory store snapshot failed: %v", err)
+			}
+		}
+	}()
+}
+
+// Close takes a final snapshot and closes the log
+func (m *MemoryStore) Close() error {
+	if m.wal == nil {
+		return nil
+	}
+	m.persistMu.Lock()
+	defer m.persistMu.Unlock()
+	err := m.snapshotLocked()
+	if closeErr := m.wal.file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// buildSnapshot encodes every row; no mutation can run meanwhile
+func (m *MemoryStore) buildSnapshot(seq uint64) (*memorySnapshot, error) {
+	snapshot := &memorySnapshot{
+		Seq:     seq,
+		TakenAt: time.Now(),
+		Counters: map[string]uint{
+			tableTruckers:          m.truckerCounter,
+			tableShippers:          m.shipperCounter,
+			tableLoads:             m.loadCounter,
+			tableBookings:          m.bookingCounter,
+			tableTrackingLinks:     m.trackingCounter,
+			tableEvents:            m.eventCounter,
+			tableWebhookEndpoints:  m.endpointCounter,
+			tableWebhookDeliveries: m.deliveryCounter,
+			tableSessions:          m.sessionCounter,
+			tableLaneAlerts:        m.alertCounter,
+			tableRecurringLoads:    m.recurringCounter,
+			tableOutboxMessages:    m.outboxCounter,
+			tableHandledEvents:     m.handledCounter,
+		},
+		Tables: make(map[string][]json.RawMessage),
+	}
+
+	var rows []interface{}
+	for _, trucker := range m.truckers {
+		rows = append(rows, trucker)
+	}
+	for _, shipper := range m.shippers {
+		rows = append(rows, shipper)
+	}
+	for _, load := range m.loads {
+		rows = append(rows, load)
+	}
+	for _, trucker := range m.deletedTruckers {
+		rows = append(rows, trucker)
+	}
+	for _, shipper := range m.deletedShippers {
+		rows = append(rows, shipper)
+	}
+	for _, load := range m.deletedLoads {
+		rows = append(rows, load)
+	}
+	for _, booking := range m.bookings {
+		rows = append(rows, booking)
+	}
+	for _, link := range m.trackingLinks {
+		rows = append(rows, link)
+	}
+	for _, session := range m.sessions {
+		rows = append(rows, session)
+	}
+	for _, event := range m.events {
+		rows = append(rows, event)
+	}
+	for _, endpoint := range m.webhookEndpoints {
+		rows = append(rows, endpoint)
+	}
+	for _, delivery := range m.webhookDeliveries {
+		rows = append(rows, delivery)
+	}
+	for _, alert := range m.laneAlerts {
+		rows = append(rows, alert)
+	}
+	for _, notification := range m.alertNotifications {
+		rows = append(rows, notification)
+	}
+	for _, suggestion := range m.backhaulSuggestions {
+		rows = append(rows, suggestion)
+	}
+	for _, recurring := range m.recurringLoads {
+		rows = append(rows, recurring)
+	}
+	for _, message := range m.outbox {
+		rows = append(rows, message)
+	}
+	for _, handled := range m.handledEvents {
+		rows = append(rows, handled)
+	}
+
+	for _, row := range rows {
+		table, data, err := encodeRow(row)
+		if err != nil {
+			return nil, fmt.Errorf("encode %T: %w", row, err)
+		}
+		snapshot.Tables[table] = append(snapshot.Tables[table], data)
+	}
+	return snapshot, nil
+}
+
+// encodeRow returns the table of a model and its stored form
+func encodeRow(row interface{}) (string, []byte, error) {
+	var table string
+	var value interface{} = row
+	switch r := row.(type) {
+	case *models.Trucker:
+		table = tableTruckers
+	case *models.Shipper:
+		table = tableShippers
+	case *models.Load:
+		table, value = tableLoads, loadRow{r, r.ExpiryRemindedAt}
+	case *models.Booking:
+		table, value = tableBookings, bookingRow{r, r.OTP}
+	case *models.TrackingLink:
+		table = tableTrackingLinks
+	case *models.WhatsAppSession:
+		table = tableSessions
+	case *models.Event:
+		table = tableEvents
+	case *models.WebhookEndpoint:
+		table, value = tableWebhookEndpoints, webhookEndpointRow{r, r.Secret}
+	case *models.WebhookDelivery:
+		table = tableWebhookDeliveries
+	case *models.LaneAlert:
+		table = tableLaneAlerts
+	case *models.AlertNotification:
+		table = tableAlertNotifications
+	case *models.BackhaulSuggestion:
+		table = tableBackhaulSuggestions
+	case *models.RecurringLoad:
+		table = tableRecurringLoads
+	case *models.OutboxMessage:
+		table, value = tableOutboxMessages, outboxMessageRow{OutboxMessage: r}
+	case *models.HandledEvent:
+		table = tableHandledEvents
+	default:
+		return "", nil, fmt.Errorf("no table for %T", row)
+	}
+	data, err := json.Marshal(value)
+	return table, data, err
+}
+
+// restorer rebuilds a MemoryStore from a snapshot and log entries
+type restorer struct {
+	m           *MemoryStore
+	seq         uint64 // highest sequence number seen
+	snapshotSeq uint64 // log rows up to this are already in the snapshot
+
+	// Sequence number each row was restored at, keyed by table and row key
+	rowSeqs map[string]uint64
+
+	// Rows kept in slices, keyed by ID until finish orders them
+	events        map[uint]*models.Event
+	notifications map[uint]*models.AlertNotification
+	suggestions   map[uint]*models.BackhaulSuggestion
+}
+
+func newRestorer(m *MemoryStore) *restorer {
+	return &restorer{
+		m:             m,
+		rowSeqs:       make(map[string]uint64),
+		events:        make(map[uint]*models.Event),
+		notifications: make(map[uint]*models.AlertNotification),
+		suggestions:   make(map[uint]*models.BackhaulSuggestion),
+	}
+}
+
+func (r *restorer) restoreSnapshot(snapshot *memorySnapshot) error {
+	r.seq, r.snapshotSeq = snapshot.Seq, snapshot.Seq
+	for table, rows := range snapshot.Tables {
+		for _, data := range rows {
+			if err := r.restoreRow(walRow{Seq: 0, Table: table, Row: data}); err != nil {
+				return err
+			}
+		}
+	}
+
+	m := r.m
+	counters := snapshot.Counters
+	m.truckerCounter = max(m.truckerCounter, counters[tableTruckers])
+	m.shipperCounter = max(m.shipperCounter, counters[tableShippers])
+	m.loadCounter = max(m.loadCounter, counters[tableLoads])
+	m.bookingCounter = max(m.bookingCounter, counters[tableBookings])
+	m.trackingCounter = max(m.trackingCounter, counters[tableTrackingLinks])
+	m.eventCounter = max(m.eventCounter, counters[tableEvents])
+	m.endpointCounter = max(m.endpointCounter, counters[tableWebhookEndpoints])
+	m.deliveryCounter = max(m.deliveryCounter, counters[tableWebhookDeliveries])
+	m.sessionCounter = max(m.sessionCounter, counters[tableSessions])
+	m.alertCounter = max(m.alertCounter, counters[tableLaneAlerts])
+	m.recurringCounter = max(m.recurringCounter, counters[tableRecurringLoads])
+	m.outboxCounter = max(m.outboxCounter, counters[tableOutboxMessages])
+	m.handledCounter = max(m.handledCounter, counters[tableHandledEvents])
+	return nil
+}
+
+func (r *restorer) restoreEntry(entry *walEntry) error {
+	for _, row := range entry.Rows {
+		if row.Seq <= r.snapshotSeq {
+			continue // already in the snapshot
+		}
+		if err := r.restoreRow(row); err != nil {
+			return err
+		}
+		r.seq = max(r.seq, row.Seq)
+	}
+	return nil
+}
+
+// restoreRow puts a row into the store unless a newer version of it is there
+func (r *restorer) restoreRow(row walRow) error {
+	m := r.m
+	newer := func(key string) bool {
+		key = row.Table + "/" + key
+		if seq, seen := r.rowSeqs[key]; seen && seq > row.Seq {
+			return false
+		}
+		r.rowSeqs[key] = row.Seq
+		return true
+	}
+
+	var err error
+	switch row.Table {
+	case tableTruckers:
+		var trucker models.Trucker
+		if err = json.Unmarshal(row.Row, &trucker); err == nil && newer(trucker.TruckerID) {
+			if trucker.DeletedAt.Valid {
+				delete(m.truckers, trucker.ID)
+				delete(m.truckersByTruckerID, trucker.TruckerID)
+				m.deletedTruckers[trucker.ID] = &trucker
+			} else {
+				delete(m.deletedTruckers, trucker.ID)
+				m.truckers[trucker.ID] = &trucker
+				m.truckersByTruckerID[trucker.TruckerID] = &trucker
+			}
+			m.truckerCounter = max(m.truckerCounter, trucker.ID)
+		}
+	case tableShippers:
+		var shipper models.Shipper
+		if err = json.Unmarshal(row.Row, &shipper); err == nil && newer(shipper.ShipperID) {
+			if shipper.DeletedAt.Valid {
+				delete(m.shippers, shipper.ShipperID)
+				m.deletedShippers[shipper.ShipperID] = &shipper
+			} else {
+				delete(m.deletedShippers, shipper.ShipperID)
+				m.shippers[shipper.ShipperID] = &shipper
+			}
+			m.shipperCounter = max(m.shipperCounter, shipper.ID)
+		}
+	case tableLoads:
+		var stored loadRow
+		if err = json.Unmarshal(row.Row, &stored); err == nil && stored.Load != nil && newer(stored.LoadID) {
+			load := stored.Load
+			load.ExpiryRemindedAt = stored.ExpiryRemindedAt
+			if load.DeletedAt.Valid {
+				delete(m.loads, load.ID)
+				delete(m.loadsByLoadID, load.LoadID)
+				m.deletedLoads[load.ID] = load
+			} else {
+				delete(m.deletedLoads, load.ID)
+				m.loads[load.ID] = load
+				m.loadsByLoadID[load.LoadID] = load
+			}
+			m.loadCounter = max(m.loadCounter, load.ID)
+		}
+	case tableBookings:
+		var stored bookingRow
+		if err = json.Unmarshal(row.Row, &stored); err == nil && stored.Booking != nil && newer(stored.BookingID) {
+			booking := stored.Booking
+			booking.OTP = stored.OTP
+			m.bookings[booking.ID] = booking
+			m.bookingsByBookingID[booking.BookingID] = booking
+			m.bookingCounter = max(m.bookingCounter, booking.ID)
+		}
+	case tableTrackingLinks:
+		var link models.TrackingLink
+		if err = json.Unmarshal(row.Row, &link); err == nil && newer(link.Token) {
+			m.trackingLinks[link.Token] = &link
+			m.trackingCounter = max(m.trackingCounter, link.ID)
+		}
+	case tableSessions:
+		var session models.WhatsAppSession
+		if err = json.Unmarshal(row.Row, &session); err == nil && newer(session.PhoneNumber) {
+			m.sessions[session.PhoneNumber] = &session
+			m.sessionCounter = max(m.sessionCounter, session.ID)
+		}
+	case tableEvents:
+		var event models.Event
+		if err = json.Unmarshal(row.Row, &event); err == nil && newer(fmt.Sprint(event.ID)) {
+			r.events[event.ID] = &event
+			m.eventCounter = max(m.eventCounter, event.ID)
+		}
+	case tableWebhookEndpoints:
+		var stored webhookEndpointRow
+		if err = json.Unmarshal(row.Row, &stored); err == nil && stored.WebhookEndpoint != nil && newer(stored.EndpointID) {
+			endpoint := stored.WebhookEndpoint
+			endpoint.Secret = stored.Secret
+			m.webhookEndpoints[endpoint.EndpointID] = endpoint
+			m.endpointCounter = max(m.endpointCounter, endpoint.ID)
+		}
+	case tableWebhookDeliveries:
+		var delivery models.WebhookDelivery
+		if err = json.Unmarshal(row.Row, &delivery); err == nil && newer(delivery.DeliveryID) {
+			m.webhookDeliveries[delivery.DeliveryID] = &delivery
+			m.deliveryCounter = max(m.deliveryCounter, delivery.ID)
+		}
+	case tableLaneAlerts:
+		var alert models.LaneAlert
+		if err = json.Unmarshal(row.Row, &alert); err == nil && newer(alert.AlertID) {
+			m.laneAlerts[alert.AlertID] = &alert
+			m.alertCounter = max(m.alertCounter, alert.ID)
+		}
+	case tableAlertNotifications:
+		var notification models.AlertNotification
+		if err = json.Unmarshal(row.Row, &notification); err == nil && newer(fmt.Sprint(notification.ID)) {
+			r.notifications[notification.ID] = &notification
+		}
+	case tableBackhaulSuggestions:
+		var suggestion models.BackhaulSuggestion
+		if err = json.Unmarshal(row.Row, &suggestion); err == nil && newer(fmt.Sprint(suggestion.ID)) {
+			r.suggestions[suggestion.ID] = &suggestion
+		}
+	case tableRecurringLoads:
+		var recurring models.RecurringLoad
+		if err = json.Unmarshal(row.Row, &recurring); err == nil && newer(recurring.RecurringID) {
+			m.recurringLoads[recurring.RecurringID] = &recurring
+			m.recurringCounter = max(m.recurringCounter, recurring.ID)
+		}
+	case tableOutboxMessages:
+		var message models.OutboxMessage
+		if err = json.Unmarshal(row.Row, &message); err == nil && newer(fmt.Sprint(message.ID)) {
+			if row.Deleted {
+				delete(m.outbox, message.ID)
+			} else {
+				m.outbox[message.ID] = &message
+			}
+			m.outboxCounter = max(m.outboxCounter, message.ID)
+		}
+	case tableHandledEvents:
+		var handled models.HandledEvent
+		if err = json.Unmarshal(row.Row, &handled); err == nil && newer(fmt.Sprint(handled.ID)) {
+			m.handledEvents[handledEventKey(handled.Consumer, handled.IdempotencyKey)] = &handled
+			m.handledCounter = max(m.handledCounter, handled.ID)
+		}
+	default:
+		return fmt.Errorf("restore memory store: unknown table %q", row.Table)
+	}
+	if err != nil {
+		return fmt.Errorf("restore memory store: %s row: %w", strings.TrimSuffix(row.Table, "s"), err)
+	}
+	return nil
+}
+
+// finish orders the rows kept in slices by ID
+func (r *restorer) finish() {
+	m := r.m
+	m.events = sortedByID(r.events)
+	m.alertNotifications = sortedByID(r.notifications)
+	m.backhaulSuggestions = sortedByID(r.suggestions)
+	for _, message := range m.outbox {
+		message.Event = r.events[message.EventID]
+	}
+}
+
+func sortedByID[T any](rows map[uint]*T) []*T {
+	ids := make([]uint, 0, len(rows))
+	for id := range rows {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	sorted := make([]*T, 0, len(ids))
+	for _, id := range ids {
+		sorted = append(sorted, rows[id])
+	}
+	return sorted
+}
+
+// writeFileSync writes a file and syncs it to disk
+func writeFileSync(path string, data []byte) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// syncDir makes a rename in dir durable
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
@@ -0,0 +1,384 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/Ananth-NQI/truckpe-backend/internal/models"
+)
+
+var walActor = models.Actor{ID: "waltest", Channel: models.ChannelSystem}
+
+// workload runs a mix of mutations touching every table, calling step after
+// each one
+func workload(t *testing.T, m *MemoryStore, step func()) {
+	t.Helper()
+	ctx := context.Background()
+	must := func(err error) {
+		t.Helper()
+		if err != nil {
+			t.Fatal(err)
+		}
+		step()
+	}
+	mustGet := func(_ interface{}, err error) { t.Helper(); must(err) }
+
+	shipper := &models.Shipper{CompanyName: "Shipper", GSTNumber: "27AAAAA0001A1Z5", Phone: "+919900000001", City: "Mumbai"}
+	mustGet(m.CreateShipper(ctx, shipper, walActor))
+	trucker, err := m.CreateTrucker(ctx, &models.TruckerRegistration{
+		Name: "Trucker", Phone: "+919800000001", VehicleNo: "MH12AB0001", VehicleType: "32ft", Capacity: 20,
+	}, walActor)
+	must(err)
+	must(m.UpdateTruckerLocation(ctx, trucker.TruckerID, "Mumbai", "", walActor))
+
+	newLoad := func() *models.Load {
+		return &models.Load{
+			ShipperID: shipper.ShipperID, ShipperName: "Shipper", ShipperPhone: shipper.Phone,
+			FromCity: "Mumbai", ToCity: "Pune", Material: "Steel", Weight: 10, VehicleType: "32ft",
+			Price: 20000, LoadingDate: time.Now().Add(48 * time.Hour).Truncate(time.Second),
+		}
+	}
+	load, err := m.CreateLoad(ctx, newLoad(), walActor)
+	must(err)
+	mustGet(m.CreateLoads(ctx, []*models.Load{newLoad(), newLoad()}, walActor))
+	must(m.MarkLoadExpiryReminded(ctx, load.LoadID, time.Now()))
+	price := 21000.0
+	mustGet(m.UpdateLoad(ctx, load.LoadID, &models.LoadChanges{Price: &price}, walActor))
+
+	booking, err := m.CreateBooking(ctx, load.LoadID, trucker.TruckerID, 0, walActor)
+	must(err)
+	must(m.UpdateBookingStatus(ctx, booking.BookingID, models.BookingStatusInTransit, walActor))
+	must(m.UpdateBookingPOD(ctx, booking.BookingID, "https://example.com/pod.jpg", walActor))
+	mustGet(m.CreateTrackingLink(ctx, &models.TrackingLink{Token: "token", BookingID: booking.BookingID, ShipperID: shipper.ShipperID}, walActor))
+	must(m.RevokeTrackingLinks(ctx, booking.BookingID, walActor))
+	must(m.UpdateBookingStatus(ctx, booking.BookingID, models.BookingStatusDelivered, walActor))
+
+	endpoint, err := m.CreateWebhookEndpoint(ctx, &models.WebhookEndpoint{ShipperID: shipper.ShipperID, URL: "https://example.com/hook", Secret: "whsec"})
+	must(err)
+	delivery, err := m.CreateWebhookDelivery(ctx, &models.WebhookDelivery{EndpointID: endpoint.EndpointID})
+	must(err)
+	delivery.Attempts = 1
+	must(m.UpdateWebhookDelivery(ctx, delivery))
+	must(m.DeactivateWebhookEndpoint(ctx, endpoint.EndpointID))
+
+	alert, err := m.CreateLaneAlert(ctx, &models.LaneAlert{TruckerID: trucker.TruckerID, FromCity: "Pune"})
+	must(err)
+	must(m.CreateAlertNotification(ctx, &models.AlertNotification{AlertID: alert.AlertID, TruckerID: trucker.TruckerID, LoadID: load.LoadID}))
+	must(m.DeactivateLaneAlert(ctx, alert.AlertID))
+	must(m.CreateBackhaulSuggestion(ctx, &models.BackhaulSuggestion{TruckerID: trucker.TruckerID, LoadID: load.LoadID}))
+	must(m.ConvertBackhaulSuggestion(ctx, trucker.TruckerID, load.LoadID, booking.BookingID))
+
+	recurring, err := m.CreateRecurringLoad(ctx, &models.RecurringLoad{ShipperID: shipper.ShipperID, FromCity: "Mumbai", ToCity: "Pune", Days: "mon"})
+	must(err)
+	recurring.Price = 22000
+	must(m.UpdateRecurringLoad(ctx, recurring))
+	must(m.SaveSession(ctx, &models.WhatsAppSession{PhoneNumber: trucker.Phone, LastCommand: "STATUS"}))
+	must(m.MarkEventHandled(ctx, "alerts", "event-1"))
+	mustGet(m.WithdrawLoad(ctx, fmt.Sprint(load.ID+1), walActor))
+	mustGet(m.ExpireLoads(ctx, time.Now().Add(30*24*time.Hour), walActor))
+}
+
+// state is every row and counter of a store in a comparable form, leaving
+// out the rows of the skipped tables
+func state(t *testing.T, m *MemoryStore, skip ...string) string {
+	t.Helper()
+	snapshot, err := m.buildSnapshot(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tables := make(map[string][]string)
+	for table, rows := range snapshot.Tables {
+		if slices.Contains(skip, table) {
+			continue
+		}
+		for _, row := range rows {
+			tables[table] = append(tables[table], string(row))
+		}
+		sort.Strings(tables[table])
+	}
+	data, err := json.MarshalIndent(map[string]interface{}{"counters": snapshot.Counters, "tables": tables}, "", " ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func openStore(t *testing.T, dir string) *MemoryStore {
+	t.Helper()
+	m, err := OpenMemoryStore(dir, nil)
+	if err != nil {
+		t.Fatalf("OpenMemoryStore: %v", err)
+	}
+	t.Cleanup(func() { m.wal.file.Close() })
+	return m
+}
+
+func TestMemoryStoreReopen(t *testing.T) {
+	for _, snapshotAt := range []int{-1, 0, 10, 1000} {
+		t.Run(fmt.Sprintf("snapshot after %d", snapshotAt), func(t *testing.T) {
+			dir := t.TempDir()
+			m := openStore(t, dir)
+			steps := 0
+			workload(t, m, func() {
+				if steps == snapshotAt {
+					if err := m.Snapshot(); err != nil {
+						t.Fatalf("Snapshot: %v", err)
+					}
+				}
+				steps++
+			})
+			if snapshotAt == 1000 {
+				if err := m.Close(); err != nil {
+					t.Fatalf("Close: %v", err)
+				}
+			}
+			want := state(t, m)
+
+			reopened := openStore(t, dir)
+			if got := state(t, reopened); got != want {
+				t.Fatalf("reopened store differs\ngot:  %s\nwant: %s", got, want)
+			}
+
+			// Hidden fields survive, and IDs carry on where they left off
+			load, _ := reopened.GetLoad(context.Background(), "1")
+			booking, _ := reopened.GetBooking(context.Background(), "1")
+			endpoint, _ := reopened.GetWebhookEndpoint(context.Background(), models.FormatID(models.PrefixWebhookEndpoint, 1))
+			if load.ExpiryRemindedAt == nil || booking.OTP == "" || endpoint.Secret != "whsec" {
+				t.Fatalf("hidden fields lost: reminded=%v otp=%q secret=%q", load.ExpiryRemindedAt, booking.OTP, endpoint.Secret)
+			}
+			created, err := reopened.CreateLoad(context.Background(), &models.Load{ShipperID: load.ShipperID, FromCity: "Pune", ToCity: "Nashik"}, walActor)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if created.ID != 4 {
+				t.Fatalf("first load after reopening has ID %d, want 4", created.ID)
+			}
+			events, _ := reopened.GetEvents(context.Background(), models.EntityLoad, created.LoadID)
+			if len(events) != 1 || events[0].ID != reopened.events[len(reopened.events)-2].ID+1 {
+				t.Fatalf("event after reopening not numbered after the restored ones")
+			}
+		})
+	}
+}
+
+// TestMemoryStoreReopenDeleted checks deleted rows come back deleted: hidden
+// from reads, still restorable and not blocking their phone numbers
+func TestMemoryStoreReopenDeleted(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	m := openStore(t, dir)
+	workload(t, m, func() {})
+
+	trucker, err := m.CreateTrucker(ctx, &models.TruckerRegistration{
+		Name: "Gone", Phone: "+919800000002", VehicleNo: "MH12AB0002", VehicleType: "32ft", Capacity: 20,
+	}, walActor)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shipper, err := m.CreateShipper(ctx, &models.Shipper{CompanyName: "Gone", GSTNumber: "27AAAAA0002A1Z5", Phone: "+919900000002", City: "Pune"}, walActor)
+	if err != nil {
+		t.Fatal(err)
+	}
+	load, err := m.CreateLoad(ctx, &models.Load{ShipperID: shipper.ShipperID, FromCity: "Pune", ToCity: "Nashik", Weight: 10}, walActor)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.DeleteLoad(ctx, load.LoadID, walActor); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.DeleteShipper(ctx, shipper.ShipperID, walActor); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Snapshot(); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.DeleteTrucker(ctx, trucker.TruckerID, walActor); err != nil {
+		t.Fatal(err)
+	}
+	want := state(t, m)
+
+	reopened := openStore(t, dir)
+	if got := state(t, reopened); got != want {
+		t.Fatalf("reopened store differs\ngot:  %s\nwant: %s", got, want)
+	}
+	if _, err := reopened.GetTruckerByPhone(ctx, trucker.Phone); !errors.Is(err, models.ErrNotFound) {
+		t.Fatalf("deleted trucker readable after reopening: %v", err)
+	}
+	if _, err := reopened.GetLoad(ctx, load.LoadID); !errors.Is(err, models.ErrNotFound) {
+		t.Fatalf("deleted load readable after reopening: %v", err)
+	}
+	if _, err := reopened.RestoreShipper(ctx, shipper.ShipperID, walActor); err != nil {
+		t.Fatalf("RestoreShipper after reopening: %v", err)
+	}
+	if _, err := reopened.CreateTrucker(ctx, &models.TruckerRegistration{
+		Name: "Again", Phone: trucker.Phone, VehicleNo: trucker.VehicleNo, VehicleType: "32ft", Capacity: 20,
+	}, walActor); err != nil {
+		t.Fatalf("phone of a deleted trucker not free after reopening: %v", err)
+	}
+}
+
+// TestMemoryStoreTornLog cuts the log around and inside every line, as a
+// crash mid-write would, and checks the store reopens to exactly the
+// mutations whose lines were complete. A mutation that recorded events
+// writes a second line once they are published; if only that one is lost,
+// the events are still in the outbox.
+func TestMemoryStoreTornLog(t *testing.T) {
+	dir := t.TempDir()
+	m := openStore(t, dir)
+	path := filepath.Join(dir, walFileName)
+
+	type step struct {
+		mutated   int64 // log size once the mutation was written
+		published int64 // log size once its events were published
+		events    int
+		state     string
+	}
+	steps := []step{{state: state(t, m)}}
+	workload(t, m, func() {
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		steps = append(steps, step{published: info.Size(), events: len(m.events), state: state(t, m)})
+	})
+	log, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var cuts []int64
+	for start := int64(0); start < int64(len(log)); {
+		end := start + int64(bytes.IndexByte(log[start:], '\n')) + 1
+		cuts = append(cuts, start, start+1, (start+end)/2, end-1)
+		start = end
+	}
+	cuts = append(cuts, int64(len(log)))
+	for i := len(steps) - 1; i > 0; i-- {
+		s := &steps[i]
+		s.events -= steps[i-1].events
+		s.mutated = s.published
+		if previous := steps[i-1].published; previous < s.published {
+			s.mutated = previous + int64(bytes.IndexByte(log[previous:], '\n')) + 1
+		}
+	}
+	if len(steps) < 25 {
+		t.Fatalf("workload ran %d mutations", len(steps)-1)
+	}
+
+	crashed := t.TempDir()
+	for _, cut := range cuts {
+		if err := os.WriteFile(filepath.Join(crashed, walFileName), log[:cut], 0o644); err != nil {
+			t.Fatal(err)
+		}
+		last := steps[0]
+		for _, s := range steps {
+			if s.mutated <= cut {
+				last = s
+			}
+		}
+		complete, pending := last.published, 0
+		if cut < last.published {
+			complete, pending = last.mutated, last.events
+		}
+
+		reopened, err := OpenMemoryStore(crashed, nil)
+		if err != nil {
+			t.Fatalf("log cut at byte %d: %v", cut, err)
+		}
+		reopened.wal.file.Close()
+		if got, want := state(t, reopened, tableOutboxMessages), last.state; got != want {
+			t.Fatalf("log cut at byte %d of %d reopened to\n%s\nwant the state after byte %d\n%s", cut, len(log), got, complete, want)
+		}
+		messages, err := reopened.GetPendingOutboxMessages(context.Background(), time.Now().Add(time.Hour), 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(messages) != pending {
+			t.Fatalf("log cut at byte %d left %d events to publish, want %d", cut, len(messages), pending)
+		}
+		for _, message := range messages {
+			if message.Event == nil || message.IdempotencyKey != message.Event.IdempotencyKey() {
+				t.Fatalf("pending message %s restored without its event", message.IdempotencyKey)
+			}
+		}
+		// The torn tail is gone, so new lines follow the last complete one
+		if info, _ := os.Stat(filepath.Join(crashed, walFileName)); info.Size() != complete {
+			t.Fatalf("log cut at byte %d left %d bytes, want %d", cut, info.Size(), complete)
+		}
+	}
+}
+
+func TestMemoryStoreCorruptLog(t *testing.T) {
+	dir := t.TempDir()
+	m := openStore(t, dir)
+	var sizes []int64
+	var states []string
+	path := filepath.Join(dir, walFileName)
+	workload(t, m, func() {
+		info, _ := os.Stat(path)
+		sizes = append(sizes, info.Size())
+		states = append(states, state(t, m))
+	})
+
+	// A flipped byte in the fifth line loses it and everything after it
+	log, _ := os.ReadFile(path)
+	log[sizes[3]+20] ^= 0xff
+	if err := os.WriteFile(path, log, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if got := state(t, openStore(t, dir)); got != states[3] {
+		t.Fatalf("corrupt log reopened to\n%s\nwant the state after four mutations\n%s", got, states[3])
+	}
+}
+
+// TestMemoryStoreSnapshotCrash reopens a store that died after writing a
+// snapshot but before emptying the log; the log must not be applied twice
+func TestMemoryStoreSnapshotCrash(t *testing.T) {
+	dir := t.TempDir()
+	m := openStore(t, dir)
+	workload(t, m, func() {})
+	path := filepath.Join(dir, walFileName)
+	log, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Snapshot(); err != nil {
+		t.Fatal(err)
+	}
+	want := state(t, m)
+
+	// Put back the log the snapshot replaced, plus a mutation made after it
+	if _, err := m.CreateTrucker(context.Background(), &models.TruckerRegistration{
+		Name: "Late", Phone: "+919800000002", VehicleNo: "MH12AB0002", VehicleType: "32ft", Capacity: 20,
+	}, walActor); err != nil {
+		t.Fatal(err)
+	}
+	late, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, append(log, late...), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	reopened := openStore(t, dir)
+	if len(reopened.events) != len(m.events) || len(reopened.truckers) != 2 {
+		t.Fatalf("replay after a snapshot crash has %d events and %d truckers, want %d and 2",
+			len(reopened.events), len(reopened.truckers), len(m.events))
+	}
+	if _, err := reopened.GetTrucker(context.Background(), models.FormatID(models.PrefixTrucker, 2)); err != nil {
+		t.Fatalf("mutation after the snapshot lost: %v", err)
+	}
+	if want == state(t, reopened) {
+		t.Fatalf("late trucker not restored")
+	}
+}
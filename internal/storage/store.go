@@ -1,32 +1,136 @@
 package storage
 
-import "github.com/Ananth-NQI/truckpe-backend/internal/models"
+import (
+	"context"
+	"time"
 
-// Store defines the interface for storage operations
+	"github.com/Ananth-NQI/truckpe-backend/internal/models"
+)
+
+// Store defines the interface for storage operations.
+// Mutations of loads, bookings, truckers and shippers take the acting user
+// and record an audit event.
 type Store interface {
 	// Trucker operations
-	CreateTrucker(reg *models.TruckerRegistration) (*models.Trucker, error)
-	GetTrucker(id string) (*models.Trucker, error)
-	GetTruckerByPhone(phone string) (*models.Trucker, error)
+	CreateTrucker(ctx context.Context, reg *models.TruckerRegistration, actor models.Actor) (*models.Trucker, error)
+	GetTrucker(ctx context.Context, id string) (*models.Trucker, error)
+	GetTruckerByPhone(ctx context.Context, phone string) (*models.Trucker, error)
+	UpdateTruckerLocation(ctx context.Context, id, city, cityID string, actor models.Actor) error
 
 	// Load operations
-	CreateLoad(load *models.Load) (*models.Load, error)
-	GetLoad(id string) (*models.Load, error)
-	GetAvailableLoads() ([]*models.Load, error)
-	SearchLoads(search *models.LoadSearch) ([]*models.Load, error)
-	UpdateLoadStatus(id string, status string) error
+	CreateLoad(ctx context.Context, load *models.Load, actor models.Actor) (*models.Load, error)
+	CreateLoads(ctx context.Context, loads []*models.Load, actor models.Actor) ([]*models.Load, error) // all or nothing
+	GetLoad(ctx context.Context, id string) (*models.Load, error)
+	GetAvailableLoads(ctx context.Context) ([]*models.Load, error)
+	SearchLoads(ctx context.Context, search *models.LoadSearch) ([]*models.Load, error)
+	UpdateLoadStatus(ctx context.Context, id string, status string, actor models.Actor) error
+	ListLoads(ctx context.Context, filter *models.LoadFilter, opts *models.ListOptions) (*models.LoadPage, error)
+
+	// Load expiry operations
+	ExpireLoads(ctx context.Context, now time.Time, actor models.Actor) ([]*models.Load, error) // unlists available loads past ExpiresAt
+	GetLoadsExpiringBefore(ctx context.Context, before time.Time) ([]*models.Load, error)       // available loads not yet reminded of expiry
+	MarkLoadExpiryReminded(ctx context.Context, id string, at time.Time) error
+	RenewLoad(ctx context.Context, id string, loadingDate time.Time, price float64, actor models.Actor) (*models.Load, error) // price 0 keeps the price
+
+	// Load editing operations, only while no truck is booked
+	UpdateLoad(ctx context.Context, id string, changes *models.LoadChanges, actor models.Actor) (*models.Load, error)
+	WithdrawLoad(ctx context.Context, id string, actor models.Actor) (*models.Load, error)
 
 	// Booking operations
-	CreateBooking(loadID, truckerID string) (*models.Booking, error)
-	GetBooking(id string) (*models.Booking, error)
-	GetBookingsByTrucker(truckerID string) ([]*models.Booking, error)
-	GetBookingsByLoad(loadID string) ([]*models.Booking, error)
-	UpdateBookingStatus(id string, status string) error
+	CreateBooking(ctx context.Context, loadID, truckerID string, weight float64, actor models.Actor) (*models.Booking, error) // weight only for part loads, 0 for as much as fits
+	GetBooking(ctx context.Context, id string) (*models.Booking, error)
+	GetBookingsByTrucker(ctx context.Context, truckerID string) ([]*models.Booking, error)
+	GetBookingsByLoad(ctx context.Context, loadID string) ([]*models.Booking, error)
+	UpdateBookingStatus(ctx context.Context, id string, status string, actor models.Actor) error
+	UpdateBookingPOD(ctx context.Context, id string, podURL string, actor models.Actor) error
+	ListBookings(ctx context.Context, filter *models.BookingFilter, opts *models.ListOptions) (*models.BookingPage, error)
 
 	// SHIPPER OPERATIONS:
-	CreateShipper(shipper *models.Shipper) (*models.Shipper, error)
-	GetShipper(id string) (*models.Shipper, error)
-	GetShipperByPhone(phone string) (*models.Shipper, error)
-	GetShipperByGST(gst string) (*models.Shipper, error)
-	GetLoadsByShipper(shipperID string) ([]*models.Load, error)
+	CreateShipper(ctx context.Context, shipper *models.Shipper, actor models.Actor) (*models.Shipper, error)
+	GetShipper(ctx context.Context, id string) (*models.Shipper, error)
+	GetShipperByPhone(ctx context.Context, phone string) (*models.Shipper, error)
+	GetShipperByGST(ctx context.Context, gst string) (*models.Shipper, error)
+	GetLoadsByShipper(ctx context.Context, shipperID string) ([]*models.Load, error)
+
+	// Account and listing administration. Deactivating or deleting cancels the
+	// bookings not yet picked up and fails, changing nothing, while one is in
+	// transit. Deleted rows are soft-deleted: every other operation treats them
+	// as gone, so their phone, vehicle and GST numbers can register again.
+	DeactivateTrucker(ctx context.Context, id string, actor models.Actor) (*models.Trucker, error)
+	DeleteTrucker(ctx context.Context, id string, actor models.Actor) error
+	RestoreTrucker(ctx context.Context, id string, actor models.Actor) (*models.Trucker, error)    // Conflict if the phone or vehicle registered again
+	DeactivateShipper(ctx context.Context, id string, actor models.Actor) (*models.Shipper, error) // also withdraws the shipper's open loads
+	DeleteShipper(ctx context.Context, id string, actor models.Actor) error
+	RestoreShipper(ctx context.Context, id string, actor models.Actor) (*models.Shipper, error) // Conflict if the phone or GST number registered again
+	DeleteAccount(ctx context.Context, truckerID, shipperID string, actor models.Actor) error   // deletes a number's trucker and shipper together, or neither; either ID may be empty
+	DeactivateLoad(ctx context.Context, id string, actor models.Actor) (*models.Load, error)    // withdraws the load
+	DeleteLoad(ctx context.Context, id string, actor models.Actor) error
+	RestoreLoad(ctx context.Context, id string, actor models.Actor) (*models.Load, error) // relists a withdrawn load
+
+	// WhatsApp session operations
+	GetSession(ctx context.Context, phone string) (*models.WhatsAppSession, error)
+	SaveSession(ctx context.Context, session *models.WhatsAppSession) error
+
+	// Tracking link operations
+	CreateTrackingLink(ctx context.Context, link *models.TrackingLink, actor models.Actor) (*models.TrackingLink, error)
+	GetTrackingLink(ctx context.Context, token string) (*models.TrackingLink, error)
+	RevokeTrackingLinks(ctx context.Context, bookingID string, actor models.Actor) error
+
+	// Webhook operations
+	CreateWebhookEndpoint(ctx context.Context, endpoint *models.WebhookEndpoint) (*models.WebhookEndpoint, error)
+	GetWebhookEndpoint(ctx context.Context, id string) (*models.WebhookEndpoint, error)
+	GetWebhookEndpointsByShipper(ctx context.Context, shipperID string) ([]*models.WebhookEndpoint, error)
+	DeactivateWebhookEndpoint(ctx context.Context, id string) error
+	CreateWebhookDelivery(ctx context.Context, delivery *models.WebhookDelivery) (*models.WebhookDelivery, error)
+	GetWebhookDelivery(ctx context.Context, id string) (*models.WebhookDelivery, error)
+	GetWebhookDeliveries(ctx context.Context, endpointID string, status string) ([]*models.WebhookDelivery, error)
+	GetDueWebhookDeliveries(ctx context.Context, now time.Time) ([]*models.WebhookDelivery, error)
+	UpdateWebhookDelivery(ctx context.Context, delivery *models.WebhookDelivery) error
+
+	// Lane alert operations
+	CreateLaneAlert(ctx context.Context, alert *models.LaneAlert) (*models.LaneAlert, error)
+	GetLaneAlert(ctx context.Context, id string) (*models.LaneAlert, error)
+	GetLaneAlertsByTrucker(ctx context.Context, truckerID string) ([]*models.LaneAlert, error) // active only
+	GetLaneAlertsByOrigin(ctx context.Context, fromCity string) ([]*models.LaneAlert, error)   // active only, any name of the city
+	DeactivateLaneAlert(ctx context.Context, id string) error
+	CreateAlertNotification(ctx context.Context, notification *models.AlertNotification) error
+	CountAlertNotifications(ctx context.Context, truckerID string, since time.Time) (int, error)
+	GetAlertNotificationsByLoad(ctx context.Context, loadID string) ([]*models.AlertNotification, error)
+
+	// Backhaul suggestion operations
+	CreateBackhaulSuggestion(ctx context.Context, suggestion *models.BackhaulSuggestion) error
+	ConvertBackhaulSuggestion(ctx context.Context, truckerID, loadID, bookingID string) error // marks the latest matching suggestion as booked
+	GetBackhaulStats(ctx context.Context, since time.Time) (*models.BackhaulStats, error)
+	GetBackhaulSuggestionsByLoad(ctx context.Context, loadID string) ([]*models.BackhaulSuggestion, error)
+
+	// Recurring load operations
+	CreateRecurringLoad(ctx context.Context, recurring *models.RecurringLoad) (*models.RecurringLoad, error)
+	GetRecurringLoad(ctx context.Context, id string) (*models.RecurringLoad, error)
+	GetRecurringLoadsByShipper(ctx context.Context, shipperID string) ([]*models.RecurringLoad, error)
+	GetActiveRecurringLoads(ctx context.Context) ([]*models.RecurringLoad, error)   // not paused
+	UpdateRecurringLoad(ctx context.Context, recurring *models.RecurringLoad) error // Conflict if the row changed since recurring was read
+	// PostRecurringOccurrence creates the load of the occurrence at
+	// loadingDate and advances ScheduledUntil to it in one transaction;
+	// Conflict if the row changed since recurring was read
+	PostRecurringOccurrence(ctx context.Context, recurring *models.RecurringLoad, loadingDate time.Time, actor models.Actor) (*models.Load, error)
+
+	// Event log operations (append-only, oldest first)
+	GetEvents(ctx context.Context, entityType, entityID string) ([]*models.Event, error)
+
+	// Outbox operations. Every recorded event is queued in the outbox with
+	// its change and published once the change is committed; these find
+	// the ones whose publishing was cut short, oldest first, with Event set.
+	GetPendingOutboxMessages(ctx context.Context, before time.Time, limit int) ([]*models.OutboxMessage, error)
+	DeleteOutboxMessages(ctx context.Context, ids []uint) error
+
+	// Handled event operations. Subscribers record the events they acted
+	// on, by consumer name and IdempotencyKey, so they can skip them when
+	// they are published again; marking an event twice is not an error.
+	IsEventHandled(ctx context.Context, consumer, idempotencyKey string) (bool, error)
+	MarkEventHandled(ctx context.Context, consumer, idempotencyKey string) error
+
+	// Export operations call fn for each matching row in ID order without
+	// loading the whole table; an error from fn stops the iteration
+	EachLoad(ctx context.Context, filter *models.ExportFilter, fn func(load *models.Load) error) error
+	EachBooking(ctx context.Context, filter *models.ExportFilter, fn func(booking *models.Booking) error) error
 }
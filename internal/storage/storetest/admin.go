@@ -0,0 +1,278 @@
+package storetest
+
+import (
+	"github.com/Ananth-NQI/truckpe-backend/internal/models"
+)
+
+func testTruckerAdmin(h *harness) {
+	t := h.t
+	trucker := h.trucker(1, 20)
+	if !trucker.Active {
+		t.Fatalf("new trucker is not active: %+v", trucker)
+	}
+
+	// A booking in transit blocks deactivation and nothing changes
+	moving := h.trucker(2, 20)
+	movingBooking := h.book(h.load("SH00001", nil).LoadID, moving.TruckerID, 0)
+	h.setBookingStatus(movingBooking.BookingID, models.BookingStatusInTransit)
+	before := h.getTrucker(moving.TruckerID).Version
+	_, err := h.store.DeactivateTrucker(h.ctx, moving.TruckerID, actor)
+	expectError(t, err, models.ErrUnavailable, "trucker_unavailable")
+	err = h.store.DeleteTrucker(h.ctx, moving.TruckerID, actor)
+	expectError(t, err, models.ErrUnavailable, "trucker_unavailable")
+	if got := h.getTrucker(moving.TruckerID); !got.Active || got.Version != before {
+		t.Fatalf("refused deactivation changed the trucker: %+v", got)
+	}
+
+	// Deactivating cancels the bookings not picked up and hands the load back
+	load := h.load("SH00001", nil)
+	booking := h.book(load.LoadID, trucker.TruckerID, 0)
+	deactivated, err := h.store.DeactivateTrucker(h.ctx, trucker.TruckerID, actor)
+	expectNoError(t, err, "DeactivateTrucker")
+	deactivatedVersion := deactivated.Version
+	if deactivated.Active || deactivated.Available {
+		t.Fatalf("deactivated trucker = active %v, available %v, want neither", deactivated.Active, deactivated.Available)
+	}
+	if got := h.getBooking(booking.BookingID); got.Status != models.BookingStatusCancelled {
+		t.Fatalf("booking status = %s, want %s", got.Status, models.BookingStatusCancelled)
+	}
+	if got := h.getLoad(load.LoadID); got.Status != models.LoadStatusAvailable || got.TrucksBooked != 0 {
+		t.Fatalf("released load = %s with %d trucks booked, want available with none", got.Status, got.TrucksBooked)
+	}
+	expectStrings(t, h.eventTypes(models.EntityLoad, load.LoadID),
+		[]string{models.EventLoadCreated, models.EventLoadStatusChanged, models.EventLoadReleased}, "released load events")
+	_, err = h.store.CreateBooking(h.ctx, h.load("SH00001", nil).LoadID, trucker.TruckerID, 0, actor)
+	expectError(t, err, models.ErrUnavailable, "trucker_unavailable")
+
+	// Deactivating again changes nothing
+	again, err := h.store.DeactivateTrucker(h.ctx, trucker.TruckerID, actor)
+	expectNoError(t, err, "DeactivateTrucker again")
+	if again.Version != deactivated.Version {
+		t.Fatalf("second deactivation moved the version from %d to %d", deactivated.Version, again.Version)
+	}
+
+	// Deleted truckers are gone from every lookup, but bookings stay readable
+	expectNoError(t, h.store.DeleteTrucker(h.ctx, trucker.TruckerID, actor), "DeleteTrucker")
+	_, err = h.store.GetTrucker(h.ctx, trucker.TruckerID)
+	expectError(t, err, models.ErrNotFound, "trucker_not_found")
+	_, err = h.store.GetTruckerByPhone(h.ctx, trucker.Phone)
+	expectError(t, err, models.ErrNotFound, "trucker_not_found")
+	err = h.store.DeleteTrucker(h.ctx, trucker.TruckerID, actor)
+	expectError(t, err, models.ErrNotFound, "trucker_not_found")
+	h.getBooking(booking.BookingID)
+
+	// The phone and vehicle can register again, which blocks restoring
+	reg := &models.TruckerRegistration{
+		Name: "Again", Phone: trucker.Phone, VehicleNo: trucker.VehicleNo, VehicleType: "32ft", Capacity: 20,
+	}
+	again, err = h.store.CreateTrucker(h.ctx, reg, actor)
+	expectNoError(t, err, "CreateTrucker with a deleted trucker's phone")
+	_, err = h.store.RestoreTrucker(h.ctx, trucker.TruckerID, actor)
+	expectError(t, err, models.ErrConflict, "trucker_phone_conflict")
+
+	expectNoError(t, h.store.DeleteTrucker(h.ctx, again.TruckerID, actor), "DeleteTrucker again")
+	restored, err := h.store.RestoreTrucker(h.ctx, trucker.TruckerID, actor)
+	expectNoError(t, err, "RestoreTrucker")
+	if !restored.Active || !restored.Available || !h.getTrucker(trucker.TruckerID).Active {
+		t.Fatalf("restored trucker = %+v, want active and available", restored)
+	}
+	if restored.Version != deactivatedVersion+2 {
+		t.Fatalf("restored trucker version = %d, want %d after deleting and restoring", restored.Version, deactivatedVersion+2)
+	}
+	_, err = h.store.RestoreTrucker(h.ctx, "TR0", actor)
+	expectError(t, err, models.ErrNotFound, "trucker_not_found")
+
+	expectStrings(t, h.eventTypes(models.EntityTrucker, trucker.TruckerID), []string{
+		models.EventTruckerRegistered, models.EventTruckerUpdated, models.EventTruckerDeactivated,
+		models.EventTruckerDeleted, models.EventTruckerRestored,
+	}, "trucker events")
+}
+
+func testShipperAdmin(h *harness) {
+	t := h.t
+	shipper := h.shipper(1)
+	trucker := h.trucker(1, 20)
+	if !shipper.Active {
+		t.Fatalf("new shipper is not active: %+v", shipper)
+	}
+
+	// A booking in transit on any of the shipper's loads blocks deactivation
+	delivered := h.load(shipper.ShipperID, nil)
+	moving := h.book(delivered.LoadID, h.trucker(2, 20).TruckerID, 0)
+	h.setBookingStatus(moving.BookingID, models.BookingStatusInTransit)
+	_, err := h.store.DeactivateShipper(h.ctx, shipper.ShipperID, actor)
+	expectError(t, err, models.ErrUnavailable, "shipper_unavailable")
+	h.setBookingStatus(moving.BookingID, models.BookingStatusDelivered)
+
+	// Deactivating withdraws open loads and cancels their bookings
+	open := h.load(shipper.ShipperID, nil)
+	booked := h.load(shipper.ShipperID, nil)
+	booking := h.book(booked.LoadID, trucker.TruckerID, 0)
+	deactivated, err := h.store.DeactivateShipper(h.ctx, shipper.ShipperID, actor)
+	expectNoError(t, err, "DeactivateShipper")
+	if deactivated.Active {
+		t.Fatalf("deactivated shipper is still active")
+	}
+	for _, id := range []string{open.LoadID, booked.LoadID} {
+		if got := h.getLoad(id); got.Status != models.LoadStatusWithdrawn {
+			t.Fatalf("load %s status = %s, want %s", id, got.Status, models.LoadStatusWithdrawn)
+		}
+	}
+	if got := h.getLoad(delivered.LoadID); got.Status != models.LoadStatusDelivered {
+		t.Fatalf("delivered load status = %s, want it left alone", got.Status)
+	}
+	if got := h.getBooking(booking.BookingID); got.Status != models.BookingStatusCancelled {
+		t.Fatalf("booking status = %s, want %s", got.Status, models.BookingStatusCancelled)
+	}
+	if !h.getTrucker(trucker.TruckerID).Available {
+		t.Fatalf("trucker of a cancelled booking is not available again")
+	}
+
+	// Deactivated shippers cannot post loads
+	_, err = h.store.CreateLoad(h.ctx, newLoad(shipper.ShipperID), actor)
+	expectError(t, err, models.ErrUnavailable, "shipper_unavailable")
+	_, err = h.store.CreateLoads(h.ctx, []*models.Load{newLoad(shipper.ShipperID)}, actor)
+	expectError(t, err, models.ErrUnavailable, "shipper_unavailable")
+
+	// Deleted shippers are gone from every lookup; their loads stay readable
+	expectNoError(t, h.store.DeleteShipper(h.ctx, shipper.ShipperID, actor), "DeleteShipper")
+	_, err = h.store.GetShipper(h.ctx, shipper.ShipperID)
+	expectError(t, err, models.ErrNotFound, "shipper_not_found")
+	_, err = h.store.GetShipperByPhone(h.ctx, shipper.Phone)
+	expectError(t, err, models.ErrNotFound, "shipper_not_found")
+	_, err = h.store.GetShipperByGST(h.ctx, shipper.GSTNumber)
+	expectError(t, err, models.ErrNotFound, "shipper_not_found")
+	h.getLoad(open.LoadID)
+	_, err = h.store.CreateLoad(h.ctx, newLoad(shipper.ShipperID), actor)
+	expectError(t, err, models.ErrUnavailable, "shipper_unavailable")
+
+	// The phone and GST number can register again, which blocks restoring
+	again, err := h.store.CreateShipper(h.ctx, &models.Shipper{
+		CompanyName: "Again", GSTNumber: shipper.GSTNumber, Phone: shipper.Phone, City: "Mumbai",
+	}, actor)
+	expectNoError(t, err, "CreateShipper with a deleted shipper's phone")
+	_, err = h.store.RestoreShipper(h.ctx, shipper.ShipperID, actor)
+	expectError(t, err, models.ErrConflict, "shipper_phone_conflict")
+
+	expectNoError(t, h.store.DeleteShipper(h.ctx, again.ShipperID, actor), "DeleteShipper again")
+	restored, err := h.store.RestoreShipper(h.ctx, shipper.ShipperID, actor)
+	expectNoError(t, err, "RestoreShipper")
+	if !restored.Active {
+		t.Fatalf("restored shipper is not active")
+	}
+	h.load(shipper.ShipperID, nil)
+	if got := h.getLoad(open.LoadID); got.Status != models.LoadStatusWithdrawn {
+		t.Fatalf("restoring the shipper relisted load %s", open.LoadID)
+	}
+	_, err = h.store.RestoreShipper(h.ctx, "SH99999", actor)
+	expectError(t, err, models.ErrNotFound, "shipper_not_found")
+
+	expectStrings(t, h.eventTypes(models.EntityShipper, shipper.ShipperID), []string{
+		models.EventShipperRegistered, models.EventShipperDeactivated, models.EventShipperDeleted, models.EventShipperRestored,
+	}, "shipper events")
+}
+
+func testAccountDeletion(h *harness) {
+	t := h.t
+	shipper := h.shipper(1)
+	trucker := h.trucker(1, 20)
+
+	// The shipper's booking in transit keeps the trucker from being deleted too
+	load := h.load(shipper.ShipperID, nil)
+	moving := h.book(load.LoadID, h.trucker(2, 20).TruckerID, 0)
+	h.setBookingStatus(moving.BookingID, models.BookingStatusInTransit)
+	booking := h.book(h.load("SH00009", nil).LoadID, trucker.TruckerID, 0)
+	err := h.store.DeleteAccount(h.ctx, trucker.TruckerID, shipper.ShipperID, actor)
+	expectError(t, err, models.ErrUnavailable, "shipper_unavailable")
+	if got := h.getTrucker(trucker.TruckerID); !got.Active {
+		t.Fatalf("trucker deactivated by a deletion that failed")
+	}
+	if got := h.getBooking(booking.BookingID); got.Status != models.BookingStatusConfirmed {
+		t.Fatalf("trucker's booking status = %s after a deletion that failed, want %s", got.Status, models.BookingStatusConfirmed)
+	}
+
+	// Once it is delivered both go together
+	h.setBookingStatus(moving.BookingID, models.BookingStatusDelivered)
+	expectNoError(t, h.store.DeleteAccount(h.ctx, trucker.TruckerID, shipper.ShipperID, actor), "DeleteAccount")
+	_, err = h.store.GetTrucker(h.ctx, trucker.TruckerID)
+	expectError(t, err, models.ErrNotFound, "trucker_not_found")
+	_, err = h.store.GetShipper(h.ctx, shipper.ShipperID)
+	expectError(t, err, models.ErrNotFound, "shipper_not_found")
+	if got := h.getBooking(booking.BookingID); got.Status != models.BookingStatusCancelled {
+		t.Fatalf("deleted trucker's booking status = %s, want %s", got.Status, models.BookingStatusCancelled)
+	}
+
+	// Either ID may be left out
+	expectNoError(t, h.store.DeleteAccount(h.ctx, h.trucker(3, 20).TruckerID, "", actor), "DeleteAccount")
+	expectNoError(t, h.store.DeleteAccount(h.ctx, "", h.shipper(2).ShipperID, actor), "DeleteAccount")
+}
+
+func testLoadAdmin(h *harness) {
+	t := h.t
+	first := h.trucker(1, 20)
+	second := h.trucker(2, 20)
+	load := h.load("SH00001", func(load *models.Load) { load.TruckCount = 2 })
+	confirmed := h.book(load.LoadID, first.TruckerID, 0)
+	moving := h.book(load.LoadID, second.TruckerID, 0)
+	h.setBookingStatus(moving.BookingID, models.BookingStatusInTransit)
+
+	// A truck in transit blocks deactivation
+	_, err := h.store.DeactivateLoad(h.ctx, load.LoadID, actor)
+	expectError(t, err, models.ErrUnavailable, "load_unavailable")
+	err = h.store.DeleteLoad(h.ctx, load.LoadID, actor)
+	expectError(t, err, models.ErrUnavailable, "load_unavailable")
+	h.setBookingStatus(moving.BookingID, models.BookingStatusDelivered)
+
+	// Once it is delivered, the confirmed truck is released and the rest withdrawn
+	deactivated, err := h.store.DeactivateLoad(h.ctx, load.LoadID, actor)
+	expectNoError(t, err, "DeactivateLoad")
+	if deactivated.Status != models.LoadStatusWithdrawn || deactivated.TrucksBooked != 1 {
+		t.Fatalf("deactivated load = %s with %d trucks booked, want withdrawn with the delivered one",
+			deactivated.Status, deactivated.TrucksBooked)
+	}
+	if got := h.getBooking(confirmed.BookingID); got.Status != models.BookingStatusCancelled {
+		t.Fatalf("confirmed booking status = %s, want %s", got.Status, models.BookingStatusCancelled)
+	}
+	if got := h.getBooking(moving.BookingID); got.Status != models.BookingStatusDelivered {
+		t.Fatalf("delivered booking status = %s, want it left alone", got.Status)
+	}
+	if !h.getTrucker(first.TruckerID).Available {
+		t.Fatalf("trucker of the cancelled booking is not available again")
+	}
+
+	// Deleted loads are gone from reads, but their bookings stay
+	expectNoError(t, h.store.DeleteLoad(h.ctx, load.LoadID, actor), "DeleteLoad")
+	_, err = h.store.GetLoad(h.ctx, load.LoadID)
+	expectError(t, err, models.ErrNotFound, "load_not_found")
+	h.getBooking(confirmed.BookingID)
+
+	listed := h.load("SH00001", nil)
+	expectNoError(t, h.store.DeleteLoad(h.ctx, listed.LoadID, actor), "DeleteLoad of a listed load")
+	available, err := h.store.GetAvailableLoads(h.ctx)
+	expectNoError(t, err, "GetAvailableLoads")
+	expectStrings(t, loadIDs(available), []string{}, "available loads after deleting")
+
+	// Restoring relists a withdrawn load
+	restored, err := h.store.RestoreLoad(h.ctx, listed.LoadID, actor)
+	expectNoError(t, err, "RestoreLoad")
+	if restored.Status != models.LoadStatusAvailable || h.getLoad(listed.LoadID).Status != models.LoadStatusAvailable {
+		t.Fatalf("restored load status = %s, want %s", restored.Status, models.LoadStatusAvailable)
+	}
+	// Created, withdrawn, deleted and restored, each a new version
+	if restored.Version != 4 {
+		t.Fatalf("restored load version = %d, want 4", restored.Version)
+	}
+	expectStrings(t, h.eventTypes(models.EntityLoad, listed.LoadID), []string{
+		models.EventLoadCreated, models.EventLoadStatusChanged, models.EventLoadDeleted, models.EventLoadRestored,
+	}, "deleted load events")
+	_, err = h.store.RestoreLoad(h.ctx, "LD0", actor)
+	expectError(t, err, models.ErrNotFound, "load_not_found")
+
+	// A deactivated shipper's loads stay down
+	shipper := h.shipper(1)
+	own := h.load(shipper.ShipperID, nil)
+	_, err = h.store.DeactivateShipper(h.ctx, shipper.ShipperID, actor)
+	expectNoError(t, err, "DeactivateShipper")
+	_, err = h.store.RestoreLoad(h.ctx, own.LoadID, actor)
+	expectError(t, err, models.ErrUnavailable, "shipper_unavailable")
+}
@@ -0,0 +1,273 @@
+package storetest
+
+import (
+	"errors"
+	"math"
+
+	"github.com/Ananth-NQI/truckpe-backend/internal/models"
+)
+
+func testBookings(h *harness) {
+	t := h.t
+	trucker := h.trucker(1, 20)
+	load := h.load("SH00001", nil)
+
+	booking := h.book(load.LoadID, trucker.TruckerID, 0)
+	if booking.BookingID == "" || booking.ID == 0 {
+		t.Fatalf("CreateBooking did not assign IDs: %+v", booking)
+	}
+	if booking.LoadID != load.LoadID || booking.TruckerID != trucker.TruckerID || booking.ShipperID != load.ShipperID {
+		t.Fatalf("booking links %s/%s/%s, want %s/%s/%s",
+			booking.LoadID, booking.TruckerID, booking.ShipperID, load.LoadID, trucker.TruckerID, load.ShipperID)
+	}
+	if booking.AgreedPrice != load.Price || booking.Commission != load.Price*0.05 || booking.NetAmount != load.Price*0.95 ||
+		booking.Weight != load.Weight || booking.PartLoad {
+		t.Fatalf("booking pays ₹%.0f (commission %.0f, net %.0f) for %.1f t, want the whole load",
+			booking.AgreedPrice, booking.Commission, booking.NetAmount, booking.Weight)
+	}
+	if booking.Status != models.BookingStatusConfirmed || booking.PaymentStatus != models.PaymentStatusPending ||
+		booking.ConfirmedAt == nil || booking.OTP == "" {
+		t.Fatalf("new booking = %s/%s confirmed %v, want confirmed with pending payment and an OTP",
+			booking.Status, booking.PaymentStatus, booking.ConfirmedAt)
+	}
+
+	// Side effects: the load is booked and the truck is taken
+	if got := h.getLoad(load.LoadID); got.Status != models.LoadStatusBooked || got.TrucksBooked != 1 || got.BookedWeight != load.Weight {
+		t.Fatalf("booked load = %s with %d trucks and %.1f t, want booked with 1 truck", got.Status, got.TrucksBooked, got.BookedWeight)
+	}
+	if h.getTrucker(trucker.TruckerID).Available {
+		t.Fatalf("trucker is still available after booking")
+	}
+	expectStrings(t, h.eventTypes(models.EntityBooking, booking.BookingID), []string{models.EventBookingCreated}, "booking events")
+	expectStrings(t, h.eventTypes(models.EntityLoad, load.LoadID),
+		[]string{models.EventLoadCreated, models.EventLoadStatusChanged}, "booked load events")
+	expectStrings(t, h.eventTypes(models.EntityTrucker, trucker.TruckerID),
+		[]string{models.EventTruckerRegistered, models.EventTruckerUpdated}, "booked trucker events")
+
+	// Lookups by BookingID, numeric ID, trucker and load
+	for _, id := range []string{booking.BookingID, numericID(booking.ID)} {
+		if got := h.getBooking(id); got.BookingID != booking.BookingID {
+			t.Fatalf("GetBooking(%s) = %s, want %s", id, got.BookingID, booking.BookingID)
+		}
+	}
+	for _, id := range []string{"BK0", "999999"} {
+		_, err := h.store.GetBooking(h.ctx, id)
+		expectError(t, err, models.ErrNotFound, "booking_not_found")
+	}
+
+	// Bookings by trucker and by load, newest first
+	second := h.load("SH00001", nil)
+	other := h.trucker(2, 20)
+	otherBooking := h.book(second.LoadID, other.TruckerID, 0)
+	h.setBookingStatus(otherBooking.BookingID, models.BookingStatusDelivered)
+	third := h.load("SH00001", nil)
+	thirdBooking := h.book(third.LoadID, other.TruckerID, 0)
+
+	byTrucker, err := h.store.GetBookingsByTrucker(h.ctx, other.TruckerID)
+	expectNoError(t, err, "GetBookingsByTrucker")
+	expectStrings(t, bookingIDs(byTrucker), []string{thirdBooking.BookingID, otherBooking.BookingID}, "bookings by trucker")
+	byLoad, err := h.store.GetBookingsByLoad(h.ctx, load.LoadID)
+	expectNoError(t, err, "GetBookingsByLoad")
+	expectStrings(t, bookingIDs(byLoad), []string{booking.BookingID}, "bookings by load")
+	byLoad, err = h.store.GetBookingsByLoad(h.ctx, "LD0")
+	expectNoError(t, err, "GetBookingsByLoad")
+	expectStrings(t, bookingIDs(byLoad), []string{}, "bookings of an unknown load")
+
+	// Booking errors
+	_, err = h.store.CreateBooking(h.ctx, "LD0", trucker.TruckerID, 0, actor)
+	expectError(t, err, models.ErrNotFound, "load_not_found")
+	_, err = h.store.CreateBooking(h.ctx, h.load("SH00001", nil).LoadID, "TR0", 0, actor)
+	expectError(t, err, models.ErrNotFound, "trucker_not_found")
+	_, err = h.store.CreateBooking(h.ctx, load.LoadID, h.trucker(3, 20).TruckerID, 0, actor)
+	expectError(t, err, models.ErrUnavailable, "load_unavailable")
+	_, err = h.store.CreateBooking(h.ctx, h.load("SH00001", nil).LoadID, trucker.TruckerID, 0, actor)
+	expectError(t, err, models.ErrUnavailable, "trucker_unavailable")
+	_, err = h.store.CreateBooking(h.ctx, h.load("SH00001", nil).LoadID, h.trucker(4, 5).TruckerID, 0, actor)
+	expectError(t, err, models.ErrUnavailable, "trucker_capacity_unavailable")
+
+	// Numeric IDs book too
+	numeric := h.load("SH00001", nil)
+	free := h.trucker(5, 20)
+	if booked := h.book(numericID(numeric.ID), numericID(free.ID), 0); booked.LoadID != numeric.LoadID || booked.TruckerID != free.TruckerID {
+		t.Fatalf("booking by numeric IDs links %s/%s, want %s/%s", booked.LoadID, booked.TruckerID, numeric.LoadID, free.TruckerID)
+	}
+}
+
+func testMultiTruckLoads(h *harness) {
+	t := h.t
+	load := h.load("SH00001", func(load *models.Load) {
+		load.Weight = 40
+		load.Price = 80000
+		load.TruckCount = 2
+	})
+
+	first := h.book(load.LoadID, h.trucker(1, 25).TruckerID, 0)
+	if first.Weight != 20 || first.AgreedPrice != 40000 {
+		t.Fatalf("first truck carries %.1f t for ₹%.0f, want 20 t for ₹40000", first.Weight, first.AgreedPrice)
+	}
+	if got := h.getLoad(load.LoadID); got.Status != models.LoadStatusAvailable || got.TrucksBooked != 1 {
+		t.Fatalf("load after one truck = %s with %d trucks, want available with 1", got.Status, got.TrucksBooked)
+	}
+
+	h.book(load.LoadID, h.trucker(2, 25).TruckerID, 0)
+	if got := h.getLoad(load.LoadID); got.Status != models.LoadStatusBooked || got.TrucksBooked != 2 || got.BookedWeight != 40 {
+		t.Fatalf("load after two trucks = %s with %d trucks and %.1f t, want booked with 2", got.Status, got.TrucksBooked, got.BookedWeight)
+	}
+	expectStrings(t, h.eventTypes(models.EntityLoad, load.LoadID),
+		[]string{models.EventLoadCreated, models.EventLoadAllocated, models.EventLoadStatusChanged}, "multi-truck load events")
+
+	_, err := h.store.CreateBooking(h.ctx, load.LoadID, h.trucker(3, 25).TruckerID, 0, actor)
+	expectError(t, err, models.ErrUnavailable, "load_unavailable")
+}
+
+func testPartLoads(h *harness) {
+	t := h.t
+	load := h.load("SH00001", func(load *models.Load) {
+		load.Weight = 10
+		load.Price = 10000
+		load.Splittable = true
+	})
+	other := h.load("SH00001", func(load *models.Load) {
+		load.Weight = 10
+		load.Price = 5000
+		load.Splittable = true
+	})
+	trucker := h.trucker(1, 8)
+
+	part := h.book(load.LoadID, trucker.TruckerID, 4)
+	if part.Weight != 4 || part.AgreedPrice != 4000 || !part.PartLoad {
+		t.Fatalf("part booking carries %.1f t for ₹%.0f (part %v), want 4 t for ₹4000", part.Weight, part.AgreedPrice, part.PartLoad)
+	}
+	if got := h.getLoad(load.LoadID); got.Status != models.LoadStatusAvailable || got.BookedWeight != 4 {
+		t.Fatalf("load after 4 t = %s with %.1f t booked, want available with 4", got.Status, got.BookedWeight)
+	}
+
+	// More than is left on the load, and more than is left on the truck
+	_, err := h.store.CreateBooking(h.ctx, load.LoadID, h.trucker(2, 20).TruckerID, 7, actor)
+	expectError(t, err, models.ErrUnavailable, "load_weight_unavailable")
+	_, err = h.store.CreateBooking(h.ctx, other.LoadID, trucker.TruckerID, 5, actor)
+	expectError(t, err, models.ErrUnavailable, "trucker_capacity_unavailable")
+
+	// The same truck takes a second part load up to its capacity; weight 0 fills it
+	shared := h.book(other.LoadID, trucker.TruckerID, 0)
+	if math.Abs(shared.Weight-4) > 0.001 {
+		t.Fatalf("second part load carries %.1f t, want the 4 t left on the truck", shared.Weight)
+	}
+
+	// The rest of the first load closes it
+	h.book(load.LoadID, h.trucker(3, 20).TruckerID, 0)
+	if got := h.getLoad(load.LoadID); got.Status != models.LoadStatusBooked || math.Abs(got.BookedWeight-10) > 0.001 {
+		t.Fatalf("load after the rest = %s with %.1f t booked, want booked with 10", got.Status, got.BookedWeight)
+	}
+
+	// A full truckload needs an empty truck
+	_, err = h.store.CreateBooking(h.ctx, h.load("SH00001", nil).LoadID, trucker.TruckerID, 0, actor)
+	if !errors.Is(err, models.ErrUnavailable) {
+		t.Fatalf("booking a full load on a shared truck: got %v, want %v", err, models.ErrUnavailable)
+	}
+}
+
+func testBookingLifecycle(h *harness) {
+	t := h.t
+	trucker := h.trucker(1, 25)
+	partner := h.trucker(2, 25)
+	load := h.load("SH00001", func(load *models.Load) {
+		load.Weight = 40
+		load.TruckCount = 2
+		load.ToCity = "Nagpur"
+	})
+	booking := h.book(load.LoadID, trucker.TruckerID, 0)
+	partnerBooking := h.book(load.LoadID, partner.TruckerID, 0)
+
+	h.setBookingStatus(booking.BookingID, models.BookingStatusInTransit)
+	if got := h.getBooking(booking.BookingID); got.Status != models.BookingStatusInTransit || got.PickedUpAt == nil {
+		t.Fatalf("picked up booking = %s at %v, want in transit with a pickup time", got.Status, got.PickedUpAt)
+	}
+
+	// Delivering one truck frees it at the destination; the load waits for the other truck
+	h.setBookingStatus(booking.BookingID, models.BookingStatusDelivered)
+	if got := h.getBooking(booking.BookingID); got.Status != models.BookingStatusDelivered || got.DeliveredAt == nil {
+		t.Fatalf("delivered booking = %s at %v, want delivered with a delivery time", got.Status, got.DeliveredAt)
+	}
+	freed := h.getTrucker(trucker.TruckerID)
+	if !freed.Available || freed.TotalTrips != 1 || freed.CurrentCity != "Nagpur" {
+		t.Fatalf("delivered trucker = available %v, %d trips, in %s; want available, 1 trip, in Nagpur",
+			freed.Available, freed.TotalTrips, freed.CurrentCity)
+	}
+	if got := h.getLoad(load.LoadID); got.Status != models.LoadStatusBooked {
+		t.Fatalf("load with a truck still on the road = %s, want %s", got.Status, models.LoadStatusBooked)
+	}
+
+	h.setBookingStatus(partnerBooking.BookingID, models.BookingStatusDelivered)
+	if got := h.getLoad(load.LoadID); got.Status != models.LoadStatusDelivered {
+		t.Fatalf("load with every truck delivered = %s, want %s", got.Status, models.LoadStatusDelivered)
+	}
+	expectStrings(t, h.eventTypes(models.EntityLoad, load.LoadID),
+		[]string{models.EventLoadCreated, models.EventLoadAllocated, models.EventLoadStatusChanged, models.EventLoadStatusChanged}, "delivered load events")
+	expectStrings(t, h.eventTypes(models.EntityTrucker, trucker.TruckerID),
+		[]string{models.EventTruckerRegistered, models.EventTruckerUpdated, models.EventTruckerUpdated}, "delivered trucker events")
+
+	h.setBookingStatus(booking.BookingID, models.BookingStatusCompleted)
+	if got := h.getBooking(booking.BookingID); got.CompletedAt == nil || got.PaymentStatus != models.PaymentStatusCompleted {
+		t.Fatalf("completed booking = payment %s at %v, want completed", got.PaymentStatus, got.CompletedAt)
+	}
+	expectStrings(t, h.eventTypes(models.EntityBooking, booking.BookingID),
+		[]string{models.EventBookingCreated, models.EventBookingStatusChanged, models.EventBookingStatusChanged, models.EventBookingStatusChanged}, "booking events")
+
+	// A truck still carrying another part load stays unavailable after one delivery
+	shared := h.trucker(3, 10)
+	first := h.book(h.load("SH00001", func(load *models.Load) { load.Splittable = true }).LoadID, shared.TruckerID, 4)
+	h.book(h.load("SH00001", func(load *models.Load) { load.Splittable = true }).LoadID, shared.TruckerID, 4)
+	h.setBookingStatus(first.BookingID, models.BookingStatusDelivered)
+	if got := h.getTrucker(shared.TruckerID); got.Available || got.TotalTrips != 1 {
+		t.Fatalf("trucker with a part load on board = available %v, %d trips; want unavailable, 1 trip", got.Available, got.TotalTrips)
+	}
+
+	expectError(t, h.store.UpdateBookingStatus(h.ctx, "BK0", models.BookingStatusDelivered, actor), models.ErrNotFound, "booking_not_found")
+
+	// Proof of delivery
+	expectNoError(t, h.store.UpdateBookingPOD(h.ctx, booking.BookingID, "https://example.com/pod.jpg", actor), "UpdateBookingPOD")
+	if got := h.getBooking(booking.BookingID); got.PodURL != "https://example.com/pod.jpg" {
+		t.Fatalf("PodURL = %q, want the uploaded URL", got.PodURL)
+	}
+	expectError(t, h.store.UpdateBookingPOD(h.ctx, "BK0", "https://example.com/pod.jpg", actor), models.ErrNotFound, "booking_not_found")
+}
+
+func testListBookings(h *harness) {
+	t := h.t
+	var bookings []*models.Booking
+	for i, price := range []float64{30000, 10000, 20000} {
+		price := price
+		load := h.load("SH00001", func(load *models.Load) { load.Price = price })
+		bookings = append(bookings, h.book(load.LoadID, h.trucker(i+1, 20).TruckerID, 0))
+	}
+	h.setBookingStatus(bookings[0].BookingID, models.BookingStatusDelivered)
+
+	opts := &models.ListOptions{Limit: 2, Sort: models.SortPrice, Order: models.SortDesc}
+	page, err := h.store.ListBookings(h.ctx, &models.BookingFilter{ShipperID: "SH00001"}, opts)
+	expectNoError(t, err, "ListBookings")
+	expectStrings(t, bookingIDs(page.Bookings), []string{bookings[0].BookingID, bookings[2].BookingID}, "first page")
+	opts = &models.ListOptions{Limit: 2, Sort: models.SortPrice, Order: models.SortDesc, Cursor: page.NextCursor}
+	page, err = h.store.ListBookings(h.ctx, &models.BookingFilter{ShipperID: "SH00001"}, opts)
+	expectNoError(t, err, "ListBookings")
+	expectStrings(t, bookingIDs(page.Bookings), []string{bookings[1].BookingID}, "second page")
+
+	filters := []struct {
+		name   string
+		filter models.BookingFilter
+		want   []string
+	}{
+		{"trucker", models.BookingFilter{TruckerID: bookings[1].TruckerID}, []string{bookings[1].BookingID}},
+		{"load", models.BookingFilter{LoadID: bookings[2].LoadID}, []string{bookings[2].BookingID}},
+		{"status", models.BookingFilter{Status: models.BookingStatusConfirmed}, []string{bookings[2].BookingID, bookings[1].BookingID}},
+		{"other shipper", models.BookingFilter{ShipperID: "SH00002"}, []string{}},
+	}
+	for _, f := range filters {
+		page, err := h.store.ListBookings(h.ctx, &f.filter, &models.ListOptions{})
+		expectNoError(t, err, "ListBookings by "+f.name)
+		expectStrings(t, bookingIDs(page.Bookings), f.want, "ListBookings by "+f.name)
+	}
+
+	_, err = h.store.ListBookings(h.ctx, &models.BookingFilter{}, &models.ListOptions{Sort: models.SortLoadingDate})
+	expectError(t, err, models.ErrInvalid, "sort_invalid")
+}
@@ -0,0 +1,233 @@
+package storetest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Ananth-NQI/truckpe-backend/internal/models"
+)
+
+// contenders is how many truckers race for the same load
+const contenders = 200
+
+// race runs book(i) for i in [0, n) all at once and returns each call's error
+func race(n int, book func(i int) error) []error {
+	errs := make([]error, n)
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-start
+			errs[i] = book(i)
+		}(i)
+	}
+	close(start)
+	wg.Wait()
+	return errs
+}
+
+func testConcurrentBookings(h *harness) {
+	t := h.t
+	truckers := make([]*models.Trucker, contenders)
+	for i := range truckers {
+		truckers[i] = h.trucker(i+1, 20)
+	}
+
+	// Everyone sends BOOK for the same single-truck load; exactly one wins
+	load := h.load("SH00001", nil)
+	errs := race(contenders, func(i int) error {
+		_, err := h.store.CreateBooking(h.ctx, load.LoadID, truckers[i].TruckerID, 0, actor)
+		return err
+	})
+	winners := 0
+	for i, err := range errs {
+		if err == nil {
+			winners++
+			continue
+		}
+		if !errors.Is(err, models.ErrUnavailable) {
+			t.Fatalf("trucker %d lost the race with %v, want load_unavailable", i+1, err)
+		}
+	}
+	if winners != 1 {
+		t.Fatalf("%d of %d simultaneous bookings of one load succeeded, want exactly 1", winners, contenders)
+	}
+	got := h.getLoad(load.LoadID)
+	if got.Status != models.LoadStatusBooked || got.TrucksBooked != 1 || got.BookedWeight != load.Weight {
+		t.Fatalf("raced load = %s with %d trucks and %.1f t, want booked with 1 truck", got.Status, got.TrucksBooked, got.BookedWeight)
+	}
+	bookings, err := h.store.GetBookingsByLoad(h.ctx, load.LoadID)
+	expectNoError(t, err, "GetBookingsByLoad")
+	if len(bookings) != 1 {
+		t.Fatalf("raced load has %d bookings, want 1", len(bookings))
+	}
+	if h.getTrucker(bookings[0].TruckerID).Available {
+		t.Fatalf("winning trucker is still available")
+	}
+
+	// A three-truck load takes exactly three of the rest
+	multi := h.load("SH00001", func(load *models.Load) {
+		load.Weight = 30
+		load.TruckCount = 3
+	})
+	rest := truckers[1:]
+	errs = race(len(rest), func(i int) error {
+		_, err := h.store.CreateBooking(h.ctx, multi.LoadID, rest[i].TruckerID, 0, actor)
+		return err
+	})
+	winners = 0
+	for _, err := range errs {
+		if err == nil {
+			winners++
+		} else if !errors.Is(err, models.ErrUnavailable) {
+			t.Fatalf("booking a three-truck load failed with %v, want success or load_unavailable", err)
+		}
+	}
+	got = h.getLoad(multi.LoadID)
+	if winners != 3 || got.TrucksBooked != 3 || got.Status != models.LoadStatusBooked {
+		t.Fatalf("%d bookings of a three-truck load succeeded and it is %s with %d trucks, want 3 and booked",
+			winners, got.Status, got.TrucksBooked)
+	}
+
+	// One trucker booking many loads at once gets only one of them
+	trucker := h.trucker(contenders+1, 20)
+	loads := make([]*models.Load, 20)
+	for i := range loads {
+		loads[i] = h.load("SH00001", nil)
+	}
+	errs = race(len(loads), func(i int) error {
+		_, err := h.store.CreateBooking(h.ctx, loads[i].LoadID, trucker.TruckerID, 0, actor)
+		return err
+	})
+	winners = 0
+	for _, err := range errs {
+		if err == nil {
+			winners++
+		} else if !errors.Is(err, models.ErrUnavailable) {
+			t.Fatalf("booking while the truck is taken failed with %v, want trucker unavailable", err)
+		}
+	}
+	active, err := h.store.GetBookingsByTrucker(h.ctx, trucker.TruckerID)
+	expectNoError(t, err, "GetBookingsByTrucker")
+	if winners != 1 || len(active) != 1 {
+		t.Fatalf("one trucker won %d of %d simultaneous loads with %d bookings, want 1", winners, len(loads), len(active))
+	}
+}
+
+func testConcurrentRegistrations(h *harness) {
+	t := h.t
+	// Everyone registers the same phone number at once; exactly one gets it
+	errs := race(contenders, func(i int) error {
+		_, err := h.store.CreateTrucker(h.ctx, &models.TruckerRegistration{
+			Name:        fmt.Sprintf("Trucker %d", i+1),
+			Phone:       "+919800099999",
+			VehicleNo:   fmt.Sprintf("KA01CD%04d", i+1),
+			VehicleType: "32ft",
+			Capacity:    20,
+		}, actor)
+		return err
+	})
+	winners := 0
+	for _, err := range errs {
+		if err == nil {
+			winners++
+			continue
+		}
+		expectError(t, err, models.ErrConflict, "trucker_phone_conflict")
+	}
+	if winners != 1 {
+		t.Fatalf("%d of %d simultaneous registrations of one phone succeeded, want exactly 1", winners, contenders)
+	}
+
+	// The same for a shipper's GST number
+	errs = race(contenders, func(i int) error {
+		_, err := h.store.CreateShipper(h.ctx, &models.Shipper{
+			CompanyName: fmt.Sprintf("Shipper %d", i+1),
+			GSTNumber:   "27BBBBB9999B1Z5",
+			Phone:       fmt.Sprintf("+9199100%05d", i+1),
+			City:        "Mumbai",
+		}, actor)
+		return err
+	})
+	winners = 0
+	for _, err := range errs {
+		if err == nil {
+			winners++
+			continue
+		}
+		expectError(t, err, models.ErrConflict, "shipper_gst_number_conflict")
+	}
+	if winners != 1 {
+		t.Fatalf("%d of %d simultaneous registrations of one GST number succeeded, want exactly 1", winners, contenders)
+	}
+}
+
+func testVersions(h *harness) {
+	t := h.t
+	load := h.load("SH00001", nil)
+	trucker := h.trucker(1, 20)
+	if load.Version != 1 || trucker.Version != 1 {
+		t.Fatalf("new load and trucker at versions %d and %d, want 1", load.Version, trucker.Version)
+	}
+	// expecting is a request context sending If-Match: version
+	expecting := func(version uint) context.Context {
+		return models.WithExpectedVersion(h.ctx, version)
+	}
+
+	// Updating a load from a stale read fails and changes nothing
+	price := 18000.0
+	_, err := h.store.UpdateLoad(expecting(2), load.LoadID, &models.LoadChanges{Price: &price}, actor)
+	expectError(t, err, models.ErrPreconditionFailed, "load_precondition_failed")
+	if got := h.getLoad(load.LoadID); got.Price != load.Price || got.Version != 1 {
+		t.Fatalf("load after a stale edit = ₹%.0f at version %d, want unchanged", got.Price, got.Version)
+	}
+	updated, err := h.store.UpdateLoad(expecting(1), load.LoadID, &models.LoadChanges{Price: &price}, actor)
+	expectNoError(t, err, "UpdateLoad at the current version")
+	if got := h.getLoad(load.LoadID); updated.Version != 2 || got.Version != 2 || got.Price != price {
+		t.Fatalf("edited load at versions %d/%d for ₹%.0f, want version 2", updated.Version, got.Version, got.Price)
+	}
+	_, err = h.store.RenewLoad(expecting(1), load.LoadID, time.Now().Add(72*time.Hour), 0, actor)
+	expectError(t, err, models.ErrPreconditionFailed, "load_precondition_failed")
+	_, err = h.store.WithdrawLoad(expecting(1), load.LoadID, actor)
+	expectError(t, err, models.ErrPreconditionFailed, "load_precondition_failed")
+	expectError(t, h.store.UpdateLoadStatus(expecting(1), load.LoadID, models.LoadStatusExpired, actor),
+		models.ErrPreconditionFailed, "load_precondition_failed")
+
+	// Booking moves both the load and the trucker on a version
+	booking := h.book(load.LoadID, trucker.TruckerID, 0)
+	if booking.Version != 1 || h.getLoad(load.LoadID).Version != 3 || h.getTrucker(trucker.TruckerID).Version != 2 {
+		t.Fatalf("after booking: booking, load and trucker at versions %d, %d and %d, want 1, 3 and 2",
+			booking.Version, h.getLoad(load.LoadID).Version, h.getTrucker(trucker.TruckerID).Version)
+	}
+
+	expectError(t, h.store.UpdateBookingStatus(expecting(2), booking.BookingID, models.BookingStatusInTransit, actor),
+		models.ErrPreconditionFailed, "booking_precondition_failed")
+	if got := h.getBooking(booking.BookingID); got.Status != models.BookingStatusConfirmed {
+		t.Fatalf("booking after a stale status change = %s, want confirmed", got.Status)
+	}
+	expectNoError(t, h.store.UpdateBookingStatus(expecting(1), booking.BookingID, models.BookingStatusInTransit, actor), "UpdateBookingStatus")
+	expectError(t, h.store.UpdateBookingPOD(expecting(1), booking.BookingID, "https://example.com/pod.jpg", actor),
+		models.ErrPreconditionFailed, "booking_precondition_failed")
+	expectNoError(t, h.store.UpdateBookingPOD(expecting(2), booking.BookingID, "https://example.com/pod.jpg", actor), "UpdateBookingPOD")
+	if got := h.getBooking(booking.BookingID); got.Version != 3 {
+		t.Fatalf("booking at version %d after two updates, want 3", got.Version)
+	}
+
+	expectError(t, h.store.UpdateTruckerLocation(expecting(1), trucker.TruckerID, "Pune", "", actor),
+		models.ErrPreconditionFailed, "trucker_precondition_failed")
+	expectNoError(t, h.store.UpdateTruckerLocation(expecting(2), trucker.TruckerID, "Pune", "", actor), "UpdateTruckerLocation")
+
+	// Delivery updates the load and the trucker as side effects
+	h.setBookingStatus(booking.BookingID, models.BookingStatusDelivered)
+	if got := h.getLoad(load.LoadID); got.Status != models.LoadStatusDelivered || got.Version != 4 {
+		t.Fatalf("delivered load = %s at version %d, want delivered at version 4", got.Status, got.Version)
+	}
+	if got := h.getTrucker(trucker.TruckerID); !got.Available || got.Version != 4 {
+		t.Fatalf("trucker after delivery available=%v at version %d, want available at version 4", got.Available, got.Version)
+	}
+}
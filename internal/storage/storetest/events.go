@@ -0,0 +1,233 @@
+package storetest
+
+import (
+	"errors"
+	"time"
+
+	"github.com/Ananth-NQI/truckpe-backend/internal/models"
+)
+
+func testEvents(h *harness) {
+	t := h.t
+	sub := h.bus.Subscribe(100)
+	defer sub.Close()
+
+	shipper := h.shipper(1)
+	load := h.load(shipper.ShipperID, nil)
+	trucker := h.trucker(1, 20)
+	booking := h.book(load.LoadID, trucker.TruckerID, 0)
+
+	// Failed mutations record and publish nothing
+	_, err := h.store.CreateBooking(h.ctx, load.LoadID, h.trucker(2, 20).TruckerID, 0, actor)
+	expectError(t, err, models.ErrUnavailable, "load_unavailable")
+
+	var published []*models.Event
+	for len(published) < 7 {
+		select {
+		case event := <-sub.C:
+			published = append(published, event)
+		case <-time.After(time.Second):
+			t.Fatalf("got %d events on the bus, want 7", len(published))
+		}
+	}
+	select {
+	case event := <-sub.C:
+		t.Fatalf("unexpected event on the bus: %s %s", event.EventType, event.EntityID)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	var types []string
+	for _, event := range published {
+		if event.ID == 0 {
+			t.Fatalf("published event %s has no ID", event.EventType)
+		}
+		if event.ActorID != actor.ID || event.Channel != actor.Channel {
+			t.Fatalf("event %s by %s/%s, want %s/%s", event.EventType, event.ActorID, event.Channel, actor.ID, actor.Channel)
+		}
+		types = append(types, event.EventType)
+	}
+	expectStrings(t, types, []string{
+		models.EventShipperRegistered,
+		models.EventLoadCreated,
+		models.EventTruckerRegistered,
+		models.EventBookingCreated,
+		models.EventLoadStatusChanged,
+		models.EventTruckerUpdated,
+		models.EventTruckerRegistered,
+	}, "published events")
+
+	// The log holds the same events with their parties and changes
+	recorded, err := h.store.GetEvents(h.ctx, models.EntityBooking, booking.BookingID)
+	expectNoError(t, err, "GetEvents")
+	if len(recorded) != 1 {
+		t.Fatalf("GetEvents = %d booking events, want 1", len(recorded))
+	}
+	event := recorded[0]
+	if event.ShipperID != shipper.ShipperID || event.TruckerID != trucker.TruckerID || event.OccurredAt.IsZero() ||
+		event.Before != nil || event.After["load_id"] != load.LoadID || event.After["status"] != models.BookingStatusConfirmed {
+		t.Fatalf("booking event = %+v, want it for %s and %s with the new booking", event, shipper.ShipperID, trucker.TruckerID)
+	}
+
+	recorded, err = h.store.GetEvents(h.ctx, models.EntityLoad, load.LoadID)
+	expectNoError(t, err, "GetEvents")
+	if len(recorded) != 2 || recorded[1].Before["status"] != models.LoadStatusAvailable || recorded[1].After["status"] != models.LoadStatusBooked ||
+		recorded[1].After["booking_id"] != booking.BookingID {
+		t.Fatalf("load events = %d, want creation then available → booked by %s", len(recorded), booking.BookingID)
+	}
+
+	expectStrings(t, h.eventTypes(models.EntityLoad, "LD0"), []string{}, "events of an unknown load")
+}
+
+func testExport(h *harness) {
+	t := h.t
+	first := h.load("SH00001", nil)
+	booked := h.load("SH00001", nil)
+	h.load("SH00002", nil)
+	last := h.load("SH00001", nil)
+
+	trucker := h.trucker(1, 20)
+	other := h.trucker(2, 20)
+	delivered := h.book(booked.LoadID, trucker.TruckerID, 0)
+	open := h.book(last.LoadID, other.TruckerID, 0)
+	h.setBookingStatus(delivered.BookingID, models.BookingStatusDelivered)
+
+	eachLoad := func(filter models.ExportFilter) []string {
+		t.Helper()
+		ids := []string{}
+		expectNoError(t, h.store.EachLoad(h.ctx, &filter, func(load *models.Load) error {
+			ids = append(ids, load.LoadID)
+			return nil
+		}), "EachLoad")
+		return ids
+	}
+	eachBooking := func(filter models.ExportFilter) []string {
+		t.Helper()
+		ids := []string{}
+		expectNoError(t, h.store.EachBooking(h.ctx, &filter, func(booking *models.Booking) error {
+			ids = append(ids, booking.BookingID)
+			return nil
+		}), "EachBooking")
+		return ids
+	}
+
+	past := time.Now().Add(-time.Hour)
+	future := time.Now().Add(time.Hour)
+
+	expectStrings(t, eachLoad(models.ExportFilter{ShipperID: "SH00001"}), []string{first.LoadID, booked.LoadID, last.LoadID}, "shipper loads in ID order")
+	expectStrings(t, eachLoad(models.ExportFilter{ShipperID: "SH00001", Status: models.LoadStatusDelivered}), []string{booked.LoadID}, "delivered loads")
+	expectStrings(t, eachLoad(models.ExportFilter{From: &past, To: &future, ShipperID: "SH00001"}), []string{first.LoadID, booked.LoadID, last.LoadID}, "loads created in range")
+	expectStrings(t, eachLoad(models.ExportFilter{From: &future}), []string{}, "loads created later")
+
+	expectStrings(t, eachBooking(models.ExportFilter{}), []string{delivered.BookingID, open.BookingID}, "bookings in ID order")
+	expectStrings(t, eachBooking(models.ExportFilter{TruckerID: other.TruckerID}), []string{open.BookingID}, "bookings by trucker")
+	expectStrings(t, eachBooking(models.ExportFilter{ShipperID: "SH00001", Status: models.BookingStatusConfirmed}), []string{open.BookingID}, "confirmed bookings")
+	expectStrings(t, eachBooking(models.ExportFilter{ByDeliveryDate: true}), []string{delivered.BookingID}, "delivered bookings")
+	expectStrings(t, eachBooking(models.ExportFilter{ByDeliveryDate: true, From: &past, To: &future}), []string{delivered.BookingID}, "bookings delivered in range")
+	expectStrings(t, eachBooking(models.ExportFilter{To: &past}), []string{}, "bookings created earlier")
+
+	// An error from fn stops the export and is returned as is
+	stop := errors.New("stop")
+	calls := 0
+	err := h.store.EachLoad(h.ctx, &models.ExportFilter{}, func(load *models.Load) error {
+		calls++
+		return stop
+	})
+	if !errors.Is(err, stop) || calls != 1 {
+		t.Fatalf("EachLoad with a failing fn = %v after %d calls, want stop after 1", err, calls)
+	}
+	calls = 0
+	err = h.store.EachBooking(h.ctx, &models.ExportFilter{}, func(booking *models.Booking) error {
+		calls++
+		return stop
+	})
+	if !errors.Is(err, stop) || calls != 1 {
+		t.Fatalf("EachBooking with a failing fn = %v after %d calls, want stop after 1", err, calls)
+	}
+}
+
+func testOutbox(h *harness) {
+	t := h.t
+	sub := h.bus.Subscribe(100)
+	defer sub.Close()
+
+	// Committed events are published and leave the outbox, failed mutations queue nothing
+	load := h.load("SH00001", nil)
+	booking := h.book(load.LoadID, h.trucker(1, 20).TruckerID, 0)
+	_, err := h.store.CreateBooking(h.ctx, load.LoadID, h.trucker(2, 20).TruckerID, 0, actor)
+	expectError(t, err, models.ErrUnavailable, "load_unavailable")
+
+	pending, err := h.store.GetPendingOutboxMessages(h.ctx, time.Now().Add(time.Hour), 0)
+	expectNoError(t, err, "GetPendingOutboxMessages")
+	if len(pending) != 0 {
+		t.Fatalf("%d events left in the outbox after publishing, want none", len(pending))
+	}
+
+	keys := make(map[string]bool)
+	for len(keys) < 6 {
+		select {
+		case event := <-sub.C:
+			if keys[event.IdempotencyKey()] {
+				t.Fatalf("event %s published twice", event.IdempotencyKey())
+			}
+			keys[event.IdempotencyKey()] = true
+		case <-time.After(time.Second):
+			t.Fatalf("got %d events on the bus, want 6", len(keys))
+		}
+	}
+	recorded, err := h.store.GetEvents(h.ctx, models.EntityBooking, booking.BookingID)
+	expectNoError(t, err, "GetEvents")
+	if len(recorded) != 1 || !keys[recorded[0].IdempotencyKey()] {
+		t.Fatalf("booking event was not published under its idempotency key")
+	}
+
+	// Clearing messages that are already gone is not an error
+	expectNoError(t, h.store.DeleteOutboxMessages(h.ctx, []uint{1, 2, 3}), "DeleteOutboxMessages")
+	expectNoError(t, h.store.DeleteOutboxMessages(h.ctx, nil), "DeleteOutboxMessages")
+
+	// An event a durable subscriber has no room for stays in the outbox
+	// until publishing it again reaches the subscriber
+	durable := h.bus.SubscribeDurable(0)
+	h.load("SH00001", nil)
+	pending, err = h.store.GetPendingOutboxMessages(h.ctx, time.Now().Add(time.Hour), 0)
+	expectNoError(t, err, "GetPendingOutboxMessages")
+	if len(pending) != 1 || pending[0].Event == nil {
+		t.Fatalf("%d events left in the outbox with a full durable subscriber, want 1", len(pending))
+	}
+	taken := make(chan *models.Event, 1)
+	go func() { taken <- <-durable.C }()
+	deadline := time.Now().Add(time.Second)
+	for !h.bus.Publish(pending[0].Event) {
+		if time.Now().After(deadline) {
+			t.Fatalf("durable subscriber never took event %s", pending[0].IdempotencyKey)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if event := <-taken; event.IdempotencyKey() != pending[0].IdempotencyKey {
+		t.Fatalf("durable subscriber got %s, want %s", event.IdempotencyKey(), pending[0].IdempotencyKey)
+	}
+	durable.Close()
+}
+
+func testHandledEvents(h *harness) {
+	t := h.t
+
+	handled, err := h.store.IsEventHandled(h.ctx, "alerts", "event-1")
+	expectNoError(t, err, "IsEventHandled")
+	if handled {
+		t.Fatalf("event-1 handled before it was marked")
+	}
+
+	// Marking twice is fine, and each consumer keeps its own record
+	expectNoError(t, h.store.MarkEventHandled(h.ctx, "alerts", "event-1"), "MarkEventHandled")
+	expectNoError(t, h.store.MarkEventHandled(h.ctx, "alerts", "event-1"), "MarkEventHandled")
+	handled, err = h.store.IsEventHandled(h.ctx, "alerts", "event-1")
+	expectNoError(t, err, "IsEventHandled")
+	if !handled {
+		t.Fatalf("event-1 not handled by alerts after marking it")
+	}
+	handled, err = h.store.IsEventHandled(h.ctx, "webhooks", "event-1")
+	expectNoError(t, err, "IsEventHandled")
+	if handled {
+		t.Fatalf("event-1 handled by webhooks after alerts marked it")
+	}
+}
@@ -0,0 +1,46 @@
+package storetest
+
+import (
+	"github.com/Ananth-NQI/truckpe-backend/internal/models"
+)
+
+func testIDs(h *harness) {
+	t := h.t
+	seen := map[string]bool{}
+	expectID := func(prefix, id string) {
+		t.Helper()
+		canonical, err := models.ParseID(prefix, id)
+		if err != nil || canonical != id {
+			t.Fatalf("ParseID(%s, %s) = %s, %v; want the ID back", prefix, id, canonical, err)
+		}
+		if seen[id] {
+			t.Fatalf("ID %s issued twice", id)
+		}
+		seen[id] = true
+	}
+
+	for n := 1; n <= 3; n++ {
+		shipper := h.shipper(n)
+		expectID(models.PrefixShipper, shipper.ShipperID)
+		trucker := h.trucker(n, 20)
+		expectID(models.PrefixTrucker, trucker.TruckerID)
+		load := h.load(shipper.ShipperID, nil)
+		expectID(models.PrefixLoad, load.LoadID)
+		expectID(models.PrefixBooking, h.book(load.LoadID, trucker.TruckerID, 0).BookingID)
+
+		alert, err := h.store.CreateLaneAlert(h.ctx, &models.LaneAlert{TruckerID: trucker.TruckerID, FromCity: "Mumbai"})
+		expectNoError(t, err, "CreateLaneAlert")
+		expectID(models.PrefixLaneAlert, alert.AlertID)
+		recurring, err := h.store.CreateRecurringLoad(h.ctx, newRecurring(shipper.ShipperID))
+		expectNoError(t, err, "CreateRecurringLoad")
+		expectID(models.PrefixRecurringLoad, recurring.RecurringID)
+		endpoint, err := h.store.CreateWebhookEndpoint(h.ctx, &models.WebhookEndpoint{ShipperID: shipper.ShipperID, URL: "https://example.com/hook"})
+		expectNoError(t, err, "CreateWebhookEndpoint")
+		expectID(models.PrefixWebhookEndpoint, endpoint.EndpointID)
+		delivery, err := h.store.CreateWebhookDelivery(h.ctx, &models.WebhookDelivery{
+			EndpointID: endpoint.EndpointID, ShipperID: shipper.ShipperID, EventType: models.WebhookEventDelivered,
+		})
+		expectNoError(t, err, "CreateWebhookDelivery")
+		expectID(models.PrefixWebhookDelivery, delivery.DeliveryID)
+	}
+}
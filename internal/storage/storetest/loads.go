@@ -0,0 +1,281 @@
+package storetest
+
+import (
+	"errors"
+	"time"
+
+	"github.com/Ananth-NQI/truckpe-backend/internal/models"
+)
+
+func testLoads(h *harness) {
+	t := h.t
+	first := h.load("SH00001", nil)
+	if first.LoadID == "" || first.ID == 0 {
+		t.Fatalf("CreateLoad did not assign IDs: %+v", first)
+	}
+	if first.Status != models.LoadStatusAvailable || first.TruckCount != 1 || first.ExpiresAt == nil {
+		t.Fatalf("new load has wrong defaults: status %s, trucks %d, expires %v", first.Status, first.TruckCount, first.ExpiresAt)
+	}
+	if want := first.LoadingDate.Add(models.LoadExpiry); !first.ExpiresAt.Equal(want) {
+		t.Fatalf("ExpiresAt = %v, want %v", first.ExpiresAt, want)
+	}
+
+	// Both the LoadID and the numeric ID find the load
+	for _, id := range []string{first.LoadID, numericID(first.ID)} {
+		if got := h.getLoad(id); got.LoadID != first.LoadID {
+			t.Fatalf("GetLoad(%s) = %s, want %s", id, got.LoadID, first.LoadID)
+		}
+	}
+	for _, id := range []string{"LD0", "999999", "unknown"} {
+		_, err := h.store.GetLoad(h.ctx, id)
+		expectError(t, err, models.ErrNotFound, "load_not_found")
+	}
+
+	// A batch is created in order
+	batch, err := h.store.CreateLoads(h.ctx, []*models.Load{newLoad("SH00001"), newLoad("SH00002")}, actor)
+	expectNoError(t, err, "CreateLoads")
+	if len(batch) != 2 || batch[0].LoadID == "" || batch[1].LoadID == "" || batch[0].LoadID == batch[1].LoadID {
+		t.Fatalf("CreateLoads = %v, want two loads with distinct IDs", loadIDs(batch))
+	}
+
+	// Loads past their expiry and loads no longer available are not listed
+	stale := h.load("SH00001", func(load *models.Load) {
+		expiresAt := time.Now().Add(-time.Hour)
+		load.ExpiresAt = &expiresAt
+	})
+	closed := h.load("SH00001", nil)
+	expectNoError(t, h.store.UpdateLoadStatus(h.ctx, closed.LoadID, models.LoadStatusInTransit, actor), "UpdateLoadStatus")
+	if got := h.getLoad(closed.LoadID); got.Status != models.LoadStatusInTransit {
+		t.Fatalf("status = %s, want %s", got.Status, models.LoadStatusInTransit)
+	}
+	expectError(t, h.store.UpdateLoadStatus(h.ctx, "LD0", models.LoadStatusBooked, actor), models.ErrNotFound, "load_not_found")
+
+	available, err := h.store.GetAvailableLoads(h.ctx)
+	expectNoError(t, err, "GetAvailableLoads")
+	expectStrings(t, loadIDs(available), []string{batch[1].LoadID, batch[0].LoadID, first.LoadID}, "available loads, newest first")
+
+	byShipper, err := h.store.GetLoadsByShipper(h.ctx, "SH00001")
+	expectNoError(t, err, "GetLoadsByShipper")
+	expectStrings(t, loadIDs(byShipper), []string{closed.LoadID, stale.LoadID, batch[0].LoadID, first.LoadID}, "shipper loads, newest first")
+	byShipper, err = h.store.GetLoadsByShipper(h.ctx, "SH99999")
+	expectNoError(t, err, "GetLoadsByShipper")
+	expectStrings(t, loadIDs(byShipper), []string{}, "loads of an unknown shipper")
+
+	expectStrings(t, h.eventTypes(models.EntityLoad, closed.LoadID),
+		[]string{models.EventLoadCreated, models.EventLoadStatusChanged}, "load events")
+}
+
+func testSearchLoads(h *harness) {
+	t := h.t
+	cheap := h.load("SH00001", nil)
+	heavy := h.load("SH00001", func(load *models.Load) {
+		load.Weight = 30
+		load.Price = 60000
+		load.VehicleType = "Trailer"
+	})
+	delhi := h.load("SH00001", func(load *models.Load) {
+		load.FromCity = "Delhi"
+		load.ToCity = "Jaipur"
+		load.Material = "Cotton"
+	})
+	booked := h.load("SH00001", nil)
+	expectNoError(t, h.store.UpdateLoadStatus(h.ctx, booked.LoadID, models.LoadStatusBooked, actor), "UpdateLoadStatus")
+
+	cases := []struct {
+		name   string
+		search models.LoadSearch
+		want   []string
+	}{
+		{"everything listed", models.LoadSearch{}, []string{delhi.LoadID, heavy.LoadID, cheap.LoadID}},
+		{"origin by alias", models.LoadSearch{FromCity: "Bombay"}, []string{heavy.LoadID, cheap.LoadID}},
+		{"destination", models.LoadSearch{ToCity: "jaipur"}, []string{delhi.LoadID}},
+		{"vehicle substring", models.LoadSearch{VehicleType: "trail"}, []string{heavy.LoadID}},
+		{"price range", models.LoadSearch{MinPrice: 30000, MaxPrice: 70000}, []string{heavy.LoadID}},
+		{"truck capacity", models.LoadSearch{MaxWeight: 20}, []string{delhi.LoadID, cheap.LoadID}},
+		{"material", models.LoadSearch{Material: "cott"}, []string{delhi.LoadID}},
+		{"no match", models.LoadSearch{FromCity: "Chennai"}, []string{}},
+	}
+	for _, c := range cases {
+		loads, err := h.store.SearchLoads(h.ctx, &c.search)
+		expectNoError(t, err, "SearchLoads "+c.name)
+		expectStrings(t, loadIDs(loads), c.want, "SearchLoads "+c.name)
+	}
+}
+
+func testListLoads(h *harness) {
+	t := h.t
+	var created []*models.Load
+	for _, price := range []float64{30000, 10000, 20000} {
+		price := price
+		created = append(created, h.load("SH00001", func(load *models.Load) { load.Price = price }))
+	}
+	h.load("SH00002", nil)
+
+	// Walk the shipper's loads by ascending price, two at a time
+	opts := &models.ListOptions{Limit: 2, Sort: models.SortPrice, Order: models.SortAsc}
+	page, err := h.store.ListLoads(h.ctx, &models.LoadFilter{ShipperID: "SH00001"}, opts)
+	expectNoError(t, err, "ListLoads")
+	expectStrings(t, loadIDs(page.Loads), []string{created[1].LoadID, created[2].LoadID}, "first page")
+	if page.NextCursor == "" {
+		t.Fatalf("first page has no NextCursor")
+	}
+	opts = &models.ListOptions{Limit: 2, Sort: models.SortPrice, Order: models.SortAsc, Cursor: page.NextCursor}
+	page, err = h.store.ListLoads(h.ctx, &models.LoadFilter{ShipperID: "SH00001"}, opts)
+	expectNoError(t, err, "ListLoads")
+	expectStrings(t, loadIDs(page.Loads), []string{created[0].LoadID}, "second page")
+	if page.NextCursor != "" {
+		t.Fatalf("last page has NextCursor %q", page.NextCursor)
+	}
+
+	// Newest first by default
+	page, err = h.store.ListLoads(h.ctx, &models.LoadFilter{ShipperID: "SH00001", Status: models.LoadStatusAvailable}, &models.ListOptions{})
+	expectNoError(t, err, "ListLoads")
+	expectStrings(t, loadIDs(page.Loads), []string{created[2].LoadID, created[1].LoadID, created[0].LoadID}, "default order")
+
+	_, err = h.store.ListLoads(h.ctx, &models.LoadFilter{}, &models.ListOptions{Sort: "weight"})
+	expectError(t, err, models.ErrInvalid, "sort_invalid")
+}
+
+func testLoadExpiry(h *harness) {
+	t := h.t
+	now := time.Now()
+	past := func(load *models.Load) {
+		expiresAt := now.Add(-time.Hour)
+		load.ExpiresAt = &expiresAt
+	}
+	soon := func(load *models.Load) {
+		expiresAt := now.Add(2 * time.Hour)
+		load.ExpiresAt = &expiresAt
+	}
+
+	expired := h.load("SH00001", past)
+	partlyBooked := h.load("SH00001", func(load *models.Load) {
+		load.TruckCount = 2
+		past(load)
+	})
+	expiring := h.load("SH00001", soon)
+	reminded := h.load("SH00001", soon)
+	h.load("SH00001", nil) // expires in three days
+
+	expectNoError(t, h.store.MarkLoadExpiryReminded(h.ctx, reminded.LoadID, now), "MarkLoadExpiryReminded")
+	expectError(t, h.store.MarkLoadExpiryReminded(h.ctx, "LD0", now), models.ErrNotFound, "load_not_found")
+
+	due, err := h.store.GetLoadsExpiringBefore(h.ctx, now.Add(3*time.Hour))
+	expectNoError(t, err, "GetLoadsExpiringBefore")
+	expectStrings(t, loadIDs(due), []string{expired.LoadID, partlyBooked.LoadID, expiring.LoadID}, "loads expiring, reminded ones left out")
+
+	// Book one truck of the two-truck load while it is listed, then let it lapse
+	renewed, err := h.store.RenewLoad(h.ctx, partlyBooked.LoadID, now.Add(24*time.Hour).Truncate(time.Second), 0, actor)
+	expectNoError(t, err, "RenewLoad")
+	h.book(renewed.LoadID, h.trucker(1, 20).TruckerID, 0)
+	_, err = h.store.RenewLoad(h.ctx, partlyBooked.LoadID, now.Add(-models.LoadExpiry-time.Hour), 0, actor)
+	expectNoError(t, err, "RenewLoad into the past")
+
+	// The partly booked load keeps its booking and is closed as booked
+	swept, err := h.store.ExpireLoads(h.ctx, now, models.SystemActor)
+	expectNoError(t, err, "ExpireLoads")
+	expectStrings(t, loadIDs(swept), []string{expired.LoadID, partlyBooked.LoadID}, "expired loads")
+	if got := h.getLoad(expired.LoadID); got.Status != models.LoadStatusExpired {
+		t.Fatalf("unbooked load status = %s, want %s", got.Status, models.LoadStatusExpired)
+	}
+	if got := h.getLoad(partlyBooked.LoadID); got.Status != models.LoadStatusBooked || got.TrucksBooked != 1 {
+		t.Fatalf("partly booked load = %s with %d trucks, want booked with 1", got.Status, got.TrucksBooked)
+	}
+	swept, err = h.store.ExpireLoads(h.ctx, now, models.SystemActor)
+	expectNoError(t, err, "ExpireLoads again")
+	expectStrings(t, loadIDs(swept), []string{}, "second sweep")
+
+	// Renewing relists an expired load with a new date, price and expiry
+	loadingDate := now.Add(72 * time.Hour).Truncate(time.Second)
+	renewed, err = h.store.RenewLoad(h.ctx, expired.LoadID, loadingDate, 25000, actor)
+	expectNoError(t, err, "RenewLoad")
+	got := h.getLoad(expired.LoadID)
+	if got.Status != models.LoadStatusAvailable || got.Price != 25000 || !got.LoadingDate.Equal(loadingDate) ||
+		got.ExpiresAt == nil || !got.ExpiresAt.Equal(loadingDate.Add(models.LoadExpiry)) || got.ExpiryRemindedAt != nil {
+		t.Fatalf("renewed load = %s ₹%.0f loading %v expiring %v, want available ₹25000 loading %v",
+			got.Status, got.Price, got.LoadingDate, got.ExpiresAt, loadingDate)
+	}
+	if renewed.LoadID != expired.LoadID {
+		t.Fatalf("RenewLoad returned %s, want %s", renewed.LoadID, expired.LoadID)
+	}
+
+	// A price of 0 keeps the price; booked loads cannot be renewed
+	renewed, err = h.store.RenewLoad(h.ctx, expired.LoadID, loadingDate, 0, actor)
+	expectNoError(t, err, "RenewLoad")
+	if renewed.Price != 25000 {
+		t.Fatalf("price = %.0f, want 25000 kept", renewed.Price)
+	}
+	_, err = h.store.RenewLoad(h.ctx, partlyBooked.LoadID, loadingDate, 0, actor)
+	expectError(t, err, models.ErrUnavailable, "load_unavailable")
+	_, err = h.store.RenewLoad(h.ctx, "LD0", loadingDate, 0, actor)
+	expectError(t, err, models.ErrNotFound, "load_not_found")
+
+	expectStrings(t, h.eventTypes(models.EntityLoad, expired.LoadID),
+		[]string{models.EventLoadCreated, models.EventLoadStatusChanged, models.EventLoadRenewed, models.EventLoadRenewed}, "expired load events")
+}
+
+func testLoadEditing(h *harness) {
+	t := h.t
+	load := h.load("SH00001", nil)
+	originalPrice := load.Price
+	originalDate := load.LoadingDate
+
+	// Changing nothing records nothing
+	unchanged, err := h.store.UpdateLoad(h.ctx, load.LoadID, &models.LoadChanges{}, actor)
+	expectNoError(t, err, "UpdateLoad without changes")
+	if unchanged.LoadID != load.LoadID {
+		t.Fatalf("UpdateLoad returned %s, want %s", unchanged.LoadID, load.LoadID)
+	}
+
+	price := 18000.0
+	loadingDate := originalDate.Add(24 * time.Hour)
+	vehicle := "Container"
+	updated, err := h.store.UpdateLoad(h.ctx, load.LoadID, &models.LoadChanges{Price: &price, LoadingDate: &loadingDate, VehicleType: &vehicle}, actor)
+	expectNoError(t, err, "UpdateLoad")
+	got := h.getLoad(load.LoadID)
+	if updated.Price != price || got.Price != price || !got.LoadingDate.Equal(loadingDate) || got.VehicleType != vehicle {
+		t.Fatalf("edited load = ₹%.0f %v %s, want ₹%.0f %v %s", got.Price, got.LoadingDate, got.VehicleType, price, loadingDate, vehicle)
+	}
+	if got.ExpiresAt == nil || !got.ExpiresAt.Equal(loadingDate.Add(models.LoadExpiry)) {
+		t.Fatalf("ExpiresAt = %v, want it moved with the loading date", got.ExpiresAt)
+	}
+
+	recorded, err := h.store.GetEvents(h.ctx, models.EntityLoad, load.LoadID)
+	expectNoError(t, err, "GetEvents")
+	last := recorded[len(recorded)-1]
+	if last.EventType != models.EventLoadUpdated || last.Before["price"] != originalPrice || last.After["price"] != price ||
+		last.Before["vehicle_type"] != "32ft" || last.After["vehicle_type"] != vehicle {
+		t.Fatalf("last event = %s %v → %v, want the edit", last.EventType, last.Before, last.After)
+	}
+	expectStrings(t, h.eventTypes(models.EntityLoad, load.LoadID),
+		[]string{models.EventLoadCreated, models.EventLoadUpdated}, "edited load events")
+
+	_, err = h.store.UpdateLoad(h.ctx, "LD0", &models.LoadChanges{Price: &price}, actor)
+	expectError(t, err, models.ErrNotFound, "load_not_found")
+
+	// Once a truck is booked the load can neither be edited nor withdrawn
+	booked := h.load("SH00001", func(load *models.Load) { load.TruckCount = 2 })
+	h.book(booked.LoadID, h.trucker(1, 20).TruckerID, 0)
+	_, err = h.store.UpdateLoad(h.ctx, booked.LoadID, &models.LoadChanges{Price: &price}, actor)
+	expectError(t, err, models.ErrUnavailable, "load_unavailable")
+	_, err = h.store.WithdrawLoad(h.ctx, booked.LoadID, actor)
+	expectError(t, err, models.ErrUnavailable, "load_unavailable")
+
+	withdrawn, err := h.store.WithdrawLoad(h.ctx, load.LoadID, actor)
+	expectNoError(t, err, "WithdrawLoad")
+	if withdrawn.Status != models.LoadStatusWithdrawn || h.getLoad(load.LoadID).Status != models.LoadStatusWithdrawn {
+		t.Fatalf("withdrawn load status = %s, want %s", withdrawn.Status, models.LoadStatusWithdrawn)
+	}
+	_, err = h.store.WithdrawLoad(h.ctx, load.LoadID, actor)
+	expectError(t, err, models.ErrUnavailable, "load_unavailable")
+	_, err = h.store.UpdateLoad(h.ctx, load.LoadID, &models.LoadChanges{Price: &price}, actor)
+	expectError(t, err, models.ErrUnavailable, "load_unavailable")
+	_, err = h.store.WithdrawLoad(h.ctx, "LD0", actor)
+	expectError(t, err, models.ErrNotFound, "load_not_found")
+
+	// A withdrawn load cannot be booked
+	_, err = h.store.CreateBooking(h.ctx, load.LoadID, h.trucker(2, 20).TruckerID, 0, actor)
+	if !errors.Is(err, models.ErrUnavailable) {
+		t.Fatalf("booking a withdrawn load: got %v, want %v", err, models.ErrUnavailable)
+	}
+}
@@ -0,0 +1,352 @@
+package storetest
+
+import (
+	"time"
+
+	"github.com/Ananth-NQI/truckpe-backend/internal/models"
+)
+
+func testTrackingLinks(h *harness) {
+	t := h.t
+	link, err := h.store.CreateTrackingLink(h.ctx, &models.TrackingLink{
+		Token:     "token-1",
+		BookingID: "BK1",
+		ShipperID: "SH00001",
+		ExpiresAt: time.Now().Add(24 * time.Hour),
+	}, actor)
+	expectNoError(t, err, "CreateTrackingLink")
+	if link.ID == 0 {
+		t.Fatalf("CreateTrackingLink did not assign an ID")
+	}
+	_, err = h.store.CreateTrackingLink(h.ctx, &models.TrackingLink{
+		Token: "token-2", BookingID: "BK1", ShipperID: "SH00001", ExpiresAt: time.Now().Add(time.Hour),
+	}, actor)
+	expectNoError(t, err, "CreateTrackingLink")
+
+	got, err := h.store.GetTrackingLink(h.ctx, "token-1")
+	expectNoError(t, err, "GetTrackingLink")
+	if got.BookingID != "BK1" || !got.IsActive() {
+		t.Fatalf("GetTrackingLink = %s active %v, want an active link for BK1", got.BookingID, got.IsActive())
+	}
+	_, err = h.store.GetTrackingLink(h.ctx, "unknown")
+	expectError(t, err, models.ErrNotFound, "tracking_link_not_found")
+
+	// Revoking covers every link of the booking, once
+	expectNoError(t, h.store.RevokeTrackingLinks(h.ctx, "BK1", actor), "RevokeTrackingLinks")
+	for _, token := range []string{"token-1", "token-2"} {
+		got, err = h.store.GetTrackingLink(h.ctx, token)
+		expectNoError(t, err, "GetTrackingLink")
+		if got.RevokedAt == nil || got.IsActive() {
+			t.Fatalf("link %s is still active after revoking", token)
+		}
+	}
+	expectNoError(t, h.store.RevokeTrackingLinks(h.ctx, "BK1", actor), "RevokeTrackingLinks again")
+	expectNoError(t, h.store.RevokeTrackingLinks(h.ctx, "BK0", actor), "RevokeTrackingLinks without links")
+
+	expectStrings(t, h.eventTypes(models.EntityBooking, "BK1"),
+		[]string{models.EventTrackingLinkCreated, models.EventTrackingLinkCreated, models.EventTrackingLinkRevoked}, "tracking link events")
+	expectStrings(t, h.eventTypes(models.EntityBooking, "BK0"), []string{}, "events of a booking without links")
+}
+
+func testWebhooks(h *harness) {
+	t := h.t
+	endpoint, err := h.store.CreateWebhookEndpoint(h.ctx, &models.WebhookEndpoint{
+		ShipperID:  "SH00001",
+		URL:        "https://example.com/hook",
+		Secret:     "secret",
+		EventTypes: models.WebhookEventDelivered,
+	})
+	expectNoError(t, err, "CreateWebhookEndpoint")
+	if endpoint.EndpointID == "" || !endpoint.Active {
+		t.Fatalf("new endpoint = %q active %v, want an active endpoint with an ID", endpoint.EndpointID, endpoint.Active)
+	}
+	second, err := h.store.CreateWebhookEndpoint(h.ctx, &models.WebhookEndpoint{ShipperID: "SH00001", URL: "https://example.com/other"})
+	expectNoError(t, err, "CreateWebhookEndpoint")
+
+	got, err := h.store.GetWebhookEndpoint(h.ctx, endpoint.EndpointID)
+	expectNoError(t, err, "GetWebhookEndpoint")
+	if got.URL != endpoint.URL || got.Secret != "secret" {
+		t.Fatalf("GetWebhookEndpoint = %s, want %s with its secret", got.URL, endpoint.URL)
+	}
+	_, err = h.store.GetWebhookEndpoint(h.ctx, "WH0")
+	expectError(t, err, models.ErrNotFound, "webhook_endpoint_not_found")
+
+	// Deactivated endpoints are still listed, oldest first
+	expectNoError(t, h.store.DeactivateWebhookEndpoint(h.ctx, second.EndpointID), "DeactivateWebhookEndpoint")
+	expectError(t, h.store.DeactivateWebhookEndpoint(h.ctx, "WH0"), models.ErrNotFound, "webhook_endpoint_not_found")
+	endpoints, err := h.store.GetWebhookEndpointsByShipper(h.ctx, "SH00001")
+	expectNoError(t, err, "GetWebhookEndpointsByShipper")
+	if len(endpoints) != 2 || endpoints[0].EndpointID != endpoint.EndpointID || !endpoints[0].Active || endpoints[1].Active {
+		t.Fatalf("GetWebhookEndpointsByShipper = %d endpoints, want the active one then the deactivated one", len(endpoints))
+	}
+
+	// Deliveries start pending and are due until scheduled for later
+	later := time.Now().Add(time.Hour)
+	var deliveries []*models.WebhookDelivery
+	for _, next := range []*time.Time{nil, &later, nil} {
+		delivery, err := h.store.CreateWebhookDelivery(h.ctx, &models.WebhookDelivery{
+			EndpointID:    endpoint.EndpointID,
+			ShipperID:     "SH00001",
+			EventType:     models.WebhookEventDelivered,
+			Payload:       `{"booking_id":"BK1"}`,
+			NextAttemptAt: next,
+		})
+		expectNoError(t, err, "CreateWebhookDelivery")
+		if delivery.DeliveryID == "" || delivery.Status != models.WebhookDeliveryPending {
+			t.Fatalf("new delivery = %q %s, want a pending delivery with an ID", delivery.DeliveryID, delivery.Status)
+		}
+		deliveries = append(deliveries, delivery)
+	}
+
+	due, err := h.store.GetDueWebhookDeliveries(h.ctx, time.Now())
+	expectNoError(t, err, "GetDueWebhookDeliveries")
+	expectStrings(t, deliveryIDs(due), []string{deliveries[0].DeliveryID, deliveries[2].DeliveryID}, "due deliveries, oldest first")
+
+	// Updates are kept; delivered ones are no longer due
+	delivered, err := h.store.GetWebhookDelivery(h.ctx, deliveries[0].DeliveryID)
+	expectNoError(t, err, "GetWebhookDelivery")
+	now := time.Now()
+	delivered.Status = models.WebhookDeliverySucceeded
+	delivered.Attempts = 1
+	delivered.ResponseCode = 200
+	delivered.DeliveredAt = &now
+	expectNoError(t, h.store.UpdateWebhookDelivery(h.ctx, delivered), "UpdateWebhookDelivery")
+	got2, err := h.store.GetWebhookDelivery(h.ctx, deliveries[0].DeliveryID)
+	expectNoError(t, err, "GetWebhookDelivery")
+	if got2.Status != models.WebhookDeliverySucceeded || got2.Attempts != 1 || got2.ResponseCode != 200 || got2.DeliveredAt == nil {
+		t.Fatalf("updated delivery = %s after %d attempts (%d), want succeeded after 1 (200)", got2.Status, got2.Attempts, got2.ResponseCode)
+	}
+	due, err = h.store.GetDueWebhookDeliveries(h.ctx, later.Add(time.Minute))
+	expectNoError(t, err, "GetDueWebhookDeliveries")
+	expectStrings(t, deliveryIDs(due), []string{deliveries[1].DeliveryID, deliveries[2].DeliveryID}, "due deliveries later on")
+
+	// Newest first, optionally by status
+	all, err := h.store.GetWebhookDeliveries(h.ctx, endpoint.EndpointID, "")
+	expectNoError(t, err, "GetWebhookDeliveries")
+	expectStrings(t, deliveryIDs(all), []string{deliveries[2].DeliveryID, deliveries[1].DeliveryID, deliveries[0].DeliveryID}, "deliveries, newest first")
+	pending, err := h.store.GetWebhookDeliveries(h.ctx, endpoint.EndpointID, models.WebhookDeliveryPending)
+	expectNoError(t, err, "GetWebhookDeliveries")
+	expectStrings(t, deliveryIDs(pending), []string{deliveries[2].DeliveryID, deliveries[1].DeliveryID}, "pending deliveries")
+
+	_, err = h.store.GetWebhookDelivery(h.ctx, "WD0")
+	expectError(t, err, models.ErrNotFound, "webhook_delivery_not_found")
+	err = h.store.UpdateWebhookDelivery(h.ctx, &models.WebhookDelivery{DeliveryID: "WD0", EndpointID: endpoint.EndpointID})
+	expectError(t, err, models.ErrNotFound, "webhook_delivery_not_found")
+	all, err = h.store.GetWebhookDeliveries(h.ctx, endpoint.EndpointID, "")
+	expectNoError(t, err, "GetWebhookDeliveries")
+	if len(all) != 3 {
+		t.Fatalf("updating an unknown delivery left %d deliveries, want 3", len(all))
+	}
+}
+
+func testLaneAlerts(h *harness) {
+	t := h.t
+	alert, err := h.store.CreateLaneAlert(h.ctx, &models.LaneAlert{TruckerID: "TR1", FromCity: "Mumbai", ToCity: "Pune"})
+	expectNoError(t, err, "CreateLaneAlert")
+	if alert.AlertID == "" || !alert.Active {
+		t.Fatalf("new alert = %q active %v, want an active alert with an ID", alert.AlertID, alert.Active)
+	}
+	anywhere, err := h.store.CreateLaneAlert(h.ctx, &models.LaneAlert{TruckerID: "TR1", FromCity: "Mumbai"})
+	expectNoError(t, err, "CreateLaneAlert")
+	_, err = h.store.CreateLaneAlert(h.ctx, &models.LaneAlert{TruckerID: "TR2", FromCity: "Delhi"})
+	expectNoError(t, err, "CreateLaneAlert")
+
+	got, err := h.store.GetLaneAlert(h.ctx, alert.AlertID)
+	expectNoError(t, err, "GetLaneAlert")
+	if got.FromCity != "Mumbai" || got.ToCity != "Pune" {
+		t.Fatalf("GetLaneAlert = %s → %s, want Mumbai → Pune", got.FromCity, got.ToCity)
+	}
+	_, err = h.store.GetLaneAlert(h.ctx, "AL0")
+	expectError(t, err, models.ErrNotFound, "lane_alert_not_found")
+
+	byTrucker, err := h.store.GetLaneAlertsByTrucker(h.ctx, "TR1")
+	expectNoError(t, err, "GetLaneAlertsByTrucker")
+	expectStrings(t, alertIDs(byTrucker), []string{alert.AlertID, anywhere.AlertID}, "alerts by trucker")
+	byOrigin, err := h.store.GetLaneAlertsByOrigin(h.ctx, "Bombay")
+	expectNoError(t, err, "GetLaneAlertsByOrigin")
+	expectStrings(t, alertIDs(byOrigin), []string{alert.AlertID, anywhere.AlertID}, "alerts by origin alias")
+
+	// Deactivated alerts are kept but no longer match
+	expectNoError(t, h.store.DeactivateLaneAlert(h.ctx, anywhere.AlertID), "DeactivateLaneAlert")
+	expectError(t, h.store.DeactivateLaneAlert(h.ctx, "AL0"), models.ErrNotFound, "lane_alert_not_found")
+	byOrigin, err = h.store.GetLaneAlertsByOrigin(h.ctx, "mumbai")
+	expectNoError(t, err, "GetLaneAlertsByOrigin")
+	expectStrings(t, alertIDs(byOrigin), []string{alert.AlertID}, "active alerts by origin")
+	byTrucker, err = h.store.GetLaneAlertsByTrucker(h.ctx, "TR1")
+	expectNoError(t, err, "GetLaneAlertsByTrucker")
+	expectStrings(t, alertIDs(byTrucker), []string{alert.AlertID}, "active alerts by trucker")
+	if got, err = h.store.GetLaneAlert(h.ctx, anywhere.AlertID); err != nil || got.Active {
+		t.Fatalf("deactivated alert = %v, %v; want it inactive", got, err)
+	}
+
+	// Notifications are counted per trucker since a time
+	now := time.Now()
+	for _, n := range []*models.AlertNotification{
+		{AlertID: alert.AlertID, TruckerID: "TR1", LoadID: "LD1", SentAt: now.Add(-48 * time.Hour)},
+		{AlertID: alert.AlertID, TruckerID: "TR1", LoadID: "LD2", SentAt: now},
+		{AlertID: alert.AlertID, TruckerID: "TR2", LoadID: "LD2", SentAt: now},
+	} {
+		expectNoError(t, h.store.CreateAlertNotification(h.ctx, n), "CreateAlertNotification")
+	}
+	count, err := h.store.CountAlertNotifications(h.ctx, "TR1", now.Add(-24*time.Hour))
+	expectNoError(t, err, "CountAlertNotifications")
+	if count != 1 {
+		t.Fatalf("CountAlertNotifications = %d, want 1", count)
+	}
+	notified, err := h.store.GetAlertNotificationsByLoad(h.ctx, "LD2")
+	expectNoError(t, err, "GetAlertNotificationsByLoad")
+	if len(notified) != 2 || notified[0].TruckerID != "TR1" || notified[1].TruckerID != "TR2" {
+		t.Fatalf("GetAlertNotificationsByLoad = %d notifications, want TR1 then TR2", len(notified))
+	}
+}
+
+func testBackhaul(h *harness) {
+	t := h.t
+	now := time.Now()
+	for _, s := range []*models.BackhaulSuggestion{
+		{TruckerID: "TR1", DeliveredBookingID: "BK1", LoadID: "LD1", Rank: 1, DistanceKm: 12, SentAt: now.Add(-10 * 24 * time.Hour)},
+		{TruckerID: "TR1", DeliveredBookingID: "BK2", LoadID: "LD2", Rank: 1, DistanceKm: 30, SentAt: now},
+		{TruckerID: "TR1", DeliveredBookingID: "BK2", LoadID: "LD3", Rank: 2, DistanceKm: 60, SentAt: now},
+		{TruckerID: "TR2", DeliveredBookingID: "BK3", LoadID: "LD2", Rank: 1, DistanceKm: 5, SentAt: now},
+	} {
+		expectNoError(t, h.store.CreateBackhaulSuggestion(h.ctx, s), "CreateBackhaulSuggestion")
+	}
+
+	expectNoError(t, h.store.ConvertBackhaulSuggestion(h.ctx, "TR1", "LD2", "BK9"), "ConvertBackhaulSuggestion")
+	expectError(t, h.store.ConvertBackhaulSuggestion(h.ctx, "TR1", "LD2", "BK10"), models.ErrNotFound, "backhaul_suggestion_not_found")
+	expectError(t, h.store.ConvertBackhaulSuggestion(h.ctx, "TR3", "LD1", "BK9"), models.ErrNotFound, "backhaul_suggestion_not_found")
+
+	stats, err := h.store.GetBackhaulStats(h.ctx, now.Add(-7*24*time.Hour))
+	expectNoError(t, err, "GetBackhaulStats")
+	if stats.Suggested != 3 || stats.Converted != 1 || stats.ConversionRate != 1.0/3 {
+		t.Fatalf("GetBackhaulStats = %+v, want 1 of 3 converted", stats)
+	}
+
+	suggested, err := h.store.GetBackhaulSuggestionsByLoad(h.ctx, "LD2")
+	expectNoError(t, err, "GetBackhaulSuggestionsByLoad")
+	if len(suggested) != 2 || suggested[0].TruckerID != "TR1" || suggested[0].ConvertedBookingID != "BK9" ||
+		suggested[0].ConvertedAt == nil || suggested[1].TruckerID != "TR2" || suggested[1].ConvertedBookingID != "" {
+		t.Fatalf("GetBackhaulSuggestionsByLoad = %d suggestions, want TR1's converted then TR2's", len(suggested))
+	}
+}
+
+func testRecurringLoads(h *harness) {
+	t := h.t
+	recurring, err := h.store.CreateRecurringLoad(h.ctx, newRecurring("SH00001"))
+	expectNoError(t, err, "CreateRecurringLoad")
+	if recurring.RecurringID == "" || recurring.ID == 0 {
+		t.Fatalf("CreateRecurringLoad did not assign IDs: %+v", recurring)
+	}
+	paused, err := h.store.CreateRecurringLoad(h.ctx, newRecurring("SH00001"))
+	expectNoError(t, err, "CreateRecurringLoad")
+	other, err := h.store.CreateRecurringLoad(h.ctx, newRecurring("SH00002"))
+	expectNoError(t, err, "CreateRecurringLoad")
+
+	got, err := h.store.GetRecurringLoad(h.ctx, recurring.RecurringID)
+	expectNoError(t, err, "GetRecurringLoad")
+	if got.Days != "mon,wed,fri" || got.Price != 20000 {
+		t.Fatalf("GetRecurringLoad = %s ₹%.0f, want mon,wed,fri ₹20000", got.Days, got.Price)
+	}
+	_, err = h.store.GetRecurringLoad(h.ctx, "RL0")
+	expectError(t, err, models.ErrNotFound, "recurring_load_not_found")
+
+	// Updates are kept; paused templates are not scheduled
+	got, err = h.store.GetRecurringLoad(h.ctx, paused.RecurringID)
+	expectNoError(t, err, "GetRecurringLoad")
+	got.Paused = true
+	got.Price = 22000
+	expectNoError(t, h.store.UpdateRecurringLoad(h.ctx, got), "UpdateRecurringLoad")
+	got, err = h.store.GetRecurringLoad(h.ctx, paused.RecurringID)
+	expectNoError(t, err, "GetRecurringLoad")
+	if !got.Paused || got.Price != 22000 {
+		t.Fatalf("updated recurring load = paused %v ₹%.0f, want paused ₹22000", got.Paused, got.Price)
+	}
+
+	byShipper, err := h.store.GetRecurringLoadsByShipper(h.ctx, "SH00001")
+	expectNoError(t, err, "GetRecurringLoadsByShipper")
+	expectStrings(t, recurringIDs(byShipper), []string{recurring.RecurringID, paused.RecurringID}, "recurring loads by shipper")
+	active, err := h.store.GetActiveRecurringLoads(h.ctx)
+	expectNoError(t, err, "GetActiveRecurringLoads")
+	expectStrings(t, recurringIDs(active), []string{recurring.RecurringID, other.RecurringID}, "active recurring loads")
+
+	unknown := newRecurring("SH00001")
+	unknown.RecurringID = "RL0"
+	expectError(t, h.store.UpdateRecurringLoad(h.ctx, unknown), models.ErrNotFound, "recurring_load_not_found")
+	byShipper, err = h.store.GetRecurringLoadsByShipper(h.ctx, "SH00001")
+	expectNoError(t, err, "GetRecurringLoadsByShipper")
+	if len(byShipper) != 2 {
+		t.Fatalf("updating an unknown recurring load left %d templates, want 2", len(byShipper))
+	}
+}
+
+func testRecurringOccurrences(h *harness) {
+	t := h.t
+	created, err := h.store.CreateRecurringLoad(h.ctx, newRecurring("SH00001"))
+	expectNoError(t, err, "CreateRecurringLoad")
+
+	// A change made from a stale copy fails instead of undoing another
+	first, err := h.store.GetRecurringLoad(h.ctx, created.RecurringID)
+	expectNoError(t, err, "GetRecurringLoad")
+	stale, err := h.store.GetRecurringLoad(h.ctx, created.RecurringID)
+	expectNoError(t, err, "GetRecurringLoad")
+	first.Paused = true
+	expectNoError(t, h.store.UpdateRecurringLoad(h.ctx, first), "UpdateRecurringLoad")
+	stale.Price = 25000
+	expectError(t, h.store.UpdateRecurringLoad(h.ctx, stale), models.ErrConflict, "recurring_load_version_conflict")
+	got, err := h.store.GetRecurringLoad(h.ctx, created.RecurringID)
+	expectNoError(t, err, "GetRecurringLoad")
+	if !got.Paused || got.Price != 20000 {
+		t.Fatalf("recurring load after a stale update = paused %v ₹%.0f, want paused ₹20000", got.Paused, got.Price)
+	}
+
+	// Posting an occurrence creates its load and advances ScheduledUntil together
+	got.Paused = false
+	expectNoError(t, h.store.UpdateRecurringLoad(h.ctx, got), "UpdateRecurringLoad")
+	loadingDate := time.Now().Add(48 * time.Hour).Truncate(time.Second)
+	load, err := h.store.PostRecurringOccurrence(h.ctx, got, loadingDate, actor)
+	expectNoError(t, err, "PostRecurringOccurrence")
+	if load.LoadID == "" || load.RecurringID != created.RecurringID || !load.LoadingDate.Equal(loadingDate) {
+		t.Fatalf("posted occurrence = %s from %q on %v, want a load from %s on %v", load.LoadID, load.RecurringID, load.LoadingDate, created.RecurringID, loadingDate)
+	}
+	posted, err := h.store.GetRecurringLoad(h.ctx, created.RecurringID)
+	expectNoError(t, err, "GetRecurringLoad")
+	if !posted.ScheduledUntil.Equal(loadingDate) || posted.Version != got.Version {
+		t.Fatalf("recurring load scheduled until %v at version %d, want %v at %d", posted.ScheduledUntil, posted.Version, loadingDate, got.Version)
+	}
+
+	// A second poster working from the same copy posts nothing
+	_, err = h.store.PostRecurringOccurrence(h.ctx, stale, loadingDate, actor)
+	expectError(t, err, models.ErrConflict, "recurring_load_version_conflict")
+	loads, err := h.store.GetLoadsByShipper(h.ctx, "SH00001")
+	expectNoError(t, err, "GetLoadsByShipper")
+	if len(loads) != 1 {
+		t.Fatalf("shipper has %d loads after posting one occurrence twice, want 1", len(loads))
+	}
+}
+
+// deliveryIDs lists the DeliveryIDs of webhook deliveries in order
+func deliveryIDs(deliveries []*models.WebhookDelivery) []string {
+	ids := []string{}
+	for _, delivery := range deliveries {
+		ids = append(ids, delivery.DeliveryID)
+	}
+	return ids
+}
+
+// alertIDs lists the AlertIDs of lane alerts in order
+func alertIDs(alerts []*models.LaneAlert) []string {
+	ids := []string{}
+	for _, alert := range alerts {
+		ids = append(ids, alert.AlertID)
+	}
+	return ids
+}
+
+// recurringIDs lists the RecurringIDs of recurring loads in order
+func recurringIDs(recurringLoads []*models.RecurringLoad) []string {
+	ids := []string{}
+	for _, recurring := range recurringLoads {
+		ids = append(ids, recurring.RecurringID)
+	}
+	return ids
+}
@@ -0,0 +1,143 @@
+package storetest
+
+import (
+	"time"
+
+	"github.com/Ananth-NQI/truckpe-backend/internal/models"
+)
+
+func testTruckers(h *harness) {
+	t := h.t
+	trucker := h.trucker(1, 20)
+	if trucker.TruckerID == "" || trucker.ID == 0 {
+		t.Fatalf("CreateTrucker did not assign IDs: %+v", trucker)
+	}
+	if !trucker.Available || trucker.Verified || trucker.Rating != 5 || trucker.TotalTrips != 0 {
+		t.Fatalf("new trucker has wrong defaults: %+v", trucker)
+	}
+
+	// Lookups by TruckerID, numeric ID and phone
+	for _, id := range []string{trucker.TruckerID, numericID(trucker.ID)} {
+		if got := h.getTrucker(id); got.TruckerID != trucker.TruckerID {
+			t.Fatalf("GetTrucker(%s) = %s, want %s", id, got.TruckerID, trucker.TruckerID)
+		}
+	}
+	byPhone, err := h.store.GetTruckerByPhone(h.ctx, trucker.Phone)
+	expectNoError(t, err, "GetTruckerByPhone")
+	if byPhone.TruckerID != trucker.TruckerID {
+		t.Fatalf("GetTruckerByPhone = %s, want %s", byPhone.TruckerID, trucker.TruckerID)
+	}
+
+	for _, id := range []string{"TR0", "999999", "unknown"} {
+		_, err = h.store.GetTrucker(h.ctx, id)
+		expectError(t, err, models.ErrNotFound, "trucker_not_found")
+	}
+	_, err = h.store.GetTruckerByPhone(h.ctx, "+910000000000")
+	expectError(t, err, models.ErrNotFound, "trucker_not_found")
+
+	// Phone and vehicle number are unique
+	_, err = h.store.CreateTrucker(h.ctx, &models.TruckerRegistration{
+		Name: "Copy", Phone: trucker.Phone, VehicleNo: "KA01ZZ9999", VehicleType: "19ft", Capacity: 9,
+	}, actor)
+	expectError(t, err, models.ErrConflict, "trucker_phone_conflict")
+	_, err = h.store.CreateTrucker(h.ctx, &models.TruckerRegistration{
+		Name: "Copy", Phone: "+919811111111", VehicleNo: trucker.VehicleNo, VehicleType: "19ft", Capacity: 9,
+	}, actor)
+	expectError(t, err, models.ErrConflict, "trucker_vehicle_no_conflict")
+
+	// Location updates are kept and audited
+	expectNoError(t, h.store.UpdateTruckerLocation(h.ctx, trucker.TruckerID, "Pune", "MH-PNQ", actor), "UpdateTruckerLocation")
+	if got := h.getTrucker(trucker.TruckerID); got.CurrentCity != "Pune" || got.CurrentCityID != "MH-PNQ" {
+		t.Fatalf("location = %s/%s, want Pune/MH-PNQ", got.CurrentCity, got.CurrentCityID)
+	}
+	err = h.store.UpdateTruckerLocation(h.ctx, "TR0", "Pune", "", actor)
+	expectError(t, err, models.ErrNotFound, "trucker_not_found")
+
+	expectStrings(t, h.eventTypes(models.EntityTrucker, trucker.TruckerID),
+		[]string{models.EventTruckerRegistered, models.EventTruckerUpdated}, "trucker events")
+}
+
+func testShippers(h *harness) {
+	t := h.t
+	shipper := h.shipper(1)
+	if shipper.ShipperID == "" || shipper.ID == 0 {
+		t.Fatalf("CreateShipper did not assign IDs: %+v", shipper)
+	}
+
+	for _, id := range []string{shipper.ShipperID, numericID(shipper.ID)} {
+		got, err := h.store.GetShipper(h.ctx, id)
+		expectNoError(t, err, "GetShipper")
+		if got.ShipperID != shipper.ShipperID {
+			t.Fatalf("GetShipper(%s) = %s, want %s", id, got.ShipperID, shipper.ShipperID)
+		}
+	}
+	byPhone, err := h.store.GetShipperByPhone(h.ctx, shipper.Phone)
+	expectNoError(t, err, "GetShipperByPhone")
+	byGST, err := h.store.GetShipperByGST(h.ctx, shipper.GSTNumber)
+	expectNoError(t, err, "GetShipperByGST")
+	if byPhone.ShipperID != shipper.ShipperID || byGST.ShipperID != shipper.ShipperID {
+		t.Fatalf("lookups found %s and %s, want %s", byPhone.ShipperID, byGST.ShipperID, shipper.ShipperID)
+	}
+
+	_, err = h.store.GetShipper(h.ctx, "SH99999")
+	expectError(t, err, models.ErrNotFound, "shipper_not_found")
+	_, err = h.store.GetShipperByPhone(h.ctx, "+910000000000")
+	expectError(t, err, models.ErrNotFound, "shipper_not_found")
+	_, err = h.store.GetShipperByGST(h.ctx, "00XXXXX0000X0X0")
+	expectError(t, err, models.ErrNotFound, "shipper_not_found")
+
+	// Phone and GST number are unique
+	_, err = h.store.CreateShipper(h.ctx, &models.Shipper{CompanyName: "Copy", GSTNumber: "29BBBBB0000B1Z5", Phone: shipper.Phone}, actor)
+	expectError(t, err, models.ErrConflict, "shipper_phone_conflict")
+	_, err = h.store.CreateShipper(h.ctx, &models.Shipper{CompanyName: "Copy", GSTNumber: shipper.GSTNumber, Phone: "+919922222222"}, actor)
+	expectError(t, err, models.ErrConflict, "shipper_gst_number_conflict")
+
+	second := h.shipper(2)
+	if second.ShipperID == shipper.ShipperID {
+		t.Fatalf("two shippers share ShipperID %s", shipper.ShipperID)
+	}
+
+	expectStrings(t, h.eventTypes(models.EntityShipper, shipper.ShipperID),
+		[]string{models.EventShipperRegistered}, "shipper events")
+}
+
+func testSessions(h *harness) {
+	t := h.t
+	_, err := h.store.GetSession(h.ctx, "+919800000001")
+	expectError(t, err, models.ErrNotFound, "session_not_found")
+
+	session := &models.WhatsAppSession{
+		PhoneNumber: "+919800000001",
+		LastCommand: "BOOK",
+		Context:     `{"load_id":"LD1"}`,
+		ExpiresAt:   time.Now().Add(time.Hour),
+	}
+	expectNoError(t, h.store.SaveSession(h.ctx, session), "SaveSession")
+
+	got, err := h.store.GetSession(h.ctx, session.PhoneNumber)
+	expectNoError(t, err, "GetSession")
+	if got.LastCommand != "BOOK" || got.Context != session.Context {
+		t.Fatalf("GetSession = %+v, want the saved session", got)
+	}
+
+	// Saving again replaces the phone number's session
+	expectNoError(t, h.store.SaveSession(h.ctx, &models.WhatsAppSession{
+		PhoneNumber: session.PhoneNumber,
+		LastCommand: "TRACK",
+		ExpiresAt:   time.Now().Add(time.Hour),
+	}), "SaveSession again")
+	got, err = h.store.GetSession(h.ctx, session.PhoneNumber)
+	expectNoError(t, err, "GetSession")
+	if got.LastCommand != "TRACK" || got.Context != "" {
+		t.Fatalf("GetSession = %+v, want the replaced session", got)
+	}
+
+	// Expired sessions are not returned
+	expectNoError(t, h.store.SaveSession(h.ctx, &models.WhatsAppSession{
+		PhoneNumber: session.PhoneNumber,
+		LastCommand: "TRACK",
+		ExpiresAt:   time.Now().Add(-time.Minute),
+	}), "SaveSession expired")
+	_, err = h.store.GetSession(h.ctx, session.PhoneNumber)
+	expectError(t, err, models.ErrNotFound, "session_not_found")
+}
@@ -0,0 +1,288 @@
+// Package storetest is a conformance suite for storage.Store implementations.
+// Every backend runs the same checks, so the memory store and the database
+// store agree on results, typed errors and side effects.
+package storetest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/Ananth-NQI/truckpe-backend/internal/events"
+	"github.com/Ananth-NQI/truckpe-backend/internal/models"
+	"github.com/Ananth-NQI/truckpe-backend/internal/storage"
+)
+
+// Factory creates an empty store that publishes its events to bus.
+// It is called once per subtest.
+type Factory func(t *testing.T, bus *events.Bus) storage.Store
+
+// harness is what each check gets: a fresh store, its bus and helpers to
+// seed it. Helpers fail the test on any error.
+type harness struct {
+	t     *testing.T
+	ctx   context.Context
+	store storage.Store
+	bus   *events.Bus
+}
+
+// actor is the user recorded on every mutation made by the suite
+var actor = models.Actor{ID: "storetest", Channel: models.ChannelSystem}
+
+// Run runs the whole suite against stores made by newStore
+func Run(t *testing.T, newStore Factory) {
+	checks := []struct {
+		name string
+		fn   func(h *harness)
+	}{
+		{"Truckers", testTruckers},
+		{"Shippers", testShippers},
+		{"IDs", testIDs},
+		{"Loads", testLoads},
+		{"SearchLoads", testSearchLoads},
+		{"ListLoads", testListLoads},
+		{"LoadExpiry", testLoadExpiry},
+		{"LoadEditing", testLoadEditing},
+		{"Bookings", testBookings},
+		{"MultiTruckLoads", testMultiTruckLoads},
+		{"PartLoads", testPartLoads},
+		{"BookingLifecycle", testBookingLifecycle},
+		{"ConcurrentBookings", testConcurrentBookings},
+		{"ConcurrentRegistrations", testConcurrentRegistrations},
+		{"Versions", testVersions},
+		{"ListBookings", testListBookings},
+		{"TruckerAdmin", testTruckerAdmin},
+		{"ShipperAdmin", testShipperAdmin},
+		{"AccountDeletion", testAccountDeletion},
+		{"LoadAdmin", testLoadAdmin},
+		{"Sessions", testSessions},
+		{"TrackingLinks", testTrackingLinks},
+		{"Webhooks", testWebhooks},
+		{"LaneAlerts", testLaneAlerts},
+		{"Backhaul", testBackhaul},
+		{"RecurringLoads", testRecurringLoads},
+		{"RecurringOccurrences", testRecurringOccurrences},
+		{"Events", testEvents},
+		{"Outbox", testOutbox},
+		{"HandledEvents", testHandledEvents},
+		{"Export", testExport},
+	}
+
+	for _, check := range checks {
+		check := check
+		t.Run(check.name, func(t *testing.T) {
+			bus := events.NewBus()
+			check.fn(&harness{
+				t:     t,
+				ctx:   context.Background(),
+				store: newStore(t, bus),
+				bus:   bus,
+			})
+		})
+	}
+}
+
+// trucker registers the nth test trucker with the given capacity in tons
+func (h *harness) trucker(n int, capacity float64) *models.Trucker {
+	h.t.Helper()
+	trucker, err := h.store.CreateTrucker(h.ctx, &models.TruckerRegistration{
+		Name:        fmt.Sprintf("Trucker %d", n),
+		Phone:       fmt.Sprintf("+9198000%05d", n),
+		VehicleNo:   fmt.Sprintf("MH12AB%04d", n),
+		VehicleType: "32ft",
+		Capacity:    capacity,
+	}, actor)
+	if err != nil {
+		h.t.Fatalf("CreateTrucker: %v", err)
+	}
+	return trucker
+}
+
+// shipper registers the nth test shipper
+func (h *harness) shipper(n int) *models.Shipper {
+	h.t.Helper()
+	shipper, err := h.store.CreateShipper(h.ctx, &models.Shipper{
+		CompanyName: fmt.Sprintf("Shipper %d", n),
+		GSTNumber:   fmt.Sprintf("27AAAAA%04dA1Z5", n),
+		Phone:       fmt.Sprintf("+9199000%05d", n),
+		City:        "Mumbai",
+	}, actor)
+	if err != nil {
+		h.t.Fatalf("CreateShipper: %v", err)
+	}
+	return shipper
+}
+
+// newLoad builds a 10 ton Mumbai to Pune load for ₹20,000, loading in two days
+func newLoad(shipperID string) *models.Load {
+	return &models.Load{
+		ShipperID:    shipperID,
+		ShipperName:  "Test Shipper",
+		ShipperPhone: "+919900000001",
+		FromCity:     "Mumbai",
+		ToCity:       "Pune",
+		Material:     "Steel",
+		Weight:       10,
+		VehicleType:  "32ft",
+		Price:        20000,
+		PaymentTerms: models.PaymentTermsAdvance,
+		LoadingDate:  time.Now().Add(48 * time.Hour).Truncate(time.Second),
+	}
+}
+
+// newRecurring builds a Mumbai to Pune load repeating Monday, Wednesday and Friday
+func newRecurring(shipperID string) *models.RecurringLoad {
+	return &models.RecurringLoad{
+		ShipperID:   shipperID,
+		ShipperName: "Test Shipper",
+		FromCity:    "Mumbai",
+		ToCity:      "Pune",
+		Material:    "Steel",
+		Weight:      10,
+		Price:       20000,
+		Days:        "mon,wed,fri",
+		Time:        "08:00",
+		StartDate:   time.Now().Truncate(24 * time.Hour),
+	}
+}
+
+// load creates newLoad after applying edit, which may be nil
+func (h *harness) load(shipperID string, edit func(load *models.Load)) *models.Load {
+	h.t.Helper()
+	load := newLoad(shipperID)
+	if edit != nil {
+		edit(load)
+	}
+	created, err := h.store.CreateLoad(h.ctx, load, actor)
+	if err != nil {
+		h.t.Fatalf("CreateLoad: %v", err)
+	}
+	return created
+}
+
+// book books a load for a trucker
+func (h *harness) book(loadID, truckerID string, weight float64) *models.Booking {
+	h.t.Helper()
+	booking, err := h.store.CreateBooking(h.ctx, loadID, truckerID, weight, actor)
+	if err != nil {
+		h.t.Fatalf("CreateBooking(%s, %s): %v", loadID, truckerID, err)
+	}
+	return booking
+}
+
+// setBookingStatus moves a booking to a new status
+func (h *harness) setBookingStatus(bookingID, status string) {
+	h.t.Helper()
+	if err := h.store.UpdateBookingStatus(h.ctx, bookingID, status, actor); err != nil {
+		h.t.Fatalf("UpdateBookingStatus(%s, %s): %v", bookingID, status, err)
+	}
+}
+
+// getLoad reads a load back from the store
+func (h *harness) getLoad(id string) *models.Load {
+	h.t.Helper()
+	load, err := h.store.GetLoad(h.ctx, id)
+	if err != nil {
+		h.t.Fatalf("GetLoad(%s): %v", id, err)
+	}
+	return load
+}
+
+// getTrucker reads a trucker back from the store
+func (h *harness) getTrucker(id string) *models.Trucker {
+	h.t.Helper()
+	trucker, err := h.store.GetTrucker(h.ctx, id)
+	if err != nil {
+		h.t.Fatalf("GetTrucker(%s): %v", id, err)
+	}
+	return trucker
+}
+
+// getBooking reads a booking back from the store
+func (h *harness) getBooking(id string) *models.Booking {
+	h.t.Helper()
+	booking, err := h.store.GetBooking(h.ctx, id)
+	if err != nil {
+		h.t.Fatalf("GetBooking(%s): %v", id, err)
+	}
+	return booking
+}
+
+// eventTypes lists the types of the events recorded for an entity, oldest first
+func (h *harness) eventTypes(entityType, entityID string) []string {
+	h.t.Helper()
+	recorded, err := h.store.GetEvents(h.ctx, entityType, entityID)
+	if err != nil {
+		h.t.Fatalf("GetEvents(%s, %s): %v", entityType, entityID, err)
+	}
+	types := []string{}
+	for _, event := range recorded {
+		types = append(types, event.EventType)
+	}
+	return types
+}
+
+// expectError checks an error's kind and, if code is not empty, its models.Error code
+func expectError(t *testing.T, err error, kind error, code string) {
+	t.Helper()
+	if !errors.Is(err, kind) {
+		t.Fatalf("got error %v, want %v", err, kind)
+	}
+	if code == "" {
+		return
+	}
+	var domainErr *models.Error
+	if !errors.As(err, &domainErr) {
+		t.Fatalf("got error %T %v, want *models.Error", err, err)
+	}
+	if domainErr.Code() != code {
+		t.Fatalf("got error code %q, want %q", domainErr.Code(), code)
+	}
+}
+
+// expectNoError fails the test on an unexpected error
+func expectNoError(t *testing.T, err error, what string) {
+	t.Helper()
+	if err != nil {
+		t.Fatalf("%s: %v", what, err)
+	}
+}
+
+// expectStrings compares two string lists, e.g. IDs or event types, in order
+func expectStrings(t *testing.T, got, want []string, what string) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("%s: got %v, want %v", what, got, want)
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			t.Fatalf("%s: got %v, want %v", what, got, want)
+		}
+	}
+}
+
+// numericID is the primary key of a row as a string, accepted wherever a public ID is
+func numericID(id uint) string {
+	return strconv.FormatUint(uint64(id), 10)
+}
+
+// loadIDs lists the LoadIDs of loads in order
+func loadIDs(loads []*models.Load) []string {
+	ids := []string{}
+	for _, load := range loads {
+		ids = append(ids, load.LoadID)
+	}
+	return ids
+}
+
+// bookingIDs lists the BookingIDs of bookings in order
+func bookingIDs(bookings []*models.Booking) []string {
+	ids := []string{}
+	for _, booking := range bookings {
+		ids = append(ids, booking.BookingID)
+	}
+	return ids
+}
@@ -1,8 +1,13 @@
 package main
 
 import (
+	"context"
 	"log"
 	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
@@ -11,6 +16,8 @@ import (
 	"github.com/joho/godotenv"
 
 	"github.com/Ananth-NQI/truckpe-backend/database"
+	"github.com/Ananth-NQI/truckpe-backend/internal/events"
+	"github.com/Ananth-NQI/truckpe-backend/internal/handlers"
 	"github.com/Ananth-NQI/truckpe-backend/internal/models" // Fixed: added 'internal'
 	"github.com/Ananth-NQI/truckpe-backend/internal/routes"
 	"github.com/Ananth-NQI/truckpe-backend/internal/storage"
@@ -25,49 +32,62 @@ func main() {
 		}
 	}
 
-	// Initialize storage
+	// "truckpe migrate ..." manages the schema instead of serving
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		os.Exit(runMigrate(os.Args[2:]))
+	}
+
+	// Unbooked loads are unlisted this many hours after their loading date
+	if hours, err := strconv.Atoi(os.Getenv("LOAD_EXPIRY_HOURS")); err == nil && hours > 0 {
+		models.LoadExpiry = time.Duration(hours) * time.Hour
+	}
+
+	// Initialize storage; every store mutation publishes to the event bus
 	var store storage.Store
+	var persisted *storage.MemoryStore // closed on shutdown, taking a final snapshot
+	bus := events.NewBus()
 
 	// Check if we should use memory store (for testing)
 	if os.Getenv("USE_MEMORY_STORE") == "true" {
-		log.Println("⚠️  Using in-memory storage (not for production!)")
-		store = storage.NewMemoryStore()
+		if dir := os.Getenv("MEMORY_STORE_DIR"); dir != "" {
+			// Keep the in-memory data across restarts in dir
+			memory, err := storage.OpenMemoryStore(dir, bus)
+			if err != nil {
+				log.Fatal("Failed to open memory store:", err)
+			}
+			interval := storage.DefaultSnapshotInterval
+			if minutes, err := strconv.Atoi(os.Getenv("MEMORY_SNAPSHOT_MINUTES")); err == nil && minutes > 0 {
+				interval = time.Duration(minutes) * time.Minute
+			}
+			memory.StartSnapshots(interval)
+			log.Printf("⚠️  Using in-memory storage persisted to %s (not for production!)", dir)
+			store = memory
+			persisted = memory
+		} else {
+			log.Println("⚠️  Using in-memory storage (not for production!)")
+			store = storage.NewMemoryStore(bus)
+		}
 	} else {
 		// Connect to database
-		log.Println("📦 Connecting to PostgreSQL database...")
+		log.Printf("📦 Connecting to %s...", getStorageType())
 		database.Connect()
 
 		// Run migrations
 		log.Println("🔄 Running database migrations...")
-		err := database.DB.AutoMigrate(
-			&models.Trucker{},
-			&models.Load{},
-			&models.Booking{},
-			&models.WhatsAppSession{},
-			&models.Shipper{},
-		)
-		if err != nil {
+		if err := database.Migrate(context.Background(), database.DB); err != nil {
 			log.Fatal("Failed to migrate database:", err)
 		}
 		log.Println("✅ Database migrations completed!")
 
 		// Use database store
-		store = storage.NewDatabaseStore(database.DB)
-		log.Println("✅ Using PostgreSQL database storage")
+		store = storage.NewDatabaseStore(database.DB, bus)
+		log.Printf("✅ Using %s storage", getStorageType())
 	}
 
 	// Create fiber app
 	app := fiber.New(fiber.Config{
-		AppName: "TruckPe Backend v1.0.0",
-		ErrorHandler: func(c *fiber.Ctx, err error) error {
-			code := fiber.StatusInternalServerError
-			if e, ok := err.(*fiber.Error); ok {
-				code = e.Code
-			}
-			return c.Status(code).JSON(fiber.Map{
-				"error": err.Error(),
-			})
-		},
+		AppName:      "TruckPe Backend v1.0.0",
+		ErrorHandler: handlers.ErrorHandler,
 	})
 
 	// Middleware
@@ -76,9 +96,10 @@ func main() {
 	}))
 	app.Use(recover.New())
 	app.Use(cors.New(cors.Config{
-		AllowOrigins: "*",
-		AllowHeaders: "Origin, Content-Type, Accept, Authorization",
-		AllowMethods: "GET, POST, PUT, DELETE, OPTIONS",
+		AllowOrigins:  "*",
+		AllowHeaders:  "Origin, Content-Type, Accept, Authorization, If-Match, X-Actor-ID, Last-Event-ID",
+		AllowMethods:  "GET, POST, PUT, PATCH, DELETE, OPTIONS",
+		ExposeHeaders: "ETag", // read back for If-Match
 	}))
 
 	// Health check endpoint with database status
@@ -138,7 +159,7 @@ func main() {
 	})
 
 	// Setup routes
-	routes.SetupRoutes(app, store)
+	routes.SetupRoutes(app, store, bus)
 
 	// Get port from environment or use default
 	port := os.Getenv("PORT")
@@ -154,7 +175,29 @@ func main() {
 	log.Println("========================================")
 	log.Println("✅ TEST: Logging is working!")
 
-	log.Fatal(app.Listen(":" + port))
+	// Cloud Run sends SIGTERM before replacing an instance; finish the
+	// requests in flight and close the store before exiting
+	go func() {
+		stop := make(chan os.Signal, 1)
+		signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
+		<-stop
+		log.Println("🛑 Shutting down...")
+		if err := app.ShutdownWithTimeout(10 * time.Second); err != nil {
+			log.Printf("❌ Server shutdown failed: %v", err)
+		}
+	}()
+
+	if err := app.Listen(":" + port); err != nil {
+		log.Fatal(err)
+	}
+
+	if persisted != nil {
+		if err := persisted.Close(); err != nil {
+			log.Fatal("Failed to close memory store:", err)
+		}
+		log.Println("✅ Memory store snapshot saved")
+	}
+	log.Println("👋 TruckPe Backend stopped")
 }
 
 func getEnvironment() string {
@@ -166,7 +209,13 @@ func getEnvironment() string {
 
 func getStorageType() string {
 	if os.Getenv("USE_MEMORY_STORE") == "true" {
+		if os.Getenv("MEMORY_STORE_DIR") != "" {
+			return "In-Memory (Persisted)"
+		}
 		return "In-Memory (Testing)"
 	}
+	if database.Driver() == database.DriverSQLite {
+		return "SQLite Database"
+	}
 	return "PostgreSQL Database"
 }
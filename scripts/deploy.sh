@@ -1,13 +1,23 @@
 #!/bin/bash
 # Deploy to Cloud Run with environment variables
 
+# Cloud Run runs on PostgreSQL (Cloud SQL): revisions overlap during a
+# rollout and a mounted bucket has no file locking, so SQLite is only for
+# single-node deployments on a local disk
+INSTANCE_CONNECTION_NAME=${INSTANCE_CONNECTION_NAME:-$(sed -n 's/^INSTANCE_CONNECTION_NAME: *//p' .env.yaml | tr -d "\"'")}
+if [ -z "$INSTANCE_CONNECTION_NAME" ]; then
+    echo "❌ Set INSTANCE_CONNECTION_NAME (project:region:instance), here or in .env.yaml"
+    exit 1
+fi
+
 echo "🚀 Deploying TruckPe to Cloud Run..."
 
 if gcloud run deploy truckpe-backend \
   --source . \
   --region=us-central1 \
   --env-vars-file .env.yaml \
-  --update-env-vars USE_MEMORY_STORE=true \
+  --update-env-vars DB_DRIVER=postgres,INSTANCE_CONNECTION_NAME="$INSTANCE_CONNECTION_NAME" \
+  --add-cloudsql-instances="$INSTANCE_CONNECTION_NAME" \
   --allow-unauthenticated; then
     echo "✅ Deployment complete!"
     echo "📱 Test with WhatsApp: +1 415 523 8886"
@@ -16,4 +26,4 @@ else
     echo "❌ Deployment failed! Check logs with:"
     echo "gcloud run services logs read truckpe-backend --region=us-central1 --limit=20"
     exit 1
-fi
\ No newline at end of file
+fi